@@ -110,6 +110,10 @@ var (
 		Name:  "nostack",
 		Usage: "disable stack output",
 	}
+	ForkFlag = cli.StringFlag{
+		Name:  "fork",
+		Usage: "The fork to execute at, activating it and all preceding ones (overrides the prestate config)",
+	}
 )
 
 func init() {
@@ -133,6 +137,7 @@ func init() {
 		ReceiverFlag,
 		DisableMemoryFlag,
 		DisableStackFlag,
+		ForkFlag,
 	}
 	app.Commands = []cli.Command{
 		compileCommand,
@@ -182,6 +182,11 @@ func runCmd(ctx *cli.Context) error {
 		defer pprof.StopCPUProfile()
 	}
 
+	if fork := ctx.GlobalString(ForkFlag.Name); fork != "" {
+		if chainConfig, err = runtime.ForkChainConfig(fork); err != nil {
+			utils.Fatalf("Invalid fork: %v", err)
+		}
+	}
 	if chainConfig != nil {
 		runtimeConfig.ChainConfig = chainConfig
 	}
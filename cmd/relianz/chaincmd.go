@@ -27,8 +27,10 @@ import (
 
 	"github.com/relianz2019/relianz/cmd/utils"
 	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
 	"github.com/relianz2019/relianz/console"
 	"github.com/relianz2019/relianz/core"
+	"github.com/relianz2019/relianz/core/rawdb"
 	"github.com/relianz2019/relianz/core/state"
 	"github.com/relianz2019/relianz/core/types"
 	"github.com/relianz2019/relianz/rlz/downloader"
@@ -41,6 +43,26 @@ import (
 )
 
 var (
+	dumpIterativeFlag = cli.BoolFlag{
+		Name:  "iterative",
+		Usage: "Stream the accounts as JSON lines instead of a single JSON object",
+	}
+	dumpNoCodeFlag = cli.BoolFlag{
+		Name:  "nocode",
+		Usage: "Exclude contract code from the iterative dump",
+	}
+	dumpNoStorageFlag = cli.BoolFlag{
+		Name:  "nostorage",
+		Usage: "Exclude contract storage from the iterative dump",
+	}
+	dumpStartFlag = cli.StringFlag{
+		Name:  "start",
+		Usage: "Hashed address to start the iterative dump from",
+	}
+	dumpLimitFlag = cli.IntFlag{
+		Name:  "limit",
+		Usage: "Maximum number of accounts to dump iteratively (default = all)",
+	}
 	initCommand = cli.Command{
 		Action:    utils.MigrateFlags(initGenesis),
 		Name:      "init",
@@ -54,7 +76,8 @@ var (
 		Description: `
 The init command initializes a new genesis block and definition for the network.
 This is a destructive action and changes the network in which you will be
-participating.
+participating. If the database already contains a chain, the new configuration
+is checked against it first and rejected if it would alter imported blocks.
 
 It expects the genesis file as argument.`,
 	}
@@ -163,11 +186,20 @@ Remove blockchain and state databases`,
 			utils.DataDirFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
+			dumpIterativeFlag,
+			dumpNoCodeFlag,
+			dumpNoStorageFlag,
+			dumpStartFlag,
+			dumpLimitFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The arguments are interpreted as block numbers or hashes.
-Use "relianz dump 0" to dump the genesis block.`,
+Use "relianz dump 0" to dump the genesis block.
+
+With --iterative the state is streamed one account per line in the order of the
+hashed addresses, without holding it in memory. A dump cut short by --limit ends
+with a line holding the hashed address to pass to --start to continue from.`,
 	}
 )
 
@@ -189,20 +221,28 @@ func initGenesis(ctx *cli.Context) error {
 	if err := json.NewDecoder(file).Decode(genesis); err != nil {
 		utils.Fatalf("invalid genesis file: %v", err)
 	}
-	// Open an initialise both full and light databases
+	writeGenesis(ctx, genesis)
+	return nil
+}
+
+// writeGenesis initialises both the full and light databases with the genesis
+// block, refusing to alter the blocks of an existing chain.
+func writeGenesis(ctx *cli.Context, genesis *core.Genesis) {
 	stack := makeFullNode(ctx)
 	for _, name := range []string{"chaindata", "lightchaindata"} {
 		chaindb, err := stack.OpenDatabase(name, 0, 0)
 		if err != nil {
 			utils.Fatalf("Failed to open database: %v", err)
 		}
+		if err := core.CheckGenesisCompatible(chaindb, genesis); err != nil {
+			utils.Fatalf("Refusing to write incompatible genesis block: %v", err)
+		}
 		_, hash, err := core.SetupGenesisBlock(chaindb, genesis)
 		if err != nil {
 			utils.Fatalf("Failed to write genesis block: %v", err)
 		}
 		log.Info("Successfully wrote genesis state", "database", name, "hash", hash)
 	}
-	return nil
 }
 
 func importChain(ctx *cli.Context) error {
@@ -245,20 +285,12 @@ func importChain(ctx *cli.Context) error {
 	chain.Stop()
 	fmt.Printf("Import done in %v.\n\n", time.Since(start))
 
-	// Output pre-compaction stats mostly to see the import trashing
-	db := chainDb.(*ethdb.LDBDatabase)
-
-	stats, err := db.LDB().GetProperty("leveldb.stats")
-	if err != nil {
-		utils.Fatalf("Failed to read database stats: %v", err)
-	}
-	fmt.Println(stats)
-
-	ioStats, err := db.LDB().GetProperty("leveldb.iostats")
-	if err != nil {
-		utils.Fatalf("Failed to read database iostats: %v", err)
+	// Output pre-compaction stats mostly to see the import trashing, only
+	// LevelDB reports any
+	db, isLDB := rawdb.KeyValueStore(chainDb).(*ethdb.LDBDatabase)
+	if isLDB {
+		printLDBStats(db)
 	}
-	fmt.Println(ioStats)
 
 	fmt.Printf("Trie cache misses:  %d\n", trie.CacheMisses())
 	fmt.Printf("Trie cache unloads: %d\n\n", trie.CacheUnloads())
@@ -272,31 +304,35 @@ func importChain(ctx *cli.Context) error {
 	fmt.Printf("Allocations:   %.3f million\n", float64(mem.Mallocs)/1000000)
 	fmt.Printf("GC pause:      %v\n\n", time.Duration(mem.PauseTotalNs))
 
-	if ctx.GlobalIsSet(utils.NoCompactionFlag.Name) {
+	if !isLDB || ctx.GlobalIsSet(utils.NoCompactionFlag.Name) {
 		return nil
 	}
 
 	// Compact the entire database to more accurately measure disk io and print the stats
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = db.LDB().CompactRange(util.Range{}); err != nil {
+	if err := db.LDB().CompactRange(util.Range{}); err != nil {
 		utils.Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
 
-	stats, err = db.LDB().GetProperty("leveldb.stats")
+	printLDBStats(db)
+	return nil
+}
+
+// printLDBStats prints the internal and io statistics of a LevelDB database.
+func printLDBStats(db *ethdb.LDBDatabase) {
+	stats, err := db.LDB().GetProperty("leveldb.stats")
 	if err != nil {
 		utils.Fatalf("Failed to read database stats: %v", err)
 	}
 	fmt.Println(stats)
 
-	ioStats, err = db.LDB().GetProperty("leveldb.iostats")
+	ioStats, err := db.LDB().GetProperty("leveldb.iostats")
 	if err != nil {
 		utils.Fatalf("Failed to read database iostats: %v", err)
 	}
 	fmt.Println(ioStats)
-
-	return nil
 }
 
 func exportChain(ctx *cli.Context) error {
@@ -337,7 +373,7 @@ func importPreimages(ctx *cli.Context) error {
 		utils.Fatalf("This command requires an argument.")
 	}
 	stack := makeFullNode(ctx)
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*ethdb.LDBDatabase)
+	diskdb := utils.MakeChainDatabase(ctx, stack)
 
 	start := time.Now()
 	if err := utils.ImportPreimages(diskdb, ctx.Args().First()); err != nil {
@@ -353,7 +389,7 @@ func exportPreimages(ctx *cli.Context) error {
 		utils.Fatalf("This command requires an argument.")
 	}
 	stack := makeFullNode(ctx)
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*ethdb.LDBDatabase)
+	diskdb := utils.MakeChainDatabase(ctx, stack)
 
 	start := time.Now()
 	if err := utils.ExportPreimages(diskdb, ctx.Args().First()); err != nil {
@@ -376,7 +412,7 @@ func copyDb(ctx *cli.Context) error {
 	dl := downloader.New(syncmode, chainDb, new(event.TypeMux), chain, nil, nil)
 
 	// Create a source peer to satisfy downloader requests from
-	db, err := ethdb.NewLDBDatabase(ctx.Args().First(), ctx.GlobalInt(utils.CacheFlag.Name), 256)
+	db, err := ethdb.NewDatabase("", ctx.Args().First(), ctx.GlobalInt(utils.CacheFlag.Name), 256)
 	if err != nil {
 		return err
 	}
@@ -401,12 +437,14 @@ func copyDb(ctx *cli.Context) error {
 	fmt.Printf("Database copy done in %v\n", time.Since(start))
 
 	// Compact the entire database to remove any sync overhead
-	start = time.Now()
-	fmt.Println("Compacting entire database...")
-	if err = chainDb.(*ethdb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
-		utils.Fatalf("Compaction failed: %v", err)
+	if diskdb, ok := rawdb.KeyValueStore(chainDb).(*ethdb.LDBDatabase); ok {
+		start = time.Now()
+		fmt.Println("Compacting entire database...")
+		if err = diskdb.LDB().CompactRange(util.Range{}); err != nil {
+			utils.Fatalf("Compaction failed: %v", err)
+		}
+		fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
 	}
-	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
 
 	return nil
 }
@@ -455,11 +493,27 @@ func dump(ctx *cli.Context) error {
 			fmt.Println("{}")
 			utils.Fatalf("block not found")
 		} else {
-			state, err := state.New(block.Root(), state.NewDatabase(chainDb))
+			statedb, err := state.New(block.Root(), state.NewDatabase(chainDb))
 			if err != nil {
 				utils.Fatalf("could not create new state: %v", err)
 			}
-			fmt.Printf("%s\n", state.Dump())
+			if !ctx.Bool(dumpIterativeFlag.Name) {
+				fmt.Printf("%s\n", statedb.Dump())
+				continue
+			}
+			conf := &state.DumpConfig{
+				SkipCode:    ctx.Bool(dumpNoCodeFlag.Name),
+				SkipStorage: ctx.Bool(dumpNoStorageFlag.Name),
+				Max:         ctx.Int(dumpLimitFlag.Name),
+			}
+			if ctx.IsSet(dumpStartFlag.Name) {
+				if conf.Start, err = hexutil.Decode(ctx.String(dumpStartFlag.Name)); err != nil {
+					utils.Fatalf("Invalid start hash: %v", err)
+				}
+			}
+			if err := statedb.IterativeDump(conf, json.NewEncoder(os.Stdout)); err != nil {
+				utils.Fatalf("Failed to dump state: %v", err)
+			}
 		}
 	}
 	chainDb.Close()
@@ -0,0 +1,272 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of go-relianz.
+//
+// go-relianz is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-relianz is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-relianz. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/relianz2019/relianz/cmd/utils"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/log"
+	"github.com/relianz2019/relianz/node"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var dbCommand = cli.Command{
+	Name:     "db",
+	Usage:    "Low level database operations",
+	Category: "BLOCKCHAIN COMMANDS",
+	Description: `
+
+The subcommands operate directly on the databases of a stopped node.`,
+	Subcommands: []cli.Command{
+		{
+			Name:      "freezer-info",
+			Usage:     "Show the content of the ancient chain store",
+			ArgsUsage: " ",
+			Action:    utils.MigrateFlags(freezerInfo),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.AncientFlag,
+			},
+			Description: `
+    relianz db freezer-info
+
+Canonical blocks older than --ancient.threshold are moved out of the key-value
+store into append-only files. The command repairs these files if a crash left
+them out of sync, then prints the number of items and the disk space of every
+table along with the range of blocks frozen.`,
+		},
+		{
+			Name:      "convert",
+			Usage:     "Copy the chain databases into another storage engine",
+			ArgsUsage: "<engine>",
+			Action:    utils.MigrateFlags(convertDB),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.CacheFlag,
+				utils.CacheDatabaseFlag,
+			},
+			Description: `
+    relianz db convert <engine>
+
+Copies every entry of the chain databases into a new database of the given
+engine, either leveldb or bolt, and swaps it in place of the original. The
+original database is kept next to it, suffixed with the name of its engine,
+while the ancient chain store within it is moved over to the new database.
+Should the conversion be interrupted, run the command again before starting the
+node to either finish or roll it back.`,
+		},
+	},
+}
+
+// freezerInfo prints the content of the ancient chain store.
+func freezerInfo(ctx *cli.Context) error {
+	if len(ctx.Args()) != 0 {
+		utils.Fatalf("This command takes no arguments")
+	}
+	stack, _ := makeConfigNode(ctx)
+
+	dir := ancientDir(ctx, stack)
+	info, err := rawdb.InspectFreezer(dir)
+	if err != nil {
+		utils.Fatalf("Failed to open ancient store: %v", err)
+	}
+	fmt.Println("Directory:", dir)
+	for _, table := range info.Tables {
+		fmt.Printf("%-10s %10d items %12s\n", table.Name, table.Items, table.Size)
+	}
+	if info.Frozen == 0 {
+		fmt.Println("No blocks frozen")
+		return nil
+	}
+	fmt.Printf("Blocks #0 [%x] - #%d [%x]\n", info.First, info.Frozen-1, info.Last)
+	return nil
+}
+
+// ancientDir returns the directory of the ancient chain store, defaulting to
+// within the chain database.
+func ancientDir(ctx *cli.Context, stack *node.Node) string {
+	dir := ctx.GlobalString(utils.AncientFlag.Name)
+	switch {
+	case dir == "":
+		dir = filepath.Join(stack.ResolvePath("chaindata"), "ancient")
+	case !filepath.IsAbs(dir):
+		dir = stack.ResolvePath(dir)
+	}
+	return dir
+}
+
+// convertingSuffix is appended to the directory a database is copied into while
+// being converted, until the copy is swapped in place of the original.
+const convertingSuffix = ".converting"
+
+// convertDB copies the chain databases into the storage engine given as argument,
+// keeping the originals as backups.
+func convertDB(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires the target engine as argument")
+	}
+	engine := ctx.Args().First()
+	if engine != ethdb.EngineLevelDB && engine != ethdb.EngineBolt {
+		utils.Fatalf("Unknown database engine %q, want one of %v", engine, ethdb.Engines)
+	}
+	stack, _ := makeConfigNode(ctx)
+	cache := ctx.GlobalInt(utils.CacheFlag.Name) * ctx.GlobalInt(utils.CacheDatabaseFlag.Name) / 100
+
+	for _, name := range []string{"chaindata", "lightchaindata"} {
+		if err := convertDatabase(log.New("database", name), stack.ResolvePath(name), engine, cache); err != nil {
+			utils.Fatalf("Failed to convert %s database: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// convertDatabase copies the database in dir into the given engine and swaps the
+// copy in place of the original, which is kept next to it as a backup. The copy
+// is only swapped in once complete, so a conversion interrupted by a crash is
+// either finished or rolled back when run again.
+func convertDatabase(logger log.Logger, dir string, engine string, cache int) error {
+	if err := recoverConversion(logger, dir); err != nil {
+		return err
+	}
+	current := ethdb.DetectEngine(dir)
+	switch current {
+	case "":
+		logger.Info("Database doesn't exist, skipping", "path", dir)
+		return nil
+	case engine:
+		logger.Info("Database already uses the engine, skipping", "engine", engine)
+		return nil
+	}
+	backup := dir + "." + current
+	if common.FileExist(backup) {
+		return fmt.Errorf("backup location %s already exists, move it out of the way first", backup)
+	}
+	tmp := dir + convertingSuffix
+	if err := copyDatabase(logger, dir, tmp, current, engine, cache); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	// The copy is complete, back the original up and swap the copy in
+	if err := os.Rename(dir, backup); err != nil {
+		return fmt.Errorf("failed to back up database: %v", err)
+	}
+	if err := swapConversion(tmp, dir, backup); err != nil {
+		return err
+	}
+	logger.Info("Database converted", "engine", engine, "backup", backup)
+	return nil
+}
+
+// recoverConversion finishes or rolls back a conversion of the database in dir
+// interrupted by a crash. As the original is only moved to its backup location
+// once the copy completed, a copy next to the original is an incomplete one and
+// is removed, while a copy next to the backup alone is swapped in.
+func recoverConversion(logger log.Logger, dir string) error {
+	tmp := dir + convertingSuffix
+	if !common.FileExist(tmp) {
+		return nil
+	}
+	if ethdb.DetectEngine(dir) != "" {
+		logger.Warn("Removing incomplete database conversion", "path", tmp)
+		return os.RemoveAll(tmp)
+	}
+	for _, engine := range ethdb.Engines {
+		if backup := dir + "." + engine; ethdb.DetectEngine(backup) == engine {
+			logger.Warn("Finishing interrupted database conversion", "path", tmp, "backup", backup)
+			if err := swapConversion(tmp, dir, backup); err != nil {
+				return err
+			}
+			logger.Info("Database converted", "engine", ethdb.DetectEngine(dir), "backup", backup)
+			return nil
+		}
+	}
+	return fmt.Errorf("interrupted conversion %s found without the original database", tmp)
+}
+
+// swapConversion moves the completed copy of a database in place of the backed
+// up original, carrying over the ancient store kept within the original.
+func swapConversion(tmp string, dir string, backup string) error {
+	if ancient := filepath.Join(backup, "ancient"); common.FileExist(ancient) {
+		if err := os.Rename(ancient, filepath.Join(tmp, "ancient")); err != nil {
+			return fmt.Errorf("failed to move ancient store: %v", err)
+		}
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return fmt.Errorf("failed to replace database: %v", err)
+	}
+	return nil
+}
+
+// copyDatabase copies every entry of the source database into a new database of
+// the target engine, reporting the progress periodically.
+func copyDatabase(logger log.Logger, src, dst string, from, to string, cache int) error {
+	sdb, err := ethdb.NewDatabase(from, src, cache, 256)
+	if err != nil {
+		return err
+	}
+	defer sdb.Close()
+
+	ddb, err := ethdb.NewDatabase(to, dst, cache, 256)
+	if err != nil {
+		return err
+	}
+	defer ddb.Close()
+
+	var (
+		it     = sdb.NewIterator()
+		batch  = ddb.NewBatch()
+		keys   uint64
+		size   common.StorageSize
+		start  = time.Now()
+		logged = time.Now()
+	)
+	defer it.Release()
+
+	for it.Next() {
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			return err
+		}
+		keys++
+		size += common.StorageSize(len(it.Key()) + len(it.Value()))
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			logger.Info("Converting database", "keys", keys, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	logger.Info("Copied database", "from", from, "to", to, "keys", keys, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of go-relianz.
+//
+// go-relianz is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-relianz is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-relianz. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/log"
+)
+
+// newConvertTestDatabase creates a LevelDB database with a few entries and an
+// ancient store within, returning its directory.
+func newConvertTestDatabase(t *testing.T, root string) string {
+	dir := filepath.Join(root, "chaindata")
+	db, err := ethdb.NewDatabase(ethdb.EngineLevelDB, dir, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if err := db.Put([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i))); err != nil {
+			t.Fatalf("failed to write database: %v", err)
+		}
+	}
+	db.Close()
+
+	if err := os.MkdirAll(filepath.Join(dir, "ancient"), 0755); err != nil {
+		t.Fatalf("failed to create ancient store: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "ancient", "headers.cidx"), []byte("ancient"), 0644); err != nil {
+		t.Fatalf("failed to write ancient store: %v", err)
+	}
+	return dir
+}
+
+// checkConvertedDatabase checks that the database in dir was fully converted into
+// bolt, with the ancient store and a LevelDB backup carried over.
+func checkConvertedDatabase(t *testing.T, dir string) {
+	if engine := ethdb.DetectEngine(dir); engine != ethdb.EngineBolt {
+		t.Fatalf("engine mismatch: have %q, want %q", engine, ethdb.EngineBolt)
+	}
+	if engine := ethdb.DetectEngine(dir + "." + ethdb.EngineLevelDB); engine != ethdb.EngineLevelDB {
+		t.Errorf("backup engine mismatch: have %q, want %q", engine, ethdb.EngineLevelDB)
+	}
+	if _, err := os.Stat(dir + convertingSuffix); !os.IsNotExist(err) {
+		t.Errorf("conversion directory left behind: %v", err)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "ancient", "headers.cidx")); err != nil || string(data) != "ancient" {
+		t.Errorf("ancient store not carried over: %q, %v", data, err)
+	}
+	db, err := ethdb.NewDatabase("", dir, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open converted database: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 100; i++ {
+		if value, err := db.Get([]byte(fmt.Sprintf("key%d", i))); err != nil || string(value) != fmt.Sprintf("value%d", i) {
+			t.Errorf("entry %d mismatch: have %q, %v", i, value, err)
+		}
+	}
+}
+
+// Tests that a database is converted into another engine, with the original
+// backed up and a second conversion refused while the backup is in the way.
+func TestConvertDatabase(t *testing.T) {
+	root, err := ioutil.TempDir("", "rlz-convert")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	dir := newConvertTestDatabase(t, root)
+	if err := convertDatabase(log.Root(), dir, ethdb.EngineBolt, 0); err != nil {
+		t.Fatalf("failed to convert database: %v", err)
+	}
+	checkConvertedDatabase(t, dir)
+
+	// Converting into the current engine is a no-op, converting back is refused
+	if err := convertDatabase(log.Root(), dir, ethdb.EngineBolt, 0); err != nil {
+		t.Errorf("failed to skip conversion into the current engine: %v", err)
+	}
+	if err := os.Rename(dir+"."+ethdb.EngineLevelDB, dir+"."+ethdb.EngineBolt); err != nil {
+		t.Fatalf("failed to move backup: %v", err)
+	}
+	if err := convertDatabase(log.Root(), dir, ethdb.EngineLevelDB, 0); err == nil {
+		t.Errorf("conversion over an existing backup accepted")
+	}
+}
+
+// Tests that conversions interrupted at every step are finished or rolled back
+// when the conversion is run again.
+func TestConvertDatabaseRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(dir string) error // Steps of the conversion done before the crash
+	}{
+		{
+			name: "copying",
+			prepare: func(dir string) error {
+				db, err := ethdb.NewDatabase(ethdb.EngineBolt, dir+convertingSuffix, 0, 0)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+				return db.Put([]byte("key0"), []byte("value0"))
+			},
+		},
+		{
+			name: "backed up",
+			prepare: func(dir string) error {
+				if err := copyDatabase(log.Root(), dir, dir+convertingSuffix, ethdb.EngineLevelDB, ethdb.EngineBolt, 0); err != nil {
+					return err
+				}
+				return os.Rename(dir, dir+"."+ethdb.EngineLevelDB)
+			},
+		},
+		{
+			name: "ancients moved",
+			prepare: func(dir string) error {
+				if err := copyDatabase(log.Root(), dir, dir+convertingSuffix, ethdb.EngineLevelDB, ethdb.EngineBolt, 0); err != nil {
+					return err
+				}
+				if err := os.Rename(dir, dir+"."+ethdb.EngineLevelDB); err != nil {
+					return err
+				}
+				return os.Rename(filepath.Join(dir+"."+ethdb.EngineLevelDB, "ancient"), filepath.Join(dir+convertingSuffix, "ancient"))
+			},
+		},
+	}
+	for _, tt := range tests {
+		root, err := ioutil.TempDir("", "rlz-convert")
+		if err != nil {
+			t.Fatalf("failed to create temporary directory: %v", err)
+		}
+		dir := newConvertTestDatabase(t, root)
+		if err := tt.prepare(dir); err != nil {
+			t.Fatalf("%s: failed to interrupt conversion: %v", tt.name, err)
+		}
+		if err := convertDatabase(log.Root(), dir, ethdb.EngineBolt, 0); err != nil {
+			t.Fatalf("%s: failed to recover conversion: %v", tt.name, err)
+		}
+		checkConvertedDatabase(t, dir)
+		os.RemoveAll(root)
+	}
+	// A conversion with neither the original nor a backup can't be recovered
+	root, err := ioutil.TempDir("", "rlz-convert")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	dir := filepath.Join(root, "chaindata")
+	if err := os.MkdirAll(dir+convertingSuffix, 0755); err != nil {
+		t.Fatalf("failed to create conversion directory: %v", err)
+	}
+	if err := convertDatabase(log.Root(), dir, ethdb.EngineBolt, 0); err == nil {
+		t.Errorf("orphaned conversion accepted")
+	}
+}
@@ -60,6 +60,9 @@ var (
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
+		utils.AncientFlag,
+		utils.AncientThresholdFlag,
+		utils.DBEngineFlag,
 		utils.KeyStoreDirFlag,
 		utils.NoUSBFlag,
 		utils.DashboardEnabledFlag,
@@ -75,12 +78,14 @@ var (
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolSnapshotFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
 		utils.TxPoolGlobalSlotsFlag,
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
+		utils.TxPoolGlobalBytesFlag,
 		utils.TxPoolLifetimeFlag,
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
@@ -92,6 +97,7 @@ var (
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheGCFlag,
+		utils.CacheSnapshotFlag,
 		utils.TrieCacheGenFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
@@ -122,6 +128,9 @@ var (
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
 		utils.ExtraDataFlag,
+		utils.SCAEnableFlag,
+		utils.SCAMainRPCAddrFlag,
+		utils.SCAMainRPCPortFlag,
 		configFileFlag,
 	}
 
@@ -176,6 +185,14 @@ func init() {
 		versionCommand,
 		bugCommand,
 		licenseCommand,
+		// See ufocmd.go:
+		ufoCommand,
+		// See sidechaincmd.go:
+		sidechainCommand,
+		// See snapshotcmd.go:
+		snapshotCommand,
+		// See dbcmd.go:
+		dbCommand,
 		// See config.go
 		dumpConfigCommand,
 	}
@@ -24,7 +24,13 @@ import (
 	"strings"
 
 	"github.com/relianz2019/relianz/cmd/utils"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/consensus"
 	"github.com/relianz2019/relianz/consensus/ethash"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/log"
+	"github.com/relianz2019/relianz/node"
 	"github.com/relianz2019/relianz/rlz"
 	"github.com/relianz2019/relianz/params"
 	"gopkg.in/urfave/cli.v1"
@@ -32,26 +38,28 @@ import (
 
 var (
 	makecacheCommand = cli.Command{
-		Action:    utils.MigrateFlags(makecache),
+		Action:    utils.MigrateFlags(minerFeatureAction(consensus.FeatureDataset, makecache)),
 		Name:      "makecache",
 		Usage:     "Generate ethash verification cache (for testing)",
 		ArgsUsage: "<blockNum> <outputDir>",
 		Category:  "MISCELLANEOUS COMMANDS",
 		Description: `
-The makecache command generates an ethash cache in <outputDir>.
+The makecache command generates an ethash cache in <outputDir>. It is only
+available on chains sealed by proof-of-work.
 
 This command exists to support the system testing project.
 Regular users do not need to execute it.
 `,
 	}
 	makedagCommand = cli.Command{
-		Action:    utils.MigrateFlags(makedag),
+		Action:    utils.MigrateFlags(minerFeatureAction(consensus.FeatureDataset, makedag)),
 		Name:      "makedag",
 		Usage:     "Generate ethash mining DAG (for testing)",
 		ArgsUsage: "<blockNum> <outputDir>",
 		Category:  "MISCELLANEOUS COMMANDS",
 		Description: `
-The makedag command generates an ethash DAG in <outputDir>.
+The makedag command generates an ethash DAG in <outputDir>. It is only
+available on chains sealed by proof-of-work.
 
 This command exists to support the system testing project.
 Regular users do not need to execute it.
@@ -76,6 +84,69 @@ The output of this command is supposed to be machine-readable.
 	}
 )
 
+// minerFeatureAction wraps the action of a command only meaningful to consensus
+// engines supporting the given miner feature, refusing to run it on chains
+// sealed by other engines. The engine is only created to ask for its features,
+// on top of a throwaway in-memory database.
+func minerFeatureAction(feature consensus.MinerFeature, action func(*cli.Context) error) func(*cli.Context) error {
+	return func(ctx *cli.Context) error {
+		stack, _ := makeConfigNode(ctx)
+		engine := utils.MakeEngine(ctx, stack, selectedChainConfig(ctx, stack), ethdb.NewMemDatabase())
+		if !consensus.HasFeature(consensus.MinerFeatures(engine), feature) {
+			utils.Fatalf("The consensus engine of the selected chain does not support the %s miner feature", feature)
+		}
+		return action(ctx)
+	}
+}
+
+// selectedChainConfig returns the configuration of the chain selected on the
+// command line, preferring the one stored in an existing data directory. If the
+// database can't be opened, e.g. as a running node holds it, the configuration
+// of the selected genesis is used instead.
+func selectedChainConfig(ctx *cli.Context, stack *node.Node) *params.ChainConfig {
+	config, err := storedChainConfig(ctx, stack)
+	if err != nil {
+		log.Warn("Failed to read stored chain config, using the selected genesis", "err", err)
+	}
+	if config != nil {
+		return config
+	}
+	if genesis := utils.MakeGenesis(ctx); genesis != nil {
+		return genesis.Config
+	}
+	return params.MainnetChainConfig
+}
+
+// storedChainConfig reads the chain configuration stored in the data directory,
+// returning nil if there is none. Only the key-value store is opened, looking up
+// the genesis hash in the ancient chain store if the genesis was frozen already.
+func storedChainConfig(ctx *cli.Context, stack *node.Node) (*params.ChainConfig, error) {
+	light := ctx.GlobalBool(utils.LightModeFlag.Name)
+
+	name := "chaindata"
+	if light {
+		name = "lightchaindata"
+	}
+	if _, err := os.Stat(stack.ResolvePath(name)); err != nil {
+		return nil, nil
+	}
+	db, err := stack.OpenDatabase(name, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	hash := rawdb.ReadCanonicalHash(db, 0)
+	if dir := ancientDir(ctx, stack); hash == (common.Hash{}) && !light && common.FileExist(dir) {
+		info, err := rawdb.InspectFreezer(dir)
+		if err != nil {
+			return nil, err
+		}
+		hash = info.First
+	}
+	return rawdb.ReadChainConfig(db, hash), nil
+}
+
 // makecache generates an ethash verification cache into the provided folder.
 func makecache(ctx *cli.Context) error {
 	args := ctx.Args()
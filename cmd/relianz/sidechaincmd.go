@@ -0,0 +1,139 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of go-relianz.
+//
+// go-relianz is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-relianz is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-relianz. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/relianz2019/relianz/cmd/utils"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/sidechain"
+	"github.com/relianz2019/relianz/core/ufo"
+	"github.com/relianz2019/relianz/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	sidechainFundFlag = cli.StringFlag{
+		Name:  "fund",
+		Usage: "Comma separated accounts carrying their main chain balance over to the side chain",
+	}
+	sidechainMainBlockFlag = cli.Uint64Flag{
+		Name:  "mainblock",
+		Usage: "Main chain block to derive the side chain from (default = latest)",
+	}
+	sidechainCommand = cli.Command{
+		Name:     "sidechain",
+		Usage:    "Manage Alien side chains anchored to a main chain",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+
+An Alien side chain is sealed by its own signers and periodically announces its
+blocks to the main chain it was derived from. Start a side chain node with --sca
+and the main chain RPC endpoint to anchor to.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "init",
+				Usage:     "Derive a side chain genesis block from the main chain",
+				ArgsUsage: "<chainId> <signer> [<signer>...]",
+				Action:    utils.MigrateFlags(sidechainInit),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.LightModeFlag,
+					utils.SCAMainRPCAddrFlag,
+					utils.SCAMainRPCPortFlag,
+					utils.SCAPeriod,
+					sidechainFundFlag,
+					sidechainMainBlockFlag,
+				},
+				Description: `
+    relianz sidechain init <chainId> <signer> [<signer>...]
+
+Derives the genesis block of a side chain with the given chain id from the state
+of the main chain reachable at --sca.mainrpcaddr and --sca.mainrpcport, and
+initializes the databases with it. The side chain is anchored to the latest main
+chain block unless --mainblock is given, and sealed every --sca.period seconds by
+the given signers until the first election. The signers and the accounts listed
+in --fund start out with the balances they hold on the main chain.
+
+The printed proposal payload registers the side chain on the main chain once the
+main chain signers accept it.`,
+			},
+		},
+	}
+)
+
+// sidechainInit derives a side chain genesis block from the main chain and
+// writes it into the databases.
+func sidechainInit(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 2 {
+		utils.Fatalf("This command requires a chain id and at least one signer as arguments")
+	}
+	chainID, ok := new(big.Int).SetString(args[0], 10)
+	if !ok || chainID.Sign() <= 0 {
+		utils.Fatalf("Invalid chain id %q", args[0])
+	}
+	config := &sidechain.GenesisConfig{
+		ChainID: chainID,
+		Period:  uint64(ctx.Int(utils.SCAPeriod.Name)),
+		Signers: parseSidechainAccounts(args[1:]),
+	}
+	if ctx.IsSet(sidechainFundFlag.Name) {
+		config.Funded = parseSidechainAccounts(strings.Split(ctx.String(sidechainFundFlag.Name), ","))
+	}
+	if ctx.IsSet(sidechainMainBlockFlag.Name) {
+		config.MainBlock = new(big.Int).SetUint64(ctx.Uint64(sidechainMainBlockFlag.Name))
+	}
+	endpoint := utils.MakeMainChainRPC(ctx)
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		utils.Fatalf("Failed to connect to main chain at %s: %v", endpoint, err)
+	}
+	defer client.Close()
+
+	genesis, err := sidechain.Genesis(context.Background(), sidechain.NewMainChain(client), config)
+	if err != nil {
+		utils.Fatalf("Failed to derive side chain genesis: %v", err)
+	}
+	writeGenesis(ctx, genesis)
+
+	hash := genesis.ToBlock(nil).Hash()
+	fmt.Println("Side chain genesis:", hash.Hex())
+	fmt.Println("Main chain anchor: ", common.BytesToHash(genesis.ExtraData[:common.HashLength]).Hex())
+	fmt.Println("Register payload:  ", string(ufo.Encode(&ufo.Proposal{
+		Type:          ufo.ProposalSideChainAdd,
+		ValidLoops:    1,
+		SideChainHash: &hash,
+	})))
+	return nil
+}
+
+// parseSidechainAccounts parses account arguments, aborting on failure.
+func parseSidechainAccounts(args []string) []common.Address {
+	accounts := make([]common.Address, 0, len(args))
+	for _, arg := range args {
+		if arg = strings.TrimSpace(arg); !common.IsHexAddress(arg) {
+			utils.Fatalf("Invalid account %q", arg)
+		}
+		accounts = append(accounts, common.HexToAddress(arg))
+	}
+	return accounts
+}
@@ -0,0 +1,141 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of go-relianz.
+//
+// go-relianz is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-relianz is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-relianz. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/core/state"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/rpc"
+)
+
+// sidechainTestAPI is a main chain stand-in serving headers and balances of a
+// static chain with head #9, recording the blocks balances were requested at.
+type sidechainTestAPI struct {
+	balances map[common.Address]*big.Int
+	queried  []rpc.BlockNumber
+	lock     sync.Mutex
+}
+
+func (api *sidechainTestAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) (*types.Header, error) {
+	if number < 0 {
+		number = 9
+	}
+	return &types.Header{
+		Number:     big.NewInt(int64(number)),
+		Time:       big.NewInt(int64(number) * 10),
+		GasLimit:   8000000,
+		Difficulty: big.NewInt(1),
+	}, nil
+}
+
+func (api *sidechainTestAPI) GetBalance(account common.Address, number rpc.BlockNumber) (*hexutil.Big, error) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	api.queried = append(api.queried, number)
+	balance := api.balances[account]
+	if balance == nil {
+		balance = new(big.Int)
+	}
+	return (*hexutil.Big)(balance), nil
+}
+
+type sidechainTestNetAPI struct{}
+
+func (api *sidechainTestNetAPI) Version() string { return "1" }
+
+// Tests that the side chain genesis is derived with the period, funded accounts
+// and main chain block given as flags of the init subcommand.
+func TestSidechainInit(t *testing.T) {
+	var (
+		signer = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		funded = common.HexToAddress("0x2000000000000000000000000000000000000002")
+		api    = &sidechainTestAPI{balances: map[common.Address]*big.Int{
+			signer: big.NewInt(100),
+			funded: big.NewInt(200),
+		}}
+	)
+	server := rpc.NewServer()
+	if err := server.RegisterName("rlz", api); err != nil {
+		t.Fatalf("failed to register main chain API: %v", err)
+	}
+	if err := server.RegisterName("net", new(sidechainTestNetAPI)); err != nil {
+		t.Fatalf("failed to register net API: %v", err)
+	}
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	endpoint, _ := url.Parse(httpsrv.URL)
+	host, port, _ := net.SplitHostPort(endpoint.Host)
+
+	datadir := tmpdir(t)
+	defer os.RemoveAll(datadir)
+
+	rlz := runGeth(t, "sidechain", "init", "--datadir", datadir,
+		"--sca.mainrpcaddr", host, "--sca.mainrpcport", port, "--sca.period", "5",
+		"--fund", funded.Hex(), "--mainblock", "7", "42", signer.Hex())
+	_, matches := rlz.ExpectRegexp(`Side chain genesis: (0x[0-9a-f]{64})`)
+	rlz.WaitExit()
+
+	if len(api.queried) != 2 || api.queried[0] != 7 || api.queried[1] != 7 {
+		t.Errorf("balance queries mismatch: have %v, want two at block 7", api.queried)
+	}
+	// Check the genesis written into the database against the flags
+	db, err := ethdb.NewLDBDatabase(filepath.Join(datadir, clientIdentifier, "chaindata"), 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open chain database: %v", err)
+	}
+	defer db.Close()
+
+	hash := rawdb.ReadCanonicalHash(db, 0)
+	if len(matches) > 1 && hash != common.HexToHash(matches[1]) {
+		t.Errorf("genesis hash mismatch: have %x, printed %s", hash, matches[1])
+	}
+	config := rawdb.ReadChainConfig(db, hash)
+	if config == nil || config.Alien == nil {
+		t.Fatalf("alien chain config missing: %v", config)
+	}
+	if config.ChainId.Uint64() != 42 {
+		t.Errorf("chain id mismatch: have %v, want 42", config.ChainId)
+	}
+	if config.Alien.Period != 5 {
+		t.Errorf("period mismatch: have %d, want 5", config.Alien.Period)
+	}
+	if config.Alien.GenesisTimestamp != 75 {
+		t.Errorf("genesis timestamp mismatch: have %d, want 75 (anchor #7 + period)", config.Alien.GenesisTimestamp)
+	}
+	statedb, err := state.New(rawdb.ReadHeader(db, hash, 0).Root, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open genesis state: %v", err)
+	}
+	if balance := statedb.GetBalance(funded); balance.Cmp(big.NewInt(200)) != 0 {
+		t.Errorf("funded balance mismatch: have %v, want 200", balance)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of go-relianz.
+//
+// go-relianz is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-relianz is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-relianz. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"time"
+
+	"github.com/relianz2019/relianz/cmd/utils"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/core/state/pruner"
+	"github.com/relianz2019/relianz/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	snapshotRetainFlag = cli.Uint64Flag{
+		Name:  "retain",
+		Usage: "Number of recent canonical block states to retain",
+		Value: pruner.DefaultRetain,
+	}
+	snapshotBloomSizeFlag = cli.Uint64Flag{
+		Name:  "bloomfilter.size",
+		Usage: "Megabytes of memory allocated to the filter tracking the retained state",
+		Value: pruner.DefaultBloomSize,
+	}
+	snapshotCommand = cli.Command{
+		Name:     "snapshot",
+		Usage:    "Maintain the state stored in the database",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+
+The subcommands operate on the state of a stopped node, deleting state that is no
+longer reachable from the recent canonical blocks and verifying what is retained.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "prune-state",
+				Usage:     "Delete the state unreachable from the recent canonical blocks",
+				ArgsUsage: " ",
+				Action:    utils.MigrateFlags(pruneState),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					snapshotRetainFlag,
+					snapshotBloomSizeFlag,
+				},
+				Description: `
+    relianz snapshot prune-state
+
+Marks every trie node and contract code reachable from the state of the last
+--retain canonical blocks present on disk, deletes all other state from the
+database and verifies the retained state afterwards. Memory use is bounded by
+--bloomfilter.size, a smaller filter merely retains more unreachable state.
+
+The retained state roots are recorded before anything is deleted. If pruning is
+interrupted, the node refuses to start until the command is run again, resuming
+with the recorded roots.`,
+			},
+			{
+				Name:      "verify-state",
+				Usage:     "Verify the state of a block is complete",
+				ArgsUsage: "[<root>]",
+				Action:    utils.MigrateFlags(verifyState),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+				},
+				Description: `
+    relianz snapshot verify-state [<root>]
+
+Iterates over the entire state with the given root, defaulting to the state of
+the head block, and reports the first missing trie node or contract code.`,
+			},
+		},
+	}
+)
+
+// pruneState deletes the state unreachable from the recent canonical blocks.
+func pruneState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	config := pruner.Config{
+		Retain:    ctx.Uint64(snapshotRetainFlag.Name),
+		BloomSize: ctx.Uint64(snapshotBloomSizeFlag.Name),
+	}
+	start := time.Now()
+	if err := pruner.NewPruner(chainDb, config).Prune(); err != nil {
+		utils.Fatalf("Failed to prune state: %v", err)
+	}
+	log.Info("State pruning completed", "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// verifyState checks the state of a block for missing entries.
+func verifyState(ctx *cli.Context) error {
+	if len(ctx.Args()) > 1 {
+		utils.Fatalf("This command takes at most a state root as argument")
+	}
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	var root common.Hash
+	if len(ctx.Args()) == 1 {
+		root = common.HexToHash(ctx.Args()[0])
+	} else {
+		hash := rawdb.ReadHeadBlockHash(chainDb)
+		number := rawdb.ReadHeaderNumber(chainDb, hash)
+		if number == nil {
+			utils.Fatalf("Head block not found")
+		}
+		header := rawdb.ReadHeader(chainDb, hash, *number)
+		if header == nil {
+			utils.Fatalf("Head header %d [%x] not found", *number, hash)
+		}
+		root = header.Root
+	}
+	if err := pruner.VerifyState(chainDb, []common.Hash{root}); err != nil {
+		utils.Fatalf("State verification failed: %v", err)
+	}
+	return nil
+}
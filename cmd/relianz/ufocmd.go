@@ -0,0 +1,219 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of go-relianz.
+//
+// go-relianz is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-relianz is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-relianz. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/relianz2019/relianz/cmd/utils"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/core/ufo"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var ufoCommand = cli.Command{
+	Name:     "ufo",
+	Usage:    "Build and inspect Alien consensus transaction payloads",
+	Category: "MISCELLANEOUS COMMANDS",
+	Description: `
+
+The Alien consensus engine is driven by transactions carrying ufo payloads in
+their data field. The subcommands build every supported payload, printing both
+its text form and the hex data to attach to a transaction, and decode existing
+payloads into their typed fields.`,
+	Subcommands: []cli.Command{
+		{
+			Name:      "vote",
+			Usage:     "Build a payload voting for the transaction recipient",
+			ArgsUsage: " ",
+			Action:    utils.MigrateFlags(ufoVote),
+		},
+		{
+			Name:      "confirm",
+			Usage:     "Build a payload confirming a block",
+			ArgsUsage: "<number>",
+			Action:    utils.MigrateFlags(ufoConfirm),
+		},
+		{
+			Name:      "propose",
+			Usage:     "Build a payload proposing a chain parameter change",
+			ArgsUsage: "<type> <validLoops> <value>",
+			Action:    utils.MigrateFlags(ufoPropose),
+			Description: `
+    relianz ufo propose <type> <validLoops> <value>
+
+Builds a proposal to be decided upon by the signers within the given number of
+loops. The value is interpreted according to the proposal type:
+
+    1  add signer candidate             candidate address
+    2  remove signer candidate          candidate address
+    3  modify miner reward              reward per thousand
+    4  add side chain                   side chain genesis hash
+    5  remove side chain                side chain genesis hash
+    6  modify min voter balance         balance in wei
+    7  modify proposal deposit          deposit in wei`,
+		},
+		{
+			Name:      "declare",
+			Usage:     "Build a payload declaring a decision on a proposal",
+			ArgsUsage: "<proposalHash> <yes|no>",
+			Action:    utils.MigrateFlags(ufoDeclare),
+		},
+		{
+			Name:      "notify",
+			Usage:     "Build a payload announcing a side chain block",
+			ArgsUsage: "<chainHash> <number> <blockHash>",
+			Action:    utils.MigrateFlags(ufoNotify),
+		},
+		{
+			Name:      "decode",
+			Usage:     "Decode a payload into its typed fields",
+			ArgsUsage: "<payload>",
+			Action:    utils.MigrateFlags(ufoDecode),
+			Description: `
+    relianz ufo decode <payload>
+
+Decodes a payload given either in its text form or as hex transaction data.`,
+		},
+	},
+}
+
+// ufoVote prints a vote payload.
+func ufoVote(ctx *cli.Context) error {
+	if len(ctx.Args()) != 0 {
+		utils.Fatalf("This command takes no arguments")
+	}
+	printUfoPayload(&ufo.Vote{})
+	return nil
+}
+
+// ufoConfirm prints a block confirmation payload.
+func ufoConfirm(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires a block number as argument")
+	}
+	number, err := strconv.ParseUint(ctx.Args()[0], 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid block number: %v", err)
+	}
+	printUfoPayload(&ufo.Confirm{Number: number})
+	return nil
+}
+
+// ufoPropose prints a proposal payload.
+func ufoPropose(ctx *cli.Context) error {
+	if len(ctx.Args()) != 3 {
+		utils.Fatalf("This command requires a proposal type, a loop count and a value as arguments")
+	}
+	typ, err := strconv.ParseUint(ctx.Args()[0], 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid proposal type: %v", err)
+	}
+	loops, err := strconv.ParseUint(ctx.Args()[1], 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid loop count: %v", err)
+	}
+	proposal, err := ufo.NewProposal(ufo.ProposalType(typ), loops, ctx.Args()[2])
+	if err != nil {
+		utils.Fatalf("Invalid proposal: %v", err)
+	}
+	printUfoPayload(proposal)
+	return nil
+}
+
+// ufoDeclare prints a proposal declaration payload.
+func ufoDeclare(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("This command requires a proposal hash and a decision as arguments")
+	}
+	hash := parseUfoHash(ctx.Args()[0])
+
+	var decision bool
+	switch ctx.Args()[1] {
+	case "yes":
+		decision = true
+	case "no":
+		decision = false
+	default:
+		utils.Fatalf("Invalid decision %q, want yes or no", ctx.Args()[1])
+	}
+	printUfoPayload(&ufo.Declare{ProposalHash: hash, Decision: decision})
+	return nil
+}
+
+// ufoNotify prints a side chain block notification payload.
+func ufoNotify(ctx *cli.Context) error {
+	if len(ctx.Args()) != 3 {
+		utils.Fatalf("This command requires a chain hash, a block number and a block hash as arguments")
+	}
+	number, err := strconv.ParseUint(ctx.Args()[1], 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid block number: %v", err)
+	}
+	printUfoPayload(&ufo.SideChainNotify{
+		ChainHash: parseUfoHash(ctx.Args()[0]),
+		Number:    number,
+		BlockHash: parseUfoHash(ctx.Args()[2]),
+	})
+	return nil
+}
+
+// ufoDecode prints the typed fields of a text or hex encoded payload.
+func ufoDecode(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires a payload as argument")
+	}
+	data := []byte(ctx.Args()[0])
+	if !ufo.IsUfo(data) {
+		var err error
+		if data, err = hexutil.Decode(hexutil.CPToHex(ctx.Args()[0])); err != nil {
+			utils.Fatalf("Payload is neither ufo text nor hex data: %v", err)
+		}
+	}
+	payload, err := ufo.Decode(data)
+	if err != nil {
+		utils.Fatalf("Failed to decode payload: %v", err)
+	}
+	out, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to format payload: %v", err)
+	}
+	fmt.Println("Category:", payload.Category())
+	fmt.Println("Event:   ", payload.Event())
+	fmt.Println(string(out))
+	return nil
+}
+
+// parseUfoHash parses a hash argument, aborting on failure.
+func parseUfoHash(s string) common.Hash {
+	b, err := hexutil.Decode(hexutil.CPToHex(s))
+	if err != nil || len(b) != common.HashLength {
+		utils.Fatalf("Invalid hash %q", s)
+	}
+	return common.BytesToHash(b)
+}
+
+// printUfoPayload prints the text form of a payload along with the hex data to
+// attach to a transaction.
+func printUfoPayload(payload ufo.Payload) {
+	data := ufo.Encode(payload)
+	fmt.Println("Payload:", string(data))
+	fmt.Println("Data:   ", hexutil.Encode(data))
+}
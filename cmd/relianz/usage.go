@@ -65,6 +65,9 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			configFileFlag,
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.AncientThresholdFlag,
+			utils.DBEngineFlag,
 			utils.KeyStoreDirFlag,
 			//	utils.NoUSBFlag,
 			utils.NetworkIdFlag,
@@ -112,12 +115,14 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolSnapshotFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
 			utils.TxPoolGlobalSlotsFlag,
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
+			utils.TxPoolGlobalBytesFlag,
 			utils.TxPoolLifetimeFlag,
 		},
 	},
@@ -127,6 +132,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.CacheFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.CacheSnapshotFlag,
 			utils.TrieCacheGenFlag,
 		},
 	},
@@ -186,6 +192,14 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ExtraDataFlag,
 		},
 	},
+	{
+		Name: "SIDE CHAIN",
+		Flags: []cli.Flag{
+			utils.SCAEnableFlag,
+			utils.SCAMainRPCAddrFlag,
+			utils.SCAMainRPCPortFlag,
+		},
+	},
 	{
 		Name: "GAS PRICE ORACLE",
 		Flags: []cli.Flag{
@@ -119,6 +119,20 @@ var (
 		Usage: "Data directory for the databases and keystore",
 		Value: DirectoryString{node.DefaultDataDir()},
 	}
+	AncientFlag = DirectoryFlag{
+		Name:  "datadir.ancient",
+		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
+	}
+	AncientThresholdFlag = cli.Uint64Flag{
+		Name:  "ancient.threshold",
+		Usage: "Number of recent blocks to keep out of the ancient store, at least 1024 (0 disables it)",
+		Value: rlz.DefaultConfig.DatabaseFreezerThreshold,
+	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Storage engine for new databases (leveldb or bolt), existing ones keep theirs",
+		Value: ethdb.EngineLevelDB,
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -255,6 +269,11 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolSnapshotFlag = cli.StringFlag{
+		Name:  "txpool.snapshot",
+		Usage: "Disk snapshot of all transactions to survive node restarts (empty = disabled)",
+		Value: core.DefaultTxPoolConfig.Snapshot,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -285,6 +304,11 @@ var (
 		Usage: "Maximum number of non-executable transaction slots for all accounts",
 		Value: rlz.DefaultConfig.TxPool.GlobalQueue,
 	}
+	TxPoolGlobalBytesFlag = cli.Uint64Flag{
+		Name:  "txpool.globalbytes",
+		Usage: "Maximum number of bytes taken up by all transactions in the pool",
+		Value: rlz.DefaultConfig.TxPool.GlobalBytes,
+	}
 	TxPoolLifetimeFlag = cli.DurationFlag{
 		Name:  "txpool.lifetime",
 		Usage: "Maximum amount of time non-executable transaction are queued",
@@ -306,6 +330,11 @@ var (
 		Usage: "Percentage of cache memory allowance to use for trie pruning",
 		Value: 25,
 	}
+	CacheSnapshotFlag = cli.IntFlag{
+		Name:  "cache.snapshot",
+		Usage: "Percentage of cache memory allowance to use for snapshot caching (0 disables the state snapshot)",
+		Value: 10,
+	}
 	TrieCacheGenFlag = cli.IntFlag{
 		Name:  "trie-cache-gens",
 		Usage: "Number of trie node generations to keep in memory",
@@ -537,17 +566,17 @@ var (
 	// PBFT settings
 	PBFTEnableFlag = cli.BoolFlag{
 		Name:  "pbft",
-		Usage: "PBFT miner coinbase send confirm transaction",
+		Usage: "Confirm new chain heads through the PBFT confirmation protocol while mining",
 	}
 
 	// Data side chain settings
 	SCAEnableFlag = cli.BoolFlag{
 		Name:  "sca",
-		Usage: "Side chain for App (dsc)",
+		Usage: "Side chain for App (dsc), anchored to the main chain RPC endpoint",
 	}
 	SCAMainRPCAddrFlag = cli.StringFlag{
 		Name:  "sca.mainrpcaddr",
-		Usage: "Address of main chain ",
+		Usage: "Address of main chain RPC endpoint",
 		Value: node.DefaultHTTPHost,
 	}
 	SCAMainRPCPortFlag = cli.IntFlag{
@@ -914,6 +943,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	case ctx.GlobalBool(RinkebyFlag.Name):
 		cfg.DataDir = filepath.Join(node.DefaultDataDir(), "rinkeby")
 	}
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		cfg.DBEngine = ctx.GlobalString(DBEngineFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
@@ -945,6 +977,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolSnapshotFlag.Name) {
+		cfg.Snapshot = ctx.GlobalString(TxPoolSnapshotFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -963,6 +998,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolGlobalQueueFlag.Name) {
 		cfg.GlobalQueue = ctx.GlobalUint64(TxPoolGlobalQueueFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolGlobalBytesFlag.Name) {
+		cfg.GlobalBytes = ctx.GlobalUint64(TxPoolGlobalBytesFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
@@ -989,6 +1027,17 @@ func setRlzash(ctx *cli.Context, cfg *rlz.Config) {
 	}
 }
 
+// MakeMainChainRPC returns the main chain RPC endpoint side chains are anchored to.
+func MakeMainChainRPC(ctx *cli.Context) string {
+	return fmt.Sprintf("http://%s:%d", ctx.GlobalString(SCAMainRPCAddrFlag.Name), ctx.GlobalInt(SCAMainRPCPortFlag.Name))
+}
+
+func setSideChain(ctx *cli.Context, cfg *rlz.Config) {
+	if ctx.GlobalBool(SCAEnableFlag.Name) {
+		cfg.MainChainRPC = MakeMainChainRPC(ctx)
+	}
+}
+
 // checkExclusive verifies that only a single isntance of the provided flags was
 // set by the user. Each flag might optionally be followed by a string type to
 // specialize it further.
@@ -1053,6 +1102,7 @@ func SetRlzConfig(ctx *cli.Context, stack *node.Node, cfg *rlz.Config) {
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
 	setRlzash(ctx, cfg)
+	setSideChain(ctx, cfg)
 
 	switch {
 	case ctx.GlobalIsSet(SyncModeFlag.Name):
@@ -1076,6 +1126,15 @@ func SetRlzConfig(ctx *cli.Context, stack *node.Node, cfg *rlz.Config) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
+	}
+	if ctx.GlobalIsSet(AncientThresholdFlag.Name) {
+		cfg.DatabaseFreezerThreshold = ctx.GlobalUint64(AncientThresholdFlag.Name)
+	}
+	if threshold := cfg.DatabaseFreezerThreshold; threshold != 0 && threshold < params.FreezerThresholdMin {
+		Fatalf("--%s must be 0 or at least %d", AncientThresholdFlag.Name, params.FreezerThresholdMin)
+	}
 
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1085,6 +1144,9 @@ func SetRlzConfig(ctx *cli.Context, stack *node.Node, cfg *rlz.Config) {
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheSnapshotFlag.Name) {
+		cfg.SnapshotCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100
+	}
 	if ctx.GlobalIsSet(MinerThreadsFlag.Name) {
 		cfg.MinerThreads = ctx.GlobalInt(MinerThreadsFlag.Name)
 	}
@@ -1219,11 +1281,15 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 		cache   = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 		handles = makeDatabaseHandles()
 	)
-	name := "chaindata"
+	var (
+		chainDb ethdb.Database
+		err     error
+	)
 	if ctx.GlobalBool(LightModeFlag.Name) {
-		name = "lightchaindata"
+		chainDb, err = stack.OpenDatabase("lightchaindata", cache, handles)
+	} else {
+		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", cache, handles, ctx.GlobalString(AncientFlag.Name), ctx.GlobalUint64(AncientThresholdFlag.Name))
 	}
-	chainDb, err := stack.OpenDatabase(name, cache, handles)
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
@@ -1243,6 +1309,26 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	return genesis
 }
 
+// MakeEngine creates the consensus engine of a chain from set command line flags.
+func MakeEngine(ctx *cli.Context, stack *node.Node, config *params.ChainConfig, chainDb ethdb.Database) consensus.Engine {
+	if config.Clique != nil {
+		return clique.New(config.Clique, chainDb)
+	} else if config.Alien != nil {
+		return alien.New(config.Alien, chainDb)
+	}
+	if ctx.GlobalBool(FakePoWFlag.Name) {
+		return ethash.NewFaker()
+	}
+	return ethash.New(ethash.Config{
+		CacheDir:       stack.ResolvePath(rlz.DefaultConfig.Rlzash.CacheDir),
+		CachesInMem:    rlz.DefaultConfig.Rlzash.CachesInMem,
+		CachesOnDisk:   rlz.DefaultConfig.Rlzash.CachesOnDisk,
+		DatasetDir:     stack.ResolvePath(rlz.DefaultConfig.Rlzash.DatasetDir),
+		DatasetsInMem:  rlz.DefaultConfig.Rlzash.DatasetsInMem,
+		DatasetsOnDisk: rlz.DefaultConfig.Rlzash.DatasetsOnDisk,
+	})
+}
+
 // MakeChain creates a chain manager from set command line flags.
 func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chainDb ethdb.Database) {
 	var err error
@@ -1252,25 +1338,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if err != nil {
 		Fatalf("%v", err)
 	}
-	var engine consensus.Engine
-	if config.Clique != nil {
-		engine = clique.New(config.Clique, chainDb)
-	} else if config.Alien != nil {
-		engine = alien.New(config.Alien, chainDb)
-
-	} else {
-		engine = ethash.NewFaker()
-		if !ctx.GlobalBool(FakePoWFlag.Name) {
-			engine = ethash.New(ethash.Config{
-				CacheDir:       stack.ResolvePath(rlz.DefaultConfig.Rlzash.CacheDir),
-				CachesInMem:    rlz.DefaultConfig.Rlzash.CachesInMem,
-				CachesOnDisk:   rlz.DefaultConfig.Rlzash.CachesOnDisk,
-				DatasetDir:     stack.ResolvePath(rlz.DefaultConfig.Rlzash.DatasetDir),
-				DatasetsInMem:  rlz.DefaultConfig.Rlzash.DatasetsInMem,
-				DatasetsOnDisk: rlz.DefaultConfig.Rlzash.DatasetsOnDisk,
-			})
-		}
-	}
+	engine := MakeEngine(ctx, stack, config, chainDb)
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
@@ -1278,10 +1346,14 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		Disabled:      ctx.GlobalString(GCModeFlag.Name) == "archive",
 		TrieNodeLimit: rlz.DefaultConfig.TrieCache,
 		TrieTimeLimit: rlz.DefaultConfig.TrieTimeout,
+		SnapshotLimit: rlz.DefaultConfig.SnapshotCache,
 	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheSnapshotFlag.Name) {
+		cache.SnapshotLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100
+	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
 	chain, err = core.NewBlockChain(chainDb, cache, config, engine, vmcfg)
 	if err != nil {
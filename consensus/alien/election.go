@@ -0,0 +1,67 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package alien
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/consensus"
+	"github.com/relianz2019/relianz/core/types"
+)
+
+// VoteOf implements core.VoteReader, returning the candidate the voter's stake
+// is counted for in the snapshot of the given header.
+func (a *Alien) VoteOf(chain consensus.ChainReader, header *types.Header, voter common.Address) (common.Address, bool, error) {
+	snap, err := a.snapshot(chain, header.Number.Uint64(), header.Hash(), nil, nil, defaultLoopCntRecalculateSigners)
+	if err != nil {
+		return common.Address{}, false, err
+	}
+	vote, ok := snap.Votes[voter]
+	if !ok || vote == nil {
+		return common.Address{}, false, nil
+	}
+	return vote.Candidate, true, nil
+}
+
+// NextSigners implements core.VoteReader, returning the candidates with the
+// highest tally in the snapshot of the given header, which are elected into the
+// signer queue of the next loop should the tally not change until then. Equal
+// tallies are ordered by address.
+func (a *Alien) NextSigners(chain consensus.ChainReader, header *types.Header) ([]common.Address, error) {
+	snap, err := a.snapshot(chain, header.Number.Uint64(), header.Hash(), nil, nil, defaultLoopCntRecalculateSigners)
+	if err != nil {
+		return nil, err
+	}
+	signers := make([]common.Address, 0, len(snap.Tally))
+	for candidate, stake := range snap.Tally {
+		if stake != nil && stake.Sign() > 0 {
+			signers = append(signers, candidate)
+		}
+	}
+	sort.Slice(signers, func(i, j int) bool {
+		if cmp := snap.Tally[signers[i]].Cmp(snap.Tally[signers[j]]); cmp != 0 {
+			return cmp > 0
+		}
+		return bytes.Compare(signers[i][:], signers[j][:]) < 0
+	})
+	if uint64(len(signers)) > a.config.MaxSignerCount {
+		signers = signers[:a.config.MaxSignerCount]
+	}
+	return signers, nil
+}
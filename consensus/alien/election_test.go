@@ -0,0 +1,74 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package alien
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/params"
+)
+
+// Tests that votes and the projected signers are read from the snapshot of the
+// requested header, electing the highest tallies up to the signer count.
+func TestElection(t *testing.T) {
+	config := *params.AllAlienProtocolChanges.Alien
+	config.MaxSignerCount = 2
+
+	var (
+		engine = New(&config, ethdb.NewMemDatabase())
+		voter  = common.Address{0xff}
+		header = &types.Header{Number: big.NewInt(7)}
+		first  = common.Address{0x01}
+		second = common.Address{0x02}
+		third  = common.Address{0x03}
+	)
+	engine.recents.Add(header.Hash(), &Snapshot{
+		config: &config,
+		Number: 7,
+		Hash:   header.Hash(),
+		Votes: map[common.Address]*Vote{
+			voter: {Voter: voter, Candidate: second, Stake: big.NewInt(300)},
+		},
+		Tally: map[common.Address]*big.Int{
+			first:  big.NewInt(100),
+			second: big.NewInt(300),
+			third:  big.NewInt(100),
+		},
+	})
+	candidate, voted, err := engine.VoteOf(nil, header, voter)
+	if err != nil {
+		t.Fatalf("failed to retrieve vote: %v", err)
+	}
+	if !voted || candidate != second {
+		t.Errorf("vote mismatch: have %x (%v), want %x", candidate, voted, second)
+	}
+	if _, voted, err := engine.VoteOf(nil, header, common.Address{0xee}); err != nil || voted {
+		t.Errorf("vote of non-voter reported: voted %v, err %v", voted, err)
+	}
+	signers, err := engine.NextSigners(nil, header)
+	if err != nil {
+		t.Fatalf("failed to retrieve next signers: %v", err)
+	}
+	if want := []common.Address{second, first}; !reflect.DeepEqual(signers, want) {
+		t.Errorf("next signers mismatch: have %x, want %x", signers, want)
+	}
+}
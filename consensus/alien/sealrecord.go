@@ -0,0 +1,72 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package alien
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/types"
+)
+
+// errMissingHeaderExtra is returned if a header's extra-data is too short to hold
+// the vanity, the encoded header extra and the seal.
+var errMissingHeaderExtra = errors.New("extra-data too short for alien header extra")
+
+// SealRecord implements core.SealRecordReader, describing how the given header
+// was sealed. It only relies on the header itself, so light clients can index
+// the headers they sync.
+func (a *Alien) SealRecord(header *types.Header) (*types.SealRecord, error) {
+	signer, err := a.Author(header)
+	if err != nil {
+		return nil, err
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errMissingHeaderExtra
+	}
+	var headerExtra HeaderExtra
+	if err := decodeHeaderExtra(a.config, header.Number, header.Extra[extraVanity:len(header.Extra)-extraSeal], &headerExtra); err != nil {
+		return nil, err
+	}
+	return &types.SealRecord{
+		Signer:    signer,
+		LoopStart: headerExtra.LoopStartTime,
+		Queue:     headerExtra.SignerQueue,
+		Missing:   headerExtra.SignerMissing,
+		Reward:    a.blockReward(header.Number.Uint64()),
+	}, nil
+}
+
+// SealHash returns the hash of a header prior to it being sealed, which is the
+// hash the signer signs.
+func (a *Alien) SealHash(header *types.Header) common.Hash {
+	return sigHash(header)
+}
+
+// blockReward returns the reward minted for the block with the given number,
+// halving every year of blocks as in accumulateRewards. The reward is shared by
+// the signer and its voters.
+func (a *Alien) blockReward(number uint64) *big.Int {
+	if a.config.Period == 0 {
+		return new(big.Int)
+	}
+	blockNumPerYear := secondsPerYear / a.config.Period
+	initSignerBlockReward := new(big.Int).Div(totalBlockReward, big.NewInt(int64(2*blockNumPerYear)))
+
+	return new(big.Int).Rsh(initSignerBlockReward, uint(number/blockNumPerYear))
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package alien
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/params"
+)
+
+// Tests that seal records are recovered from signed headers alone.
+func TestSealRecord(t *testing.T) {
+	var (
+		config = params.AllAlienProtocolChanges.Alien
+		engine = New(config, ethdb.NewMemDatabase())
+
+		queue   = []common.Address{{0x01}, {0x02}, {0x03}}
+		missing = []common.Address{{0x02}}
+
+		blockNumPerYear = secondsPerYear / config.Period
+		initReward      = new(big.Int).Div(totalBlockReward, big.NewInt(int64(2*blockNumPerYear)))
+	)
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	tests := []struct {
+		number  uint64
+		extra   HeaderExtra
+		record  *types.SealRecord
+		comment string
+	}{
+		{
+			number:  6,
+			extra:   HeaderExtra{LoopStartTime: 1000, SignerQueue: queue},
+			record:  &types.SealRecord{Signer: signer, LoopStart: 1000, Queue: queue, Reward: initReward},
+			comment: "first block of a loop",
+		},
+		{
+			number:  7,
+			extra:   HeaderExtra{LoopStartTime: 1000, SignerMissing: missing},
+			record:  &types.SealRecord{Signer: signer, LoopStart: 1000, Missing: missing, Reward: initReward},
+			comment: "block after a missed slot",
+		},
+		{
+			number:  blockNumPerYear + 1,
+			extra:   HeaderExtra{LoopStartTime: 2000},
+			record:  &types.SealRecord{Signer: signer, LoopStart: 2000, Reward: new(big.Int).Rsh(initReward, 1)},
+			comment: "block of the second year",
+		},
+	}
+	for _, tt := range tests {
+		number := new(big.Int).SetUint64(tt.number)
+		enc, err := encodeHeaderExtra(config, number, tt.extra)
+		if err != nil {
+			t.Fatalf("%s: failed to encode header extra: %v", tt.comment, err)
+		}
+		header := &types.Header{
+			Number: number,
+			Time:   new(big.Int).SetUint64(tt.extra.LoopStartTime),
+			Extra:  append(append(make([]byte, extraVanity), enc...), make([]byte, extraSeal)...),
+		}
+		sig, err := crypto.Sign(sigHash(header).Bytes(), key)
+		if err != nil {
+			t.Fatalf("%s: failed to sign header: %v", tt.comment, err)
+		}
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+
+		record, err := engine.SealRecord(header)
+		if err != nil {
+			t.Fatalf("%s: failed to retrieve seal record: %v", tt.comment, err)
+		}
+		if record.Signer != tt.record.Signer || record.LoopStart != tt.record.LoopStart || record.Reward.Cmp(tt.record.Reward) != 0 {
+			t.Errorf("%s: seal record mismatch: have %+v, want %+v", tt.comment, record, tt.record)
+		}
+		if len(record.Queue) != len(tt.record.Queue) || (len(tt.record.Queue) > 0 && !reflect.DeepEqual(record.Queue, tt.record.Queue)) {
+			t.Errorf("%s: signer queue mismatch: have %x, want %x", tt.comment, record.Queue, tt.record.Queue)
+		}
+		if len(record.Missing) != len(tt.record.Missing) || (len(tt.record.Missing) > 0 && !reflect.DeepEqual(record.Missing, tt.record.Missing)) {
+			t.Errorf("%s: missed signers mismatch: have %x, want %x", tt.comment, record.Missing, tt.record.Missing)
+		}
+	}
+	// Headers without a valid seal must be rejected
+	header := &types.Header{Number: big.NewInt(1), Extra: make([]byte, extraVanity+extraSeal)}
+	if _, err := engine.SealRecord(header); err == nil {
+		t.Errorf("unsealed header accepted")
+	}
+}
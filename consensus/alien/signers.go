@@ -0,0 +1,49 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package alien
+
+import (
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/consensus"
+	"github.com/relianz2019/relianz/core/types"
+)
+
+// Signers implements core.SignerSetReader, returning the distinct signers of the
+// signer queue the given header was sealed against. Block confirmations for PBFT
+// finality are counted against them.
+func (a *Alien) Signers(chain consensus.ChainReader, header *types.Header) ([]common.Address, error) {
+	number, hash := header.Number.Uint64(), header.Hash()
+	if number > 0 {
+		number, hash = number-1, header.ParentHash
+	}
+	snap, err := a.snapshot(chain, number, hash, nil, nil, defaultLoopCntRecalculateSigners)
+	if err != nil {
+		return nil, err
+	}
+	// The signer queue repeats signers if there are fewer candidates than slots
+	var (
+		signers = make([]common.Address, 0, len(snap.Signers))
+		seen    = make(map[common.Address]bool, len(snap.Signers))
+	)
+	for _, signer := range snap.Signers {
+		if !seen[*signer] {
+			seen[*signer] = true
+			signers = append(signers, *signer)
+		}
+	}
+	return signers, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package alien
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/params"
+)
+
+// Tests that the signer set of a block is taken from the signer queue of its
+// parent snapshot, without the repeated entries of an underfilled queue.
+func TestSigners(t *testing.T) {
+	var (
+		engine = New(params.AllAlienProtocolChanges.Alien, ethdb.NewMemDatabase())
+		first  = common.Address{0x01}
+		second = common.Address{0x02}
+		parent = common.Hash{0xaa}
+	)
+	engine.recents.Add(parent, &Snapshot{
+		config:  params.AllAlienProtocolChanges.Alien,
+		Number:  7,
+		Hash:    parent,
+		Signers: []*common.Address{&first, &second, &first, &second},
+	})
+	header := &types.Header{ParentHash: parent, Number: big.NewInt(8)}
+
+	signers, err := engine.Signers(nil, header)
+	if err != nil {
+		t.Fatalf("failed to retrieve signers: %v", err)
+	}
+	if want := []common.Address{first, second}; !reflect.DeepEqual(signers, want) {
+		t.Errorf("signers mismatch: have %x, want %x", signers, want)
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import "github.com/relianz2019/relianz/consensus"
+
+// MinerFeatures implements consensus.FeatureEngine. Authorized signers seal
+// blocks locally without a fixed schedule, so none of the optional miner
+// features apply.
+func (c *Clique) MinerFeatures() []consensus.MinerFeature {
+	return nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+// MinerFeature names a part of the miner RPC and command line surface that is
+// only meaningful to some consensus engines.
+type MinerFeature string
+
+const (
+	// FeaturePoW is remote proof-of-work sealing and hash rate reporting through
+	// rlz_getWork, rlz_submitWork, rlz_submitHashrate, rlz_hashrate and
+	// miner_getHashrate.
+	FeaturePoW MinerFeature = "pow"
+
+	// FeatureDataset is the generation of ethash verification caches and mining
+	// datasets through the makecache and makedag commands.
+	FeatureDataset MinerFeature = "dataset"
+
+	// FeatureSchedule is the signer slot schedule reported by miner_schedule.
+	FeatureSchedule MinerFeature = "schedule"
+)
+
+// FeatureEngine is implemented by consensus engines declaring the optional miner
+// features they support.
+type FeatureEngine interface {
+	// MinerFeatures returns the optional miner features the engine supports.
+	MinerFeatures() []MinerFeature
+}
+
+// MinerFeatures returns the optional miner features supported by a consensus
+// engine. Engines not declaring their features are the proof-of-work ones, which
+// support remote sealing and dataset generation.
+func MinerFeatures(engine Engine) []MinerFeature {
+	if engine, ok := engine.(FeatureEngine); ok {
+		return engine.MinerFeatures()
+	}
+	return []MinerFeature{FeaturePoW, FeatureDataset}
+}
+
+// HasFeature reports whether the feature is in the given list.
+func HasFeature(features []MinerFeature, feature MinerFeature) bool {
+	for _, f := range features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
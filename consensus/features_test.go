@@ -0,0 +1,54 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"reflect"
+	"testing"
+)
+
+// featureEngine is a consensus engine stub declaring its miner features.
+type featureEngine struct {
+	Engine
+	features []MinerFeature
+}
+
+func (e *featureEngine) MinerFeatures() []MinerFeature { return e.features }
+
+// Tests that engines get the miner features they declare, and the proof-of-work
+// ones unless declaring any.
+func TestMinerFeatures(t *testing.T) {
+	tests := []struct {
+		engine Engine
+		want   []MinerFeature
+	}{
+		{nil, []MinerFeature{FeaturePoW, FeatureDataset}},
+		{&featureEngine{}, nil},
+		{&featureEngine{features: []MinerFeature{FeatureSchedule}}, []MinerFeature{FeatureSchedule}},
+	}
+	for i, tt := range tests {
+		if have := MinerFeatures(tt.engine); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: features mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	if !HasFeature(MinerFeatures(nil), FeatureDataset) {
+		t.Errorf("proof-of-work engine lacks the dataset feature")
+	}
+	if HasFeature(MinerFeatures(&featureEngine{features: []MinerFeature{FeatureSchedule}}), FeaturePoW) {
+		t.Errorf("scheduled engine claims the proof-of-work feature")
+	}
+}
@@ -141,6 +141,13 @@ func (c *Console) init(preload []string) error {
 			continue // manually mapped or ignore
 		}
 		if file, ok := web3ext.Modules[api]; ok {
+			if api == "miner" {
+				// Only offer the methods of the miner features the node supports
+				var features []string
+				if err := c.client.Call(&features, "miner_features"); err == nil {
+					file = web3ext.MinerJS(features)
+				}
+			}
 			// Load our extension for the module.
 			if err = c.jsre.Compile(fmt.Sprintf("%s.js", api), file); err != nil {
 				return fmt.Errorf("%s.js: %v", api, err)
@@ -72,3 +72,26 @@ func TestInstructionIteratorEmpty(t *testing.T) {
 		t.Errorf("Expected 0, but got %v instead.", cnt)
 	}
 }
+
+// Tests that the Constantinople contract creation and code hash instructions
+// survive a compile and disassemble round trip.
+func TestConstantinopleRoundTrip(t *testing.T) {
+	compiler := NewCompiler(false)
+	compiler.Feed(Lex("test", []byte("push 0\npush 0\npush 0\npush 0\ncreate2\nextcodehash\n"), false))
+
+	bin, errs := compiler.Compile()
+	if len(errs) != 0 {
+		t.Fatalf("failed to compile: %v", errs)
+	}
+	if bin != "6000600060006000f53f" {
+		t.Fatalf("binary mismatch: have %s, want 6000600060006000f53f", bin)
+	}
+	code, _ := hex.DecodeString(bin)
+	instrs, err := Disassemble(code)
+	if err != nil {
+		t.Fatalf("failed to disassemble: %v", err)
+	}
+	if len(instrs) != 6 || instrs[4] != "000008: CREATE2\n" || instrs[5] != "000009: EXTCODEHASH\n" {
+		t.Errorf("disassembly mismatch: have %q", instrs)
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/relianz2019/relianz/consensus"
 	"github.com/relianz2019/relianz/core/rawdb"
 	"github.com/relianz2019/relianz/core/state"
+	"github.com/relianz2019/relianz/core/state/snapshot"
 	"github.com/relianz2019/relianz/core/types"
 	"github.com/relianz2019/relianz/core/vm"
 	"github.com/relianz2019/relianz/crypto"
@@ -70,6 +71,7 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+	SnapshotLimit int           // Memory allowance (MB) to use for caching snapshot entries in memory, 0 disables the snapshot
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -112,6 +114,7 @@ type BlockChain struct {
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache   state.Database // State database to reuse between imports (contains state cache)
+	snaps        *snapshot.Tree // Snapshot tree for fast trie leaf access
 	bodyCache    *lru.Cache     // Cache for the most recent block bodies
 	bodyRLPCache *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
 	blockCache   *lru.Cache     // Cache for the most recent entire blocks
@@ -135,6 +138,9 @@ type BlockChain struct {
 // available in the database. It initialises the default Relianz Validator and
 // Processor.
 func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *params.ChainConfig, engine consensus.Engine, vmConfig vm.Config) (*BlockChain, error) {
+	if err := vm.ValidatePrecompiles(chainConfig); err != nil {
+		return nil, err
+	}
 	if cacheConfig == nil {
 		cacheConfig = &CacheConfig{
 			TrieNodeLimit: 256 * 1024 * 1024,
@@ -190,6 +196,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			}
 		}
 	}
+	// Load any existing snapshot, regenerating it if loading failed
+	if bc.cacheConfig.SnapshotLimit > 0 {
+		bc.snaps = snapshot.New(rawdb.KeyValueStore(db), bc.stateCache.TrieDB(), bc.cacheConfig.SnapshotLimit, bc.CurrentBlock().Root())
+	}
 	// Take ownership of this particular state
 	go bc.update()
 	return bc, nil
@@ -275,6 +285,13 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	bc.hc.SetHead(head, delFn)
 	currentHeader := bc.hc.CurrentHeader()
 
+	// Frozen blocks above the new head are not part of the chain anymore either
+	if frdb, ok := bc.db.(rawdb.AncientWriter); ok {
+		if err := frdb.TruncateAncients(currentHeader.Number.Uint64() + 1); err != nil {
+			log.Crit("Failed to truncate ancient data", "number", currentHeader.Number, "err", err)
+		}
+	}
+
 	// Clear out any stale content from the caches
 	bc.bodyCache.Purge()
 	bc.bodyRLPCache.Purge()
@@ -295,6 +312,10 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	if currentFastBlock := bc.CurrentFastBlock(); currentFastBlock != nil && currentHeader.Number.Uint64() < currentFastBlock.NumberU64() {
 		bc.currentFastBlock.Store(bc.GetBlock(currentHeader.Hash(), currentHeader.Number.Uint64()))
 	}
+	// The snapshot cannot be rewound, regenerate it for the new head
+	if bc.snaps != nil {
+		bc.snaps.Rebuild(bc.CurrentBlock().Root())
+	}
 	// If either blocks reached nil, reset to the genesis state
 	if currentBlock := bc.CurrentBlock(); currentBlock == nil {
 		bc.currentBlock.Store(bc.genesisBlock)
@@ -327,6 +348,10 @@ func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	bc.currentBlock.Store(block)
 	bc.mu.Unlock()
 
+	// Generate the snapshot of the synced state
+	if bc.snaps != nil {
+		bc.snaps.Rebuild(block.Root())
+	}
 	log.Info("Committed new head block", "number", block.Number(), "hash", hash)
 	return nil
 }
@@ -383,7 +408,7 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache)
+	return state.NewWithSnapshot(root, bc.stateCache, bc.snaps)
 }
 
 // Reset purges the entire blockchain, restoring it to its genesis state.
@@ -653,6 +678,14 @@ func (bc *BlockChain) Stop() {
 
 	bc.wg.Wait()
 
+	// Flatten the snapshot into the disk layer at the head state, so it can be
+	// loaded again on restart without regeneration.
+	if bc.snaps != nil {
+		if err := bc.snaps.Cap(bc.CurrentBlock().Root(), 0); err != nil {
+			log.Error("Failed to persist state snapshot", "err", err)
+		}
+		bc.snaps.Stop()
+	}
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// We're writing three different states to catch different restart scenarios:
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
@@ -903,6 +936,18 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	if err != nil {
 		return NonStatTy, err
 	}
+	// Flatten the snapshot diff layers beyond the in-memory tries, while the state
+	// the disk layer moves to is still referenced. Only blocks extending the head
+	// do so, keeping side chains from flattening the canonical layers away. The
+	// snapshot is only regenerated if the head has no layer to build on, as that
+	// would restart any generation in progress.
+	if bc.snaps != nil && block.ParentHash() == currentBlock.Hash() {
+		if bc.snaps.Snapshot(currentBlock.Root()) == nil {
+			bc.snaps.Rebuild(root)
+		} else if err := bc.snaps.Cap(root, triesInMemory-1); err != nil {
+			log.Warn("Failed to cap snapshot tree", "root", root, "err", err)
+		}
+	}
 	triedb := bc.stateCache.TrieDB()
 
 	// If we're running an archive node, always flush
@@ -1136,7 +1181,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		} else {
 			parent = chain[i-1]
 		}
-		state, err := state.New(parent.Root(), bc.stateCache)
+		state, err := state.NewWithSnapshot(parent.Root(), bc.stateCache, bc.snaps)
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
@@ -0,0 +1,44 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/consensus"
+	"github.com/relianz2019/relianz/core/types"
+)
+
+// VoteReader is implemented by consensus engines electing their signers through
+// stake weighted votes, exposing the vote tally to wallets.
+type VoteReader interface {
+	// VoteOf returns the candidate the voter's stake is counted for as of the
+	// given header, or false if no vote of the voter is counted.
+	VoteOf(chain consensus.ChainReader, header *types.Header, voter common.Address) (common.Address, bool, error)
+
+	// NextSigners returns the signers projected to seal the loop following the
+	// given header, should the tally not change until then.
+	NextSigners(chain consensus.ChainReader, header *types.Header) ([]common.Address, error)
+}
+
+// SealRecordReader is implemented by consensus engines sealing blocks through a
+// rotating signer queue, describing every header for the signer history index.
+type SealRecordReader interface {
+	// SealRecord returns how the given header was sealed, including the signer
+	// queue of its loop. As light clients index headers without their state, it
+	// must only rely on the header itself.
+	SealRecord(header *types.Header) (*types.SealRecord, error)
+}
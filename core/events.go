@@ -24,6 +24,17 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// TxRejectedEvent is posted when an admission hook refuses a transaction.
+type TxRejectedEvent struct{ Rejection *TxRejection }
+
+// DroppedTxsEvent is posted when transactions leave the transaction pool without
+// being included in a block.
+type DroppedTxsEvent struct {
+	Txs         []*types.Transaction
+	Reason      string             // One of the TxDrop* reasons
+	Replacement *types.Transaction // Transaction taking the place of the dropped ones, if replaced
+}
+
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
 	Logs []*types.Log
@@ -49,3 +60,11 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// NewConfirmationsEvent is posted when signed block confirmations are accepted
+// by the finality tracker.
+type NewConfirmationsEvent struct{ Confirmations []*types.Confirmation }
+
+// ChainFinalizedEvent is posted when a canonical block has been confirmed by a
+// quorum of signers and became irreversible.
+type ChainFinalizedEvent struct{ Header *types.Header }
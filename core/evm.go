@@ -17,6 +17,7 @@
 package core
 
 import (
+	"errors"
 	"math/big"
 
 	"github.com/relianz2019/relianz/common"
@@ -45,16 +46,60 @@ func NewEVMContext(msg Message, header *types.Header, chain ChainContext, author
 		beneficiary = *author
 	}
 	return vm.Context{
-		CanTransfer: CanTransfer,
-		Transfer:    Transfer,
-		GetHash:     GetHashFn(header, chain),
-		Origin:      msg.From(),
-		Coinbase:    beneficiary,
-		BlockNumber: new(big.Int).Set(header.Number),
-		Time:        new(big.Int).Set(header.Time),
-		Difficulty:  new(big.Int).Set(header.Difficulty),
-		GasLimit:    header.GasLimit,
-		GasPrice:    new(big.Int).Set(msg.GasPrice()),
+		CanTransfer:    CanTransfer,
+		Transfer:       Transfer,
+		GetHash:        GetHashFn(header, chain),
+		GetSignerQueue: GetSignerQueueFn(header, chain),
+		Origin:         msg.From(),
+		Coinbase:       beneficiary,
+		BlockNumber:    new(big.Int).Set(header.Number),
+		Time:           new(big.Int).Set(header.Time),
+		Difficulty:     new(big.Int).Set(header.Difficulty),
+		GasLimit:       header.GasLimit,
+		GasPrice:       new(big.Int).Set(msg.GasPrice()),
+	}
+}
+
+var (
+	// errNoSealRecords is returned when retrieving the signer queue of a chain
+	// whose consensus engine does not seal through a signer queue.
+	errNoSealRecords = errors.New("consensus engine has no signer queue")
+
+	// errNoLoopQueue is returned if none of the blocks of a signer loop carries
+	// the signer queue of the loop.
+	errNoLoopQueue = errors.New("signer queue of the loop not found")
+)
+
+// GetSignerQueueFn returns a GetSignerQueueFunc which retrieves the signer queue
+// the parent of the referenced header was sealed in.
+func GetSignerQueueFn(ref *types.Header, chain ChainContext) func() ([]common.Address, error) {
+	return func() ([]common.Address, error) {
+		reader, ok := chain.Engine().(SealRecordReader)
+		if !ok {
+			return nil, errNoSealRecords
+		}
+		header := chain.GetHeader(ref.ParentHash, ref.Number.Uint64()-1)
+		if header == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		record, err := reader.SealRecord(header)
+		if err != nil {
+			return nil, err
+		}
+		// Only the first block of a loop carries the signer queue, look it up
+		// among the ancestors sealed in the same loop
+		for loopStart := record.LoopStart; len(record.Queue) == 0; {
+			if header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1); header == nil {
+				return nil, consensus.ErrUnknownAncestor
+			}
+			if record, err = reader.SealRecord(header); err != nil {
+				return nil, err
+			}
+			if record.LoopStart != loopStart {
+				return nil, errNoLoopQueue
+			}
+		}
+		return record.Queue, nil
 	}
 }
 
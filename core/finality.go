@@ -0,0 +1,415 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/consensus"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/event"
+	"github.com/relianz2019/relianz/log"
+)
+
+const (
+	// maxConfirmedBlocks is the maximum number of not yet final blocks for which
+	// confirmations are tracked at once (prevent DOS).
+	maxConfirmedBlocks = 1024
+
+	// maxPendingConfirmations is the maximum number of confirmations of blocks not
+	// yet imported to hold on to until the blocks arrive (prevent DOS).
+	maxPendingConfirmations = 4096
+
+	// pendingConfirmationWindow is the distance from the current head within which
+	// confirmations of blocks not yet imported are held on to. Farther ahead ones
+	// are refused, farther behind ones are dropped as their block is not coming.
+	pendingConfirmationWindow = 64
+)
+
+var (
+	// ErrKnownConfirmation is returned if a signer's confirmation of a block has
+	// already been accepted.
+	ErrKnownConfirmation = errors.New("known confirmation")
+
+	// ErrStaleConfirmation is returned if a confirmation refers to a block at or
+	// below the current finalized block.
+	ErrStaleConfirmation = errors.New("stale confirmation")
+
+	// ErrFutureConfirmation is returned if a confirmation refers to a block too far
+	// ahead of the current head to be held on to.
+	ErrFutureConfirmation = errors.New("confirmation too far in the future")
+
+	// ErrConfirmationNotSigner is returned if a confirmation of a known block is not
+	// signed by one of the signers in charge of it.
+	ErrConfirmationNotSigner = errors.New("confirmation by unauthorized signer")
+
+	errConfirmationChainId   = errors.New("confirmation for a different chain")
+	errUnknownConfirmedBlock = errors.New("confirmation of unknown block queued")
+	errPendingNotSigner      = errors.New("confirmation of unknown block by signer unknown at head")
+	errPendingOverflow       = errors.New("too many confirmations of unknown blocks")
+	errConfirmUnauthorized   = errors.New("no confirmation signer authorized")
+)
+
+// SignerSetReader is implemented by consensus engines that seal blocks through a
+// bounded set of signers, allowing block confirmations to be counted against it.
+type SignerSetReader interface {
+	// Signers returns the signers in charge of sealing at the given header.
+	Signers(chain consensus.ChainReader, header *types.Header) ([]common.Address, error)
+}
+
+// ConfirmSignFn is a signer callback function to request a confirmation hash to
+// be signed by a backing account.
+type ConfirmSignFn func(signer common.Address, hash []byte) ([]byte, error)
+
+// FinalityTracker collects signed block confirmations and marks canonical blocks
+// final once more than two thirds of their signers have confirmed them. Finality is carried
+// over to all ancestors, so only the highest finalized block is tracked.
+//
+// Confirmations may arrive ahead of their blocks and blocks may reach a quorum
+// while on a side chain, so the tracker needs to be rechecked on every new chain
+// head to pick those up.
+type FinalityTracker struct {
+	db      ethdb.Database
+	chain   consensus.ChainReader
+	signers SignerSetReader
+
+	signer common.Address // Local signer to issue confirmations with
+	signFn ConfirmSignFn  // Signer function to authorize confirmations with
+
+	votes     map[common.Hash]map[common.Address]*types.Confirmation // Confirmations of not yet final blocks
+	numbers   map[common.Hash]uint64                                 // Block numbers of the confirmed blocks
+	quorums   map[common.Hash]*types.Header                          // Not yet final blocks confirmed by a quorum
+	pending   map[pendingConfirmation]*types.Confirmation            // Confirmations of blocks not yet imported
+	finalized *types.Header                                          // Highest canonical block confirmed by a quorum
+
+	confirmFeed   event.Feed
+	finalizedFeed event.Feed
+	scope         event.SubscriptionScope
+
+	lock sync.RWMutex
+}
+
+// pendingConfirmation identifies a signer's confirmation of a block not yet
+// imported.
+type pendingConfirmation struct {
+	hash   common.Hash
+	signer common.Address
+}
+
+// NewFinalityTracker creates a tracker for the given chain, resuming from the
+// finalized block persisted in db.
+func NewFinalityTracker(db ethdb.Database, chain consensus.ChainReader, signers SignerSetReader) *FinalityTracker {
+	t := &FinalityTracker{
+		db:      db,
+		chain:   chain,
+		signers: signers,
+		votes:   make(map[common.Hash]map[common.Address]*types.Confirmation),
+		numbers: make(map[common.Hash]uint64),
+		quorums: make(map[common.Hash]*types.Header),
+		pending: make(map[pendingConfirmation]*types.Confirmation),
+	}
+	if hash := rawdb.ReadFinalizedBlockHash(db); hash != (common.Hash{}) {
+		t.finalized = chain.GetHeaderByHash(hash)
+	}
+	return t
+}
+
+// Authorize injects the signer and the signing callback used to confirm blocks
+// sealed by others.
+func (t *FinalityTracker) Authorize(signer common.Address, signFn ConfirmSignFn) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.signer = signer
+	t.signFn = signFn
+}
+
+// Confirm signs a confirmation of header with the authorized signer and feeds it
+// into the tracker as any remote confirmation.
+func (t *FinalityTracker) Confirm(signer common.Address, header *types.Header) error {
+	t.lock.RLock()
+	authorized, signFn := t.signer, t.signFn
+	t.lock.RUnlock()
+
+	if signFn == nil || authorized != signer {
+		return errConfirmUnauthorized
+	}
+	conf := types.NewConfirmation(t.chain.Config().ChainId, header)
+	sig, err := signFn(signer, conf.SigHash().Bytes())
+	if err != nil {
+		return err
+	}
+	conf.Signature = sig
+
+	return t.Add([]*types.Confirmation{conf})[0]
+}
+
+// Add validates a batch of confirmations and counts the acceptable ones towards
+// the finality of their blocks. The returned slice holds the per confirmation
+// validation errors.
+func (t *FinalityTracker) Add(confs []*types.Confirmation) []error {
+	errs := make([]error, len(confs))
+
+	t.lock.Lock()
+	var accepted []*types.Confirmation
+	for i, conf := range confs {
+		if errs[i] = t.add(conf); errs[i] == nil {
+			accepted = append(accepted, conf)
+		}
+	}
+	final := t.finalizeCanonical()
+	t.lock.Unlock()
+
+	t.notify(accepted, final)
+	return errs
+}
+
+// Recheck re-evaluates the tracked confirmations against the current chain. It
+// counts the queued confirmations of blocks imported since they arrived, drops
+// the queued ones which went stale or out of the window around the head, and
+// finalizes blocks which reached a quorum on a side chain, but became canonical
+// through a reorg since.
+func (t *FinalityTracker) Recheck() {
+	t.lock.Lock()
+	var (
+		head     = t.chain.CurrentHeader().Number.Uint64()
+		accepted []*types.Confirmation
+	)
+	for key, conf := range t.pending {
+		if t.finalized != nil && conf.Number <= t.finalized.Number.Uint64() {
+			delete(t.pending, key)
+			continue
+		}
+		if !withinPendingWindow(conf.Number, head) {
+			delete(t.pending, key)
+			continue
+		}
+		if t.chain.GetHeader(conf.Hash, conf.Number) == nil {
+			continue
+		}
+		delete(t.pending, key)
+		if err := t.add(conf); err == nil {
+			accepted = append(accepted, conf)
+		}
+	}
+	final := t.finalizeCanonical()
+	t.lock.Unlock()
+
+	t.notify(accepted, final)
+}
+
+// notify announces newly accepted confirmations and the newly finalized block,
+// if any, to the subscribers.
+func (t *FinalityTracker) notify(accepted []*types.Confirmation, final *types.Header) {
+	if len(accepted) > 0 {
+		t.confirmFeed.Send(NewConfirmationsEvent{Confirmations: accepted})
+	}
+	if final != nil {
+		log.Info("Block finalized by signer quorum", "number", final.Number, "hash", final.Hash())
+		t.finalizedFeed.Send(ChainFinalizedEvent{Header: final})
+	}
+}
+
+// add validates and records a single confirmation, noting its block if it was
+// confirmed by a quorum. Confirmations of blocks not yet imported are queued up
+// until the block arrives if they are within the window around the head and are
+// signed by a signer in charge at the head.
+//
+// The caller must hold t.lock.
+func (t *FinalityTracker) add(conf *types.Confirmation) error {
+	if conf.ChainId == nil || conf.ChainId.Cmp(t.chain.Config().ChainId) != 0 {
+		return errConfirmationChainId
+	}
+	if t.finalized != nil && conf.Number <= t.finalized.Number.Uint64() {
+		return ErrStaleConfirmation
+	}
+	signer, err := conf.Signer()
+	if err != nil {
+		return err
+	}
+	header := t.chain.GetHeader(conf.Hash, conf.Number)
+	if header == nil {
+		return t.queue(conf, signer)
+	}
+	signers, err := t.signers.Signers(t.chain, header)
+	if err != nil {
+		return err
+	}
+	if !containsAddress(signers, signer) {
+		return ErrConfirmationNotSigner
+	}
+	votes := t.votes[conf.Hash]
+	if votes == nil {
+		if len(t.votes) >= maxConfirmedBlocks {
+			t.evictLowest()
+		}
+		votes = make(map[common.Address]*types.Confirmation)
+		t.votes[conf.Hash] = votes
+		t.numbers[conf.Hash] = conf.Number
+	}
+	if _, ok := votes[signer]; ok {
+		return ErrKnownConfirmation
+	}
+	votes[signer] = conf
+
+	if 3*len(votes) > 2*len(signers) {
+		t.quorums[conf.Hash] = header
+	}
+	return nil
+}
+
+// queue holds on to the confirmation of a block not yet imported until the block
+// arrives. As the signers of the block cannot be known yet, the confirmation is
+// checked against the signers in charge at the current head.
+//
+// The caller must hold t.lock.
+func (t *FinalityTracker) queue(conf *types.Confirmation, signer common.Address) error {
+	current := t.chain.CurrentHeader()
+	head := current.Number.Uint64()
+	if conf.Number > head+pendingConfirmationWindow {
+		return ErrFutureConfirmation
+	}
+	if !withinPendingWindow(conf.Number, head) {
+		return ErrStaleConfirmation
+	}
+	signers, err := t.signers.Signers(t.chain, current)
+	if err != nil {
+		return err
+	}
+	if !containsAddress(signers, signer) {
+		return errPendingNotSigner
+	}
+	key := pendingConfirmation{hash: conf.Hash, signer: signer}
+	if _, ok := t.pending[key]; ok {
+		return ErrKnownConfirmation
+	}
+	if len(t.pending) >= maxPendingConfirmations {
+		return errPendingOverflow
+	}
+	t.pending[key] = conf
+	return errUnknownConfirmedBlock
+}
+
+// finalizeCanonical finalizes the highest canonical block confirmed by a quorum,
+// returning it if finality progressed.
+//
+// The caller must hold t.lock.
+func (t *FinalityTracker) finalizeCanonical() *types.Header {
+	var final *types.Header
+	for hash, header := range t.quorums {
+		if final != nil && header.Number.Cmp(final.Number) <= 0 {
+			continue
+		}
+		if canon := t.chain.GetHeaderByNumber(header.Number.Uint64()); canon != nil && canon.Hash() == hash {
+			final = header
+		}
+	}
+	if final != nil {
+		t.finalize(final)
+	}
+	return final
+}
+
+// finalize marks header as the highest final block and drops all confirmations
+// which became irrelevant.
+//
+// The caller must hold t.lock.
+func (t *FinalityTracker) finalize(header *types.Header) {
+	t.finalized = header
+	rawdb.WriteFinalizedBlockHash(t.db, header.Hash())
+
+	for hash, number := range t.numbers {
+		if number <= header.Number.Uint64() {
+			delete(t.votes, hash)
+			delete(t.numbers, hash)
+			delete(t.quorums, hash)
+		}
+	}
+}
+
+// evictLowest drops the confirmations of the lowest tracked block to make room
+// for a new one.
+//
+// The caller must hold t.lock.
+func (t *FinalityTracker) evictLowest() {
+	var (
+		lowest common.Hash
+		number uint64
+		found  bool
+	)
+	for hash, n := range t.numbers {
+		if !found || n < number {
+			lowest, number, found = hash, n, true
+		}
+	}
+	delete(t.votes, lowest)
+	delete(t.numbers, lowest)
+	delete(t.quorums, lowest)
+}
+
+// Finalized returns the highest block confirmed by a quorum of its signers, or
+// nil if no block was finalized yet.
+func (t *FinalityTracker) Finalized() *types.Header {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.finalized
+}
+
+// Confirmations returns the number of accepted confirmations of a not yet final
+// block.
+func (t *FinalityTracker) Confirmations(hash common.Hash) int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return len(t.votes[hash])
+}
+
+// SubscribeNewConfirmationsEvent registers a subscription of NewConfirmationsEvent.
+func (t *FinalityTracker) SubscribeNewConfirmationsEvent(ch chan<- NewConfirmationsEvent) event.Subscription {
+	return t.scope.Track(t.confirmFeed.Subscribe(ch))
+}
+
+// SubscribeChainFinalizedEvent registers a subscription of ChainFinalizedEvent.
+func (t *FinalityTracker) SubscribeChainFinalizedEvent(ch chan<- ChainFinalizedEvent) event.Subscription {
+	return t.scope.Track(t.finalizedFeed.Subscribe(ch))
+}
+
+// Stop terminates all subscriptions of the tracker.
+func (t *FinalityTracker) Stop() {
+	t.scope.Close()
+}
+
+// withinPendingWindow returns whether a confirmation of a block not yet imported
+// at the given number is within the window around the head to be held on to.
+func withinPendingWindow(number, head uint64) bool {
+	return number <= head+pendingConfirmationWindow && number+pendingConfirmationWindow >= head
+}
+
+// containsAddress returns whether addr is part of the given address list.
+func containsAddress(addrs []common.Address, addr common.Address) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,314 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/consensus"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/params"
+)
+
+// finalityTestChain is a header chain along with known side chain headers,
+// implementing the subset of consensus.ChainReader needed by the finality tracker.
+type finalityTestChain struct {
+	headers []*types.Header
+	side    map[common.Hash]*types.Header
+}
+
+func newFinalityTestChain(n int) *finalityTestChain {
+	chain := &finalityTestChain{side: make(map[common.Hash]*types.Header)}
+	parent := common.Hash{}
+	for i := 0; i < n; i++ {
+		header := &types.Header{ParentHash: parent, Number: big.NewInt(int64(i)), Time: big.NewInt(int64(i))}
+		chain.headers = append(chain.headers, header)
+		parent = header.Hash()
+	}
+	return chain
+}
+
+func (c *finalityTestChain) Config() *params.ChainConfig  { return params.AllAlienProtocolChanges }
+func (c *finalityTestChain) CurrentHeader() *types.Header { return c.headers[len(c.headers)-1] }
+
+func (c *finalityTestChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	if header := c.side[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+func (c *finalityTestChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number]
+}
+
+func (c *finalityTestChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+
+func (c *finalityTestChain) GetBlock(hash common.Hash, number uint64) *types.Block { return nil }
+
+// staticSigners is a signer set reader returning the same signers for every block.
+type staticSigners []common.Address
+
+func (s staticSigners) Signers(chain consensus.ChainReader, header *types.Header) ([]common.Address, error) {
+	return s, nil
+}
+
+func signTestConfirmation(t *testing.T, header *types.Header, key *ecdsa.PrivateKey) *types.Confirmation {
+	conf, err := types.SignConfirmation(types.NewConfirmation(params.AllAlienProtocolChanges.ChainId, header), key)
+	if err != nil {
+		t.Fatalf("failed to sign confirmation: %v", err)
+	}
+	return conf
+}
+
+func TestFinalityQuorum(t *testing.T) {
+	var (
+		keys    []*ecdsa.PrivateKey
+		signers staticSigners
+	)
+	for i := 0; i < 4; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		signers = append(signers, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	outsider, _ := crypto.GenerateKey()
+
+	db := ethdb.NewMemDatabase()
+	chain := newFinalityTestChain(5)
+	tracker := NewFinalityTracker(db, chain, signers)
+	defer tracker.Stop()
+
+	finalized := make(chan ChainFinalizedEvent, 1)
+	sub := tracker.SubscribeChainFinalizedEvent(finalized)
+	defer sub.Unsubscribe()
+
+	head := chain.headers[3]
+
+	// Non-signers and duplicates must not count towards the quorum
+	if err := tracker.Add([]*types.Confirmation{signTestConfirmation(t, head, outsider)})[0]; err == nil {
+		t.Fatalf("confirmation by non-signer accepted")
+	}
+	for i := 0; i < 2; i++ {
+		if err := tracker.Add([]*types.Confirmation{signTestConfirmation(t, head, keys[i])})[0]; err != nil {
+			t.Fatalf("confirmation %d rejected: %v", i, err)
+		}
+	}
+	if err := tracker.Add([]*types.Confirmation{signTestConfirmation(t, head, keys[0])})[0]; err != ErrKnownConfirmation {
+		t.Fatalf("duplicate confirmation error mismatch: have %v, want %v", err, ErrKnownConfirmation)
+	}
+	if tracker.Finalized() != nil {
+		t.Fatalf("block finalized below quorum")
+	}
+	// The third signer out of four exceeds the two thirds quorum
+	if err := tracker.Add([]*types.Confirmation{signTestConfirmation(t, head, keys[2])})[0]; err != nil {
+		t.Fatalf("confirmation rejected: %v", err)
+	}
+	if final := tracker.Finalized(); final == nil || final.Hash() != head.Hash() {
+		t.Fatalf("finalized block mismatch: have %v, want %v", final, head.Number)
+	}
+	select {
+	case ev := <-finalized:
+		if ev.Header.Hash() != head.Hash() {
+			t.Errorf("finalized event mismatch: have %v, want %v", ev.Header.Number, head.Number)
+		}
+	default:
+		t.Errorf("no finalized event fired")
+	}
+	if hash := rawdb.ReadFinalizedBlockHash(db); hash != head.Hash() {
+		t.Errorf("persisted finalized hash mismatch: have %x, want %x", hash, head.Hash())
+	}
+	// Confirmations of ancestors are stale from now on
+	if err := tracker.Add([]*types.Confirmation{signTestConfirmation(t, chain.headers[2], keys[3])})[0]; err != ErrStaleConfirmation {
+		t.Errorf("stale confirmation error mismatch: have %v, want %v", err, ErrStaleConfirmation)
+	}
+	// A restarted tracker resumes from the persisted finalized block
+	if final := NewFinalityTracker(db, chain, signers).Finalized(); final == nil || final.Hash() != head.Hash() {
+		t.Errorf("resumed finalized block mismatch: have %v, want %v", final, head.Number)
+	}
+}
+
+func TestFinalityRecheck(t *testing.T) {
+	var (
+		keys    []*ecdsa.PrivateKey
+		signers staticSigners
+	)
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		signers = append(signers, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	chain := newFinalityTestChain(3)
+	tracker := NewFinalityTracker(ethdb.NewMemDatabase(), chain, signers)
+	defer tracker.Stop()
+
+	// Confirmations of a side chain block count, but must not finalize it
+	side := &types.Header{ParentHash: chain.headers[1].Hash(), Number: big.NewInt(2), Time: big.NewInt(100)}
+	chain.side[side.Hash()] = side
+
+	for i, key := range keys {
+		if err := tracker.Add([]*types.Confirmation{signTestConfirmation(t, side, key)})[0]; err != nil {
+			t.Fatalf("side chain confirmation %d rejected: %v", i, err)
+		}
+	}
+	if final := tracker.Finalized(); final != nil {
+		t.Fatalf("side chain block finalized: %v", final.Number)
+	}
+	// Confirmations arriving ahead of their block must be kept until it's imported
+	head := &types.Header{ParentHash: side.Hash(), Number: big.NewInt(3), Time: big.NewInt(101)}
+	for i, key := range keys {
+		if err := tracker.Add([]*types.Confirmation{signTestConfirmation(t, head, key)})[0]; err != errUnknownConfirmedBlock {
+			t.Fatalf("future confirmation %d error mismatch: have %v, want %v", i, err, errUnknownConfirmedBlock)
+		}
+	}
+	tracker.Recheck()
+	if final := tracker.Finalized(); final != nil {
+		t.Fatalf("block finalized before being imported: %v", final.Number)
+	}
+	// Reorg onto the side chain, finalizing the new head
+	chain.headers = append(chain.headers[:2], side, head)
+	tracker.Recheck()
+
+	if final := tracker.Finalized(); final == nil || final.Hash() != head.Hash() {
+		t.Fatalf("finalized block mismatch: have %v, want %v", final, head.Number)
+	}
+	if n := tracker.Confirmations(side.Hash()); n != 0 {
+		t.Errorf("confirmations of finalized ancestor retained: %d", n)
+	}
+}
+
+// Tests that blocks are only finalized by strictly more than two thirds of their
+// signers.
+func TestFinalityQuorumBoundary(t *testing.T) {
+	tests := []struct {
+		signers int
+		quorum  int
+	}{
+		{3, 3}, // 2 out of 3 is exactly two thirds
+		{4, 3},
+		{6, 5}, // 4 out of 6 is exactly two thirds
+		{7, 5},
+	}
+	for i, tt := range tests {
+		var (
+			keys    []*ecdsa.PrivateKey
+			signers staticSigners
+		)
+		for j := 0; j < tt.signers; j++ {
+			key, _ := crypto.GenerateKey()
+			keys = append(keys, key)
+			signers = append(signers, crypto.PubkeyToAddress(key.PublicKey))
+		}
+		chain := newFinalityTestChain(3)
+		tracker := NewFinalityTracker(ethdb.NewMemDatabase(), chain, signers)
+
+		head := chain.CurrentHeader()
+		for j := 0; j < tt.quorum; j++ {
+			if final := tracker.Finalized(); final != nil {
+				t.Errorf("test %d: block finalized by %d out of %d signers", i, j, tt.signers)
+				break
+			}
+			if err := tracker.Add([]*types.Confirmation{signTestConfirmation(t, head, keys[j])})[0]; err != nil {
+				t.Fatalf("test %d: confirmation %d rejected: %v", i, j, err)
+			}
+		}
+		if final := tracker.Finalized(); final == nil || final.Hash() != head.Hash() {
+			t.Errorf("test %d: block not finalized by %d out of %d signers", i, tt.quorum, tt.signers)
+		}
+		tracker.Stop()
+	}
+}
+
+// Tests that confirmations of blocks not yet imported are only queued up if they
+// are close to the head and signed by a current signer, are deduplicated, and are
+// dropped once they went out of the window around the head.
+func TestFinalityPendingWindow(t *testing.T) {
+	var (
+		keys    []*ecdsa.PrivateKey
+		signers staticSigners
+	)
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		signers = append(signers, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	outsider, _ := crypto.GenerateKey()
+
+	chain := newFinalityTestChain(3)
+	tracker := NewFinalityTracker(ethdb.NewMemDatabase(), chain, signers)
+	defer tracker.Stop()
+
+	future := func(number uint64) *types.Header {
+		return &types.Header{Number: new(big.Int).SetUint64(number), Time: big.NewInt(1000)}
+	}
+	head := chain.CurrentHeader().Number.Uint64()
+
+	tests := []struct {
+		header *types.Header
+		key    *ecdsa.PrivateKey
+		err    error
+	}{
+		{future(head + 1), keys[0], errUnknownConfirmedBlock},
+		{future(head + 1), keys[0], ErrKnownConfirmation},
+		{future(head + 1), outsider, errPendingNotSigner},
+		{future(head + pendingConfirmationWindow), keys[1], errUnknownConfirmedBlock},
+		{future(head + pendingConfirmationWindow + 1), keys[1], ErrFutureConfirmation},
+	}
+	for i, tt := range tests {
+		if err := tracker.Add([]*types.Confirmation{signTestConfirmation(t, tt.header, tt.key)})[0]; err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	if len(tracker.pending) != 2 {
+		t.Fatalf("queued confirmation count mismatch: have %d, want %d", len(tracker.pending), 2)
+	}
+	// Extend the chain past the window of the first queued confirmation, which
+	// can never arrive any more
+	parent := chain.CurrentHeader()
+	for len(chain.headers) <= int(head+1+pendingConfirmationWindow+1) {
+		header := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(int64(len(chain.headers))), Time: big.NewInt(int64(len(chain.headers)))}
+		chain.headers = append(chain.headers, header)
+		parent = header
+	}
+	tracker.Recheck()
+	if len(tracker.pending) != 1 {
+		t.Errorf("queued confirmation count mismatch after recheck: have %d, want %d", len(tracker.pending), 1)
+	}
+	for _, conf := range tracker.pending {
+		if conf.Number != head+pendingConfirmationWindow {
+			t.Errorf("wrong queued confirmation retained: #%d", conf.Number)
+		}
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/relianz2019/relianz/core/rawdb"
 	"github.com/relianz2019/relianz/core/state"
 	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/core/vm"
 	"github.com/relianz2019/relianz/ethdb"
 	"github.com/relianz2019/relianz/log"
 	"github.com/relianz2019/relianz/params"
@@ -154,6 +155,14 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 	if genesis != nil && genesis.Config == nil {
 		return params.AllRlzashProtocolChanges, common.Hash{}, errGenesisNoConfig
 	}
+	if genesis != nil {
+		if err := genesis.Config.CheckForkOrder(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+		if err := vm.ValidatePrecompiles(genesis.Config); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
 
 	// Just commit the new block if there is no stored genesis block.
 	stored := rawdb.ReadCanonicalHash(db, 0)
@@ -198,13 +207,48 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 		return newcfg, stored, fmt.Errorf("missing block number for head header hash")
 	}
 	compatErr := storedcfg.CheckCompatible(newcfg, *height)
-	if compatErr != nil && *height != 0 && compatErr.RewindTo != 0 {
+	if compatErr != nil && *height != 0 {
 		return newcfg, stored, compatErr
 	}
 	rawdb.WriteChainConfig(db, stored, newcfg)
 	return newcfg, stored, nil
 }
 
+// CheckGenesisCompatible verifies, without modifying the database, that genesis
+// can be written on top of the chain stored in db. It returns a *GenesisMismatchError
+// if the genesis blocks differ, or a *params.ConfigCompatError if the new chain
+// configuration would alter already imported blocks.
+func CheckGenesisCompatible(db ethdb.Database, genesis *Genesis) error {
+	if genesis == nil || genesis.Config == nil {
+		return errGenesisNoConfig
+	}
+	if err := genesis.Config.CheckForkOrder(); err != nil {
+		return err
+	}
+	if err := vm.ValidatePrecompiles(genesis.Config); err != nil {
+		return err
+	}
+	stored := rawdb.ReadCanonicalHash(db, 0)
+	if (stored == common.Hash{}) {
+		return nil
+	}
+	if hash := genesis.ToBlock(nil).Hash(); hash != stored {
+		return &GenesisMismatchError{stored, hash}
+	}
+	storedcfg := rawdb.ReadChainConfig(db, stored)
+	if storedcfg == nil {
+		return nil
+	}
+	height := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadHeaderHash(db))
+	if height == nil {
+		return fmt.Errorf("missing block number for head header hash")
+	}
+	if compatErr := storedcfg.CheckCompatible(genesis.Config, *height); compatErr != nil && *height != 0 {
+		return compatErr
+	}
+	return nil
+}
+
 func (g *Genesis) configOrDefault(ghash common.Hash) *params.ChainConfig {
 	switch {
 	case g != nil:
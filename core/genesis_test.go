@@ -24,6 +24,7 @@ import (
 	"github.com/relianz2019/relianz/common"
 	"github.com/relianz2019/relianz/consensus/ethash"
 	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/core/types"
 	"github.com/relianz2019/relianz/core/vm"
 	"github.com/relianz2019/relianz/ethdb"
 	"github.com/relianz2019/relianz/params"
@@ -162,3 +163,55 @@ func TestSetupGenesis(t *testing.T) {
 		}
 	}
 }
+
+// Tests that a changed Alien parameter, which can only be corrected by rewinding
+// to the genesis block, is only written over the stored config while the chain is
+// still at genesis, and is reported as incompatible once blocks were imported.
+func TestSetupGenesisAlienIncompatible(t *testing.T) {
+	for _, height := range []uint64{0, 1} {
+		oldcfg := *params.AllAlienProtocolChanges
+		oldalien := *oldcfg.Alien
+		oldcfg.Alien = &oldalien
+
+		newcfg := oldcfg
+		newalien := oldalien
+		newalien.MaxSignerCount++
+		newcfg.Alien = &newalien
+
+		db := ethdb.NewMemDatabase()
+		genesis := (&Genesis{Config: &oldcfg}).MustCommit(db)
+		if height > 0 {
+			header := &types.Header{ParentHash: genesis.Hash(), Number: new(big.Int).SetUint64(height)}
+			rawdb.WriteHeader(db, header)
+			rawdb.WriteCanonicalHash(db, header.Hash(), height)
+			rawdb.WriteHeadHeaderHash(db, header.Hash())
+		}
+		config, hash, err := SetupGenesisBlock(db, &Genesis{Config: &newcfg})
+		if hash != genesis.Hash() {
+			t.Errorf("height %d: genesis hash mismatch: have %x, want %x", height, hash, genesis.Hash())
+		}
+		if !reflect.DeepEqual(config, &newcfg) {
+			t.Errorf("height %d: returned config mismatch: have %v, want %v", height, config, &newcfg)
+		}
+		if head := rawdb.ReadHeadHeaderHash(db); *rawdb.ReadHeaderNumber(db, head) != height {
+			t.Errorf("height %d: chain head rewound to #%d", height, *rawdb.ReadHeaderNumber(db, head))
+		}
+		stored := rawdb.ReadChainConfig(db, genesis.Hash())
+		if height == 0 {
+			if err != nil {
+				t.Fatalf("height %d: failed to set up genesis: %v", height, err)
+			}
+			if stored.Alien.MaxSignerCount != newalien.MaxSignerCount {
+				t.Errorf("height %d: stored max signer count mismatch: have %d, want %d", height, stored.Alien.MaxSignerCount, newalien.MaxSignerCount)
+			}
+			continue
+		}
+		compatErr, ok := err.(*params.ConfigCompatError)
+		if !ok || compatErr.RewindTo != 0 {
+			t.Fatalf("height %d: compatibility error mismatch: have %v, want rewind to 0", height, err)
+		}
+		if stored.Alien.MaxSignerCount != oldalien.MaxSignerCount {
+			t.Errorf("height %d: stored config overwritten: have max signer count %d, want %d", height, stored.Alien.MaxSignerCount, oldalien.MaxSignerCount)
+		}
+	}
+}
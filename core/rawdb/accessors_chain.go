@@ -27,11 +27,48 @@ import (
 	"github.com/relianz2019/relianz/rlp"
 )
 
+// readAncient retrieves a block component of the given kind from the ancient
+// store, if the database has one and the canonical block at the number is the
+// one requested.
+func readAncient(db DatabaseReader, kind string, hash common.Hash, number uint64) []byte {
+	ancients, ok := db.(AncientReader)
+	if !ok {
+		return nil
+	}
+	frozen, _ := ancients.Ancient(freezerHashTable, number)
+	if common.BytesToHash(frozen) != hash {
+		return nil
+	}
+	data, _ := ancients.Ancient(kind, number)
+	return data
+}
+
+// hasAncient reports whether a block component of the given kind is available
+// in the ancient store, if the database has one.
+func hasAncient(db DatabaseReader, kind string, hash common.Hash, number uint64) bool {
+	ancients, ok := db.(AncientReader)
+	if !ok {
+		return false
+	}
+	frozen, _ := ancients.Ancient(freezerHashTable, number)
+	if common.BytesToHash(frozen) != hash {
+		return false
+	}
+	has, _ := ancients.HasAncient(kind, number)
+	return has
+}
+
 // ReadCanonicalHash retrieves the hash assigned to a canonical block number.
 func ReadCanonicalHash(db DatabaseReader, number uint64) common.Hash {
 	data, _ := db.Get(append(append(headerPrefix, encodeBlockNumber(number)...), headerHashSuffix...))
 	if len(data) == 0 {
-		return common.Hash{}
+		// Fall back to the ancient store for frozen blocks
+		if ancients, ok := db.(AncientReader); ok {
+			data, _ = ancients.Ancient(freezerHashTable, number)
+		}
+		if len(data) == 0 {
+			return common.Hash{}
+		}
 	}
 	return common.BytesToHash(data)
 }
@@ -93,6 +130,23 @@ func WriteHeadBlockHash(db DatabaseWriter, hash common.Hash) {
 	}
 }
 
+// ReadFinalizedBlockHash retrieves the hash of the latest block confirmed by a
+// quorum of signers.
+func ReadFinalizedBlockHash(db DatabaseReader) common.Hash {
+	data, _ := db.Get(finalizedBlockKey)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteFinalizedBlockHash stores the hash of the latest finalized block.
+func WriteFinalizedBlockHash(db DatabaseWriter, hash common.Hash) {
+	if err := db.Put(finalizedBlockKey, hash.Bytes()); err != nil {
+		log.Crit("Failed to store last finalized block's hash", "err", err)
+	}
+}
+
 // ReadHeadFastBlockHash retrieves the hash of the current fast-sync head block.
 func ReadHeadFastBlockHash(db DatabaseReader) common.Hash {
 	data, _ := db.Get(headFastBlockKey)
@@ -130,6 +184,9 @@ func WriteFastTrieProgress(db DatabaseWriter, count uint64) {
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	if len(data) == 0 {
+		data = readAncient(db, freezerHeaderTable, hash, number)
+	}
 	return data
 }
 
@@ -137,7 +194,7 @@ func ReadHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawVa
 func HasHeader(db DatabaseReader, hash common.Hash, number uint64) bool {
 	key := append(append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
 	if has, err := db.Has(key); !has || err != nil {
-		return false
+		return hasAncient(db, freezerHeaderTable, hash, number)
 	}
 	return true
 }
@@ -193,6 +250,9 @@ func DeleteHeader(db DatabaseDeleter, hash common.Hash, number uint64) {
 // ReadBodyRLP retrieves the block body (transactions and uncles) in RLP encoding.
 func ReadBodyRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(append(append(blockBodyPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	if len(data) == 0 {
+		data = readAncient(db, freezerBodiesTable, hash, number)
+	}
 	return data
 }
 
@@ -208,7 +268,7 @@ func WriteBodyRLP(db DatabaseWriter, hash common.Hash, number uint64, rlp rlp.Ra
 func HasBody(db DatabaseReader, hash common.Hash, number uint64) bool {
 	key := append(append(blockBodyPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 	if has, err := db.Has(key); !has || err != nil {
-		return false
+		return hasAncient(db, freezerBodiesTable, hash, number)
 	}
 	return true
 }
@@ -243,9 +303,18 @@ func DeleteBody(db DatabaseDeleter, hash common.Hash, number uint64) {
 	}
 }
 
+// ReadTdRLP retrieves a block's total difficulty corresponding to the hash in RLP encoding.
+func ReadTdRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
+	data, _ := db.Get(append(append(append(headerPrefix, encodeBlockNumber(number)...), hash[:]...), headerTDSuffix...))
+	if len(data) == 0 {
+		data = readAncient(db, freezerDifficultyTable, hash, number)
+	}
+	return data
+}
+
 // ReadTd retrieves a block's total difficulty corresponding to the hash.
 func ReadTd(db DatabaseReader, hash common.Hash, number uint64) *big.Int {
-	data, _ := db.Get(append(append(append(headerPrefix, encodeBlockNumber(number)...), hash[:]...), headerTDSuffix...))
+	data := ReadTdRLP(db, hash, number)
 	if len(data) == 0 {
 		return nil
 	}
@@ -276,10 +345,19 @@ func DeleteTd(db DatabaseDeleter, hash common.Hash, number uint64) {
 	}
 }
 
+// ReadReceiptsRLP retrieves all the transaction receipts belonging to a block in RLP encoding.
+func ReadReceiptsRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
+	data, _ := db.Get(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	if len(data) == 0 {
+		data = readAncient(db, freezerReceiptTable, hash, number)
+	}
+	return data
+}
+
 // ReadReceipts retrieves all the transaction receipts belonging to a block.
 func ReadReceipts(db DatabaseReader, hash common.Hash, number uint64) types.Receipts {
 	// Retrieve the flattened receipt slice
-	data, _ := db.Get(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	data := ReadReceiptsRLP(db, hash, number)
 	if len(data) == 0 {
 		return nil
 	}
@@ -117,3 +117,80 @@ func WriteBloomBits(db DatabaseWriter, bit uint, section uint64, head common.Has
 		log.Crit("Failed to store bloom bits", "err", err)
 	}
 }
+
+// ReadSealRecord retrieves the seal record of a block, without its signer queue.
+func ReadSealRecord(db DatabaseReader, hash common.Hash, number uint64) *types.SealRecord {
+	data, _ := db.Get(append(append(sealRecordPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	if len(data) == 0 {
+		return nil
+	}
+	record := new(types.SealRecord)
+	if err := rlp.DecodeBytes(data, record); err != nil {
+		log.Error("Invalid seal record RLP", "number", number, "hash", hash, "err", err)
+		return nil
+	}
+	return record
+}
+
+// WriteSealRecord stores the seal record of a block. The signer queue of the
+// record is not stored, see WriteSignerQueue.
+func WriteSealRecord(db DatabaseWriter, hash common.Hash, number uint64, record *types.SealRecord) {
+	data, err := rlp.EncodeToBytes(record)
+	if err != nil {
+		log.Crit("Failed to encode seal record", "err", err)
+	}
+	if err := db.Put(append(append(sealRecordPrefix, encodeBlockNumber(number)...), hash.Bytes()...), data); err != nil {
+		log.Crit("Failed to store seal record", "err", err)
+	}
+}
+
+// ReadSignerQueue retrieves the signer queue of the loop started at the given time.
+func ReadSignerQueue(db DatabaseReader, loopStart uint64) []common.Address {
+	data, _ := db.Get(append(signerQueuePrefix, encodeBlockNumber(loopStart)...))
+	if len(data) == 0 {
+		return nil
+	}
+	var queue []common.Address
+	if err := rlp.DecodeBytes(data, &queue); err != nil {
+		log.Error("Invalid signer queue RLP", "loop", loopStart, "err", err)
+		return nil
+	}
+	return queue
+}
+
+// WriteSignerQueue stores the signer queue of the loop started at the given time.
+func WriteSignerQueue(db DatabaseWriter, loopStart uint64, queue []common.Address) {
+	data, err := rlp.EncodeToBytes(queue)
+	if err != nil {
+		log.Crit("Failed to encode signer queue", "err", err)
+	}
+	if err := db.Put(append(signerQueuePrefix, encodeBlockNumber(loopStart)...), data); err != nil {
+		log.Crit("Failed to store signer queue", "err", err)
+	}
+}
+
+// ReadSignerStats retrieves the accumulated signer statistics of an indexed
+// section, identified by the hash of its last block.
+func ReadSignerStats(db DatabaseReader, section uint64, head common.Hash) []*types.SignerStats {
+	data, _ := db.Get(append(append(signerStatsPrefix, encodeBlockNumber(section)...), head.Bytes()...))
+	if len(data) == 0 {
+		return nil
+	}
+	var stats []*types.SignerStats
+	if err := rlp.DecodeBytes(data, &stats); err != nil {
+		log.Error("Invalid signer stats RLP", "section", section, "head", head, "err", err)
+		return nil
+	}
+	return stats
+}
+
+// WriteSignerStats stores the accumulated signer statistics of an indexed section.
+func WriteSignerStats(db DatabaseWriter, section uint64, head common.Hash, stats []*types.SignerStats) {
+	data, err := rlp.EncodeToBytes(stats)
+	if err != nil {
+		log.Crit("Failed to encode signer stats", "err", err)
+	}
+	if err := db.Put(append(append(signerStatsPrefix, encodeBlockNumber(section)...), head.Bytes()...), data); err != nil {
+		log.Crit("Failed to store signer stats", "err", err)
+	}
+}
@@ -88,3 +88,37 @@ func WritePreimages(db DatabaseWriter, number uint64, preimages map[common.Hash]
 	preimageCounter.Inc(int64(len(preimages)))
 	preimageHitCounter.Inc(int64(len(preimages)))
 }
+
+// ReadPruningRoots retrieves the state roots retained by an interrupted offline
+// state pruning, or nil if no pruning is in progress.
+func ReadPruningRoots(db DatabaseReader) []common.Hash {
+	data, _ := db.Get(pruningRootsKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var roots []common.Hash
+	if err := rlp.DecodeBytes(data, &roots); err != nil {
+		log.Error("Invalid pruning roots RLP", "err", err)
+		return nil
+	}
+	return roots
+}
+
+// WritePruningRoots stores the state roots retained by an offline state pruning
+// before it starts deleting anything.
+func WritePruningRoots(db DatabaseWriter, roots []common.Hash) {
+	data, err := rlp.EncodeToBytes(roots)
+	if err != nil {
+		log.Crit("Failed to RLP encode pruning roots", "err", err)
+	}
+	if err := db.Put(pruningRootsKey, data); err != nil {
+		log.Crit("Failed to store pruning roots", "err", err)
+	}
+}
+
+// DeletePruningRoots removes the state roots of a finished offline state pruning.
+func DeletePruningRoots(db DatabaseDeleter) {
+	if err := db.Delete(pruningRootsKey); err != nil {
+		log.Crit("Failed to delete pruning roots", "err", err)
+	}
+}
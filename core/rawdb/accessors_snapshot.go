@@ -0,0 +1,120 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/log"
+	"github.com/relianz2019/relianz/rlp"
+)
+
+// ReadSnapshotRoot retrieves the state root the persisted flat state snapshot
+// represents, or an empty hash if no snapshot was persisted yet.
+func ReadSnapshotRoot(db DatabaseReader) common.Hash {
+	data, _ := db.Get(snapshotRootKey)
+	if len(data) != common.HashLength {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteSnapshotRoot stores the state root the persisted flat state snapshot
+// represents.
+func WriteSnapshotRoot(db DatabaseWriter, root common.Hash) {
+	if err := db.Put(snapshotRootKey, root[:]); err != nil {
+		log.Crit("Failed to store snapshot root", "err", err)
+	}
+}
+
+// ReadSnapshotGenerator retrieves the hash of the last account an unfinished
+// state snapshot generation completed, an empty non-nil marker if it did not
+// complete any yet, or nil if the snapshot is fully generated.
+func ReadSnapshotGenerator(db DatabaseReader) []byte {
+	data, _ := db.Get(snapshotGeneratorKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var marker []byte
+	if err := rlp.DecodeBytes(data, &marker); err != nil {
+		log.Error("Invalid snapshot generator RLP", "err", err)
+		return []byte{}
+	}
+	if marker == nil {
+		marker = []byte{}
+	}
+	return marker
+}
+
+// WriteSnapshotGenerator stores the progress of an unfinished state snapshot
+// generation.
+func WriteSnapshotGenerator(db DatabaseWriter, marker []byte) {
+	data, err := rlp.EncodeToBytes(marker)
+	if err != nil {
+		log.Crit("Failed to RLP encode snapshot generator", "err", err)
+	}
+	if err := db.Put(snapshotGeneratorKey, data); err != nil {
+		log.Crit("Failed to store snapshot generator", "err", err)
+	}
+}
+
+// DeleteSnapshotGenerator removes the progress marker of a finished state
+// snapshot generation.
+func DeleteSnapshotGenerator(db DatabaseDeleter) {
+	if err := db.Delete(snapshotGeneratorKey); err != nil {
+		log.Crit("Failed to delete snapshot generator", "err", err)
+	}
+}
+
+// ReadAccountSnapshot retrieves the snapshot entry of an account trie leaf.
+func ReadAccountSnapshot(db DatabaseReader, hash common.Hash) []byte {
+	data, _ := db.Get(append(SnapshotAccountPrefix, hash[:]...))
+	return data
+}
+
+// WriteAccountSnapshot stores the snapshot entry of an account trie leaf.
+func WriteAccountSnapshot(db DatabaseWriter, hash common.Hash, entry []byte) {
+	if err := db.Put(append(SnapshotAccountPrefix, hash[:]...), entry); err != nil {
+		log.Crit("Failed to store account snapshot", "err", err)
+	}
+}
+
+// DeleteAccountSnapshot removes the snapshot entry of an account trie leaf.
+func DeleteAccountSnapshot(db DatabaseDeleter, hash common.Hash) {
+	if err := db.Delete(append(SnapshotAccountPrefix, hash[:]...)); err != nil {
+		log.Crit("Failed to delete account snapshot", "err", err)
+	}
+}
+
+// ReadStorageSnapshot retrieves the snapshot entry of a storage trie leaf.
+func ReadStorageSnapshot(db DatabaseReader, accountHash, storageHash common.Hash) []byte {
+	data, _ := db.Get(append(append(SnapshotStoragePrefix, accountHash[:]...), storageHash[:]...))
+	return data
+}
+
+// WriteStorageSnapshot stores the snapshot entry of a storage trie leaf.
+func WriteStorageSnapshot(db DatabaseWriter, accountHash, storageHash common.Hash, entry []byte) {
+	if err := db.Put(append(append(SnapshotStoragePrefix, accountHash[:]...), storageHash[:]...), entry); err != nil {
+		log.Crit("Failed to store storage snapshot", "err", err)
+	}
+}
+
+// DeleteStorageSnapshot removes the snapshot entry of a storage trie leaf.
+func DeleteStorageSnapshot(db DatabaseDeleter, accountHash, storageHash common.Hash) {
+	if err := db.Delete(append(append(SnapshotStoragePrefix, accountHash[:]...), storageHash[:]...)); err != nil {
+		log.Crit("Failed to delete storage snapshot", "err", err)
+	}
+}
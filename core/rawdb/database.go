@@ -0,0 +1,81 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"fmt"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/log"
+)
+
+// freezerdb is a database wrapper that enables freezer data retrievals.
+type freezerdb struct {
+	ethdb.Database
+	*freezer
+}
+
+// Close implements ethdb.Database, closing both the fast key-value store and
+// the slow ancient tables.
+func (frdb *freezerdb) Close() {
+	if err := frdb.freezer.Close(); err != nil {
+		log.Error("Failed to close ancient database", "err", err)
+	}
+	frdb.Database.Close()
+}
+
+// KeyValueStore returns the key-value store backing a database, unwrapping the
+// ancient store if the database has one.
+func KeyValueStore(db ethdb.Database) ethdb.Database {
+	if frdb, ok := db.(*freezerdb); ok {
+		return frdb.Database
+	}
+	return db
+}
+
+// NewDatabaseWithFreezer creates a high level database on top of a given key-
+// value data store with a freezer moving immutable chain segments older than
+// the threshold into cold storage.
+func NewDatabaseWithFreezer(db ethdb.Database, freezer string, threshold uint64) (ethdb.Database, error) {
+	frdb, err := newFreezer(freezer, threshold)
+	if err != nil {
+		return nil, err
+	}
+	// Since the freezer can be stored separately from the user's key-value database,
+	// there's a fairly high probability that the user requests invalid combinations
+	// of the freezer and database. Ensure that we don't shoot ourselves in the foot
+	// by serving up conflicting data, leading to both datastores getting corrupted.
+	if frozen, _ := frdb.Ancients(); frozen > 0 {
+		genesis, err := frdb.Ancient(freezerHashTable, 0)
+		if err != nil {
+			frdb.Close()
+			return nil, fmt.Errorf("failed to retrieve genesis from ancient %v", err)
+		}
+		if number := ReadHeaderNumber(db, common.BytesToHash(genesis)); number == nil || *number != 0 {
+			frdb.Close()
+			return nil, fmt.Errorf("ancient chain segment %x is not part of the database", genesis)
+		}
+	}
+	frdb.wg.Add(1)
+	go frdb.freeze(db)
+
+	return &freezerdb{
+		Database: db,
+		freezer:  frdb,
+	}, nil
+}
@@ -0,0 +1,476 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/prometheus/util/flock"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/log"
+)
+
+var (
+	// errUnknownTable is returned if the user attempts to read from a table that is
+	// not tracked by the freezer.
+	errUnknownTable = errors.New("unknown table")
+
+	// errOutOrderInsertion is returned if the user attempts to inject out-of-order
+	// binary blobs into the freezer.
+	errOutOrderInsertion = errors.New("the append operation is out-order")
+)
+
+const (
+	// freezerRecheckInterval is the frequency to check the key-value database for
+	// chain progression that might permit new blocks to be frozen into immutable
+	// storage.
+	freezerRecheckInterval = time.Minute
+
+	// freezerBatchLimit is the maximum number of blocks to freeze in one batch
+	// before doing an fsync and deleting it from the key-value store.
+	freezerBatchLimit = 30000
+
+	// freezerTableSize is the maximum size of a single data file of a freezer
+	// table before it is rolled over into a new one.
+	freezerTableSize = 2 * 1000 * 1000 * 1000
+)
+
+// freezer is an append-only database to store immutable chain data into flat
+// files. Keeping old canonical blocks out of the key-value store spares it the
+// ever growing compaction costs of data that is only ever read.
+type freezer struct {
+	frozen    uint64 // Number of blocks already frozen, accessed atomically
+	threshold uint64 // Number of recent blocks not to freeze
+
+	tables       map[string]*freezerTable // Data tables for storing everything
+	instanceLock flock.Releaser           // File-system lock to prevent double opens
+	writeLock    sync.Mutex               // Serializes appends against truncations
+
+	quit chan struct{}
+	wg   sync.WaitGroup // Tracks the background freezing thread
+}
+
+// newFreezer creates a chain freezer that moves ancient chain data into
+// append-only flat file containers.
+func newFreezer(datadir string, threshold uint64) (*freezer, error) {
+	// Ensure the datadir is not a symbolic link if it exists.
+	if info, err := os.Lstat(datadir); !os.IsNotExist(err) {
+		if info.Mode()&os.ModeSymlink != 0 {
+			log.Warn("Symbolic link ancient database is not supported", "path", datadir)
+			return nil, errors.New("symbolic link datadir is not supported")
+		}
+	}
+	if err := os.MkdirAll(datadir, 0755); err != nil {
+		return nil, err
+	}
+	// Leveldb uses LOCK as the filelock filename. To prevent the
+	// name collision, we use FLOCK as the lock name.
+	lock, _, err := flock.New(filepath.Join(datadir, "FLOCK"))
+	if err != nil {
+		return nil, err
+	}
+	// Open all the supported data tables
+	freezer := &freezer{
+		threshold:    threshold,
+		tables:       make(map[string]*freezerTable),
+		instanceLock: lock,
+		quit:         make(chan struct{}),
+	}
+	for name, disableSnappy := range freezerNoSnappy {
+		table, err := newTable(datadir, name, freezerTableSize, disableSnappy)
+		if err != nil {
+			for _, table := range freezer.tables {
+				table.Close()
+			}
+			lock.Release()
+			return nil, err
+		}
+		freezer.tables[name] = table
+	}
+	if err := freezer.repair(); err != nil {
+		for _, table := range freezer.tables {
+			table.Close()
+		}
+		lock.Release()
+		return nil, err
+	}
+	log.Info("Opened ancient database", "database", datadir, "frozen", atomic.LoadUint64(&freezer.frozen))
+	return freezer, nil
+}
+
+// Close terminates the chain freezer, unmapping all the data files.
+func (f *freezer) Close() error {
+	select {
+	case <-f.quit:
+		return nil // already closed
+	default:
+		close(f.quit)
+	}
+	f.wg.Wait()
+
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := f.instanceLock.Release(); err != nil {
+		errs = append(errs, err)
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists
+// in the freezer.
+func (f *freezer) HasAncient(kind string, number uint64) (bool, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.has(number), nil
+	}
+	return false, nil
+}
+
+// Ancient retrieves an ancient binary blob from the append-only immutable files.
+func (f *freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.Retrieve(number)
+	}
+	return nil, errUnknownTable
+}
+
+// Ancients returns the length of the frozen items.
+func (f *freezer) Ancients() (uint64, error) {
+	return atomic.LoadUint64(&f.frozen), nil
+}
+
+// AncientSize returns the ancient size of the specified category.
+func (f *freezer) AncientSize(kind string) (uint64, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.size()
+	}
+	return 0, errUnknownTable
+}
+
+// AppendAncient injects all binary blobs belong to block at the end of the
+// append-only immutable table files.
+//
+// Appends are serialized against each other and against truncations, so all
+// out-of-order injections are rejected.
+func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, td []byte) (err error) {
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	// Ensure the binary blobs we are appending is continuous with freezer.
+	if atomic.LoadUint64(&f.frozen) != number {
+		return errOutOrderInsertion
+	}
+	// Rollback all inserted data if any insertion below failed to ensure
+	// the tables won't out of sync.
+	defer func() {
+		if err != nil {
+			rerr := f.repair()
+			if rerr != nil {
+				log.Crit("Failed to repair freezer", "err", rerr)
+			}
+			log.Info("Append ancient failed", "number", number, "err", err)
+		}
+	}()
+	// Inject all the components into the relevant data tables
+	if err := f.tables[freezerHashTable].Append(number, hash[:]); err != nil {
+		log.Error("Failed to append ancient hash", "number", number, "hash", hash, "err", err)
+		return err
+	}
+	if err := f.tables[freezerHeaderTable].Append(number, header); err != nil {
+		log.Error("Failed to append ancient header", "number", number, "hash", hash, "err", err)
+		return err
+	}
+	if err := f.tables[freezerBodiesTable].Append(number, body); err != nil {
+		log.Error("Failed to append ancient body", "number", number, "hash", hash, "err", err)
+		return err
+	}
+	if err := f.tables[freezerReceiptTable].Append(number, receipts); err != nil {
+		log.Error("Failed to append ancient receipts", "number", number, "hash", hash, "err", err)
+		return err
+	}
+	if err := f.tables[freezerDifficultyTable].Append(number, td); err != nil {
+		log.Error("Failed to append ancient difficulty", "number", number, "hash", hash, "err", err)
+		return err
+	}
+	atomic.AddUint64(&f.frozen, 1) // Only modify atomically
+	return nil
+}
+
+// TruncateAncients discards any recent data above the provided threshold number.
+func (f *freezer) TruncateAncients(items uint64) error {
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	if atomic.LoadUint64(&f.frozen) <= items {
+		return nil
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, items)
+	return nil
+}
+
+// Sync flushes all data tables to disk.
+func (f *freezer) Sync() error {
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// freeze is a background thread that periodically checks the blockchain for any
+// import progress and moves ancient data from the fast database into the freezer.
+//
+// This functionality is deliberately broken off from block importing to avoid
+// incurring additional data shuffling delays on block propagation.
+func (f *freezer) freeze(db ethdb.Database) {
+	defer f.wg.Done()
+
+	for {
+		select {
+		case <-f.quit:
+			log.Info("Freezer shutting down")
+			return
+		default:
+		}
+		if !f.freezeBatch(db) {
+			select {
+			case <-time.NewTimer(freezerRecheckInterval).C:
+			case <-f.quit:
+				log.Info("Freezer shutting down")
+				return
+			}
+		}
+	}
+}
+
+// freezeBatch moves the next batch of blocks past the immutability threshold
+// from the key-value store into the freezer. It reports whether the batch was
+// full, and so whether another one should be attempted right away.
+func (f *freezer) freezeBatch(db ethdb.Database) bool {
+	// Retrieve the freezing threshold.
+	hash := ReadHeadBlockHash(db)
+	if hash == (common.Hash{}) {
+		log.Debug("Current full block hash unavailable") // new chain, empty database
+		return false
+	}
+	number := ReadHeaderNumber(db, hash)
+	frozen := atomic.LoadUint64(&f.frozen)
+	switch {
+	case number == nil:
+		log.Error("Current full block number unavailable", "hash", hash)
+		return false
+
+	case *number < f.threshold:
+		log.Debug("Current full block not old enough", "number", *number, "hash", hash, "delay", f.threshold)
+		return false
+
+	case *number-f.threshold <= frozen:
+		log.Debug("Ancient blocks frozen already", "number", *number, "hash", hash, "frozen", frozen)
+		return false
+	}
+	// Seems we have data ready to be frozen, process in usable batches
+	limit := *number - f.threshold
+	if limit-frozen > freezerBatchLimit {
+		limit = frozen + freezerBatchLimit
+	}
+	var (
+		start    = time.Now()
+		first    = frozen
+		ancients = make([]common.Hash, 0, limit-frozen)
+	)
+loop:
+	for next := first; next <= limit; next++ {
+		select {
+		case <-f.quit:
+			break loop
+		default:
+		}
+		// Retrieves all the components of the canonical block
+		hash := ReadCanonicalHash(db, next)
+		if hash == (common.Hash{}) {
+			log.Error("Canonical hash missing, can't freeze", "number", next)
+			break
+		}
+		header := ReadHeaderRLP(db, hash, next)
+		if len(header) == 0 {
+			log.Error("Block header missing, can't freeze", "number", next, "hash", hash)
+			break
+		}
+		body := ReadBodyRLP(db, hash, next)
+		if len(body) == 0 {
+			log.Error("Block body missing, can't freeze", "number", next, "hash", hash)
+			break
+		}
+		receipts := ReadReceiptsRLP(db, hash, next)
+		if len(receipts) == 0 {
+			log.Error("Block receipts missing, can't freeze", "number", next, "hash", hash)
+			break
+		}
+		td := ReadTdRLP(db, hash, next)
+		if len(td) == 0 {
+			log.Error("Total difficulty missing, can't freeze", "number", next, "hash", hash)
+			break
+		}
+		log.Trace("Deep froze ancient block", "number", next, "hash", hash)
+		// Inject all the components into the relevant data tables
+		if err := f.AppendAncient(next, hash[:], header, body, receipts, td); err != nil {
+			break
+		}
+		ancients = append(ancients, hash)
+	}
+	if len(ancients) == 0 {
+		return false
+	}
+	// Batch of blocks have been frozen, flush them before wiping from leveldb
+	if err := f.Sync(); err != nil {
+		log.Crit("Failed to flush frozen tables", "err", err)
+	}
+	// Wipe out all data from the active database
+	for i, hash := range ancients {
+		number := first + uint64(i)
+		DeleteBody(db, hash, number)
+		DeleteReceipts(db, hash, number)
+		DeleteTd(db, hash, number)
+		DeleteCanonicalHash(db, number)
+
+		// The hash to number mapping is kept, it's the index into the freezer
+		if err := db.Delete(append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...)); err != nil {
+			log.Crit("Failed to delete header", "err", err)
+		}
+		deleteSideBlocks(db, number, hash)
+	}
+	log.Info("Deep froze chain segment", "blocks", len(ancients), "elapsed", common.PrettyDuration(time.Since(start)),
+		"number", first+uint64(len(ancients))-1, "hash", ancients[len(ancients)-1])
+
+	// Avoid database thrashing with tiny writes
+	return uint64(len(ancients)) >= freezerBatchLimit
+}
+
+// deleteSideBlocks wipes all the non-canonical headers, bodies, receipts and
+// difficulties stored at a frozen height.
+// Their number mappings are dropped too, as nothing can reach them anymore.
+func deleteSideBlocks(db ethdb.Database, number uint64, canonical common.Hash) {
+	var side []common.Hash
+
+	it := db.NewIteratorWithPrefix(append(headerPrefix, encodeBlockNumber(number)...))
+	for it.Next() {
+		if key := it.Key(); len(key) == len(headerPrefix)+8+common.HashLength {
+			if hash := common.BytesToHash(key[len(key)-common.HashLength:]); hash != canonical {
+				side = append(side, hash)
+			}
+		}
+	}
+	it.Release()
+
+	for _, hash := range side {
+		DeleteBlock(db, hash, number)
+	}
+}
+
+// repair truncates all data tables to the same length.
+func (f *freezer) repair() error {
+	min := uint64(math.MaxUint64)
+	for _, table := range f.tables {
+		items := atomic.LoadUint64(&table.items)
+		if min > items {
+			min = items
+		}
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(min); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, min)
+	return nil
+}
+
+// FreezerTableInfo describes the content of a single table of the freezer.
+type FreezerTableInfo struct {
+	Name  string             // Name of the table
+	Items uint64             // Number of items stored in the table
+	Size  common.StorageSize // Disk space used by the table
+}
+
+// FreezerInfo describes the content of a freezer.
+type FreezerInfo struct {
+	Frozen uint64             // Number of blocks frozen
+	First  common.Hash        // Hash of the first frozen block
+	Last   common.Hash        // Hash of the last frozen block
+	Tables []FreezerTableInfo // Content of the data tables, sorted by name
+}
+
+// InspectFreezer opens the freezer in the given directory, repairing it if need
+// be, and describes its content. It fails if the freezer is in use.
+func InspectFreezer(datadir string) (*FreezerInfo, error) {
+	f, err := newFreezer(datadir, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info := &FreezerInfo{Frozen: atomic.LoadUint64(&f.frozen)}
+	if info.Frozen > 0 {
+		first, err := f.Ancient(freezerHashTable, 0)
+		if err != nil {
+			return nil, err
+		}
+		last, err := f.Ancient(freezerHashTable, info.Frozen-1)
+		if err != nil {
+			return nil, err
+		}
+		info.First, info.Last = common.BytesToHash(first), common.BytesToHash(last)
+	}
+	for name, table := range f.tables {
+		size, err := table.size()
+		if err != nil {
+			return nil, err
+		}
+		info.Tables = append(info.Tables, FreezerTableInfo{
+			Name:  name,
+			Items: atomic.LoadUint64(&table.items),
+			Size:  common.StorageSize(size),
+		})
+	}
+	sort.Slice(info.Tables, func(i, j int) bool { return info.Tables[i].Name < info.Tables[j].Name })
+	return info, nil
+}
@@ -0,0 +1,492 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/golang/snappy"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/log"
+)
+
+var (
+	// errClosed is returned if an operation attempts to read from or write to the
+	// freezer table after it has already been closed.
+	errClosed = errors.New("closed")
+
+	// errOutOfBounds is returned if the item requested is not contained within the
+	// freezer table.
+	errOutOfBounds = errors.New("out of bounds")
+)
+
+// indexEntrySize is the size of an encoded index entry: a 2 byte data file number
+// and a 4 byte offset within it.
+const indexEntrySize = 6
+
+// indexEntry contains the number/id of the file that the data resides in, as well
+// as the offset within the file to the end of the data. The first entry of the
+// index holds the file number of the first item and a zero offset.
+type indexEntry struct {
+	filenum uint16 // Data file the item is stored in
+	offset  uint32 // Offset within the data file to the end of the item
+}
+
+// unmarshalBinary deserializes binary b into the index entry.
+func (i *indexEntry) unmarshalBinary(b []byte) {
+	i.filenum = binary.BigEndian.Uint16(b[:2])
+	i.offset = binary.BigEndian.Uint32(b[2:6])
+}
+
+// marshallBinary serializes the index entry into binary.
+func (i *indexEntry) marshallBinary() []byte {
+	b := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint16(b[:2], i.filenum)
+	binary.BigEndian.PutUint32(b[2:6], i.offset)
+	return b
+}
+
+// freezerTable represents a single chained data table within the freezer (e.g.
+// blocks). It consists of a data file (snappy encoded arbitrary data blobs) and
+// an index file (uncompressed 6 byte entries into the data file). Data is only
+// ever appended, or truncated from the head.
+type freezerTable struct {
+	items uint64 // Number of items stored in the table, accessed atomically
+
+	noCompression bool   // if true, disables snappy compression. Note: does not work retroactively
+	maxFileSize   uint32 // Max file size for data-files
+	name          string
+	path          string
+
+	head   *os.File            // File descriptor for the data head of the table
+	files  map[uint16]*os.File // open files
+	headID uint16              // number of the currently active head file
+	index  *os.File            // File descriptor for the indexEntry file of the table
+
+	headBytes uint32 // Number of bytes written to the head file
+	lock      sync.RWMutex
+}
+
+// newTable opens a freezer table, creating the data and index files if they are
+// non existent. Both files are truncated to the shortest common length to ensure
+// they don't go out of sync.
+func newTable(path string, name string, maxFileSize uint32, noCompression bool) (*freezerTable, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	idxName := fmt.Sprintf("%s.ridx", name)
+	if !noCompression {
+		idxName = fmt.Sprintf("%s.cidx", name)
+	}
+	offsets, err := os.OpenFile(filepath.Join(path, idxName), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	tab := &freezerTable{
+		index:         offsets,
+		files:         make(map[uint16]*os.File),
+		name:          name,
+		path:          path,
+		maxFileSize:   maxFileSize,
+		noCompression: noCompression,
+	}
+	if err := tab.repair(); err != nil {
+		tab.Close()
+		return nil, err
+	}
+	return tab, nil
+}
+
+// repair cross checks the head and the index file and truncates them to be in
+// sync with each other after a potential crash / data loss.
+func (t *freezerTable) repair() error {
+	buffer := make([]byte, indexEntrySize)
+
+	// Ensure the index is a multiple of indexEntrySize bytes, starting with the
+	// entry of the first item
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.Size() == 0 {
+		if _, err := t.index.Write((&indexEntry{}).marshallBinary()); err != nil {
+			return err
+		}
+		stat, err = t.index.Stat()
+		if err != nil {
+			return err
+		}
+	}
+	if overflow := stat.Size() % indexEntrySize; overflow != 0 {
+		t.index.Truncate(stat.Size() - overflow) // New file can't trigger this path
+	}
+	offsetsSize := stat.Size() - stat.Size()%indexEntrySize
+
+	// Open the head file, whatever its id, to compare against the last index entry
+	var lastIndex indexEntry
+	if _, err := t.index.ReadAt(buffer, offsetsSize-indexEntrySize); err != nil {
+		return err
+	}
+	lastIndex.unmarshalBinary(buffer)
+
+	t.head, err = t.openFile(lastIndex.filenum, os.O_RDWR|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return err
+	}
+	if stat, err = t.head.Stat(); err != nil {
+		return err
+	}
+	contentSize := stat.Size()
+
+	// Keep truncating both files until they come in sync
+	contentExp := int64(lastIndex.offset)
+	for contentExp != contentSize {
+		// Truncate the head file to the last offset pointer
+		if contentExp < contentSize {
+			log.Warn("Truncating dangling head", "table", t.name, "indexed", common.StorageSize(contentExp), "stored", common.StorageSize(contentSize))
+			if err := t.head.Truncate(contentExp); err != nil {
+				return err
+			}
+			contentSize = contentExp
+		}
+		// Truncate the index to point within the head file
+		if contentExp > contentSize {
+			log.Warn("Truncating dangling indexes", "table", t.name, "indexed", common.StorageSize(contentExp), "stored", common.StorageSize(contentSize))
+			if err := t.index.Truncate(offsetsSize - indexEntrySize); err != nil {
+				return err
+			}
+			offsetsSize -= indexEntrySize
+			if offsetsSize < indexEntrySize {
+				return fmt.Errorf("freezer table %s index corrupted", t.name)
+			}
+			t.index.ReadAt(buffer, offsetsSize-indexEntrySize)
+			var newLastIndex indexEntry
+			newLastIndex.unmarshalBinary(buffer)
+
+			// We might have slipped back into an earlier head-file here
+			if newLastIndex.filenum != lastIndex.filenum {
+				// release earlier opened file
+				t.releaseFile(lastIndex.filenum)
+				if t.head, err = t.openFile(newLastIndex.filenum, os.O_RDWR|os.O_CREATE|os.O_APPEND); err != nil {
+					return err
+				}
+				if stat, err = t.head.Stat(); err != nil {
+					return err
+				}
+				contentSize = stat.Size()
+			}
+			lastIndex = newLastIndex
+			contentExp = int64(lastIndex.offset)
+		}
+	}
+	// Ensure all reparation changes have been written to disk
+	if err := t.index.Sync(); err != nil {
+		return err
+	}
+	if err := t.head.Sync(); err != nil {
+		return err
+	}
+	// Update the item and byte counters and return
+	t.items = uint64(offsetsSize/indexEntrySize - 1) // last indexEntry points to the end of the data file
+	t.headBytes = uint32(contentSize)
+	t.headID = lastIndex.filenum
+
+	// Close opened files and preopen all files
+	if err := t.preopen(); err != nil {
+		return err
+	}
+	log.Debug("Chain freezer table opened", "items", t.items, "size", common.StorageSize(t.headBytes))
+	return nil
+}
+
+// preopen opens all files that the freezer will need. This method should be called
+// from an init-context, since it assumes that it doesn't have to bother with
+// locking. The rationale for doing preopen is to not have to do it from within
+// Retrieve, thus not needing to ever obtain a write-lock within Retrieve.
+func (t *freezerTable) preopen() (err error) {
+	// The repair might have already opened (some) files
+	t.releaseFilesAfter(0, false)
+
+	// Open all except head in RDONLY
+	var first indexEntry
+	buffer := make([]byte, indexEntrySize)
+	if _, err := t.index.ReadAt(buffer, 0); err != nil {
+		return err
+	}
+	first.unmarshalBinary(buffer)
+
+	for i := first.filenum; i < t.headID; i++ {
+		if _, err = t.openFile(i, os.O_RDONLY); err != nil {
+			return err
+		}
+	}
+	// Open head in read/write
+	t.head, err = t.openFile(t.headID, os.O_RDWR|os.O_CREATE|os.O_APPEND)
+	return err
+}
+
+// truncate discards any recent data above the provided threshold number.
+func (t *freezerTable) truncate(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// If our item count is correct, don't do anything
+	if atomic.LoadUint64(&t.items) <= items {
+		return nil
+	}
+	// Something's out of sync, truncate the table's offset index
+	log.Warn("Truncating freezer table", "table", t.name, "items", t.items, "limit", items)
+	if err := t.index.Truncate(int64(items+1) * indexEntrySize); err != nil {
+		return err
+	}
+	// Calculate the new expected size of the data file and truncate it
+	buffer := make([]byte, indexEntrySize)
+	if _, err := t.index.ReadAt(buffer, int64(items*indexEntrySize)); err != nil {
+		return err
+	}
+	var expected indexEntry
+	expected.unmarshalBinary(buffer)
+
+	// We might need to truncate back to older files
+	if expected.filenum != t.headID {
+		// If already open for reading, force-reopen for writing
+		t.releaseFile(expected.filenum)
+		newHead, err := t.openFile(expected.filenum, os.O_RDWR|os.O_APPEND)
+		if err != nil {
+			return err
+		}
+		// Release any files _after the current head -- both the previous head
+		// and any files which may have been opened for reading
+		t.releaseFilesAfter(expected.filenum, true)
+
+		// Set back the historic head
+		t.head = newHead
+		t.headID = expected.filenum
+	}
+	if err := t.head.Truncate(int64(expected.offset)); err != nil {
+		return err
+	}
+	// All data files truncated, set internal counters and return
+	atomic.StoreUint64(&t.items, items)
+	t.headBytes = expected.offset
+	return nil
+}
+
+// Close closes all opened files.
+func (t *freezerTable) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var errs []error
+	if err := t.index.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	t.index = nil
+
+	for _, f := range t.files {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.head = nil
+
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// openFile assumes that the write-lock is held by the caller.
+func (t *freezerTable) openFile(num uint16, flag int) (f *os.File, err error) {
+	var exist bool
+	if f, exist = t.files[num]; !exist {
+		var name string
+		if t.noCompression {
+			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
+		} else {
+			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
+		}
+		f, err = os.OpenFile(filepath.Join(t.path, name), flag, 0644)
+		if err != nil {
+			return nil, err
+		}
+		t.files[num] = f
+	}
+	return f, err
+}
+
+// releaseFile closes a file, and removes it from the open file cache. Assumes
+// that the caller holds the write lock.
+func (t *freezerTable) releaseFile(num uint16) {
+	if f, exist := t.files[num]; exist {
+		delete(t.files, num)
+		f.Close()
+	}
+}
+
+// releaseFilesAfter closes all open files with a higher number, and optionally
+// also deletes the files.
+func (t *freezerTable) releaseFilesAfter(num uint16, remove bool) {
+	for fnum, f := range t.files {
+		if fnum > num {
+			delete(t.files, fnum)
+			f.Close()
+			if remove {
+				os.Remove(f.Name())
+			}
+		}
+	}
+}
+
+// Append injects a binary blob at the end of the freezer table. The item number
+// is a precautionary parameter to ensure data correctness, but the table will
+// reject already existing data.
+//
+// Note, this method will *not* flush any data to disk so be sure to explicitly
+// fsync before irreversibly deleting data from the database.
+func (t *freezerTable) Append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Ensure the table is still accessible
+	if t.index == nil || t.head == nil {
+		return errClosed
+	}
+	// Ensure only the next item can be written, nothing else
+	if atomic.LoadUint64(&t.items) != item {
+		return fmt.Errorf("appending unexpected item: want %d, have %d", t.items, item)
+	}
+	// Encode the blob and write it into the data file
+	if !t.noCompression {
+		blob = snappy.Encode(nil, blob)
+	}
+	bLen := uint32(len(blob))
+	if t.headBytes+bLen < bLen || t.headBytes+bLen > t.maxFileSize {
+		// Roll over to a new head file, the old one is kept open for reading
+		nextID := t.headID + 1
+		newHead, err := t.openFile(nextID, os.O_RDWR|os.O_CREATE|os.O_APPEND|os.O_TRUNC)
+		if err != nil {
+			return err
+		}
+		t.head = newHead
+		t.headBytes = 0
+		t.headID = nextID
+	}
+	if _, err := t.head.Write(blob); err != nil {
+		return err
+	}
+	t.headBytes += bLen
+	idx := indexEntry{
+		filenum: t.headID,
+		offset:  t.headBytes,
+	}
+	// Write indexEntry
+	if _, err := t.index.Write(idx.marshallBinary()); err != nil {
+		return err
+	}
+	atomic.AddUint64(&t.items, 1)
+	return nil
+}
+
+// getBounds returns the indexes for the item, returning the start and end offset
+// and the file number the item resides in.
+func (t *freezerTable) getBounds(item uint64) (uint32, uint32, uint16, error) {
+	var startIdx, endIdx indexEntry
+	buffer := make([]byte, indexEntrySize)
+	if _, err := t.index.ReadAt(buffer, int64(item*indexEntrySize)); err != nil {
+		return 0, 0, 0, err
+	}
+	startIdx.unmarshalBinary(buffer)
+	if _, err := t.index.ReadAt(buffer, int64((item+1)*indexEntrySize)); err != nil {
+		return 0, 0, 0, err
+	}
+	endIdx.unmarshalBinary(buffer)
+	if startIdx.filenum != endIdx.filenum {
+		// If a piece of data 'crosses' a data-file, it's actually in one piece on
+		// the second data-file. We return a zero-indexEntry for the second file as
+		// start
+		return 0, endIdx.offset, endIdx.filenum, nil
+	}
+	return startIdx.offset, endIdx.offset, endIdx.filenum, nil
+}
+
+// Retrieve looks up the data offset of an item with the given number and retrieves
+// the raw binary blob from the data file.
+func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	// Ensure the table and the item is accessible
+	if t.index == nil || t.head == nil {
+		return nil, errClosed
+	}
+	if atomic.LoadUint64(&t.items) <= item {
+		return nil, errOutOfBounds
+	}
+	startOffset, endOffset, filenum, err := t.getBounds(item)
+	if err != nil {
+		return nil, err
+	}
+	dataFile, exist := t.files[filenum]
+	if !exist {
+		return nil, fmt.Errorf("missing data file %d", filenum)
+	}
+	// Retrieve the data itself, decompress and return
+	blob := make([]byte, endOffset-startOffset)
+	if _, err := dataFile.ReadAt(blob, int64(startOffset)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if t.noCompression {
+		return blob, nil
+	}
+	return snappy.Decode(nil, blob)
+}
+
+// has returns an indicator whether the specified number data exists in the
+// freezer table.
+func (t *freezerTable) has(number uint64) bool {
+	return atomic.LoadUint64(&t.items) > number
+}
+
+// size returns the total data size in the freezer table.
+func (t *freezerTable) size() (uint64, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	stat, err := t.index.Stat()
+	if err != nil {
+		return 0, err
+	}
+	total := uint64(t.maxFileSize)*uint64(t.headID) + uint64(t.headBytes) + uint64(stat.Size())
+	return total, nil
+}
+
+// Sync pushes any pending data from memory out to disk. This is an expensive
+// operation, so use it with care.
+func (t *freezerTable) Sync() error {
+	if err := t.index.Sync(); err != nil {
+		return err
+	}
+	return t.head.Sync()
+}
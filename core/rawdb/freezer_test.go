@@ -0,0 +1,329 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/ethdb"
+)
+
+// getChunk returns a chunk of data of the given size, filled with the byte b.
+func getChunk(size int, b int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(b)
+	}
+	return data
+}
+
+// Tests that items can be appended to and retrieved from a freezer table, also
+// across data file boundaries and reopens.
+func TestFreezerTableBasics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, noSnappy := range []bool{true, false} {
+		name := fmt.Sprintf("basics-%v", noSnappy)
+
+		// Fill the table with items spanning multiple data files
+		table, err := newTable(dir, name, 50, noSnappy)
+		if err != nil {
+			t.Fatalf("snappy %v: failed to open table: %v", !noSnappy, err)
+		}
+		for i := 0; i < 255; i++ {
+			if err := table.Append(uint64(i), getChunk(15, i)); err != nil {
+				t.Fatalf("snappy %v: failed to append item %d: %v", !noSnappy, i, err)
+			}
+		}
+		if err := table.Append(300, getChunk(15, 0)); err == nil {
+			t.Errorf("snappy %v: out of order append accepted", !noSnappy)
+		}
+		table.Close()
+
+		// Reopen the table and check all the items are retrievable
+		if table, err = newTable(dir, name, 50, noSnappy); err != nil {
+			t.Fatalf("snappy %v: failed to reopen table: %v", !noSnappy, err)
+		}
+		for i := 0; i < 255; i++ {
+			if blob, err := table.Retrieve(uint64(i)); err != nil {
+				t.Errorf("snappy %v: failed to retrieve item %d: %v", !noSnappy, i, err)
+			} else if !bytes.Equal(blob, getChunk(15, i)) {
+				t.Errorf("snappy %v: item %d mismatch: have %x, want %x", !noSnappy, i, blob, getChunk(15, i))
+			}
+		}
+		if _, err := table.Retrieve(255); err != errOutOfBounds {
+			t.Errorf("snappy %v: out of bounds error mismatch: have %v, want %v", !noSnappy, err, errOutOfBounds)
+		}
+		table.Close()
+	}
+}
+
+// Tests that a freezer table repairs itself on open if its data file and index
+// got out of sync, dropping the items not fully persisted.
+func TestFreezerTableRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	table, err := newTable(dir, "repair", 50, true)
+	if err != nil {
+		t.Fatalf("failed to open table: %v", err)
+	}
+	for i := 0; i < 9; i++ {
+		if err := table.Append(uint64(i), getChunk(15, i)); err != nil {
+			t.Fatalf("failed to append item %d: %v", i, err)
+		}
+	}
+	table.Close()
+
+	// Cut the last item short in the head data file and leave a partial entry
+	// at the end of the index, as an interrupted write would
+	head := filepath.Join(dir, "repair.0002.rdat")
+	if err := os.Truncate(head, 20); err != nil {
+		t.Fatalf("failed to truncate data file: %v", err)
+	}
+	index, err := os.OpenFile(filepath.Join(dir, "repair.ridx"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("failed to open index file: %v", err)
+	}
+	index.Write([]byte{0x00, 0x02, 0x00})
+	index.Close()
+
+	if table, err = newTable(dir, "repair", 50, true); err != nil {
+		t.Fatalf("failed to reopen table: %v", err)
+	}
+	defer table.Close()
+
+	if table.items != 7 {
+		t.Fatalf("repaired item count mismatch: have %d, want %d", table.items, 7)
+	}
+	for i := 0; i < 7; i++ {
+		if blob, err := table.Retrieve(uint64(i)); err != nil || !bytes.Equal(blob, getChunk(15, i)) {
+			t.Errorf("item %d mismatch: have %x, want %x (err %v)", i, blob, getChunk(15, i), err)
+		}
+	}
+	// The dropped item must be appendable again
+	if err := table.Append(7, getChunk(15, 7)); err != nil {
+		t.Fatalf("failed to append item after repair: %v", err)
+	}
+	if blob, err := table.Retrieve(7); err != nil || !bytes.Equal(blob, getChunk(15, 7)) {
+		t.Errorf("re-appended item mismatch: have %x, want %x (err %v)", blob, getChunk(15, 7), err)
+	}
+}
+
+// Tests that truncating a freezer table drops the items above the limit, also
+// across data file boundaries.
+func TestFreezerTableTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	table, err := newTable(dir, "truncate", 50, true)
+	if err != nil {
+		t.Fatalf("failed to open table: %v", err)
+	}
+	defer table.Close()
+
+	for i := 0; i < 30; i++ {
+		if err := table.Append(uint64(i), getChunk(15, i)); err != nil {
+			t.Fatalf("failed to append item %d: %v", i, err)
+		}
+	}
+	if err := table.truncate(10); err != nil {
+		t.Fatalf("failed to truncate table: %v", err)
+	}
+	if _, err := table.Retrieve(10); err != errOutOfBounds {
+		t.Errorf("truncated item error mismatch: have %v, want %v", err, errOutOfBounds)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "truncate.0009.rdat")); !os.IsNotExist(err) {
+		t.Errorf("data file above the limit not removed: %v", err)
+	}
+	for i := 10; i < 20; i++ {
+		if err := table.Append(uint64(i), getChunk(15, 100+i)); err != nil {
+			t.Fatalf("failed to append item %d after truncation: %v", i, err)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		want := getChunk(15, i)
+		if i >= 10 {
+			want = getChunk(15, 100+i)
+		}
+		if blob, err := table.Retrieve(uint64(i)); err != nil || !bytes.Equal(blob, want) {
+			t.Errorf("item %d mismatch: have %x, want %x (err %v)", i, blob, want, err)
+		}
+	}
+}
+
+// Tests that the background freezer moves old canonical blocks out of the key-
+// value store, with the chain accessors transparently serving them afterwards.
+func TestFreezerChainData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Assemble a small canonical chain
+	var (
+		kvdb   = ethdb.NewMemDatabase()
+		blocks []*types.Block
+		parent common.Hash
+	)
+	for i := 0; i < 10; i++ {
+		block := types.NewBlockWithHeader(&types.Header{ParentHash: parent, Number: big.NewInt(int64(i))})
+
+		WriteBlock(kvdb, block)
+		WriteReceipts(kvdb, block.Hash(), block.NumberU64(), nil)
+		WriteTd(kvdb, block.Hash(), block.NumberU64(), big.NewInt(int64(i+1)))
+		WriteCanonicalHash(kvdb, block.Hash(), block.NumberU64())
+
+		blocks = append(blocks, block)
+		parent = block.Hash()
+	}
+	WriteHeadBlockHash(kvdb, parent)
+
+	// Start the freezer and wait for it to move all blocks beyond the threshold
+	db, err := NewDatabaseWithFreezer(kvdb, dir, 3)
+	if err != nil {
+		t.Fatalf("failed to create freezer database: %v", err)
+	}
+	for start := time.Now(); ReadCanonicalHash(kvdb, 6) != (common.Hash{}); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("ancient blocks not frozen in time")
+		}
+	}
+	if frozen, _ := db.(AncientReader).Ancients(); frozen != 7 {
+		t.Fatalf("frozen block count mismatch: have %d, want %d", frozen, 7)
+	}
+	for i, block := range blocks {
+		hash, number := block.Hash(), block.NumberU64()
+		if i < 6 && HasHeader(kvdb, hash, number) {
+			t.Errorf("block %d: frozen header left in key-value store", i)
+		}
+		if have := ReadCanonicalHash(db, number); have != hash {
+			t.Errorf("block %d: canonical hash mismatch: have %x, want %x", i, have, hash)
+		}
+		if header := ReadHeader(db, hash, number); header == nil || header.Hash() != hash {
+			t.Errorf("block %d: header mismatch: have %v", i, header)
+		}
+		if !HasHeader(db, hash, number) || !HasBody(db, hash, number) {
+			t.Errorf("block %d: header or body reported missing", i)
+		}
+		if ReadBody(db, hash, number) == nil {
+			t.Errorf("block %d: body missing", i)
+		}
+		if receipts := ReadReceipts(db, hash, number); receipts == nil {
+			t.Errorf("block %d: receipts missing", i)
+		}
+		if td := ReadTd(db, hash, number); td == nil || td.Int64() != int64(i+1) {
+			t.Errorf("block %d: total difficulty mismatch: have %v, want %d", i, td, i+1)
+		}
+		// Frozen data must only be served for the canonical hash
+		if ReadHeader(db, common.Hash{0xff}, number) != nil || HasBody(db, common.Hash{0xff}, number) {
+			t.Errorf("block %d: frozen data served for unknown hash", i)
+		}
+	}
+	db.Close()
+
+	// The freezer must refuse to be attached to an unrelated database
+	if db, err := NewDatabaseWithFreezer(ethdb.NewMemDatabase(), dir, 3); err == nil {
+		db.Close()
+		t.Fatalf("freezer attached to an unrelated database")
+	}
+	db, err = NewDatabaseWithFreezer(kvdb, dir, 3)
+	if err != nil {
+		t.Fatalf("failed to reopen freezer database: %v", err)
+	}
+	defer db.Close()
+
+	if header := ReadHeader(db, blocks[2].Hash(), 2); header == nil || header.Hash() != blocks[2].Hash() {
+		t.Errorf("header mismatch after reopen: have %v", header)
+	}
+}
+
+// Tests that appends and truncations of the freezer running concurrently keep
+// all data tables at the same length.
+func TestFreezerConcurrentTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := newFreezer(dir, 0)
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	defer f.Close()
+
+	var (
+		done = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+
+		for i := 0; i < 1000; i++ {
+			number, _ := f.Ancients()
+			f.AppendAncient(number, getChunk(common.HashLength, i), getChunk(10, i), getChunk(10, i), getChunk(10, i), getChunk(10, i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if number, _ := f.Ancients(); number > 0 {
+				if err := f.TruncateAncients(number - 1); err != nil {
+					t.Errorf("failed to truncate freezer: %v", err)
+				}
+			}
+		}
+	}()
+	wg.Wait()
+
+	frozen, _ := f.Ancients()
+	for name, table := range f.tables {
+		if items := atomic.LoadUint64(&table.items); items != frozen {
+			t.Errorf("table %s: item count mismatch: have %d, want %d", name, items, frozen)
+		}
+	}
+}
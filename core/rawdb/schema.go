@@ -41,6 +41,18 @@ var (
 	// fastTrieProgressKey tracks the number of trie entries imported during fast sync.
 	fastTrieProgressKey = []byte("TrieSync")
 
+	// finalizedBlockKey tracks the latest block confirmed by a quorum of signers.
+	finalizedBlockKey = []byte("LastFinalized")
+
+	// pruningRootsKey tracks the state roots retained by an unfinished offline state pruning.
+	pruningRootsKey = []byte("PruningRoots")
+
+	// snapshotRootKey tracks the state root the persisted flat state snapshot represents.
+	snapshotRootKey = []byte("SnapshotRoot")
+
+	// snapshotGeneratorKey tracks the progress of an unfinished state snapshot generation.
+	snapshotGeneratorKey = []byte("SnapshotGenerator")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -53,16 +65,51 @@ var (
 	txLookupPrefix  = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 
+	sealRecordPrefix  = []byte("S") // sealRecordPrefix + num (uint64 big endian) + hash -> seal record
+	signerQueuePrefix = []byte("Q") // signerQueuePrefix + loop start (uint64 big endian) -> signer queue
+	signerStatsPrefix = []byte("U") // signerStatsPrefix + section (uint64 big endian) + hash -> signer stats
+
+	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
+	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
+
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("relianz-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	SignerIndexPrefix    = []byte("iS") // SignerIndexPrefix is the data table of the signer history indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
 )
 
+const (
+	// freezerHashTable indicates the name of the freezer canonical hash table.
+	freezerHashTable = "hashes"
+
+	// freezerHeaderTable indicates the name of the freezer header table.
+	freezerHeaderTable = "headers"
+
+	// freezerBodiesTable indicates the name of the freezer block body table.
+	freezerBodiesTable = "bodies"
+
+	// freezerReceiptTable indicates the name of the freezer receipts table.
+	freezerReceiptTable = "receipts"
+
+	// freezerDifficultyTable indicates the name of the freezer total difficulty table.
+	freezerDifficultyTable = "diffs"
+)
+
+// freezerNoSnappy configures whether compression is disabled for the ancient
+// tables. Hashes and difficulties don't compress well.
+var freezerNoSnappy = map[string]bool{
+	freezerHashTable:       true,
+	freezerHeaderTable:     false,
+	freezerBodiesTable:     false,
+	freezerReceiptTable:    false,
+	freezerDifficultyTable: true,
+}
+
 // TxLookupEntry is a positional metadata to help looking up the data content of
 // a transaction or receipt given only its hash.
 type TxLookupEntry struct {
@@ -0,0 +1,113 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package sidechain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core"
+	"github.com/relianz2019/relianz/params"
+)
+
+// extraSeal is the number of extra-data suffix bytes reserved for the signer seal.
+const extraSeal = 65
+
+var (
+	errNoChainID = errors.New("side chain id missing")
+	errNoPeriod  = errors.New("side chain block period must be positive")
+	errNoSigners = errors.New("side chain needs at least one signer")
+)
+
+// GenesisConfig holds the parameters of a side chain to derive from the main chain.
+type GenesisConfig struct {
+	ChainID   *big.Int         // Chain id of the side chain, distinct from the main chain one
+	MainBlock *big.Int         // Main chain block to anchor the side chain to (nil = latest)
+	Period    uint64           // Number of seconds between side chain blocks
+	Signers   []common.Address // Signers sealing the side chain until the first election
+	Funded    []common.Address // Further accounts carrying their main chain balance over
+	GasLimit  uint64           // Gas limit of the genesis block (0 = main chain anchor one)
+}
+
+// Genesis derives the genesis block of a side chain from the main chain state at
+// the anchor block. The extra-data vanity of the genesis block holds the anchor
+// block hash, the first signer loop starts one period after the anchor and the
+// signers and funded accounts start out with their main chain balances.
+func Genesis(ctx context.Context, mc MainChain, config *GenesisConfig) (*core.Genesis, error) {
+	switch {
+	case config.ChainID == nil:
+		return nil, errNoChainID
+	case config.Period == 0:
+		return nil, errNoPeriod
+	case len(config.Signers) == 0:
+		return nil, errNoSigners
+	}
+	anchor, err := mc.HeaderByNumber(ctx, config.MainBlock)
+	if err != nil {
+		return nil, err
+	}
+	// Carry the main chain balances of all side chain accounts over
+	alloc := make(core.GenesisAlloc)
+	for _, accounts := range [][]common.Address{config.Signers, config.Funded} {
+		for _, account := range accounts {
+			if _, ok := alloc[account]; ok {
+				continue
+			}
+			balance, err := mc.BalanceAt(ctx, account, anchor.Number)
+			if err != nil {
+				return nil, err
+			}
+			alloc[account] = core.GenesisAccount{Balance: balance}
+		}
+	}
+	signers := make([]common.UnprefixedAddress, len(config.Signers))
+	for i, signer := range config.Signers {
+		signers[i] = common.UnprefixedAddress(signer)
+	}
+	defaults := params.MainnetChainConfig.Alien
+
+	gasLimit := config.GasLimit
+	if gasLimit == 0 {
+		gasLimit = anchor.GasLimit
+	}
+	return &core.Genesis{
+		Config: &params.ChainConfig{
+			ChainId:        new(big.Int).Set(config.ChainID),
+			HomesteadBlock: big.NewInt(0),
+			EIP150Block:    big.NewInt(0),
+			EIP155Block:    big.NewInt(0),
+			EIP158Block:    big.NewInt(0),
+			ByzantiumBlock: big.NewInt(0),
+			Alien: &params.AlienConfig{
+				Period:           config.Period,
+				Epoch:            defaults.Epoch,
+				MaxSignerCount:   defaults.MaxSignerCount,
+				MinVoterBalance:  new(big.Int).Set(defaults.MinVoterBalance),
+				GenesisTimestamp: anchor.Time.Uint64() + config.Period,
+				SelfVoteSigners:  signers,
+				SideChain:        true,
+			},
+		},
+		Timestamp:  anchor.Time.Uint64(),
+		ExtraData:  append(anchor.Hash().Bytes(), make([]byte, extraSeal)...),
+		GasLimit:   gasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}, nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package sidechain implements the side chain mode of the Alien consensus engine,
+// anchoring a side chain to the main chain it was derived from.
+package sidechain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strconv"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/rlp"
+	"github.com/relianz2019/relianz/rpc"
+)
+
+// errMainChainNotFound is returned if the main chain does not know a requested
+// block.
+var errMainChainNotFound = errors.New("main chain block not found")
+
+// MainChain is the view of the main chain a side chain is anchored to.
+type MainChain interface {
+	// ChainID returns the chain id transactions sent to the main chain are
+	// signed for.
+	ChainID(ctx context.Context) (*big.Int, error)
+
+	// HeaderByNumber returns the main chain header with the given number, or
+	// the latest one if number is nil.
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+
+	// BalanceAt returns the balance of the account as of the block with the
+	// given number, or the latest one if number is nil.
+	BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error)
+
+	// PendingNonceAt returns the next nonce of the account, including the
+	// transactions pending on the main chain.
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+
+	// SuggestGasPrice returns the gas price the main chain currently accepts.
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+
+	// SendTransaction submits a signed transaction to the main chain.
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+
+	// TransactionBlock returns the number of the main chain block that included
+	// the transaction, or nil if the transaction is not included yet.
+	TransactionBlock(ctx context.Context, hash common.Hash) (*big.Int, error)
+}
+
+// rpcMainChain is a main chain accessed through its RPC API.
+type rpcMainChain struct {
+	client *rpc.Client
+}
+
+// NewMainChain creates a main chain view on top of an RPC client, which may be
+// connected to a remote node or dialed in process through rpc.DialInProc.
+func NewMainChain(client *rpc.Client) MainChain {
+	return &rpcMainChain{client: client}
+}
+
+func (mc *rpcMainChain) ChainID(ctx context.Context) (*big.Int, error) {
+	// The main chain network id equals its chain id, see rlz.New
+	var version string
+	if err := mc.client.CallContext(ctx, &version, "net_version"); err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseUint(version, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetUint64(id), nil
+}
+
+func (mc *rpcMainChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var header *types.Header
+	if err := mc.client.CallContext(ctx, &header, "rlz_getBlockByNumber", toBlockNumArg(number), false); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errMainChainNotFound
+	}
+	return header, nil
+}
+
+func (mc *rpcMainChain) BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error) {
+	var balance hexutil.Big
+	if err := mc.client.CallContext(ctx, &balance, "rlz_getBalance", account, toBlockNumArg(number)); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&balance), nil
+}
+
+func (mc *rpcMainChain) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	var nonce hexutil.Uint64
+	if err := mc.client.CallContext(ctx, &nonce, "rlz_getTransactionCount", account, "pending"); err != nil {
+		return 0, err
+	}
+	return uint64(nonce), nil
+}
+
+func (mc *rpcMainChain) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	var price hexutil.Big
+	if err := mc.client.CallContext(ctx, &price, "rlz_gasPrice"); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&price), nil
+}
+
+func (mc *rpcMainChain) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return err
+	}
+	return mc.client.CallContext(ctx, nil, "rlz_sendRawTransaction", hexutil.Bytes(data))
+}
+
+func (mc *rpcMainChain) TransactionBlock(ctx context.Context, hash common.Hash) (*big.Int, error) {
+	var receipt *struct {
+		BlockNumber *hexutil.Big `json:"blockNumber"`
+	}
+	if err := mc.client.CallContext(ctx, &receipt, "rlz_getTransactionReceipt", hash); err != nil {
+		return nil, err
+	}
+	if receipt == nil || receipt.BlockNumber == nil {
+		return nil, nil
+	}
+	return (*big.Int)(receipt.BlockNumber), nil
+}
+
+// toBlockNumArg converts a block number into its RPC argument, nil meaning the
+// latest block.
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(number)
+}
@@ -0,0 +1,291 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package sidechain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/relianz2019/relianz/accounts"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/core/ufo"
+	"github.com/relianz2019/relianz/event"
+	"github.com/relianz2019/relianz/log"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// confirmRecheckInterval is the interval at which pending notifications are
+	// looked up in the main chain.
+	confirmRecheckInterval = 3 * time.Second
+
+	// maxPendingNotifications is the maximum number of notifications awaiting
+	// inclusion in the main chain before the oldest ones are forgotten.
+	maxPendingNotifications = 64
+
+	// mainChainTimeout is the time allowed for each main chain request.
+	mainChainTimeout = 10 * time.Second
+)
+
+// errNotifyUnauthorized is returned if a notification is to be sent before a
+// signer was authorized.
+var errNotifyUnauthorized = errors.New("side chain notifier not authorized")
+
+// SignerFn signs a transaction for the main chain on behalf of the account.
+type SignerFn func(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+
+// Chain is the side chain whose blocks are announced to the main chain.
+type Chain interface {
+	// Genesis returns the genesis block identifying the side chain.
+	Genesis() *types.Block
+
+	// SubscribeChainHeadEvent registers a subscription of ChainHeadEvent.
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Confirmation is a side chain block whose notification the main chain included.
+type Confirmation struct {
+	Number    uint64      // Number of the side chain block
+	Hash      common.Hash // Hash of the side chain block
+	TxHash    common.Hash // Main chain transaction carrying the notification
+	MainBlock uint64      // Main chain block including the notification
+}
+
+// ConfirmationEvent is posted when the main chain included a notification.
+type ConfirmationEvent struct{ Confirmation *Confirmation }
+
+// notification is a side chain block notification awaiting main chain inclusion.
+type notification struct {
+	number uint64
+	hash   common.Hash
+	tx     common.Hash
+}
+
+// Notifier announces side chain blocks to the main chain through ufo side chain
+// notifications sent by the local signer, and confirms every notification once
+// the main chain included it.
+type Notifier struct {
+	mc       MainChain
+	chain    Chain
+	interval uint64        // Number of side chain blocks between notifications
+	recheck  time.Duration // Interval to look pending notifications up at
+
+	signer  common.Address // Local signer to send notifications with
+	signFn  SignerFn       // Signer function to authorize notifications with
+	chainID *big.Int       // Main chain id, retrieved on the first notification
+
+	pending []*notification // Notifications awaiting inclusion, oldest first
+
+	confirmFeed event.Feed
+	scope       event.SubscriptionScope
+
+	chainHeadCh  chan core.ChainHeadEvent
+	chainHeadSub event.Subscription
+	quit         chan struct{}
+	wg           sync.WaitGroup
+
+	lock sync.RWMutex
+}
+
+// NewNotifier creates a notifier announcing every interval-th block of the side
+// chain to the main chain.
+func NewNotifier(mc MainChain, chain Chain, interval uint64) *Notifier {
+	if interval == 0 {
+		interval = 1
+	}
+	return &Notifier{
+		mc:          mc,
+		chain:       chain,
+		interval:    interval,
+		recheck:     confirmRecheckInterval,
+		chainHeadCh: make(chan core.ChainHeadEvent, chainHeadChanSize),
+		quit:        make(chan struct{}),
+	}
+}
+
+// Authorize injects the signer and the signing callback used to send
+// notifications. Side chain blocks are only announced once authorized.
+func (n *Notifier) Authorize(signer common.Address, signFn SignerFn) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.signer = signer
+	n.signFn = signFn
+}
+
+// Start begins announcing new side chain heads.
+func (n *Notifier) Start() {
+	n.chainHeadSub = n.chain.SubscribeChainHeadEvent(n.chainHeadCh)
+
+	n.wg.Add(1)
+	go n.loop()
+}
+
+// Stop terminates the announcements and all subscriptions of the notifier.
+func (n *Notifier) Stop() {
+	n.chainHeadSub.Unsubscribe()
+	close(n.quit)
+	n.wg.Wait()
+
+	n.scope.Close()
+}
+
+// SubscribeConfirmationEvent registers a subscription of ConfirmationEvent.
+func (n *Notifier) SubscribeConfirmationEvent(ch chan<- ConfirmationEvent) event.Subscription {
+	return n.scope.Track(n.confirmFeed.Subscribe(ch))
+}
+
+// loop announces the side chain heads and periodically confirms the pending
+// notifications.
+func (n *Notifier) loop() {
+	defer n.wg.Done()
+
+	recheck := time.NewTicker(n.recheck)
+	defer recheck.Stop()
+
+	for {
+		select {
+		case ev := <-n.chainHeadCh:
+			if ev.Block.NumberU64()%n.interval != 0 {
+				continue
+			}
+			if err := n.Notify(ev.Block); err != nil && err != errNotifyUnauthorized {
+				log.Warn("Failed to notify main chain", "number", ev.Block.Number(), "hash", ev.Block.Hash(), "err", err)
+			}
+
+		case <-recheck.C:
+			n.confirm()
+
+		case <-n.chainHeadSub.Err():
+			return
+		case <-n.quit:
+			return
+		}
+	}
+}
+
+// Notify announces a side chain block to the main chain, sending the notification
+// from the authorized signer.
+func (n *Notifier) Notify(block *types.Block) error {
+	n.lock.RLock()
+	signer, signFn, chainID := n.signer, n.signFn, n.chainID
+	n.lock.RUnlock()
+
+	if signFn == nil {
+		return errNotifyUnauthorized
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), mainChainTimeout)
+	defer cancel()
+
+	if chainID == nil {
+		var err error
+		if chainID, err = n.mc.ChainID(ctx); err != nil {
+			return err
+		}
+		n.lock.Lock()
+		n.chainID = chainID
+		n.lock.Unlock()
+	}
+	nonce, err := n.mc.PendingNonceAt(ctx, signer)
+	if err != nil {
+		return err
+	}
+	price, err := n.mc.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+	data := ufo.Encode(&ufo.SideChainNotify{
+		ChainHash: n.chain.Genesis().Hash(),
+		Number:    block.NumberU64(),
+		BlockHash: block.Hash(),
+	})
+	gas, err := core.IntrinsicGas(data, false, true)
+	if err != nil {
+		return err
+	}
+	tx, err := signFn(accounts.Account{Address: signer}, types.NewTransaction(nonce, signer, new(big.Int), gas, price, data), chainID)
+	if err != nil {
+		return err
+	}
+	if err := n.mc.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+	log.Debug("Notified main chain of side chain block", "number", block.Number(), "hash", block.Hash(), "tx", tx.Hash())
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if len(n.pending) >= maxPendingNotifications {
+		log.Warn("Dropping unconfirmed side chain notification", "number", n.pending[0].number, "tx", n.pending[0].tx)
+		n.pending = n.pending[1:]
+	}
+	n.pending = append(n.pending, &notification{number: block.NumberU64(), hash: block.Hash(), tx: tx.Hash()})
+	return nil
+}
+
+// confirm looks the pending notifications up in the main chain, posting an event
+// for each one included.
+func (n *Notifier) confirm() {
+	n.lock.RLock()
+	pending := make([]*notification, len(n.pending))
+	copy(pending, n.pending)
+	n.lock.RUnlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), mainChainTimeout)
+	defer cancel()
+
+	confirmed := make(map[common.Hash]bool)
+	for _, notify := range pending {
+		number, err := n.mc.TransactionBlock(ctx, notify.tx)
+		if err != nil {
+			log.Debug("Failed to look up side chain notification", "tx", notify.tx, "err", err)
+			break
+		}
+		if number == nil {
+			continue
+		}
+		confirmed[notify.tx] = true
+
+		log.Info("Side chain block confirmed by main chain", "number", notify.number, "hash", notify.hash, "main", number)
+		n.confirmFeed.Send(ConfirmationEvent{&Confirmation{
+			Number:    notify.number,
+			Hash:      notify.hash,
+			TxHash:    notify.tx,
+			MainBlock: number.Uint64(),
+		}})
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	remaining := n.pending[:0]
+	for _, notify := range n.pending {
+		if !confirmed[notify.tx] {
+			remaining = append(remaining, notify)
+		}
+	}
+	n.pending = remaining
+}
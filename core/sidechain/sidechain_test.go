@@ -0,0 +1,333 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package sidechain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/relianz2019/relianz/accounts"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/consensus/ethash"
+	"github.com/relianz2019/relianz/core"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/core/ufo"
+	"github.com/relianz2019/relianz/core/vm"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/params"
+	"github.com/relianz2019/relianz/rlp"
+	"github.com/relianz2019/relianz/rpc"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testSigner  = crypto.PubkeyToAddress(testKey.PublicKey)
+	testFunded  = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	testBalance = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+)
+
+// testMainChain is an in-process main chain stand-in, serving the subset of the
+// main chain RPC API side chains rely on. Sent transactions stay pending until
+// they are committed into a new block.
+type testMainChain struct {
+	db     ethdb.Database
+	chain  *core.BlockChain
+	signer types.Signer
+
+	pending []*types.Transaction
+	lock    sync.Mutex
+}
+
+func newTestMainChain(t *testing.T) (*testMainChain, *rpc.Client) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		genesis = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				testSigner: {Balance: testBalance},
+				testFunded: {Balance: big.NewInt(1)},
+			},
+		}
+	)
+	genesis.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, genesis.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create main chain: %v", err)
+	}
+	mc := &testMainChain{db: db, chain: chain, signer: types.NewEIP155Signer(genesis.Config.ChainId)}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("rlz", &testMainChainAPI{mc}); err != nil {
+		t.Fatalf("failed to register main chain API: %v", err)
+	}
+	if err := server.RegisterName("net", &testNetAPI{genesis.Config.ChainId.Uint64()}); err != nil {
+		t.Fatalf("failed to register net API: %v", err)
+	}
+	return mc, rpc.DialInProc(server)
+}
+
+// commit seals the pending transactions into a new main chain block.
+func (mc *testMainChain) commit(t *testing.T) {
+	mc.lock.Lock()
+	txs := mc.pending
+	mc.pending = nil
+	mc.lock.Unlock()
+
+	blocks, _ := core.GenerateChain(mc.chain.Config(), mc.chain.CurrentBlock(), ethash.NewFaker(), mc.db, 1, func(i int, b *core.BlockGen) {
+		for _, tx := range txs {
+			b.AddTx(tx)
+		}
+	})
+	if _, err := mc.chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert main chain block: %v", err)
+	}
+}
+
+// pendingTxs returns the transactions awaiting inclusion.
+func (mc *testMainChain) pendingTxs() []*types.Transaction {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+
+	return append([]*types.Transaction(nil), mc.pending...)
+}
+
+// block resolves an RPC block number, special numbers meaning the head block.
+func (mc *testMainChain) block(number rpc.BlockNumber) *types.Block {
+	if number < 0 {
+		return mc.chain.CurrentBlock()
+	}
+	return mc.chain.GetBlockByNumber(uint64(number))
+}
+
+type testMainChainAPI struct {
+	mc *testMainChain
+}
+
+func (api *testMainChainAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) (*types.Header, error) {
+	if block := api.mc.block(number); block != nil {
+		return block.Header(), nil
+	}
+	return nil, nil
+}
+
+func (api *testMainChainAPI) GetBalance(account common.Address, number rpc.BlockNumber) (*hexutil.Big, error) {
+	block := api.mc.block(number)
+	if block == nil {
+		return nil, errMainChainNotFound
+	}
+	statedb, err := api.mc.chain.StateAt(block.Root())
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(statedb.GetBalance(account)), nil
+}
+
+func (api *testMainChainAPI) GetTransactionCount(account common.Address, number rpc.BlockNumber) (hexutil.Uint64, error) {
+	statedb, err := api.mc.chain.State()
+	if err != nil {
+		return 0, err
+	}
+	nonce := statedb.GetNonce(account)
+	for _, tx := range api.mc.pendingTxs() {
+		if from, _ := types.Sender(api.mc.signer, tx); from == account {
+			nonce++
+		}
+	}
+	return hexutil.Uint64(nonce), nil
+}
+
+func (api *testMainChainAPI) GasPrice() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(1))
+}
+
+func (api *testMainChainAPI) SendRawTransaction(data hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(data, tx); err != nil {
+		return common.Hash{}, err
+	}
+	if _, err := types.Sender(api.mc.signer, tx); err != nil {
+		return common.Hash{}, err
+	}
+	api.mc.lock.Lock()
+	api.mc.pending = append(api.mc.pending, tx)
+	api.mc.lock.Unlock()
+
+	return tx.Hash(), nil
+}
+
+func (api *testMainChainAPI) GetTransactionReceipt(hash common.Hash) (map[string]interface{}, error) {
+	blockHash, number, _ := rawdb.ReadTxLookupEntry(api.mc.db, hash)
+	if blockHash == (common.Hash{}) {
+		return nil, nil
+	}
+	return map[string]interface{}{
+		"blockHash":       blockHash,
+		"blockNumber":     hexutil.Uint64(number),
+		"transactionHash": hash,
+	}, nil
+}
+
+type testNetAPI struct {
+	networkID uint64
+}
+
+func (api *testNetAPI) Version() string {
+	return new(big.Int).SetUint64(api.networkID).String()
+}
+
+// Tests that side chain genesis blocks are derived from the main chain state at
+// the anchor block.
+func TestGenesis(t *testing.T) {
+	mainChain, client := newTestMainChain(t)
+	defer client.Close()
+
+	mc := NewMainChain(client)
+	if _, err := Genesis(context.Background(), mc, &GenesisConfig{ChainID: big.NewInt(100), Period: 1}); err != errNoSigners {
+		t.Fatalf("signerless genesis error mismatch: have %v, want %v", err, errNoSigners)
+	}
+	genesis, err := Genesis(context.Background(), mc, &GenesisConfig{
+		ChainID: big.NewInt(100),
+		Period:  3,
+		Signers: []common.Address{testSigner},
+		Funded:  []common.Address{testFunded, testSigner},
+	})
+	if err != nil {
+		t.Fatalf("failed to derive genesis: %v", err)
+	}
+	anchor := mainChain.chain.CurrentBlock()
+	if !bytes.Equal(genesis.ExtraData[:common.HashLength], anchor.Hash().Bytes()) {
+		t.Errorf("anchor mismatch: have %x, want %x", genesis.ExtraData[:common.HashLength], anchor.Hash())
+	}
+	if len(genesis.Alloc) != 2 || genesis.Alloc[testSigner].Balance.Cmp(testBalance) != 0 || genesis.Alloc[testFunded].Balance.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("alloc mismatch: have %v", genesis.Alloc)
+	}
+	config := genesis.Config.Alien
+	if config == nil || !config.SideChain {
+		t.Fatalf("side chain Alien config missing: have %v", config)
+	}
+	if config.GenesisTimestamp != anchor.Time().Uint64()+3 {
+		t.Errorf("genesis timestamp mismatch: have %d, want %d", config.GenesisTimestamp, anchor.Time().Uint64()+3)
+	}
+	if len(config.SelfVoteSigners) != 1 || common.Address(config.SelfVoteSigners[0]) != testSigner {
+		t.Errorf("signers mismatch: have %v, want [%x]", config.SelfVoteSigners, testSigner)
+	}
+}
+
+// Tests the notify/confirm round trip of side chain blocks through a main chain
+// running in the same process.
+func TestNotifyConfirm(t *testing.T) {
+	mainChain, client := newTestMainChain(t)
+	defer client.Close()
+
+	mc := NewMainChain(client)
+	genesis, err := Genesis(context.Background(), mc, &GenesisConfig{
+		ChainID: big.NewInt(100),
+		Period:  1,
+		Signers: []common.Address{testSigner},
+	})
+	if err != nil {
+		t.Fatalf("failed to derive genesis: %v", err)
+	}
+	db := ethdb.NewMemDatabase()
+	genesis.MustCommit(db)
+
+	side, err := core.NewBlockChain(db, nil, genesis.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create side chain: %v", err)
+	}
+	defer side.Stop()
+
+	// Announce every second side chain block once authorized
+	notifier := NewNotifier(mc, side, 2)
+	notifier.recheck = 10 * time.Millisecond
+
+	confirms := make(chan ConfirmationEvent, 4)
+	sub := notifier.SubscribeConfirmationEvent(confirms)
+	defer sub.Unsubscribe()
+
+	notifier.Start()
+	defer notifier.Stop()
+
+	blocks, _ := core.GenerateChain(genesis.Config, side.Genesis(), ethash.NewFaker(), db, 5, nil)
+	if _, err := side.InsertChain(blocks[:1]); err != nil {
+		t.Fatalf("failed to insert side chain block: %v", err)
+	}
+	if err := notifier.Notify(blocks[0]); err != errNotifyUnauthorized {
+		t.Fatalf("unauthorized notification error mismatch: have %v, want %v", err, errNotifyUnauthorized)
+	}
+	notifier.Authorize(testSigner, func(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+		if account.Address != testSigner {
+			return nil, errors.New("unknown account")
+		}
+		return types.SignTx(tx, types.NewEIP155Signer(chainID), testKey)
+	})
+	for _, block := range blocks[1:] {
+		if _, err := side.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("failed to insert side chain block: %v", err)
+		}
+	}
+	// Wait for the notifications of blocks 2 and 4 to reach the main chain
+	var txs []*types.Transaction
+	for deadline := time.Now().Add(time.Second); len(txs) < 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("notifications not sent: have %d, want 2", len(txs))
+		}
+		time.Sleep(10 * time.Millisecond)
+		txs = mainChain.pendingTxs()
+	}
+	for i, tx := range txs {
+		payload, err := ufo.Decode(tx.Data())
+		if err != nil {
+			t.Fatalf("notification %d: failed to decode payload: %v", i, err)
+		}
+		want := &ufo.SideChainNotify{ChainHash: side.Genesis().Hash(), Number: uint64(2 * (i + 1)), BlockHash: blocks[2*i+1].Hash()}
+		if notify, ok := payload.(*ufo.SideChainNotify); !ok || *notify != *want {
+			t.Errorf("notification %d: payload mismatch: have %+v, want %+v", i, payload, want)
+		}
+		if *tx.To() != testSigner || tx.Value().Sign() != 0 {
+			t.Errorf("notification %d: transfer mismatch: have %x with %v wei", i, tx.To(), tx.Value())
+		}
+	}
+	// Nothing is confirmed before the main chain includes the notifications
+	select {
+	case ev := <-confirms:
+		t.Fatalf("premature confirmation: %+v", ev.Confirmation)
+	case <-time.After(50 * time.Millisecond):
+	}
+	mainChain.commit(t)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case ev := <-confirms:
+			want := &Confirmation{Number: uint64(2 * (i + 1)), Hash: blocks[2*i+1].Hash(), TxHash: txs[i].Hash(), MainBlock: 1}
+			if *ev.Confirmation != *want {
+				t.Errorf("confirmation %d mismatch: have %+v, want %+v", i, ev.Confirmation, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("confirmation %d not received", i)
+		}
+	}
+}
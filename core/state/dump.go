@@ -21,10 +21,28 @@ import (
 	"fmt"
 
 	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/log"
 	"github.com/relianz2019/relianz/rlp"
 	"github.com/relianz2019/relianz/trie"
 )
 
+// DumpConfig selects the accounts and the account fields a state dump includes.
+type DumpConfig struct {
+	SkipCode    bool   // Leave the contract code out of the dump
+	SkipStorage bool   // Leave the contract storage out of the dump
+	Start       []byte // Hashed address to start the dump from, inclusive
+	Max         int    // Maximum number of accounts to dump, 0 for all of them
+}
+
+// DumpCollector receives the root and the accounts of a state as a dump iterates
+// over them in the order of their hashed addresses. An error returned for an
+// account aborts the dump.
+type DumpCollector interface {
+	OnRoot(common.Hash)
+	OnAccount(DumpAccount) error
+}
+
 type DumpAccount struct {
 	Balance  string            `json:"balance"`
 	Nonce    uint64            `json:"nonce"`
@@ -32,6 +50,9 @@ type DumpAccount struct {
 	CodeHash string            `json:"codeHash"`
 	Code     string            `json:"code"`
 	Storage  map[string]string `json:"storage"`
+
+	Address *common.Address `json:"address,omitempty"` // Nil if the preimage of the key is unknown
+	Key     hexutil.Bytes   `json:"key,omitempty"`     // Hashed address the account is stored at
 }
 
 type Dump struct {
@@ -39,34 +60,135 @@ type Dump struct {
 	Accounts map[string]DumpAccount `json:"accounts"`
 }
 
-func (self *StateDB) RawDump() Dump {
-	dump := Dump{
-		Root:     fmt.Sprintf("%x", self.trie.Hash()),
-		Accounts: make(map[string]DumpAccount),
+// OnRoot implements DumpCollector, setting the root of the dump.
+func (d *Dump) OnRoot(root common.Hash) {
+	d.Root = fmt.Sprintf("%x", root)
+}
+
+// OnAccount implements DumpCollector, keying the account by its address, or by
+// its hashed address if the preimage is unknown.
+func (d *Dump) OnAccount(account DumpAccount) error {
+	key := common.Bytes2Hex(account.Key)
+	if account.Address != nil {
+		key = common.Bytes2Hex(account.Address[:])
+	}
+	account.Address, account.Key = nil, nil
+	d.Accounts[key] = account
+	return nil
+}
+
+// IteratorDump is a page of accounts of a state dump, along with the hashed
+// address to continue the dump from.
+type IteratorDump struct {
+	Root     string        `json:"root"`
+	Accounts []DumpAccount `json:"accounts"`
+	Next     hexutil.Bytes `json:"next,omitempty"` // Nil if the dump is complete
+}
+
+// OnRoot implements DumpCollector, setting the root of the page.
+func (d *IteratorDump) OnRoot(root common.Hash) {
+	d.Root = fmt.Sprintf("%x", root)
+}
+
+// OnAccount implements DumpCollector, appending the account to the page.
+func (d *IteratorDump) OnAccount(account DumpAccount) error {
+	d.Accounts = append(d.Accounts, account)
+	return nil
+}
+
+// iterativeDump is a collector writing every account as a separate JSON line.
+type iterativeDump struct {
+	*json.Encoder
+	err error
+}
+
+// OnRoot implements DumpCollector, writing the root in its own line.
+func (d *iterativeDump) OnRoot(root common.Hash) {
+	d.encode(struct {
+		Root string `json:"root"`
+	}{fmt.Sprintf("%x", root)})
+}
+
+// OnAccount implements DumpCollector, writing the account in its own line.
+func (d *iterativeDump) OnAccount(account DumpAccount) error {
+	return d.encode(account)
+}
+
+// encode writes a value unless a previous write already failed, returning the
+// first write error.
+func (d *iterativeDump) encode(v interface{}) error {
+	if d.err == nil {
+		d.err = d.Encode(v)
 	}
+	return d.err
+}
+
+// DumpToCollector iterates over the accounts of the state selected by the config
+// and feeds them to the collector. If the dump is cut short by the account limit,
+// the hashed address of the next account is returned to continue from.
+//
+// Accounts failing to decode are logged and skipped instead of aborting the dump.
+func (self *StateDB) DumpToCollector(c DumpCollector, conf *DumpConfig) ([]byte, error) {
+	if conf == nil {
+		conf = new(DumpConfig)
+	}
+	c.OnRoot(self.trie.Hash())
 
-	it := trie.NewIterator(self.trie.NodeIterator(nil))
+	var (
+		dumped int
+		it     = trie.NewIterator(self.trie.NodeIterator(conf.Start))
+	)
 	for it.Next() {
-		addr := self.trie.GetKey(it.Key)
+		if conf.Max > 0 && dumped >= conf.Max {
+			return common.CopyBytes(it.Key), nil
+		}
 		var data Account
 		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
-			panic(err)
+			log.Error("Skipping undecodable state account", "key", hexutil.Bytes(it.Key), "err", err)
+			continue
 		}
-
-		obj := newObject(nil, common.BytesToAddress(addr), data)
 		account := DumpAccount{
 			Balance:  data.Balance.String(),
 			Nonce:    data.Nonce,
 			Root:     common.Bytes2Hex(data.Root[:]),
 			CodeHash: common.Bytes2Hex(data.CodeHash),
-			Code:     common.Bytes2Hex(obj.Code(self.db)),
-			Storage:  make(map[string]string),
+			Key:      common.CopyBytes(it.Key),
+		}
+		var addr common.Address
+		if preimage := self.trie.GetKey(it.Key); preimage != nil {
+			addr = common.BytesToAddress(preimage)
+			account.Address = &addr
 		}
-		storageIt := trie.NewIterator(obj.getTrie(self.db).NodeIterator(nil))
-		for storageIt.Next() {
-			account.Storage[common.Bytes2Hex(self.trie.GetKey(storageIt.Key))] = common.Bytes2Hex(storageIt.Value)
+		obj := newObject(nil, addr, data)
+		if !conf.SkipCode {
+			account.Code = common.Bytes2Hex(obj.Code(self.db))
 		}
-		dump.Accounts[common.Bytes2Hex(addr)] = account
+		if !conf.SkipStorage {
+			account.Storage = make(map[string]string)
+			storageIt := trie.NewIterator(obj.getTrie(self.db).NodeIterator(nil))
+			for storageIt.Next() {
+				account.Storage[common.Bytes2Hex(self.trie.GetKey(storageIt.Key))] = common.Bytes2Hex(storageIt.Value)
+			}
+			if storageIt.Err != nil {
+				return nil, storageIt.Err
+			}
+		}
+		if err := c.OnAccount(account); err != nil {
+			return nil, err
+		}
+		dumped++
+	}
+	return nil, it.Err
+}
+
+// RawDump returns the entire state as a single in-memory map. Use IteratorDump
+// or IterativeDump for large states.
+func (self *StateDB) RawDump() Dump {
+	dump := Dump{
+		Accounts: make(map[string]DumpAccount),
+	}
+	if _, err := self.DumpToCollector(&dump, nil); err != nil {
+		log.Error("Failed to dump state", "err", err)
 	}
 	return dump
 }
@@ -79,3 +201,32 @@ func (self *StateDB) Dump() []byte {
 
 	return json
 }
+
+// IteratorDump returns a page of the state selected by the config, along with
+// the hashed address to request the next page from.
+func (self *StateDB) IteratorDump(conf *DumpConfig) (IteratorDump, error) {
+	var dump IteratorDump
+	next, err := self.DumpToCollector(&dump, conf)
+	if err != nil {
+		return IteratorDump{}, err
+	}
+	dump.Next = next
+	return dump, nil
+}
+
+// IterativeDump streams the state selected by the config to the encoder, the
+// root and every account in a line of their own. If the dump is cut short by
+// the account limit, a last line holds the hashed address to continue from.
+func (self *StateDB) IterativeDump(conf *DumpConfig, output *json.Encoder) error {
+	dump := &iterativeDump{Encoder: output}
+	next, err := self.DumpToCollector(dump, conf)
+	if err != nil {
+		return err
+	}
+	if next != nil {
+		return dump.encode(struct {
+			Next hexutil.Bytes `json:"next"`
+		}{next})
+	}
+	return dump.err
+}
@@ -0,0 +1,333 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner implements the offline pruning of the state that is no longer
+// reachable from the recent canonical state roots.
+package pruner
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/core/state"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/log"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// DefaultRetain is the number of recent canonical state roots retained by
+	// default, matching the number of tries a running node keeps in memory.
+	DefaultRetain = 128
+
+	// DefaultBloomSize is the default size in megabytes of the filter tracking
+	// the retained state entries.
+	DefaultBloomSize = 2048
+
+	// progressInterval is the time between two progress reports.
+	progressInterval = 8 * time.Second
+)
+
+// errNoRetainedState is returned if none of the recent canonical state roots
+// is available in full on disk.
+var errNoRetainedState = errors.New("no complete recent state found on disk")
+
+// Config tunes the offline state pruning.
+type Config struct {
+	Retain    uint64 // Number of recent canonical state roots to retain
+	BloomSize uint64 // Size in megabytes of the filter tracking the retained state entries
+}
+
+// Pruner deletes every state trie node and contract code from the database that
+// is not reachable from the state roots of the most recent canonical blocks.
+type Pruner struct {
+	db     ethdb.Database
+	config Config
+}
+
+// NewPruner creates a state pruner for the given database, which must not be in
+// use by a running node. Headers are also looked up in its ancient store, if it
+// has one, while state is only ever swept from the key-value store.
+func NewPruner(db ethdb.Database, config Config) *Pruner {
+	if config.Retain == 0 {
+		config.Retain = DefaultRetain
+	}
+	if config.BloomSize == 0 {
+		config.BloomSize = DefaultBloomSize
+	}
+	return &Pruner{db: db, config: config}
+}
+
+// Prune marks the entries reachable from the retained state roots and the genesis
+// state and sweeps all other state entries out of the database, verifying the
+// retained state afterwards.
+//
+// The retained roots are persisted before anything is deleted, so an interrupted
+// pruning continues with the very same roots when run again.
+func (p *Pruner) Prune() error {
+	roots := rawdb.ReadPruningRoots(p.db)
+	resuming := roots != nil
+	if resuming {
+		log.Info("Resuming interrupted state pruning", "roots", len(roots))
+	} else {
+		roots = p.recentRoots()
+	}
+	bloom, retained, err := p.mark(roots, resuming)
+	if err != nil {
+		return err
+	}
+	if len(retained) == 0 {
+		return errNoRetainedState
+	}
+	p.markGenesis(bloom)
+	rawdb.WritePruningRoots(p.db, retained)
+
+	if err := p.sweep(bloom); err != nil {
+		return err
+	}
+	// Only leveldb needs an explicit compaction to reclaim the swept space
+	if db, ok := rawdb.KeyValueStore(p.db).(*ethdb.LDBDatabase); ok {
+		log.Info("Compacting database")
+		start := time.Now()
+		if err := db.LDB().CompactRange(util.Range{}); err != nil {
+			return err
+		}
+		log.Info("Compacted database", "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+
+	if err := VerifyState(p.db, retained); err != nil {
+		return err
+	}
+	rawdb.DeletePruningRoots(p.db)
+	return nil
+}
+
+// recentRoots collects the distinct state roots of the most recent canonical
+// blocks that are present on disk, newest first.
+func (p *Pruner) recentRoots() []common.Hash {
+	head := rawdb.ReadHeadBlockHash(p.db)
+	number := rawdb.ReadHeaderNumber(p.db, head)
+	if number == nil {
+		return nil
+	}
+	var (
+		roots []common.Hash
+		seen  = make(map[common.Hash]bool)
+	)
+	for n := *number; *number-n < p.config.Retain; n-- {
+		header := rawdb.ReadHeader(p.db, rawdb.ReadCanonicalHash(p.db, n), n)
+		if header != nil && !seen[header.Root] {
+			seen[header.Root] = true
+			if ok, _ := p.db.Has(header.Root[:]); ok {
+				roots = append(roots, header.Root)
+			}
+		}
+		if n == 0 {
+			break
+		}
+	}
+	return roots
+}
+
+// mark adds every entry reachable from the given state roots to a bloom filter,
+// returning the roots whose state was found complete. When resuming a pruning
+// all roots must be complete, otherwise incomplete ones are skipped.
+func (p *Pruner) mark(roots []common.Hash, resuming bool) (*stateBloom, []common.Hash, error) {
+	var (
+		bloom    = newStateBloom(p.config.BloomSize * 1024 * 1024)
+		retained []common.Hash
+		nodes    uint64
+		start    = time.Now()
+		logged   = time.Now()
+	)
+	for _, root := range roots {
+		statedb, err := state.New(root, state.NewDatabase(p.db))
+		if err != nil {
+			if resuming {
+				return nil, nil, fmt.Errorf("retained state %x missing: %v", root, err)
+			}
+			log.Warn("Skipping missing state", "root", root, "err", err)
+			continue
+		}
+		it := state.NewNodeIterator(statedb)
+		for it.Next() {
+			if it.Hash != (common.Hash{}) {
+				bloom.add(it.Hash[:])
+				nodes++
+			}
+			if time.Since(logged) > progressInterval {
+				log.Info("Marking retained state", "roots", len(retained), "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+				logged = time.Now()
+			}
+		}
+		if it.Error != nil {
+			if resuming {
+				return nil, nil, fmt.Errorf("retained state %x incomplete: %v", root, it.Error)
+			}
+			log.Warn("Skipping incomplete state", "root", root, "err", it.Error)
+			continue
+		}
+		retained = append(retained, root)
+	}
+	log.Info("Marked retained state", "roots", len(retained), "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return bloom, retained, nil
+}
+
+// markGenesis adds the genesis state to the bloom filter. It is kept regardless
+// of the retained roots, as the blockchain rewinds a head without state down to
+// the first block that has one and must not run past the genesis.
+func (p *Pruner) markGenesis(bloom *stateBloom) {
+	header := rawdb.ReadHeader(p.db, rawdb.ReadCanonicalHash(p.db, 0), 0)
+	if header == nil {
+		log.Warn("Genesis header missing, not retaining its state")
+		return
+	}
+	statedb, err := state.New(header.Root, state.NewDatabase(p.db))
+	if err != nil {
+		log.Warn("Genesis state missing", "root", header.Root, "err", err)
+		return
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+		if it.Hash != (common.Hash{}) {
+			bloom.add(it.Hash[:])
+		}
+	}
+	if it.Error != nil {
+		log.Warn("Genesis state incomplete", "root", header.Root, "err", it.Error)
+	}
+}
+
+// sweep deletes every trie node and contract code not marked in the bloom filter.
+// Both are stored under the hash of their content, which tells them apart from
+// any other database entry.
+func (p *Pruner) sweep(bloom *stateBloom) error {
+	var (
+		kvdb    = rawdb.KeyValueStore(p.db)
+		it      = kvdb.NewIterator()
+		batch   = kvdb.NewBatch()
+		deleted uint64
+		size    common.StorageSize
+		start   = time.Now()
+		logged  = time.Now()
+	)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != common.HashLength || bloom.contains(key) {
+			continue
+		}
+		if crypto.Keccak256Hash(it.Value()) != common.BytesToHash(key) {
+			continue
+		}
+		batch.Delete(key)
+		deleted++
+		size += common.StorageSize(len(key) + len(it.Value()))
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > progressInterval {
+			log.Info("Sweeping unreachable state", "deleted", deleted, "size", size, "at", common.BytesToHash(key), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Swept unreachable state", "deleted", deleted, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// VerifyState iterates over the entire state of the given roots, reporting the
+// first missing trie node or contract code.
+func VerifyState(db ethdb.Database, roots []common.Hash) error {
+	for _, root := range roots {
+		var (
+			nodes  uint64
+			start  = time.Now()
+			logged = time.Now()
+		)
+		statedb, err := state.New(root, state.NewDatabase(db))
+		if err != nil {
+			return fmt.Errorf("state %x missing: %v", root, err)
+		}
+		it := state.NewNodeIterator(statedb)
+		for it.Next() {
+			nodes++
+			if time.Since(logged) > progressInterval {
+				log.Info("Verifying state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+				logged = time.Now()
+			}
+		}
+		if it.Error != nil {
+			return fmt.Errorf("state %x incomplete: %v", root, it.Error)
+		}
+		log.Info("Verified state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	return nil
+}
+
+// stateBloom is a bloom filter over the hashes of the retained state entries.
+// A false positive merely leaves an unreachable entry in the database.
+type stateBloom struct {
+	bits []uint64
+}
+
+// newStateBloom creates a bloom filter of the given size in bytes.
+func newStateBloom(size uint64) *stateBloom {
+	if size < 8 {
+		size = 8
+	}
+	return &stateBloom{bits: make([]uint64, size/8)}
+}
+
+// add inserts a hash into the filter.
+func (b *stateBloom) add(hash []byte) {
+	for i := 0; i < 4; i++ {
+		bit := b.bit(hash, i)
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// contains reports whether a hash may have been inserted into the filter.
+func (b *stateBloom) contains(hash []byte) bool {
+	for i := 0; i < 4; i++ {
+		bit := b.bit(hash, i)
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bit derives the i-th filter position of a hash. Hashes are uniformly random,
+// so each of their four 8 byte words serves as an independent hash function.
+func (b *stateBloom) bit(hash []byte, i int) uint64 {
+	return binary.BigEndian.Uint64(hash[i*8:]) % (uint64(len(b.bits)) * 64)
+}
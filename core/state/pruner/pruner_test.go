@@ -0,0 +1,128 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/core/state"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/ethdb"
+)
+
+// newTestChain writes a canonical chain of headers into the database, each block
+// modifying the state of its parent, and returns their state roots.
+func newTestChain(t *testing.T, db ethdb.Database, blocks int) []common.Hash {
+	var (
+		roots  []common.Hash
+		parent common.Hash
+		root   common.Hash
+	)
+	for i := 0; i < blocks; i++ {
+		statedb, _ := state.New(root, state.NewDatabase(db))
+		statedb.SetBalance(common.Address{0x01}, big.NewInt(int64(i+1)))
+		statedb.SetState(common.Address{0x02}, common.Hash{byte(i)}, common.Hash{0xff})
+		statedb.SetCode(common.Address{0x02}, []byte{0x60, 0x00})
+		statedb.SetBalance(common.Address{0x03, byte(i)}, big.NewInt(1))
+
+		var err error
+		if root, err = statedb.Commit(false); err != nil {
+			t.Fatalf("block %d: failed to commit state: %v", i, err)
+		}
+		if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
+			t.Fatalf("block %d: failed to flush state: %v", i, err)
+		}
+		header := &types.Header{ParentHash: parent, Number: big.NewInt(int64(i)), Root: root}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), uint64(i))
+		rawdb.WriteHeadBlockHash(db, header.Hash())
+
+		roots = append(roots, root)
+		parent = header.Hash()
+	}
+	return roots
+}
+
+func TestPruneState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	roots := newTestChain(t, db, 4)
+
+	// An entry keyed by a hash that isn't the hash of its content must survive
+	foreign := crypto.Keccak256([]byte("foreign"))
+	db.Put(foreign, []byte("entry"))
+
+	if err := NewPruner(db, Config{Retain: 2, BloomSize: 1}).Prune(); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	// The genesis state must be kept along with the retained ones
+	for i, root := range roots {
+		err := VerifyState(db, []common.Hash{root})
+		if i == 1 && err == nil {
+			t.Errorf("state %d: not pruned", i)
+		}
+		if i != 1 && err != nil {
+			t.Errorf("state %d: retained state incomplete: %v", i, err)
+		}
+	}
+	if ok, _ := db.Has(foreign); !ok {
+		t.Errorf("foreign entry pruned")
+	}
+	if roots := rawdb.ReadPruningRoots(db); roots != nil {
+		t.Errorf("pruning marker left behind: %x", roots)
+	}
+	// Interrupt a pruning after the roots were recorded, it must resume with them
+	rawdb.WritePruningRoots(db, []common.Hash{roots[3]})
+
+	if err := NewPruner(db, Config{Retain: 4, BloomSize: 1}).Prune(); err != nil {
+		t.Fatalf("failed to resume pruning: %v", err)
+	}
+	if err := VerifyState(db, []common.Hash{roots[2]}); err == nil {
+		t.Errorf("resumed pruning retained state outside the recorded roots")
+	}
+	if err := VerifyState(db, []common.Hash{roots[3]}); err != nil {
+		t.Errorf("resumed pruning lost recorded state: %v", err)
+	}
+}
+
+func TestStateBloom(t *testing.T) {
+	bloom := newStateBloom(1024)
+	for i := 0; i < 100; i++ {
+		bloom.add(crypto.Keccak256([]byte{byte(i)}))
+	}
+	for i := 0; i < 100; i++ {
+		if !bloom.contains(crypto.Keccak256([]byte{byte(i)})) {
+			t.Errorf("hash %d: missing from bloom", i)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/log"
+	"github.com/relianz2019/relianz/trie"
+)
+
+// cacheItemSize is the rough average memory used by a cached snapshot entry,
+// used to convert the cache allowance into an item count.
+const cacheItemSize = 128
+
+// diskLayer is a low level persistent snapshot built on top of a key-value store.
+type diskLayer struct {
+	diskdb ethdb.Database // Key-value store containing the base snapshot
+	triedb *trie.Database // Trie node cache for reconstruction purposes
+	cache  *lru.Cache     // Cache to avoid hitting the disk for direct access
+
+	root  common.Hash // Root hash of the base snapshot
+	stale bool        // Signals that the layer became stale (state progressed)
+
+	genMarker []byte           // Hash of the last account generated, nil if the snapshot is complete
+	genAbort  chan chan []byte // Notification channel to abort the generator, nil if not running
+
+	lock sync.RWMutex
+}
+
+// newCache creates the read cache shared by the disk layers of a snapshot tree,
+// given its allowance in megabytes.
+func newCache(size int) *lru.Cache {
+	items := size * 1024 * 1024 / cacheItemSize
+	if items < 1 {
+		items = 1
+	}
+	cache, _ := lru.New(items)
+	return cache
+}
+
+// newDiskLayer creates a disk layer for the given root. If the generation marker
+// is non-nil, the snapshot is incomplete and a background generator is started
+// to fill in the accounts after the marker.
+func newDiskLayer(diskdb ethdb.Database, triedb *trie.Database, cache *lru.Cache, root common.Hash, genMarker []byte) *diskLayer {
+	dl := &diskLayer{
+		diskdb:    diskdb,
+		triedb:    triedb,
+		cache:     cache,
+		root:      root,
+		genMarker: genMarker,
+	}
+	if genMarker != nil {
+		dl.genAbort = make(chan chan []byte)
+		go dl.generate()
+	}
+	return dl
+}
+
+// Root returns root hash for which this snapshot was made.
+func (dl *diskLayer) Root() common.Hash {
+	return dl.root
+}
+
+// Parent always returns nil as there's no layer below the disk.
+func (dl *diskLayer) Parent() snapshot {
+	return nil
+}
+
+// Stale return whether this layer has become stale (was flattened across) or if
+// it's still live.
+func (dl *diskLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// markStale sets the stale flag as true.
+func (dl *diskLayer) markStale() {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.stale = true
+}
+
+// covered returns whether the snapshot generation already went past the given
+// account. The caller must hold the layer lock.
+func (dl *diskLayer) covered(hash common.Hash) bool {
+	return dl.genMarker == nil || bytes.Compare(hash[:], dl.genMarker) <= 0
+}
+
+// Account directly retrieves the account associated with a particular hash.
+func (dl *diskLayer) Account(hash common.Hash) (*Account, error) {
+	return decodeAccount(dl.AccountRLP(hash))
+}
+
+// AccountRLP directly retrieves the account RLP associated with a particular
+// hash.
+func (dl *diskLayer) AccountRLP(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !dl.covered(hash) {
+		return nil, ErrNotCoveredYet
+	}
+	key := string(hash[:])
+	if blob, found := dl.cache.Get(key); found {
+		return blob.([]byte), nil
+	}
+	blob := rawdb.ReadAccountSnapshot(dl.diskdb, hash)
+	dl.cache.Add(key, blob)
+	return blob, nil
+}
+
+// Storage directly retrieves the storage data associated with a particular hash,
+// within a particular account.
+func (dl *diskLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !dl.covered(accountHash) {
+		return nil, ErrNotCoveredYet
+	}
+	key := string(append(accountHash[:], storageHash[:]...))
+	if blob, found := dl.cache.Get(key); found {
+		return blob.([]byte), nil
+	}
+	blob := rawdb.ReadStorageSnapshot(dl.diskdb, accountHash, storageHash)
+	dl.cache.Add(key, blob)
+	return blob, nil
+}
+
+// Update creates a new layer on top of the existing snapshot diff tree with
+// the specified data items. Note, the maps are retained by the method to avoid
+// copying everything.
+func (dl *diskLayer) Update(blockRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer {
+	return newDiffLayer(dl, blockRoot, destructs, accounts, storage)
+}
+
+// stopGeneration aborts the background generator if it is running, returning
+// the hash of the last account generated or nil if the snapshot is complete.
+// The caller must hold the tree lock.
+func (dl *diskLayer) stopGeneration() []byte {
+	if dl.genAbort != nil {
+		abort := make(chan []byte)
+		dl.genAbort <- abort
+		<-abort
+		dl.genAbort = nil
+	}
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.genMarker
+}
+
+// flatten writes the changes of a diff layer directly on top of this disk layer,
+// returning the disk layer representing the state after the diff. Changes to
+// accounts the generator did not reach yet are skipped, it will pick them up
+// from the new root instead.
+func (dl *diskLayer) flatten(diff *diffLayer) *diskLayer {
+	marker := dl.stopGeneration()
+	dl.markStale()
+	diff.markStale()
+
+	var (
+		batch   = dl.diskdb.NewBatch()
+		covered = func(hash common.Hash) bool {
+			return marker == nil || bytes.Compare(hash[:], marker) <= 0
+		}
+	)
+	for hash := range diff.destructSet {
+		if covered(hash) {
+			dl.deleteAccount(batch, hash)
+		}
+	}
+	for hash, data := range diff.accountData {
+		if !covered(hash) {
+			continue
+		}
+		if len(data) == 0 {
+			dl.deleteAccount(batch, hash)
+			continue
+		}
+		rawdb.WriteAccountSnapshot(batch, hash, data)
+		dl.cache.Add(string(hash[:]), data)
+	}
+	for accountHash, storage := range diff.storageData {
+		if !covered(accountHash) {
+			continue
+		}
+		for storageHash, data := range storage {
+			if len(data) == 0 {
+				rawdb.DeleteStorageSnapshot(batch, accountHash, storageHash)
+			} else {
+				rawdb.WriteStorageSnapshot(batch, accountHash, storageHash, data)
+			}
+			dl.cache.Add(string(append(accountHash[:], storageHash[:]...)), data)
+		}
+	}
+	rawdb.WriteSnapshotRoot(batch, diff.root)
+	if marker != nil {
+		rawdb.WriteSnapshotGenerator(batch, marker)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write state snapshot", "err", err)
+	}
+	return newDiskLayer(dl.diskdb, dl.triedb, dl.cache, diff.root, marker)
+}
+
+// deleteAccount adds the deletion of an account along with all its storage to
+// the batch, evicting them from the cache.
+func (dl *diskLayer) deleteAccount(batch ethdb.Batch, hash common.Hash) {
+	rawdb.DeleteAccountSnapshot(batch, hash)
+	dl.cache.Remove(string(hash[:]))
+
+	it := dl.diskdb.NewIteratorWithPrefix(append(rawdb.SnapshotStoragePrefix, hash[:]...))
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(rawdb.SnapshotStoragePrefix)+2*common.HashLength {
+			continue
+		}
+		batch.Delete(key)
+		dl.cache.Remove(string(key[len(rawdb.SnapshotStoragePrefix):]))
+	}
+}
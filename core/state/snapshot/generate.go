@@ -0,0 +1,208 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/log"
+	"github.com/relianz2019/relianz/rlp"
+	"github.com/relianz2019/relianz/trie"
+)
+
+// logInterval is the time between two generation progress reports.
+const logInterval = 8 * time.Second
+
+// generateSnapshot starts generating the snapshot of the given root from scratch
+// in the background. The root is persisted along with an empty generation marker
+// upfront, so an interrupted generation is resumed for the right state.
+func generateSnapshot(diskdb ethdb.Database, triedb *trie.Database, cache *lru.Cache, root common.Hash) *diskLayer {
+	batch := diskdb.NewBatch()
+	rawdb.WriteSnapshotRoot(batch, root)
+	rawdb.WriteSnapshotGenerator(batch, []byte{})
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write snapshot generator", "err", err)
+	}
+	return newDiskLayer(diskdb, triedb, cache, root, []byte{})
+}
+
+// generate is a background thread that iterates over the state and storage tries
+// of the disk layer's root, constructing the snapshot entries of the accounts
+// after the generation marker. Any stale entry left over from an earlier state
+// is deleted on the way. Progress is persisted along with the generated entries,
+// so an interrupted generation can be resumed at the next startup.
+func (dl *diskLayer) generate() {
+	var (
+		batch = dl.diskdb.NewBatch()
+
+		start    = time.Now()
+		logged   = time.Now()
+		accounts uint64
+		slots    uint64
+	)
+	dl.lock.RLock()
+	origin := common.CopyBytes(dl.genMarker)
+	dl.lock.RUnlock()
+	marker := origin
+
+	// flush writes out the batched entries along with the generation marker and
+	// exposes them to readers by advancing the marker of the layer.
+	flush := func(done bool) {
+		if done {
+			rawdb.DeleteSnapshotGenerator(batch)
+			marker = nil
+		} else {
+			rawdb.WriteSnapshotGenerator(batch, marker)
+		}
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to write state snapshot", "err", err)
+		}
+		batch.Reset()
+
+		dl.lock.Lock()
+		dl.genMarker = marker
+		dl.lock.Unlock()
+	}
+	// wait blocks until the generator is requested to stop, reporting the last
+	// account generated.
+	wait := func() {
+		abort := <-dl.genAbort
+		abort <- marker
+	}
+	// aborted checks for a pending request to stop the generator, persisting the
+	// progress before acknowledging it.
+	aborted := func() bool {
+		select {
+		case abort := <-dl.genAbort:
+			flush(false)
+			abort <- marker
+			return true
+		default:
+			return false
+		}
+	}
+	// Generation carries on from the marker whenever the disk layer moves ahead,
+	// only report starting from scratch
+	if len(origin) == 0 {
+		log.Info("Generating state snapshot", "root", dl.root)
+	} else {
+		log.Debug("Continuing state snapshot generation", "root", dl.root, "at", common.BytesToHash(origin))
+	}
+
+	accTrie, err := trie.NewSecure(dl.root, dl.triedb, 0)
+	if err != nil {
+		log.Error("Failed to open state trie for snapshot generation", "root", dl.root, "err", err)
+		wait()
+		return
+	}
+	// Walk the stale snapshot entries along with the trie, to delete any account
+	// no longer present in it
+	stale := dl.diskdb.NewIteratorWithStart(rawdb.SnapshotAccountPrefix, origin)
+	defer stale.Release()
+
+	staleValid := stale.Next()
+	dropStale := func(limit []byte) {
+		for ; staleValid; staleValid = stale.Next() {
+			key := stale.Key()
+			if len(key) != len(rawdb.SnapshotAccountPrefix)+common.HashLength {
+				continue
+			}
+			hash := key[len(rawdb.SnapshotAccountPrefix):]
+			if limit != nil && bytes.Compare(hash, limit) >= 0 {
+				if bytes.Equal(hash, limit) {
+					staleValid = stale.Next() // Overwritten by the trie account
+				}
+				return
+			}
+			dl.deleteAccount(batch, common.BytesToHash(hash))
+		}
+	}
+	it := trie.NewIterator(accTrie.NodeIterator(origin))
+	for it.Next() {
+		dropStale(it.Key)
+		if bytes.Equal(it.Key, origin) {
+			continue // Generated before the generator was interrupted
+		}
+		var account Account
+		if err := rlp.DecodeBytes(it.Value, &account); err != nil {
+			log.Crit("Invalid account encountered during snapshot generation", "err", err)
+		}
+		accountHash := common.BytesToHash(it.Key)
+
+		// Replace any stale storage of the account, writing the account first so a
+		// partially flushed storage is never left without its account
+		dl.deleteAccount(batch, accountHash)
+		rawdb.WriteAccountSnapshot(batch, accountHash, it.Value)
+
+		if account.Root != emptyRoot {
+			storeTrie, err := trie.NewSecure(account.Root, dl.triedb, 0)
+			if err != nil {
+				log.Error("Failed to open storage trie for snapshot generation", "root", account.Root, "err", err)
+				flush(false)
+				wait()
+				return
+			}
+			storeIt := trie.NewIterator(storeTrie.NodeIterator(nil))
+			for storeIt.Next() {
+				rawdb.WriteStorageSnapshot(batch, accountHash, common.BytesToHash(storeIt.Key), storeIt.Value)
+				if batch.ValueSize() >= ethdb.IdealBatchSize {
+					flush(false)
+				}
+				slots++
+
+				if aborted() {
+					return
+				}
+			}
+			if storeIt.Err != nil {
+				log.Error("Failed to iterate storage trie for snapshot generation", "root", account.Root, "err", storeIt.Err)
+				flush(false)
+				wait()
+				return
+			}
+		}
+		marker = accountHash[:]
+		accounts++
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			flush(false)
+		}
+		if time.Since(logged) > logInterval {
+			log.Info("Generating state snapshot", "root", dl.root, "at", accountHash, "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		if aborted() {
+			return
+		}
+	}
+	if it.Err != nil {
+		log.Error("Failed to iterate state trie for snapshot generation", "root", dl.root, "err", it.Err)
+		flush(false)
+		wait()
+		return
+	}
+	dropStale(nil)
+	flush(true)
+
+	log.Info("Generated state snapshot", "root", dl.root, "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+	wait()
+}
@@ -0,0 +1,289 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a flat key-value snapshot of the accounts and
+// storage slots of the state, keyed by hash, for reads bypassing the trie.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/log"
+	"github.com/relianz2019/relianz/trie"
+)
+
+var (
+	// ErrSnapshotStale is returned from data accessors if the underlying snapshot
+	// layer had been invalidated due to the chain progressing forward far enough
+	// to not maintain the layer's original state.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// ErrNotCoveredYet is returned from data accessors if the underlying snapshot
+	// is being generated currently and the requested data item is not yet in the
+	// range of accounts covered.
+	ErrNotCoveredYet = errors.New("not covered yet")
+
+	// errSnapshotCycle is returned if a snapshot is attempted to be inserted
+	// that forms a cycle in the snapshot tree.
+	errSnapshotCycle = errors.New("snapshot cycle")
+)
+
+// Snapshot represents the functionality supported by a snapshot storage layer.
+// Accessors return nil data without an error for items not in the state.
+type Snapshot interface {
+	// Root returns the root hash for which this snapshot was made.
+	Root() common.Hash
+
+	// Account directly retrieves the account associated with a particular hash.
+	Account(hash common.Hash) (*Account, error)
+
+	// AccountRLP directly retrieves the account RLP associated with a particular
+	// hash, encoded the same way as in the account trie.
+	AccountRLP(hash common.Hash) ([]byte, error)
+
+	// Storage directly retrieves the storage data associated with a particular hash,
+	// within a particular account, encoded the same way as in the storage trie.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// snapshot is the internal version of the snapshot data layer that supports some
+// additional methods compared to the public API.
+type snapshot interface {
+	Snapshot
+
+	// Parent returns the subsequent layer of a snapshot, or nil if the base was
+	// reached.
+	Parent() snapshot
+
+	// Update creates a new layer on top of the existing snapshot diff tree with
+	// the specified data items.
+	Update(root common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer
+
+	// Stale return whether this layer has become stale (was flattened across) or
+	// if it's still live.
+	Stale() bool
+}
+
+// Tree is a snapshot tree: a persistent disk layer at its bottom, covering the
+// state of an older block, topped by in-memory diff layers tracking the state
+// changes of every block imported since. Diff layers beyond a configured depth
+// are flattened into the disk layer.
+type Tree struct {
+	diskdb ethdb.Database           // Persistent database to store the snapshot
+	triedb *trie.Database           // In-memory cache to access the trie through
+	cache  int                      // Megabytes permitted to use for read caches
+	layers map[common.Hash]snapshot // Collection of all known layers
+	lock   sync.RWMutex
+}
+
+// New attempts to load an already existing snapshot from a persistent key-value
+// store, ensuring that the head of the snapshot matches the expected one.
+//
+// If the snapshot is missing, does not match the expected root or was not fully
+// generated, it is (re)generated in the background, serving reads from the part
+// already covered in the meantime.
+func New(diskdb ethdb.Database, triedb *trie.Database, cache int, root common.Hash) *Tree {
+	snap := &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		cache:  cache,
+		layers: make(map[common.Hash]snapshot),
+	}
+	base, err := loadSnapshot(diskdb, triedb, cache, root)
+	if err != nil {
+		log.Warn("Failed to load state snapshot, regenerating", "err", err)
+		base = generateSnapshot(diskdb, triedb, newCache(cache), root)
+	}
+	snap.layers[base.root] = base
+	return snap
+}
+
+// Snapshot retrieves a snapshot belonging to the given block root, or nil if no
+// snapshot is maintained for that block.
+func (t *Tree) Snapshot(blockRoot common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if layer, ok := t.layers[blockRoot]; ok {
+		return layer
+	}
+	return nil
+}
+
+// Update adds a new snapshot into the tree, if that can be linked to an existing
+// old parent. It is disallowed to insert a disk layer (the origin of all).
+func (t *Tree) Update(blockRoot common.Hash, parentRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	// Reject noop updates to avoid self-loops in the snapshot tree. This is a
+	// special case that can only happen for blocks not changing the state.
+	if blockRoot == parentRoot {
+		return errSnapshotCycle
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// The same state may be reached through different chain branches, the layer
+	// tracking it first is kept.
+	if _, ok := t.layers[blockRoot]; ok {
+		return nil
+	}
+	parent, ok := t.layers[parentRoot]
+	if !ok {
+		return fmt.Errorf("parent [%#x] snapshot missing", parentRoot)
+	}
+	t.layers[blockRoot] = parent.Update(blockRoot, destructs, accounts, storage)
+	return nil
+}
+
+// Cap traverses downwards the snapshot tree from a head block hash until the
+// number of allowed layers are crossed. All layers beyond the permitted number
+// are flattened downwards into the disk layer.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	snap, ok := t.layers[root]
+	if !ok {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	diff, ok := snap.(*diffLayer)
+	if !ok {
+		return nil // Already flattened into the disk layer
+	}
+	// Find the diff layers to flatten, being the parent of the last one retained
+	var bottom *diffLayer
+	if layers > 0 {
+		for i := 0; i < layers-1; i++ {
+			parent, ok := diff.Parent().(*diffLayer)
+			if !ok {
+				return nil // Not enough diff layers to flatten any
+			}
+			diff = parent
+		}
+		parent, ok := diff.Parent().(*diffLayer)
+		if !ok {
+			return nil
+		}
+		bottom, diff = diff, parent
+	}
+	base := diffToDisk(diff)
+	if bottom != nil {
+		bottom.lock.Lock()
+		bottom.parent = base
+		bottom.lock.Unlock()
+	}
+	// Drop all the layers which were flattened or which branched off a flattened
+	// layer and thus can no longer reach the new disk layer
+	remaining := map[common.Hash]snapshot{base.root: base}
+	for root, layer := range t.layers {
+		if reaches(layer, base) {
+			remaining[root] = layer
+			continue
+		}
+		if diff, ok := layer.(*diffLayer); ok {
+			diff.markStale()
+		}
+	}
+	t.layers = remaining
+	return nil
+}
+
+// reaches returns whether the given layer is based on the given disk layer.
+func reaches(layer snapshot, base *diskLayer) bool {
+	for layer != nil {
+		if disk, ok := layer.(*diskLayer); ok {
+			return disk == base
+		}
+		layer = layer.Parent()
+	}
+	return false
+}
+
+// Rebuild discards all caches and diff layers and starts a new snapshot generator
+// with the given root hash, replacing the persisted snapshot data as it goes.
+func (t *Tree) Rebuild(root common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, layer := range t.layers {
+		switch layer := layer.(type) {
+		case *diskLayer:
+			layer.stopGeneration()
+			layer.markStale()
+		case *diffLayer:
+			layer.markStale()
+		}
+	}
+	log.Info("Rebuilding state snapshot", "root", root)
+	base := generateSnapshot(t.diskdb, t.triedb, newCache(t.cache), root)
+	t.layers = map[common.Hash]snapshot{base.root: base}
+}
+
+// Stop interrupts a running snapshot generation, persisting its progress so it
+// can be resumed on the next startup.
+func (t *Tree) Stop() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, layer := range t.layers {
+		if disk, ok := layer.(*diskLayer); ok {
+			disk.stopGeneration()
+		}
+	}
+}
+
+// loadSnapshot loads the persisted disk layer if it represents the given root,
+// resuming its generation if it was interrupted.
+func loadSnapshot(diskdb ethdb.Database, triedb *trie.Database, cache int, root common.Hash) (*diskLayer, error) {
+	base := rawdb.ReadSnapshotRoot(diskdb)
+	if base == (common.Hash{}) {
+		return nil, errors.New("missing or corrupted snapshot")
+	}
+	if base != root {
+		return nil, fmt.Errorf("head doesn't match snapshot: have %#x, want %#x", base, root)
+	}
+	marker := rawdb.ReadSnapshotGenerator(diskdb)
+	if marker != nil {
+		log.Info("Resuming state snapshot generation", "root", root, "at", common.BytesToHash(marker))
+	}
+	return newDiskLayer(diskdb, triedb, newCache(cache), root, marker), nil
+}
+
+// diffToDisk merges the given diff layer and all the diff layers below it into
+// the disk layer at the bottom, returning the new disk layer. The flattened
+// layers and the old disk layer are marked stale.
+func diffToDisk(bottom *diffLayer) *diskLayer {
+	var (
+		diffs []*diffLayer
+		base  *diskLayer
+	)
+	for layer := snapshot(bottom); base == nil; layer = layer.Parent() {
+		switch layer := layer.(type) {
+		case *diffLayer:
+			diffs = append(diffs, layer)
+		case *diskLayer:
+			base = layer
+		}
+	}
+	for i := len(diffs) - 1; i >= 0; i-- {
+		base = base.flatten(diffs[i])
+	}
+	return base
+}
@@ -0,0 +1,297 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/rlp"
+	"github.com/relianz2019/relianz/trie"
+)
+
+// newTestDatabase opens a database in a temporary directory, returning it along
+// with a function to close and remove it.
+func newTestDatabase(t *testing.T) (*ethdb.LDBDatabase, func()) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	db, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("failed to open database: %v", err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// newTestState writes a state of three accounts to the database, the first two
+// of them owning storage, and returns its root along with the expected snapshot
+// entries.
+func newTestState(t *testing.T, triedb *trie.Database) (common.Hash, map[common.Hash][]byte, map[common.Hash]map[common.Hash][]byte) {
+	var (
+		accounts = make(map[common.Hash][]byte)
+		storage  = make(map[common.Hash]map[common.Hash][]byte)
+	)
+	accTrie, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+	for i := byte(1); i <= 3; i++ {
+		addr := common.Address{i}
+		accountHash := crypto.Keccak256Hash(addr[:])
+
+		root := emptyRoot
+		if i < 3 {
+			storage[accountHash] = make(map[common.Hash][]byte)
+
+			stTrie, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+			for j := byte(1); j <= 4; j++ {
+				key := common.Hash{j}
+				value, _ := rlp.EncodeToBytes([]byte{i, j})
+				stTrie.Update(key[:], value)
+				storage[accountHash][crypto.Keccak256Hash(key[:])] = value
+			}
+			var err error
+			if root, err = stTrie.Commit(nil); err != nil {
+				t.Fatalf("failed to commit storage trie: %v", err)
+			}
+			if err := triedb.Commit(root, false); err != nil {
+				t.Fatalf("failed to flush storage trie: %v", err)
+			}
+		}
+		enc, _ := rlp.EncodeToBytes(&Account{Nonce: uint64(i), Balance: big.NewInt(int64(i)), Root: root, CodeHash: crypto.Keccak256(nil)})
+		accTrie.Update(addr[:], enc)
+		accounts[accountHash] = enc
+	}
+	root, err := accTrie.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit account trie: %v", err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("failed to flush account trie: %v", err)
+	}
+	return root, accounts, storage
+}
+
+// waitGeneration waits for the disk layer of the tree to finish generating.
+func waitGeneration(t *testing.T, snaps *Tree, root common.Hash) *diskLayer {
+	dl, ok := snaps.Snapshot(root).(*diskLayer)
+	if !ok {
+		t.Fatalf("disk layer %x missing", root)
+	}
+	for i := 0; i < 500; i++ {
+		dl.lock.RLock()
+		done := dl.genMarker == nil
+		dl.lock.RUnlock()
+
+		if done {
+			return dl
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("snapshot generation timed out")
+	return nil
+}
+
+// checkSnapshot verifies that a snapshot layer serves exactly the given entries.
+func checkSnapshot(t *testing.T, snap Snapshot, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) {
+	for hash, want := range accounts {
+		if have, err := snap.AccountRLP(hash); err != nil || !bytes.Equal(have, want) {
+			t.Errorf("account %x mismatch: have %x (%v), want %x", hash, have, err, want)
+		}
+	}
+	for accountHash, slots := range storage {
+		for storageHash, want := range slots {
+			if have, err := snap.Storage(accountHash, storageHash); err != nil || !bytes.Equal(have, want) {
+				t.Errorf("storage %x/%x mismatch: have %x (%v), want %x", accountHash, storageHash, have, err, want)
+			}
+		}
+	}
+}
+
+func TestSnapshotGeneration(t *testing.T) {
+	db, release := newTestDatabase(t)
+	defer release()
+
+	triedb := trie.NewDatabase(db)
+	root, accounts, storage := newTestState(t, triedb)
+
+	// Leave stale entries of an earlier state behind, which must be cleaned up
+	staleHash := common.Hash{0xff}
+	rawdb.WriteAccountSnapshot(db, staleHash, []byte{0x01})
+	rawdb.WriteStorageSnapshot(db, staleHash, common.Hash{0x01}, []byte{0x02})
+	for accountHash := range storage {
+		rawdb.WriteStorageSnapshot(db, accountHash, common.Hash{0xee}, []byte{0x03})
+	}
+	snaps := New(db, triedb, 1, root)
+	defer snaps.Stop()
+
+	waitGeneration(t, snaps, root)
+	checkSnapshot(t, snaps.Snapshot(root), accounts, storage)
+
+	if data := rawdb.ReadAccountSnapshot(db, staleHash); data != nil {
+		t.Errorf("stale account not deleted: %x", data)
+	}
+	if data := rawdb.ReadStorageSnapshot(db, staleHash, common.Hash{0x01}); data != nil {
+		t.Errorf("stale account storage not deleted: %x", data)
+	}
+	for accountHash := range storage {
+		if data := rawdb.ReadStorageSnapshot(db, accountHash, common.Hash{0xee}); data != nil {
+			t.Errorf("stale storage of %x not deleted: %x", accountHash, data)
+		}
+	}
+	if have := rawdb.ReadSnapshotRoot(db); have != root {
+		t.Errorf("persisted root mismatch: have %x, want %x", have, root)
+	}
+	if marker := rawdb.ReadSnapshotGenerator(db); marker != nil {
+		t.Errorf("generator marker left behind: %x", marker)
+	}
+}
+
+func TestSnapshotDiffLayers(t *testing.T) {
+	db, release := newTestDatabase(t)
+	defer release()
+
+	triedb := trie.NewDatabase(db)
+	root, accounts, storage := newTestState(t, triedb)
+
+	snaps := New(db, triedb, 1, root)
+	waitGeneration(t, snaps, root)
+
+	var (
+		acc1 = crypto.Keccak256Hash(common.Address{0x01}.Bytes())
+		acc2 = crypto.Keccak256Hash(common.Address{0x02}.Bytes())
+		acc4 = crypto.Keccak256Hash(common.Address{0x04}.Bytes())
+		slot = crypto.Keccak256Hash(common.Hash{0x01}.Bytes())
+
+		root1 = common.Hash{0x01}
+		root2 = common.Hash{0x02}
+	)
+	// Recreate the first account with a single storage slot and add a new one
+	if err := snaps.Update(root1, root, map[common.Hash]struct{}{acc1: {}}, map[common.Hash][]byte{acc1: {0x01}, acc4: {0x04}}, map[common.Hash]map[common.Hash][]byte{acc1: {{0xaa}: {0x0a}}}); err != nil {
+		t.Fatalf("failed to add first diff layer: %v", err)
+	}
+	// Delete the second account and a storage slot of the first
+	if err := snaps.Update(root2, root1, map[common.Hash]struct{}{acc2: {}}, nil, map[common.Hash]map[common.Hash][]byte{acc1: {{0xaa}: nil}}); err != nil {
+		t.Fatalf("failed to add second diff layer: %v", err)
+	}
+	if err := snaps.Update(root2, common.Hash{0xff}, nil, nil, nil); err != nil {
+		t.Errorf("failed to skip known diff layer: %v", err)
+	}
+	if err := snaps.Update(common.Hash{0x03}, common.Hash{0xff}, nil, nil, nil); err == nil {
+		t.Errorf("diff layer with unknown parent accepted")
+	}
+	base := snaps.Snapshot(root)
+	checkSnapshot(t, base, accounts, storage)
+
+	want := map[common.Hash][]byte{acc1: {0x01}, acc2: nil, acc4: {0x04}}
+	wantStorage := map[common.Hash]map[common.Hash][]byte{
+		acc1: {slot: nil, {0xaa}: nil},
+		acc2: {slot: nil},
+	}
+	checkSnapshot(t, snaps.Snapshot(root2), want, wantStorage)
+	checkSnapshot(t, snaps.Snapshot(root1), nil, map[common.Hash]map[common.Hash][]byte{acc1: {{0xaa}: {0x0a}}})
+
+	// Flatten the first diff layer, invalidating the base
+	if err := snaps.Cap(root2, 1); err != nil {
+		t.Fatalf("failed to cap snapshot tree: %v", err)
+	}
+	if _, err := base.AccountRLP(acc1); err != ErrSnapshotStale {
+		t.Errorf("stale disk layer error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if snaps.Snapshot(root) != nil {
+		t.Errorf("flattened disk layer still tracked")
+	}
+	if have := rawdb.ReadSnapshotRoot(db); have != root1 {
+		t.Errorf("persisted root mismatch: have %x, want %x", have, root1)
+	}
+	if data := rawdb.ReadStorageSnapshot(db, acc1, slot); data != nil {
+		t.Errorf("storage of destructed account not wiped: %x", data)
+	}
+	checkSnapshot(t, snaps.Snapshot(root2), want, wantStorage)
+
+	// Flatten everything and reload the persisted snapshot
+	if err := snaps.Cap(root2, 0); err != nil {
+		t.Fatalf("failed to flatten snapshot tree: %v", err)
+	}
+	snaps.Stop()
+
+	snaps = New(db, triedb, 1, root2)
+	if _, ok := snaps.Snapshot(root2).(*diskLayer); !ok {
+		t.Fatalf("persisted snapshot not loaded")
+	}
+	checkSnapshot(t, snaps.Snapshot(root2), want, wantStorage)
+	if data := rawdb.ReadStorageSnapshot(db, acc2, slot); data != nil {
+		t.Errorf("storage of deleted account not wiped: %x", data)
+	}
+	// Regenerating at the original root must restore it
+	snaps.Rebuild(root)
+	defer snaps.Stop()
+
+	waitGeneration(t, snaps, root)
+	checkSnapshot(t, snaps.Snapshot(root), accounts, storage)
+	if data := rawdb.ReadAccountSnapshot(db, acc4); data != nil {
+		t.Errorf("account missing from the state not deleted: %x", data)
+	}
+}
+
+// Tests that a snapshot generation interrupted right after it started, before
+// the generator persisted any progress, is resumed for the right root after a
+// restart.
+func TestSnapshotGenerationRestart(t *testing.T) {
+	db, release := newTestDatabase(t)
+	defer release()
+
+	triedb := trie.NewDatabase(db)
+	root, accounts, storage := newTestState(t, triedb)
+
+	// Generating a root missing from the database never progresses, but it has to
+	// be persisted nonetheless
+	missing := common.Hash{0xde, 0xad}
+	snaps := New(db, triedb, 1, missing)
+	if have := rawdb.ReadSnapshotRoot(db); have != missing {
+		t.Errorf("persisted root mismatch: have %x, want %x", have, missing)
+	}
+	if marker := rawdb.ReadSnapshotGenerator(db); marker == nil || len(marker) != 0 {
+		t.Errorf("generator marker mismatch: have %x, want empty", marker)
+	}
+	// Rebuilding must replace the persisted root before the old generation is
+	// resumed for it
+	snaps.Rebuild(root)
+	if have := rawdb.ReadSnapshotRoot(db); have != root {
+		t.Errorf("persisted root mismatch after rebuild: have %x, want %x", have, root)
+	}
+	snaps.Stop()
+
+	snaps = New(db, triedb, 1, root)
+	defer snaps.Stop()
+
+	if rawdb.ReadSnapshotRoot(db) != root {
+		t.Fatalf("persisted snapshot not resumed")
+	}
+	waitGeneration(t, snaps, root)
+	checkSnapshot(t, snaps.Snapshot(root), accounts, storage)
+}
@@ -79,6 +79,7 @@ type stateObject struct {
 
 	cachedStorage Storage // Storage entry cache to avoid duplicate reads
 	dirtyStorage  Storage // Storage entries that need to be flushed to disk
+	originStorage Storage // Committed values of the dirty storage entries, as of the start of the transaction
 
 	// Cache flags.
 	// When an object is marked suicided it will be delete from the trie
@@ -117,6 +118,7 @@ func newObject(db *StateDB, address common.Address, data Account) *stateObject {
 		data:          data,
 		cachedStorage: make(Storage),
 		dirtyStorage:  make(Storage),
+		originStorage: make(Storage),
 	}
 }
 
@@ -165,11 +167,24 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 	if exists {
 		return value
 	}
-	// Load from DB in case it is missing.
-	enc, err := self.getTrie(db).TryGet(key[:])
-	if err != nil {
-		self.setError(err)
-		return common.Hash{}
+	// Load from the snapshot if it covers the account, or the trie otherwise. The
+	// snapshot still holds the storage of accounts destructed in this block.
+	var (
+		enc []byte
+		err error
+	)
+	if snap := self.db.snap; snap != nil {
+		if _, destructed := self.db.snapDestructs[self.addrHash]; destructed {
+			self.cachedStorage[key] = common.Hash{}
+			return common.Hash{}
+		}
+		enc, err = snap.Storage(self.addrHash, crypto.Keccak256Hash(key[:]))
+	}
+	if self.db.snap == nil || err != nil {
+		if enc, err = self.getTrie(db).TryGet(key[:]); err != nil {
+			self.setError(err)
+			return common.Hash{}
+		}
 	}
 	if len(enc) > 0 {
 		_, content, _, err := rlp.Split(enc)
@@ -182,12 +197,25 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 	return value
 }
 
+// GetCommittedState returns the committed value of an account storage entry,
+// ignoring any modifications made since the state was last finalised.
+func (self *stateObject) GetCommittedState(db Database, key common.Hash) common.Hash {
+	if value, dirty := self.originStorage[key]; dirty {
+		return value
+	}
+	return self.GetState(db, key)
+}
+
 // SetState updates a value in account storage.
 func (self *stateObject) SetState(db Database, key, value common.Hash) {
+	prev := self.GetState(db, key)
+	if _, dirty := self.dirtyStorage[key]; !dirty {
+		self.originStorage[key] = prev
+	}
 	self.db.journal.append(storageChange{
 		account:  &self.address,
 		key:      key,
-		prevalue: self.GetState(db, key),
+		prevalue: prev,
 	})
 	self.setState(key, value)
 }
@@ -202,13 +230,25 @@ func (self *stateObject) updateTrie(db Database) Trie {
 	tr := self.getTrie(db)
 	for key, value := range self.dirtyStorage {
 		delete(self.dirtyStorage, key)
+		delete(self.originStorage, key)
+
+		var v []byte
 		if (value == common.Hash{}) {
 			self.setError(tr.TryDelete(key[:]))
-			continue
+		} else {
+			// Encoding []byte cannot fail, ok to ignore the error.
+			v, _ = rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
+			self.setError(tr.TryUpdate(key[:], v))
+		}
+		// Track the change for the snapshot, nil marking a deleted slot
+		if self.db.snap != nil {
+			storage, ok := self.db.snapStorage[self.addrHash]
+			if !ok {
+				storage = make(map[common.Hash][]byte)
+				self.db.snapStorage[self.addrHash] = storage
+			}
+			storage[crypto.Keccak256Hash(key[:])] = v
 		}
-		// Encoding []byte cannot fail, ok to ignore the error.
-		v, _ := rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
-		self.setError(tr.TryUpdate(key[:], v))
 	}
 	return tr
 }
@@ -280,6 +320,7 @@ func (self *stateObject) deepCopy(db *StateDB) *stateObject {
 	stateObject.code = self.code
 	stateObject.dirtyStorage = self.dirtyStorage.Copy()
 	stateObject.cachedStorage = self.dirtyStorage.Copy()
+	stateObject.originStorage = self.originStorage.Copy()
 	stateObject.suicided = self.suicided
 	stateObject.dirtyCode = self.dirtyCode
 	stateObject.deleted = self.deleted
@@ -18,10 +18,13 @@ package state
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"math/big"
 	"testing"
 
 	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
 	"github.com/relianz2019/relianz/crypto"
 	"github.com/relianz2019/relianz/ethdb"
 	checker "gopkg.in/check.v1"
@@ -86,6 +89,97 @@ func (s *StateSuite) TestDump(c *checker.C) {
 	}
 }
 
+func (s *StateSuite) TestIteratorDump(c *checker.C) {
+	for i := byte(1); i <= 5; i++ {
+		obj := s.state.GetOrNewStateObject(toAddr([]byte{i}))
+		obj.AddBalance(big.NewInt(int64(i)))
+		obj.SetCode(crypto.Keccak256Hash([]byte{i}), []byte{i})
+		obj.SetState(s.state.db, common.Hash{i}, common.Hash{i})
+	}
+	root, _ := s.state.Commit(false)
+	s.state, _ = New(root, s.state.db)
+
+	// Page through the state two accounts at a time, leaving out the code
+	var (
+		pages    int
+		accounts []DumpAccount
+		conf     = &DumpConfig{SkipCode: true, Max: 2}
+	)
+	for {
+		page, err := s.state.IteratorDump(conf)
+		if err != nil {
+			c.Fatalf("page %d: failed to dump: %v", pages, err)
+		}
+		accounts = append(accounts, page.Accounts...)
+		if pages++; page.Next == nil {
+			break
+		}
+		conf.Start = page.Next
+	}
+	if pages != 3 || len(accounts) != 5 {
+		c.Fatalf("paged dump mismatch: have %d pages, %d accounts, want 3 pages, 5 accounts", pages, len(accounts))
+	}
+	for i, account := range accounts {
+		if account.Address == nil || account.Code != "" || len(account.Storage) != 1 {
+			c.Errorf("account %d: dump mismatch: %+v", i, account)
+		}
+		if i > 0 && bytes.Compare(accounts[i-1].Key, account.Key) >= 0 {
+			c.Errorf("account %d: not in hashed address order", i)
+		}
+	}
+	// Stream two accounts without storage, starting from the third one
+	var out bytes.Buffer
+	if err := s.state.IterativeDump(&DumpConfig{SkipStorage: true, Start: accounts[2].Key, Max: 2}, json.NewEncoder(&out)); err != nil {
+		c.Fatalf("failed to stream dump: %v", err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if len(lines) != 4 {
+		c.Fatalf("streamed line count mismatch: have %d, want 4", len(lines))
+	}
+	var streamed DumpAccount
+	if err := json.Unmarshal(lines[1], &streamed); err != nil {
+		c.Fatalf("failed to decode streamed account: %v", err)
+	}
+	if *streamed.Address != *accounts[2].Address || streamed.Code == "" || streamed.Storage != nil {
+		c.Errorf("streamed account mismatch: %+v", streamed)
+	}
+	var next struct {
+		Next hexutil.Bytes `json:"next"`
+	}
+	if err := json.Unmarshal(lines[3], &next); err != nil || !bytes.Equal(next.Next, accounts[4].Key) {
+		c.Errorf("continuation mismatch: have %x, want %x", next.Next, accounts[4].Key)
+	}
+	var head struct {
+		Root string `json:"root"`
+	}
+	if err := json.Unmarshal(lines[0], &head); err != nil || head.Root != s.state.RawDump().Root {
+		c.Errorf("streamed root mismatch: have %s, want %s", head.Root, s.state.RawDump().Root)
+	}
+	// A failing write must stop the dump right away
+	w := &failingWriter{limit: 2}
+	if err := s.state.IterativeDump(&DumpConfig{SkipStorage: true}, json.NewEncoder(w)); err != errWriteFailed {
+		c.Errorf("stream error mismatch: have %v, want %v", err, errWriteFailed)
+	}
+	if w.writes != 3 {
+		c.Errorf("write count mismatch: have %d, want 3", w.writes)
+	}
+}
+
+var errWriteFailed = errors.New("write failed")
+
+// failingWriter is an io.Writer failing every write after the first few.
+type failingWriter struct {
+	limit  int
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.writes++; w.writes > w.limit {
+		return 0, errWriteFailed
+	}
+	return len(p), nil
+}
+
 func (s *StateSuite) SetUpTest(c *checker.C) {
 	s.db = ethdb.NewMemDatabase()
 	s.state, _ = New(common.Hash{}, NewDatabase(s.db))
@@ -24,6 +24,7 @@ import (
 	"sync"
 
 	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/state/snapshot"
 	"github.com/relianz2019/relianz/core/types"
 	"github.com/relianz2019/relianz/crypto"
 	"github.com/relianz2019/relianz/log"
@@ -53,6 +54,14 @@ type StateDB struct {
 	db   Database
 	trie Trie
 
+	// The flat snapshot serving reads bypassing the trie, if available, along
+	// with the changes to hand over to the snapshot tree on commit.
+	snaps         *snapshot.Tree
+	snap          snapshot.Snapshot
+	snapDestructs map[common.Hash]struct{}
+	snapAccounts  map[common.Hash][]byte
+	snapStorage   map[common.Hash]map[common.Hash][]byte
+
 	// This map holds 'live' objects, which will get modified while processing a state transition.
 	stateObjects      map[common.Address]*stateObject
 	stateObjectsDirty map[common.Address]struct{}
@@ -100,6 +109,31 @@ func New(root common.Hash, db Database) (*StateDB, error) {
 	}, nil
 }
 
+// NewWithSnapshot creates a new state from a given trie, serving reads from the
+// flat snapshot of the root if the snapshot tree maintains one.
+func NewWithSnapshot(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error) {
+	sdb, err := New(root, db)
+	if err != nil {
+		return nil, err
+	}
+	if snaps != nil {
+		sdb.snaps = snaps
+		sdb.resetSnapshot(root)
+	}
+	return sdb, nil
+}
+
+// resetSnapshot switches the snapshot serving reads over to the one of the given
+// root, discarding the changes collected so far.
+func (self *StateDB) resetSnapshot(root common.Hash) {
+	self.snap, self.snapDestructs, self.snapAccounts, self.snapStorage = nil, nil, nil, nil
+	if self.snap = self.snaps.Snapshot(root); self.snap != nil {
+		self.snapDestructs = make(map[common.Hash]struct{})
+		self.snapAccounts = make(map[common.Hash][]byte)
+		self.snapStorage = make(map[common.Hash]map[common.Hash][]byte)
+	}
+}
+
 // setError remembers the first non-nil error it is called with.
 func (self *StateDB) setError(err error) {
 	if self.dbErr == nil {
@@ -127,6 +161,9 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.logs = make(map[common.Hash][]*types.Log)
 	self.logSize = 0
 	self.preimages = make(map[common.Hash][]byte)
+	if self.snaps != nil {
+		self.resetSnapshot(root)
+	}
 	self.clearJournalAndRefund()
 	return nil
 }
@@ -174,6 +211,16 @@ func (self *StateDB) AddRefund(gas uint64) {
 	self.refund += gas
 }
 
+// SubRefund removes gas from the refund counter.
+// This method will panic if the refund counter goes below zero
+func (self *StateDB) SubRefund(gas uint64) {
+	self.journal.append(refundChange{prev: self.refund})
+	if gas > self.refund {
+		panic("Refund counter below zero")
+	}
+	self.refund -= gas
+}
+
 // Exist reports whether the given account address exists in the state.
 // Notably this also returns true for suicided accounts.
 func (self *StateDB) Exist(addr common.Address) bool {
@@ -244,6 +291,16 @@ func (self *StateDB) GetState(addr common.Address, bhash common.Hash) common.Has
 	return common.Hash{}
 }
 
+// GetCommittedState retrieves a value from the given account's committed
+// storage, i.e. its value at the start of the current transaction.
+func (self *StateDB) GetCommittedState(addr common.Address, bhash common.Hash) common.Hash {
+	stateObject := self.getStateObject(addr)
+	if stateObject != nil {
+		return stateObject.GetCommittedState(self.db, bhash)
+	}
+	return common.Hash{}
+}
+
 // Database retrieves the low level database supporting the lower level trie ops.
 func (self *StateDB) Database() Database {
 	return self.db
@@ -349,6 +406,10 @@ func (self *StateDB) updateStateObject(stateObject *stateObject) {
 		panic(fmt.Errorf("can't encode object at %x: %v", addr[:], err))
 	}
 	self.setError(self.trie.TryUpdate(addr[:], data))
+
+	if self.snap != nil {
+		self.snapAccounts[stateObject.addrHash] = data
+	}
 }
 
 // deleteStateObject removes the given object from the state trie.
@@ -356,6 +417,12 @@ func (self *StateDB) deleteStateObject(stateObject *stateObject) {
 	stateObject.deleted = true
 	addr := stateObject.Address()
 	self.setError(self.trie.TryDelete(addr[:]))
+
+	if self.snap != nil {
+		self.snapDestructs[stateObject.addrHash] = struct{}{}
+		delete(self.snapAccounts, stateObject.addrHash)
+		delete(self.snapStorage, stateObject.addrHash)
+	}
 }
 
 // Retrieve a state object given by the address. Returns nil if not found.
@@ -368,8 +435,17 @@ func (self *StateDB) getStateObject(addr common.Address) (stateObject *stateObje
 		return obj
 	}
 
-	// Load the object from the database.
-	enc, err := self.trie.TryGet(addr[:])
+	// Load the object from the snapshot if it covers it, or the trie otherwise.
+	var (
+		enc []byte
+		err error
+	)
+	if self.snap != nil {
+		enc, err = self.snap.AccountRLP(crypto.Keccak256Hash(addr[:]))
+	}
+	if self.snap == nil || err != nil {
+		enc, err = self.trie.TryGet(addr[:])
+	}
 	if len(enc) == 0 {
 		self.setError(err)
 		return nil
@@ -407,7 +483,15 @@ func (self *StateDB) createObject(addr common.Address) (newobj, prev *stateObjec
 	if prev == nil {
 		self.journal.append(createObjectChange{account: &addr})
 	} else {
-		self.journal.append(resetObjectChange{prev: prev})
+		// The storage of the overwritten account is gone, which the snapshot needs
+		// to know about even if the account is written again
+		var prevdestruct bool
+		if self.snap != nil {
+			if _, prevdestruct = self.snapDestructs[prev.addrHash]; !prevdestruct {
+				self.snapDestructs[prev.addrHash] = struct{}{}
+			}
+		}
+		self.journal.append(resetObjectChange{prev: prev, prevdestruct: prevdestruct})
 	}
 	self.setStateObject(newobj)
 	return newobj, prev
@@ -497,6 +581,24 @@ func (self *StateDB) Copy() *StateDB {
 	for hash, preimage := range self.preimages {
 		state.preimages[hash] = preimage
 	}
+	if self.snap != nil {
+		state.snaps, state.snap = self.snaps, self.snap
+		state.snapDestructs = make(map[common.Hash]struct{}, len(self.snapDestructs))
+		for hash := range self.snapDestructs {
+			state.snapDestructs[hash] = struct{}{}
+		}
+		state.snapAccounts = make(map[common.Hash][]byte, len(self.snapAccounts))
+		for hash, data := range self.snapAccounts {
+			state.snapAccounts[hash] = data
+		}
+		state.snapStorage = make(map[common.Hash]map[common.Hash][]byte, len(self.snapStorage))
+		for hash, storage := range self.snapStorage {
+			state.snapStorage[hash] = make(map[common.Hash][]byte, len(storage))
+			for key, data := range storage {
+				state.snapStorage[hash][key] = data
+			}
+		}
+	}
 	return state
 }
 
@@ -624,5 +726,16 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error)
 		return nil
 	})
 	log.Debug("Trie cache stats after commit", "misses", trie.CacheMisses(), "unloads", trie.CacheUnloads())
+
+	// Hand the changes over to the snapshot tree as a new diff layer, the state
+	// no longer reads from the snapshot afterwards
+	if err == nil && s.snap != nil {
+		if parent := s.snap.Root(); parent != root {
+			if err := s.snaps.Update(root, parent, s.snapDestructs, s.snapAccounts, s.snapStorage); err != nil {
+				log.Warn("Failed to update snapshot tree", "from", parent, "to", root, "err", err)
+			}
+		}
+		s.snap, s.snapDestructs, s.snapAccounts, s.snapStorage = nil, nil, nil, nil
+	}
 	return root, err
 }
@@ -20,19 +20,25 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"math/big"
 	"math/rand"
+	"os"
 	"reflect"
 	"strings"
 	"testing"
 	"testing/quick"
+	"time"
 
 	check "gopkg.in/check.v1"
 
 	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/state/snapshot"
 	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/crypto"
 	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/rlp"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -435,3 +441,114 @@ func TestCopyOfCopy(t *testing.T) {
 		t.Fatalf("2nd copy fail, expected 42, got %v", got)
 	}
 }
+
+// Tests that state reads are served from the flat snapshot and that committed
+// changes, including destructed and reset accounts, end up in its diff layers.
+func TestFlatSnapshotReads(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var (
+		sdb        = NewDatabase(db)
+		addr1      = common.Address{0x01}
+		addr2      = common.Address{0x02}
+		addr3      = common.Address{0x03}
+		key1, key2 = common.Hash{0x01}, common.Hash{0x02}
+		hash       = func(b []byte) common.Hash { return crypto.Keccak256Hash(b) }
+	)
+	state, _ := New(common.Hash{}, sdb)
+	state.SetBalance(addr1, big.NewInt(1))
+	state.SetState(addr1, key1, common.Hash{0x11})
+	state.SetState(addr1, key2, common.Hash{0x12})
+	state.SetBalance(addr2, big.NewInt(2))
+	state.SetState(addr2, key1, common.Hash{0x21})
+
+	root, _ := state.Commit(false)
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	snaps := snapshot.New(db, sdb.TrieDB(), 1, root)
+	defer snaps.Stop()
+
+	for i := 0; ; i++ {
+		_, err1 := snaps.Snapshot(root).AccountRLP(hash(addr1[:]))
+		_, err2 := snaps.Snapshot(root).AccountRLP(hash(addr2[:]))
+		if err1 == nil && err2 == nil {
+			break
+		}
+		if i == 500 {
+			t.Fatalf("snapshot generation timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	state, _ = NewWithSnapshot(root, sdb, snaps)
+	if state.snap == nil {
+		t.Fatalf("snapshot not used for reads")
+	}
+	if balance := state.GetBalance(addr1); balance.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 1", balance)
+	}
+	if value := state.GetState(addr2, key1); value != (common.Hash{0x21}) {
+		t.Errorf("storage mismatch: have %x, want %x", value, common.Hash{0x21})
+	}
+	// Destruct the second account, reset and revert the first one, then commit
+	state.Suicide(addr2)
+	state.Finalise(true)
+
+	revision := state.Snapshot()
+	state.CreateAccount(addr1)
+	state.RevertToSnapshot(revision)
+
+	state.SetState(addr1, key1, common.Hash{0x13})
+	state.SetBalance(addr3, big.NewInt(3))
+
+	root2, err := state.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	snap := snaps.Snapshot(root2)
+	if snap == nil {
+		t.Fatalf("diff layer missing for committed state")
+	}
+	value := func(b byte) []byte {
+		enc, _ := rlp.EncodeToBytes(bytes.TrimLeft(common.Hash{b}.Bytes(), "\x00"))
+		return enc
+	}
+	if data, err := snap.Storage(hash(addr1[:]), hash(key1[:])); err != nil || !bytes.Equal(data, value(0x13)) {
+		t.Errorf("updated slot mismatch: have %x (%v), want %x", data, err, value(0x13))
+	}
+	if data, err := snap.Storage(hash(addr1[:]), hash(key2[:])); err != nil || !bytes.Equal(data, value(0x12)) {
+		t.Errorf("reverted reset wiped slot: have %x (%v), want %x", data, err, value(0x12))
+	}
+	if data, err := snap.AccountRLP(hash(addr2[:])); err != nil || data != nil {
+		t.Errorf("destructed account mismatch: have %x (%v), want nil", data, err)
+	}
+	if data, err := snap.Storage(hash(addr2[:]), hash(key1[:])); err != nil || data != nil {
+		t.Errorf("destructed slot mismatch: have %x (%v), want nil", data, err)
+	}
+	// States read through the snapshot and the trie must match
+	fromSnap, _ := NewWithSnapshot(root2, sdb, snaps)
+	fromTrie, _ := New(root2, sdb)
+	for _, addr := range []common.Address{addr1, addr2, addr3} {
+		if have, want := fromSnap.GetBalance(addr), fromTrie.GetBalance(addr); have.Cmp(want) != 0 {
+			t.Errorf("balance of %x mismatch: have %v, want %v", addr, have, want)
+		}
+		if have, want := fromSnap.Exist(addr), fromTrie.Exist(addr); have != want {
+			t.Errorf("existence of %x mismatch: have %v, want %v", addr, have, want)
+		}
+		for _, key := range []common.Hash{key1, key2} {
+			if have, want := fromSnap.GetState(addr, key), fromTrie.GetState(addr, key); have != want {
+				t.Errorf("slot %x of %x mismatch: have %x, want %x", key, addr, have, want)
+			}
+		}
+	}
+}
@@ -215,6 +215,20 @@ func (m *txSortedMap) Flatten() types.Transactions {
 	return txs
 }
 
+// ReplacementPrice returns the minimum gas price a transaction needs to replace
+// an already pooled one with the given gas price, bumped by priceBump percent.
+func ReplacementPrice(price *big.Int, priceBump uint64) *big.Int {
+	threshold := new(big.Int).Div(new(big.Int).Mul(price, big.NewInt(100+int64(priceBump))), big.NewInt(100))
+
+	// Have to ensure that the new gas price is higher than the old gas price as
+	// well as the percentage threshold to ensure that this is accurate for low
+	// (Wei-level) gas price replacements
+	if threshold.Cmp(price) <= 0 {
+		threshold.Add(price, common.Big1)
+	}
+	return threshold
+}
+
 // txList is a "list" of transactions belonging to an account, sorted by account
 // nonce. The same type can be used both for storing contiguous transactions for
 // the executable/pending queue; and for storing gapped transactions for the non-
@@ -222,6 +236,7 @@ func (m *txSortedMap) Flatten() types.Transactions {
 type txList struct {
 	strict bool         // Whether nonces are strictly continuous or not
 	txs    *txSortedMap // Heap indexed sorted hash map of the transactions
+	slots  int          // Number of data slots taken up by the transactions
 
 	costcap *big.Int // Price of the highest costing transaction (reset only if exceeds balance)
 	gascap  uint64   // Gas limit of the highest spending transaction (reset only if exceeds block limit)
@@ -251,17 +266,15 @@ func (l *txList) Overlaps(tx *types.Transaction) bool {
 func (l *txList) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Transaction) {
 	// If there's an older better transaction, abort
 	old := l.txs.Get(tx.Nonce())
-	if old != nil {
-		threshold := new(big.Int).Div(new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(priceBump))), big.NewInt(100))
-		// Have to ensure that the new gas price is higher than the old gas
-		// price as well as checking the percentage threshold to ensure that
-		// this is accurate for low (Wei-level) gas price replacements
-		if old.GasPrice().Cmp(tx.GasPrice()) >= 0 || threshold.Cmp(tx.GasPrice()) > 0 {
-			return false, nil
-		}
+	if old != nil && tx.GasPrice().Cmp(ReplacementPrice(old.GasPrice(), priceBump)) < 0 {
+		return false, nil
 	}
 	// Otherwise overwrite the old transaction with the current one
 	l.txs.Put(tx)
+	l.slots += numSlots(tx)
+	if old != nil {
+		l.slots -= numSlots(old)
+	}
 	if cost := tx.Cost(); l.costcap.Cmp(cost) < 0 {
 		l.costcap = cost
 	}
@@ -275,7 +288,7 @@ func (l *txList) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Tran
 // provided threshold. Every removed transaction is returned for any post-removal
 // maintenance.
 func (l *txList) Forward(threshold uint64) types.Transactions {
-	return l.txs.Forward(threshold)
+	return l.release(l.txs.Forward(threshold))
 }
 
 // Filter removes all transactions from the list with a cost or gas limit higher
@@ -308,15 +321,26 @@ func (l *txList) Filter(costLimit *big.Int, gasLimit uint64) (types.Transactions
 				lowest = nonce
 			}
 		}
-		invalids = l.txs.Filter(func(tx *types.Transaction) bool { return tx.Nonce() > lowest })
+		invalids = l.release(l.txs.Filter(func(tx *types.Transaction) bool { return tx.Nonce() > lowest }))
 	}
-	return removed, invalids
+	return l.release(removed), invalids
 }
 
-// Cap places a hard limit on the number of items, returning all transactions
-// exceeding that limit.
+// Cap places a hard limit on the number of data slots taken up by the list,
+// returning the highest nonce transactions exceeding that limit.
 func (l *txList) Cap(threshold int) types.Transactions {
-	return l.txs.Cap(threshold)
+	// Short circuit if the list fits into the allowance
+	if l.slots <= threshold {
+		return nil
+	}
+	// Otherwise count the transactions fitting and drop the rest
+	txs := l.txs.Flatten()
+
+	keep := len(txs)
+	for slots := l.slots; keep > 0 && slots > threshold; keep-- {
+		slots -= numSlots(txs[keep-1])
+	}
+	return l.release(l.txs.Cap(keep))
 }
 
 // Remove deletes a transaction from the maintained list, returning whether the
@@ -325,12 +349,16 @@ func (l *txList) Cap(threshold int) types.Transactions {
 func (l *txList) Remove(tx *types.Transaction) (bool, types.Transactions) {
 	// Remove the transaction from the set
 	nonce := tx.Nonce()
+
+	old := l.txs.Get(nonce)
 	if removed := l.txs.Remove(nonce); !removed {
 		return false, nil
 	}
+	l.slots -= numSlots(old)
+
 	// In strict mode, filter out non-executable transactions
 	if l.strict {
-		return true, l.txs.Filter(func(tx *types.Transaction) bool { return tx.Nonce() > nonce })
+		return true, l.release(l.txs.Filter(func(tx *types.Transaction) bool { return tx.Nonce() > nonce }))
 	}
 	return true, nil
 }
@@ -343,7 +371,16 @@ func (l *txList) Remove(tx *types.Transaction) (bool, types.Transactions) {
 // prevent getting into and invalid state. This is not something that should ever
 // happen but better to be self correcting than failing!
 func (l *txList) Ready(start uint64) types.Transactions {
-	return l.txs.Ready(start)
+	return l.release(l.txs.Ready(start))
+}
+
+// release deducts the data slots of transactions removed from the list, passing
+// them through for further processing.
+func (l *txList) release(txs types.Transactions) types.Transactions {
+	for _, tx := range txs {
+		l.slots -= numSlots(tx)
+	}
+	return txs
 }
 
 // Len returns the length of the transaction list.
@@ -351,6 +388,11 @@ func (l *txList) Len() int {
 	return l.txs.Len()
 }
 
+// Slots returns the number of data slots taken up by the transaction list.
+func (l *txList) Slots() int {
+	return l.slots
+}
+
 // Empty returns whether the list of transactions is empty or not.
 func (l *txList) Empty() bool {
 	return l.Len() == 0
@@ -378,7 +420,14 @@ func (h priceHeap) Less(i, j int) bool {
 	case 1:
 		return false
 	}
-	// If the prices match, stabilize via nonces (high nonce is worse)
+	// If the prices match, evict the larger one first to free the most memory
+	switch si, sj := h[i].Size(), h[j].Size(); {
+	case si > sj:
+		return true
+	case si < sj:
+		return false
+	}
+	// If the sizes match too, stabilize via nonces (high nonce is worse)
 	return h[i].Nonce() > h[j].Nonce()
 }
 
@@ -492,13 +541,16 @@ func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) boo
 	return cheapest.GasPrice().Cmp(tx.GasPrice()) >= 0
 }
 
-// Discard finds a number of most underpriced transactions, removes them from the
-// priced list and returns them for further removal from the entire pool.
-func (l *txPricedList) Discard(count int, local *accountSet) types.Transactions {
-	drop := make(types.Transactions, 0, count) // Remote underpriced transactions to drop
-	save := make(types.Transactions, 0, 64)    // Local underpriced transactions to keep
+// Discard finds the most underpriced transactions freeing up at least the given
+// number of data slots and bytes, removes them from the priced list and returns
+// them for further removal from the entire pool. If not enough space can be freed
+// by remote transactions, nothing is discarded and false is returned.
+func (l *txPricedList) Discard(slots int, size uint64, local *accountSet) (types.Transactions, bool) {
+	drop := make(types.Transactions, 0, 128) // Remote underpriced transactions to drop
+	save := make(types.Transactions, 0, 64)  // Local underpriced transactions to keep
 
-	for len(*l.items) > 0 && count > 0 {
+	var freed uint64
+	for len(*l.items) > 0 && (slots > 0 || freed < size) {
 		// Discard stale transactions if found during cleanup
 		tx := heap.Pop(l.items).(*types.Transaction)
 		if l.all.Get(tx.Hash()) == nil {
@@ -510,11 +562,19 @@ func (l *txPricedList) Discard(count int, local *accountSet) types.Transactions
 			save = append(save, tx)
 		} else {
 			drop = append(drop, tx)
-			count--
+			slots -= numSlots(tx)
+			freed += uint64(tx.Size())
 		}
 	}
 	for _, tx := range save {
 		heap.Push(l.items, tx)
 	}
-	return drop
+	// If the remote transactions don't free up enough space, keep them all
+	if slots > 0 || freed < size {
+		for _, tx := range drop {
+			heap.Push(l.items, tx)
+		}
+		return nil, false
+	}
+	return drop, true
 }
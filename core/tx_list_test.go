@@ -17,6 +17,7 @@
 package core
 
 import (
+	"math/big"
 	"math/rand"
 	"testing"
 
@@ -49,3 +50,24 @@ func TestStrictTxListAdd(t *testing.T) {
 		}
 	}
 }
+
+// Tests that replacement prices apply the price bump, but always exceed the
+// replaced price.
+func TestReplacementPrice(t *testing.T) {
+	tests := []struct {
+		price int64
+		bump  uint64
+		want  int64
+	}{
+		{1000, 10, 1100},
+		{1005, 10, 1105},
+		{1, 10, 2},
+		{0, 10, 1},
+		{1000, 0, 1001},
+	}
+	for i, tt := range tests {
+		if have := ReplacementPrice(big.NewInt(tt.price), tt.bump); have.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("test %d: replacement price mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/consensus"
 	"github.com/relianz2019/relianz/core/state"
 	"github.com/relianz2019/relianz/core/types"
 	"github.com/relianz2019/relianz/event"
@@ -38,6 +39,14 @@ import (
 const (
 	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
 	chainHeadChanSize = 10
+
+	// txSlotSize is the size of a data slot transactions are accounted in. A
+	// transaction takes up as many slots as needed to fit its encoded size, so
+	// large payloads count against the pool limits accordingly.
+	txSlotSize = 4 * 1024
+
+	// txMaxSize is the maximum size a single transaction can have.
+	txMaxSize = 8 * txSlotSize
 )
 
 var (
@@ -76,6 +85,19 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrTxPoolOverflow is returned if the transaction pool is full and can't
+	// make room for a new transaction by evicting cheaper remote ones.
+	ErrTxPoolOverflow = errors.New("txpool is full")
+)
+
+// Reasons reported for transactions leaving the pool without being included.
+const (
+	TxDropReplaced     = "replaced"     // Superseded by a transaction with the same nonce
+	TxDropUnderpriced  = "underpriced"  // Evicted in favour of better paying transactions
+	TxDropRateLimited  = "ratelimited"  // Exceeded the account or global pool limits
+	TxDropUnexecutable = "unexecutable" // No longer payable by the sender or over the block gas limit
+	TxDropExpired      = "expired"      // Queued for longer than the configured lifetime
 )
 
 var (
@@ -99,6 +121,7 @@ var (
 	// General tx metrics
 	invalidTxCounter     = metrics.NewRegisteredCounter("txpool/invalid", nil)
 	underpricedTxCounter = metrics.NewRegisteredCounter("txpool/underpriced", nil)
+	overflowTxCounter    = metrics.NewRegisteredCounter("txpool/overflow", nil)
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -126,6 +149,7 @@ type TxPoolConfig struct {
 	NoLocals  bool          // Whether local transaction handling should be disabled
 	Journal   string        // Journal of local transactions to survive node restarts
 	Rejournal time.Duration // Time interval to regenerate the local transaction journal
+	Snapshot  string        // Snapshot of all transactions to survive node restarts (empty = disabled)
 
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
@@ -134,8 +158,11 @@ type TxPoolConfig struct {
 	GlobalSlots  uint64 // Maximum number of executable transaction slots for all accounts
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
+	GlobalBytes  uint64 // Maximum number of bytes taken up by all transactions in the pool
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	Validators []TxValidatorConfig // Admission hooks consulted in order for every new transaction
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -151,6 +178,7 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalSlots:  4096,
 	AccountQueue: 64,
 	GlobalQueue:  1024,
+	GlobalBytes:  32 * 1024 * 1024,
 
 	Lifetime: 3 * time.Hour,
 }
@@ -171,6 +199,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
 		conf.PriceBump = DefaultTxPoolConfig.PriceBump
 	}
+	if conf.GlobalBytes < txMaxSize {
+		log.Warn("Sanitizing invalid txpool global bytes", "provided", conf.GlobalBytes, "updated", DefaultTxPoolConfig.GlobalBytes)
+		conf.GlobalBytes = DefaultTxPoolConfig.GlobalBytes
+	}
 	return conf
 }
 
@@ -187,6 +219,8 @@ type TxPool struct {
 	chain        blockChain
 	gasPrice     *big.Int
 	txFeed       event.Feed
+	rejectFeed   event.Feed
+	dropFeed     event.Feed
 	scope        event.SubscriptionScope
 	chainHeadCh  chan ChainHeadEvent
 	chainHeadSub event.Subscription
@@ -199,6 +233,9 @@ type TxPool struct {
 
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
+	snap    *txSnapshot // Snapshot of all transactions to back up to disk on shutdown
+
+	validators []TxValidator // Admission hooks of the node, consulted in order
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
@@ -206,6 +243,9 @@ type TxPool struct {
 	all     *txLookup                    // All transactions to allow lookups
 	priced  *txPricedList                // All transactions sorted by price
 
+	rejections []*TxRejection    // Rejection events collected under the lock, sent once it's released
+	drops      []DroppedTxsEvent // Drop events collected under the lock, sent once it's released
+
 	wg sync.WaitGroup // for shutdown sync
 
 	homestead bool
@@ -232,6 +272,20 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 	}
 	pool.locals = newAccountSet(pool.signer)
 	pool.priced = newTxPricedList(pool.all)
+
+	// Create the admission hooks, handing them the consensus engine if available
+	ctx := new(TxValidatorContext)
+	if reader, ok := chain.(consensus.ChainReader); ok {
+		ctx.Chain = reader
+	}
+	if engineChain, ok := chain.(ChainContext); ok {
+		ctx.Engine = engineChain.Engine()
+	}
+	validators, err := NewTxValidators(config.Validators, ctx)
+	if err != nil {
+		log.Crit("Failed to create transaction validators", "err", err)
+	}
+	pool.validators = validators
 	pool.reset(nil, chain.CurrentBlock().Header())
 
 	// If local transactions and journaling is enabled, load from disk
@@ -245,6 +299,11 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If the pool snapshot is enabled, reload and revalidate its contents
+	if config.Snapshot != "" {
+		pool.snap = newTxSnapshot(config.Snapshot)
+		pool.loadSnapshot()
+	}
 	// Subscribe events from blockchain
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 
@@ -289,7 +348,7 @@ func (pool *TxPool) loop() {
 				pool.reset(head.Header(), ev.Block.Header())
 				head = ev.Block
 
-				pool.mu.Unlock()
+				pool.unlock()
 			}
 		// Be unsubscribed due to system stopped
 		case <-pool.chainHeadSub.Err():
@@ -317,12 +376,14 @@ func (pool *TxPool) loop() {
 				}
 				// Any non-locals old enough should be removed
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
-					for _, tx := range pool.queue[addr].Flatten() {
+					txs := pool.queue[addr].Flatten()
+					for _, tx := range txs {
 						pool.removeTx(tx.Hash(), true)
 					}
+					pool.dropped(TxDropExpired, nil, txs...)
 				}
 			}
-			pool.mu.Unlock()
+			pool.unlock()
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -331,7 +392,7 @@ func (pool *TxPool) loop() {
 				if err := pool.journal.rotate(pool.local()); err != nil {
 					log.Warn("Failed to rotate local tx journal", "err", err)
 				}
-				pool.mu.Unlock()
+				pool.unlock()
 			}
 		}
 	}
@@ -341,7 +402,7 @@ func (pool *TxPool) loop() {
 // manner. This method is only ever used in the tester!
 func (pool *TxPool) lockedReset(oldHead, newHead *types.Header) {
 	pool.mu.Lock()
-	defer pool.mu.Unlock()
+	defer pool.unlock()
 
 	pool.reset(oldHead, newHead)
 }
@@ -438,6 +499,11 @@ func (pool *TxPool) Stop() {
 	pool.chainHeadSub.Unsubscribe()
 	pool.wg.Wait()
 
+	if pool.snap != nil {
+		if _, err := pool.SaveSnapshot(); err != nil {
+			log.Warn("Failed to save transaction pool snapshot", "err", err)
+		}
+	}
 	if pool.journal != nil {
 		pool.journal.close()
 	}
@@ -450,6 +516,18 @@ func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- NewTxsEvent) event.Subscripti
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeTxRejectedEvent registers a subscription of TxRejectedEvent and
+// starts sending event to the given channel.
+func (pool *TxPool) SubscribeTxRejectedEvent(ch chan<- TxRejectedEvent) event.Subscription {
+	return pool.scope.Track(pool.rejectFeed.Subscribe(ch))
+}
+
+// SubscribeDroppedTxsEvent registers a subscription of DroppedTxsEvent and
+// starts sending event to the given channel.
+func (pool *TxPool) SubscribeDroppedTxsEvent(ch chan<- DroppedTxsEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -462,12 +540,14 @@ func (pool *TxPool) GasPrice() *big.Int {
 // new transaction, and drops all transactions below this threshold.
 func (pool *TxPool) SetGasPrice(price *big.Int) {
 	pool.mu.Lock()
-	defer pool.mu.Unlock()
+	defer pool.unlock()
 
 	pool.gasPrice = price
-	for _, tx := range pool.priced.Cap(price, pool.locals) {
+	drops := pool.priced.Cap(price, pool.locals)
+	for _, tx := range drops {
 		pool.removeTx(tx.Hash(), false)
 	}
+	pool.dropped(TxDropUnderpriced, nil, drops...)
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
@@ -488,6 +568,17 @@ func (pool *TxPool) Stats() (int, int) {
 	return pool.stats()
 }
 
+// PriceBump returns the minimum price bump percentage a transaction needs to
+// replace an already pooled one.
+func (pool *TxPool) PriceBump() uint64 {
+	return pool.config.PriceBump
+}
+
+// Bytes retrieves the total encoded size of all the transactions in the pool.
+func (pool *TxPool) Bytes() uint64 {
+	return pool.all.Bytes()
+}
+
 // stats retrieves the current pool stats, namely the number of pending and the
 // number of queued (non-executable) transactions.
 func (pool *TxPool) stats() (int, int) {
@@ -506,7 +597,7 @@ func (pool *TxPool) stats() (int, int) {
 // pending as well as queued transactions, grouped by account and sorted by nonce.
 func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
 	pool.mu.Lock()
-	defer pool.mu.Unlock()
+	defer pool.unlock()
 
 	pending := make(map[common.Address]types.Transactions)
 	for addr, list := range pool.pending {
@@ -524,7 +615,7 @@ func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common
 // freely modified by calling code.
 func (pool *TxPool) Pending() (map[common.Address]types.Transactions, error) {
 	pool.mu.Lock()
-	defer pool.mu.Unlock()
+	defer pool.unlock()
 
 	pending := make(map[common.Address]types.Transactions)
 	for addr, list := range pool.pending {
@@ -553,7 +644,7 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
-	if tx.Size() > 32*1024 {
+	if tx.Size() > txMaxSize {
 		return ErrOversizedData
 	}
 	// Transactions can't be negative. This may never happen using RLP decoded
@@ -591,9 +682,70 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.Gas() < intrGas {
 		return ErrIntrinsicGas
 	}
+	// Consult the admission hooks of the node in their configured order
+	for _, validator := range pool.validators {
+		if err := validator.ValidateTx(tx, from, pool.pendingState, local); err != nil {
+			return pool.reject(validator, tx, from, local, err)
+		}
+	}
 	return nil
 }
 
+// reject records the refusal of a transaction by an admission hook, returning
+// its structured reason. The event is sent once the pool lock is released.
+//
+// The caller must hold pool.mu.
+func (pool *TxPool) reject(validator TxValidator, tx *types.Transaction, from common.Address, local bool, err error) *TxRejection {
+	rejection := &TxRejection{Reason: RejectPolicy, Message: err.Error()}
+	if r, ok := err.(*TxRejection); ok {
+		rejection.Reason, rejection.Message = r.Reason, r.Message
+	}
+	rejection.Validator, rejection.Hash, rejection.From, rejection.Local = validator.Name(), tx.Hash(), from, local
+
+	metrics.GetOrRegisterCounter("txpool/rejected/"+rejection.Validator, nil).Inc(1)
+	pool.rejections = append(pool.rejections, rejection)
+
+	return rejection
+}
+
+// accepted notifies the admission hooks tracking accepted transactions of one
+// entering the pool.
+func (pool *TxPool) accepted(tx *types.Transaction, from common.Address, local bool) {
+	local = local || pool.locals.contains(from)
+	for _, validator := range pool.validators {
+		if acceptor, ok := validator.(TxAcceptor); ok {
+			acceptor.AcceptTx(tx, from, local)
+		}
+	}
+}
+
+// dropped notifies subsystems of transactions leaving the pool without being
+// included in a block, along with the transaction replacing them, if any. The
+// event is sent once the pool lock is released, so slow subscribers cannot stall
+// the pool.
+//
+// The caller must hold pool.mu.
+func (pool *TxPool) dropped(reason string, replacement *types.Transaction, txs ...*types.Transaction) {
+	if len(txs) > 0 {
+		pool.drops = append(pool.drops, DroppedTxsEvent{Txs: txs, Reason: reason, Replacement: replacement})
+	}
+}
+
+// unlock releases the pool lock and sends the events collected while holding it,
+// in the order they happened.
+func (pool *TxPool) unlock() {
+	rejections, drops := pool.rejections, pool.drops
+	pool.rejections, pool.drops = nil, nil
+	pool.mu.Unlock()
+
+	for _, rejection := range rejections {
+		pool.rejectFeed.Send(TxRejectedEvent{rejection})
+	}
+	for _, ev := range drops {
+		pool.dropFeed.Send(ev)
+	}
+}
+
 // add validates a transaction and inserts it into the non-executable queue for
 // later pending promotion and execution. If the transaction is a replacement for
 // an already pending or queued one, it overwrites the previous and returns this
@@ -616,20 +768,35 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		return false, err
 	}
 	// If the transaction pool is full, discard underpriced transactions
-	if uint64(pool.all.Count()) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
+	var (
+		slots = pool.all.Slots() + numSlots(tx) - int(pool.config.GlobalSlots+pool.config.GlobalQueue)
+		size  = pool.all.Bytes() + uint64(tx.Size())
+	)
+	if slots > 0 || size > pool.config.GlobalBytes {
 		// If the new transaction is underpriced, don't accept it
 		if !local && pool.priced.Underpriced(tx, pool.locals) {
 			log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
 			underpricedTxCounter.Inc(1)
 			return false, ErrUnderpriced
 		}
-		// New transaction is better than our worse ones, make room for it
-		drop := pool.priced.Discard(pool.all.Count()-int(pool.config.GlobalSlots+pool.config.GlobalQueue-1), pool.locals)
+		// New transaction is better than our worse ones, make room for it. Local
+		// transactions may overflow the slot limits, but never the byte limit.
+		var overflow uint64
+		if size > pool.config.GlobalBytes {
+			overflow = size - pool.config.GlobalBytes
+		}
+		drop, success := pool.priced.Discard(slots, overflow, pool.locals)
+		if !success && (!local || overflow > 0) {
+			log.Trace("Discarding overflown transaction", "hash", hash, "size", tx.Size())
+			overflowTxCounter.Inc(1)
+			return false, ErrTxPoolOverflow
+		}
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
 			underpricedTxCounter.Inc(1)
 			pool.removeTx(tx.Hash(), false)
 		}
+		pool.dropped(TxDropUnderpriced, nil, drop...)
 	}
 	// If the transaction is replacing an already pending one, do directly
 	from, _ := types.Sender(pool.signer, tx) // already validated
@@ -645,10 +812,13 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed()
 			pendingReplaceCounter.Inc(1)
+
+			pool.dropped(TxDropReplaced, tx, old)
 		}
 		pool.all.Add(tx)
 		pool.priced.Put(tx)
 		pool.journalTx(from, tx)
+		pool.accepted(tx, from, local)
 
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
 
@@ -667,6 +837,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		pool.locals.add(from)
 	}
 	pool.journalTx(from, tx)
+	pool.accepted(tx, from, local)
 
 	log.Trace("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To())
 	return replace, nil
@@ -692,6 +863,8 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed()
 		queuedReplaceCounter.Inc(1)
+
+		pool.dropped(TxDropReplaced, tx, old)
 	}
 	if pool.all.Get(hash) == nil {
 		pool.all.Add(tx)
@@ -700,6 +873,83 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 	return old != nil, nil
 }
 
+// SaveSnapshot writes all transactions of the pool into the snapshot on disk,
+// returning the number of transactions saved.
+func (pool *TxPool) SaveSnapshot() (int, error) {
+	if pool.snap == nil {
+		return 0, errNoSnapshot
+	}
+	// Gather the transactions under the lock, but write them to disk without it
+	pool.mu.Lock()
+	var entries []*txSnapshotEntry
+	for _, lists := range []map[common.Address]*txList{pool.pending, pool.queue} {
+		for addr, list := range lists {
+			beat := pool.beats[addr]
+			if beat.IsZero() {
+				beat = time.Now()
+			}
+			local := pool.locals.contains(addr)
+			for _, tx := range list.Flatten() {
+				entries = append(entries, &txSnapshotEntry{Tx: tx, Time: uint64(beat.Unix()), Local: local})
+			}
+		}
+	}
+	pool.unlock()
+
+	if err := pool.snap.save(entries); err != nil {
+		return 0, err
+	}
+	log.Info("Saved transaction pool snapshot", "transactions", len(entries))
+	return len(entries), nil
+}
+
+// loadSnapshot reinjects the transactions of the snapshot on disk into the pool,
+// revalidating them against the current state. The heartbeats of the accounts
+// are restored, so remote transactions keep expiring by their original age.
+func (pool *TxPool) loadSnapshot() {
+	entries, expired, err := pool.snap.load(pool.config.Lifetime)
+	if err != nil {
+		log.Warn("Failed to load transaction pool snapshot", "err", err)
+		return
+	}
+	var (
+		locals, remotes types.Transactions
+		beats           = make(map[common.Address]time.Time)
+	)
+	for _, entry := range entries {
+		// Skip local transactions already reloaded from the journal
+		if pool.all.Get(entry.Tx.Hash()) != nil {
+			continue
+		}
+		if entry.Local {
+			locals = append(locals, entry.Tx)
+			continue
+		}
+		remotes = append(remotes, entry.Tx)
+		if from, err := types.Sender(pool.signer, entry.Tx); err == nil {
+			beats[from] = time.Unix(int64(entry.Time), 0)
+		}
+	}
+	dropped := 0
+	for _, errs := range [][]error{pool.AddLocals(locals), pool.AddRemotes(remotes)} {
+		for _, err := range errs {
+			if err != nil {
+				log.Debug("Failed to add snapshot transaction", "err", err)
+				dropped++
+			}
+		}
+	}
+	pool.mu.Lock()
+	for addr, beat := range beats {
+		if _, ok := pool.beats[addr]; ok {
+			pool.beats[addr] = beat
+		}
+	}
+	pool.unlock()
+
+	log.Info("Loaded transaction pool snapshot", "transactions", len(entries), "expired", expired, "dropped", dropped)
+}
+
 // journalTx adds the specified transaction to the local disk journal if it is
 // deemed to have been sent from a local account.
 func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
@@ -730,6 +980,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingDiscardCounter.Inc(1)
+		pool.dropped(TxDropReplaced, list.txs.Get(tx.Nonce()), tx)
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -738,6 +989,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingReplaceCounter.Inc(1)
+		pool.dropped(TxDropReplaced, tx, old)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all.Get(hash) == nil {
@@ -782,7 +1034,7 @@ func (pool *TxPool) AddRemotes(txs []*types.Transaction) []error {
 // addTx enqueues a single transaction into the pool if it is valid.
 func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 	pool.mu.Lock()
-	defer pool.mu.Unlock()
+	defer pool.unlock()
 
 	// Try to inject the transaction and update any state
 	replace, err := pool.add(tx, local)
@@ -800,7 +1052,7 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 // addTxs attempts to queue a batch of transactions if they are valid.
 func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) []error {
 	pool.mu.Lock()
-	defer pool.mu.Unlock()
+	defer pool.unlock()
 
 	return pool.addTxsLocked(txs, local)
 }
@@ -937,6 +1189,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			pool.priced.Removed()
 			queuedNofundsCounter.Inc(1)
 		}
+		pool.dropped(TxDropUnexecutable, nil, drops...)
 		// Gather all executable transactions and promote them
 		for _, tx := range list.Ready(pool.pendingState.GetNonce(addr)) {
 			hash := tx.Hash()
@@ -947,13 +1200,15 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		}
 		// Drop all transactions over the allowed limit
 		if !pool.locals.contains(addr) {
-			for _, tx := range list.Cap(int(pool.config.AccountQueue)) {
+			caps := list.Cap(int(pool.config.AccountQueue))
+			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
 				pool.priced.Removed()
 				queuedRateLimitCounter.Inc(1)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
+			pool.dropped(TxDropRateLimited, nil, caps...)
 		}
 		// Delete the entire queue entry if it became empty.
 		if list.Empty() {
@@ -967,7 +1222,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 	// If the pending limit is overflown, start equalizing allowances
 	pending := uint64(0)
 	for _, list := range pool.pending {
-		pending += uint64(list.Len())
+		pending += uint64(list.Slots())
 	}
 	if pending > pool.config.GlobalSlots {
 		pendingBeforeCap := pending
@@ -975,8 +1230,8 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		spammers := prque.New()
 		for addr, list := range pool.pending {
 			// Only evict transactions from high rollers
-			if !pool.locals.contains(addr) && uint64(list.Len()) > pool.config.AccountSlots {
-				spammers.Push(addr, float32(list.Len()))
+			if !pool.locals.contains(addr) && uint64(list.Slots()) > pool.config.AccountSlots {
+				spammers.Push(addr, float32(list.Slots()))
 			}
 		}
 		// Gradually drop transactions from offenders
@@ -989,13 +1244,14 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Equalize balances until all the same or below threshold
 			if len(offenders) > 1 {
 				// Calculate the equalization threshold for all current offenders
-				threshold := pool.pending[offender.(common.Address)].Len()
+				threshold := pool.pending[offender.(common.Address)].Slots()
 
 				// Iteratively reduce all offenders until below limit or threshold reached
-				for pending > pool.config.GlobalSlots && pool.pending[offenders[len(offenders)-2]].Len() > threshold {
+				for pending > pool.config.GlobalSlots && pool.pending[offenders[len(offenders)-2]].Slots() > threshold {
 					for i := 0; i < len(offenders)-1; i++ {
 						list := pool.pending[offenders[i]]
-						for _, tx := range list.Cap(list.Len() - 1) {
+						caps := list.Cap(list.Slots() - 1)
+						for _, tx := range caps {
 							// Drop the transaction from the global pools too
 							hash := tx.Hash()
 							pool.all.Remove(hash)
@@ -1006,18 +1262,20 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 								pool.pendingState.SetNonce(offenders[i], nonce)
 							}
 							log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
+							pending -= uint64(numSlots(tx))
 						}
-						pending--
+						pool.dropped(TxDropRateLimited, nil, caps...)
 					}
 				}
 			}
 		}
 		// If still above threshold, reduce to limit or min allowance
 		if pending > pool.config.GlobalSlots && len(offenders) > 0 {
-			for pending > pool.config.GlobalSlots && uint64(pool.pending[offenders[len(offenders)-1]].Len()) > pool.config.AccountSlots {
+			for pending > pool.config.GlobalSlots && uint64(pool.pending[offenders[len(offenders)-1]].Slots()) > pool.config.AccountSlots {
 				for _, addr := range offenders {
 					list := pool.pending[addr]
-					for _, tx := range list.Cap(list.Len() - 1) {
+					caps := list.Cap(list.Slots() - 1)
+					for _, tx := range caps {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						pool.all.Remove(hash)
@@ -1028,8 +1286,9 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 							pool.pendingState.SetNonce(addr, nonce)
 						}
 						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
+						pending -= uint64(numSlots(tx))
 					}
-					pending--
+					pool.dropped(TxDropRateLimited, nil, caps...)
 				}
 			}
 		}
@@ -1038,7 +1297,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 	// If we've queued more transactions than the hard limit, drop oldest ones
 	queued := uint64(0)
 	for _, list := range pool.queue {
-		queued += uint64(list.Len())
+		queued += uint64(list.Slots())
 	}
 	if queued > pool.config.GlobalQueue {
 		// Sort all accounts with queued transactions by heartbeat
@@ -1058,20 +1317,27 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			addresses = addresses[:len(addresses)-1]
 
 			// Drop all transactions if they are less than the overflow
-			if size := uint64(list.Len()); size <= drop {
-				for _, tx := range list.Flatten() {
+			if slots := uint64(list.Slots()); slots <= drop {
+				txs := list.Flatten()
+				for _, tx := range txs {
 					pool.removeTx(tx.Hash(), true)
 				}
-				drop -= size
-				queuedRateLimitCounter.Inc(int64(size))
+				drop -= slots
+				queuedRateLimitCounter.Inc(int64(len(txs)))
+				pool.dropped(TxDropRateLimited, nil, txs...)
 				continue
 			}
 			// Otherwise drop only last few transactions
 			txs := list.Flatten()
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 				pool.removeTx(txs[i].Hash(), true)
-				drop--
+				if slots := uint64(numSlots(txs[i])); slots < drop {
+					drop -= slots
+				} else {
+					drop = 0
+				}
 				queuedRateLimitCounter.Inc(1)
+				pool.dropped(TxDropRateLimited, nil, txs[i])
 			}
 		}
 	}
@@ -1101,6 +1367,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			pool.priced.Removed()
 			pendingNofundsCounter.Inc(1)
 		}
+		pool.dropped(TxDropUnexecutable, nil, drops...)
 		for _, tx := range invalids {
 			hash := tx.Hash()
 			log.Trace("Demoting pending transaction", "hash", hash)
@@ -1180,8 +1447,10 @@ func (as *accountSet) add(addr common.Address) {
 // peeking into the pool in TxPool.Get without having to acquire the widely scoped
 // TxPool.mu mutex.
 type txLookup struct {
-	all  map[common.Hash]*types.Transaction
-	lock sync.RWMutex
+	all   map[common.Hash]*types.Transaction
+	slots int    // Number of data slots taken up by the transactions
+	bytes uint64 // Total encoded size of the transactions
+	lock  sync.RWMutex
 }
 
 // newTxLookup returns a new txLookup structure.
@@ -1219,11 +1488,30 @@ func (t *txLookup) Count() int {
 	return len(t.all)
 }
 
+// Slots returns the current number of data slots taken up by the lookup.
+func (t *txLookup) Slots() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.slots
+}
+
+// Bytes returns the current total encoded size of the transactions in the lookup.
+func (t *txLookup) Bytes() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.bytes
+}
+
 // Add adds a transaction to the lookup.
 func (t *txLookup) Add(tx *types.Transaction) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	t.slots += numSlots(tx)
+	t.bytes += uint64(tx.Size())
+
 	t.all[tx.Hash()] = tx
 }
 
@@ -1232,5 +1520,17 @@ func (t *txLookup) Remove(hash common.Hash) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	tx, ok := t.all[hash]
+	if !ok {
+		return
+	}
+	t.slots -= numSlots(tx)
+	t.bytes -= uint64(tx.Size())
+
 	delete(t.all, hash)
 }
+
+// numSlots calculates the number of data slots needed for a single transaction.
+func numSlots(tx *types.Transaction) int {
+	return int((uint64(tx.Size()) + txSlotSize - 1) / txSlotSize)
+}
@@ -77,6 +77,11 @@ func pricedTransaction(nonce uint64, gaslimit uint64, gasprice *big.Int, key *ec
 	return tx
 }
 
+func pricedDataTransaction(nonce uint64, gaslimit uint64, gasprice *big.Int, key *ecdsa.PrivateKey, bytes uint64) *types.Transaction {
+	tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(100), gaslimit, gasprice, make([]byte, bytes)), types.HomesteadSigner{}, key)
+	return tx
+}
+
 func setupTxPool() (*TxPool, *ecdsa.PrivateKey) {
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
 	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
@@ -100,6 +105,17 @@ func validateTxPoolInternals(pool *TxPool) error {
 	if priced := pool.priced.items.Len() - pool.priced.stales; priced != pending+queued {
 		return fmt.Errorf("total priced transaction count %d != %d pending + %d queued", priced, pending, queued)
 	}
+	// Ensure the data slots of the accounts add up to the total
+	slots := 0
+	for _, list := range pool.pending {
+		slots += list.Slots()
+	}
+	for _, list := range pool.queue {
+		slots += list.Slots()
+	}
+	if total := pool.all.Slots(); total != slots {
+		return fmt.Errorf("total slot count %d != %d account slots", total, slots)
+	}
 	// Ensure the next nonce to assign is the correct one
 	for addr, txs := range pool.pending {
 		// Find the last transaction
@@ -1421,6 +1437,85 @@ func TestTransactionPoolUnderpricing(t *testing.T) {
 	}
 }
 
+// Tests that transactions take up data slots according to their encoded size.
+func TestTransactionSlotCount(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+
+	if slots := numSlots(pricedTransaction(0, 0, big.NewInt(1), key)); slots != 1 {
+		t.Errorf("small transaction slot count mismatch: have %d, want %d", slots, 1)
+	}
+	if slots := numSlots(pricedDataTransaction(0, 0, big.NewInt(1), key, txSlotSize)); slots != 2 {
+		t.Errorf("slot sized transaction slot count mismatch: have %d, want %d", slots, 2)
+	}
+	if slots := numSlots(pricedDataTransaction(0, 0, big.NewInt(1), key, txMaxSize-1024)); slots != 8 {
+		t.Errorf("large transaction slot count mismatch: have %d, want %d", slots, 8)
+	}
+}
+
+// Tests that the pool never takes up more bytes than permitted, evicting large
+// cheap remote transactions first, and refusing even local ones if no remotes
+// are left to make room.
+func TestTransactionPoolByteLimiting(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the byte limitation with
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.GlobalBytes = 2 * txMaxSize
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	// Create a number of test accounts and fund them
+	keys := make([]*ecdsa.PrivateKey, 4)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(10000000))
+	}
+	// Fill the pool with a few large and a few small cheap transactions
+	for i := uint64(0); i < 3; i++ {
+		if err := pool.AddRemote(pricedDataTransaction(i, 200000, big.NewInt(1), keys[0], 20*1024)); err != nil {
+			t.Fatalf("failed to add large transaction %d: %v", i, err)
+		}
+	}
+	for i := uint64(0); i < 4; i++ {
+		if err := pool.AddRemote(pricedTransaction(i, 100000, big.NewInt(1), keys[1])); err != nil {
+			t.Fatalf("failed to add small transaction %d: %v", i, err)
+		}
+	}
+	// Ensure that a better priced transaction evicts a large one instead of many small ones
+	if err := pool.AddRemote(pricedDataTransaction(0, 200000, big.NewInt(2), keys[2], 10*1024)); err != nil {
+		t.Fatalf("failed to add well priced transaction: %v", err)
+	}
+	if pool.all.Get(pricedDataTransaction(2, 200000, big.NewInt(1), keys[0], 20*1024).Hash()) != nil {
+		t.Errorf("large cheap transaction not evicted")
+	}
+	if pending := pool.pending[crypto.PubkeyToAddress(keys[1].PublicKey)]; pending == nil || pending.Len() != 4 {
+		t.Errorf("small cheap transactions evicted")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Ensure that local transactions may push out remotes, but never overflow the pool
+	var err error
+	for i := uint64(0); err == nil && i < 8; i++ {
+		err = pool.AddLocal(pricedDataTransaction(i, 200000, big.NewInt(1), keys[3], 20*1024))
+		if size := pool.Bytes(); size > config.GlobalBytes {
+			t.Fatalf("pool size limit exceeded: have %d, want <= %d", size, config.GlobalBytes)
+		}
+	}
+	if err != ErrTxPoolOverflow {
+		t.Errorf("overflowing local transaction error mismatch: have %v, want %v", err, ErrTxPoolOverflow)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that more expensive transactions push out cheap ones from the pool, but
 // without producing instability by creating gaps that start jumping transactions
 // back and forth between queued/pending.
@@ -1567,6 +1662,110 @@ func TestTransactionReplacement(t *testing.T) {
 	}
 }
 
+// Tests that transactions leaving the pool without being included announce the
+// reason of their removal, along with their replacement if any.
+func TestTransactionDropEvents(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	events := make(chan DroppedTxsEvent, 32)
+	sub := pool.SubscribeDroppedTxsEvent(events)
+	defer sub.Unsubscribe()
+
+	// Replace a pending and a queued transaction, both must be announced
+	pending, queued := pricedTransaction(0, 100000, big.NewInt(1), key), pricedTransaction(2, 100000, big.NewInt(1), key)
+	pendingBump, queuedBump := pricedTransaction(0, 100000, big.NewInt(2), key), pricedTransaction(2, 100000, big.NewInt(2), key)
+
+	for _, tx := range []*types.Transaction{pending, queued, pendingBump, queuedBump} {
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	replaced := make(map[common.Hash]common.Hash)
+	for i := 0; i < 2; i++ {
+		select {
+		case ev := <-events:
+			if ev.Reason != TxDropReplaced || len(ev.Txs) != 1 || ev.Replacement == nil {
+				t.Fatalf("replacement event mismatch: have %s, %d txs, replacement %v", ev.Reason, len(ev.Txs), ev.Replacement)
+			}
+			replaced[ev.Txs[0].Hash()] = ev.Replacement.Hash()
+		case <-time.After(time.Second):
+			t.Fatalf("replacement event %d missing", i)
+		}
+	}
+	if replaced[pending.Hash()] != pendingBump.Hash() || replaced[queued.Hash()] != queuedBump.Hash() {
+		t.Fatalf("replaced transactions mismatch: have %v", replaced)
+	}
+	// Raise the minimum price, which must drop both remaining transactions
+	pool.SetGasPrice(big.NewInt(3))
+
+	select {
+	case ev := <-events:
+		if ev.Reason != TxDropUnderpriced || len(ev.Txs) != 2 || ev.Replacement != nil {
+			t.Fatalf("underpriced event mismatch: have %s, %d txs, replacement %v", ev.Reason, len(ev.Txs), ev.Replacement)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("underpriced event missing")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that drop events are sent outside the pool lock, so a subscriber not
+// consuming them does not stall the pool.
+func TestTransactionDropEventsUnlocked(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	events := make(chan DroppedTxsEvent)
+	sub := pool.SubscribeDroppedTxsEvent(events)
+	defer sub.Unsubscribe()
+
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), key)); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	// Replace the transaction while nobody consumes the drop event
+	errc := make(chan error, 1)
+	go func() {
+		errc <- pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(2), key))
+	}()
+	time.Sleep(100 * time.Millisecond) // Wait for the replacement to block on the event
+
+	stats := make(chan int, 1)
+	go func() {
+		pending, _ := pool.Stats()
+		stats <- pending
+	}()
+	select {
+	case pending := <-stats:
+		if pending != 1 {
+			t.Errorf("pending transaction count mismatch: have %d, want %d", pending, 1)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("pool stalled by drop event subscriber")
+	}
+	select {
+	case ev := <-events:
+		if ev.Reason != TxDropReplaced {
+			t.Errorf("drop reason mismatch: have %s, want %s", ev.Reason, TxDropReplaced)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("drop event missing")
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+}
+
 // Tests that local transactions are journaled to disk, but remote transactions
 // get discarded between restarts.
 func TestTransactionJournaling(t *testing.T)         { testTransactionJournaling(t, false) }
@@ -0,0 +1,187 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/log"
+	"github.com/relianz2019/relianz/rlp"
+)
+
+// txSnapshotMagic is the header identifying a transaction pool snapshot file,
+// including the version of the format.
+var txSnapshotMagic = []byte("rlztxsnap\x01")
+
+// txSnapshotMaxRecord is the maximum size of a snapshot record. Larger length
+// prefixes can only stem from corruption.
+const txSnapshotMaxRecord = 2 * txMaxSize
+
+var (
+	// errNoSnapshot is returned if the pool is requested to save a snapshot, but
+	// no snapshot file is configured.
+	errNoSnapshot = errors.New("transaction pool snapshot disabled")
+
+	// errSnapshotVersion is returned if a snapshot file was written in an unknown
+	// format.
+	errSnapshotVersion = errors.New("unknown transaction pool snapshot format")
+)
+
+// txSnapshotEntry is a transaction stored in a pool snapshot.
+type txSnapshotEntry struct {
+	Tx    *types.Transaction
+	Time  uint64 // Unix time the sender was last active in the pool
+	Local bool   // Whether the transaction was submitted locally
+}
+
+// txSnapshot is a disk backed copy of the entire transaction pool, allowing
+// remote transactions to survive node restarts. Every entry is stored in its
+// own record, prefixed with its length and checksum, so that a corrupted entry
+// only loses itself instead of the rest of the snapshot.
+type txSnapshot struct {
+	path string     // Filesystem path to store the snapshot at
+	lock sync.Mutex // Serializes concurrent saves sharing the temporary file
+}
+
+// newTxSnapshot creates a new transaction pool snapshot at the given path.
+func newTxSnapshot(path string) *txSnapshot {
+	return &txSnapshot{path: path}
+}
+
+// save replaces the snapshot on disk with the given entries. The snapshot is
+// written to a temporary file first, so a crash cannot destroy the last one.
+func (snap *txSnapshot) save(entries []*txSnapshotEntry) error {
+	snap.lock.Lock()
+	defer snap.lock.Unlock()
+
+	output, err := os.OpenFile(snap.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(output)
+	if _, err = writer.Write(txSnapshotMagic); err == nil {
+		for _, entry := range entries {
+			if err = writeTxSnapshotRecord(writer, entry); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = output.Sync()
+	}
+	if cerr := output.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(snap.path + ".new")
+		return err
+	}
+	return os.Rename(snap.path+".new", snap.path)
+}
+
+// writeTxSnapshotRecord writes a single length and checksum prefixed entry.
+func writeTxSnapshotRecord(w io.Writer, entry *txSnapshotEntry) error {
+	blob, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		return err
+	}
+	var prefix [8]byte
+	binary.BigEndian.PutUint32(prefix[:4], uint32(len(blob)))
+	binary.BigEndian.PutUint32(prefix[4:], crc32.ChecksumIEEE(blob))
+
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(blob)
+	return err
+}
+
+// load reads all intact entries from the snapshot on disk, skipping remote ones
+// the sender of which was last active longer than lifetime ago, and returns them
+// along with the number of expired ones. Corrupted entries are skipped, while a
+// corrupted length prefix ends the loading at that point.
+func (snap *txSnapshot) load(lifetime time.Duration) ([]*txSnapshotEntry, int, error) {
+	// Skip the parsing if the snapshot file doesn't exist at all
+	input, err := os.Open(snap.path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer input.Close()
+
+	reader := bufio.NewReader(input)
+	magic := make([]byte, len(txSnapshotMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return nil, 0, err
+	}
+	if !bytes.Equal(magic, txSnapshotMagic) {
+		return nil, 0, errSnapshotVersion
+	}
+	var (
+		entries   []*txSnapshotEntry
+		corrupted int
+		expired   int
+		deadline  = time.Now().Add(-lifetime)
+		prefix    [8]byte
+	)
+	for {
+		if _, err = io.ReadFull(reader, prefix[:]); err != nil {
+			break
+		}
+		size := binary.BigEndian.Uint32(prefix[:4])
+		if size > txSnapshotMaxRecord {
+			err = errors.New("oversized snapshot record")
+			break
+		}
+		blob := make([]byte, size)
+		if _, err = io.ReadFull(reader, blob); err != nil {
+			break
+		}
+		entry := new(txSnapshotEntry)
+		if crc32.ChecksumIEEE(blob) != binary.BigEndian.Uint32(prefix[4:]) || rlp.DecodeBytes(blob, entry) != nil {
+			corrupted++
+			continue
+		}
+		if !entry.Local && time.Unix(int64(entry.Time), 0).Before(deadline) {
+			expired++
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	// A clean end of file is expected, anything else means a damaged tail
+	if err != io.EOF {
+		log.Warn("Transaction pool snapshot truncated", "err", err)
+	}
+	if corrupted > 0 {
+		log.Warn("Skipped corrupted transaction pool snapshot records", "records", corrupted)
+	}
+	return entries, expired, nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/state"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/event"
+	"github.com/relianz2019/relianz/params"
+)
+
+// Tests that remote transactions survive pool restarts through the snapshot and
+// get revalidated when reloaded.
+func TestTransactionSnapshot(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Snapshot = filepath.Join(dir, "snapshot")
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	remote, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	// Add a few pending and a queued remote transaction
+	for _, nonce := range []uint64{0, 1, 3} {
+		if err := pool.AddRemote(pricedTransaction(nonce, 100000, big.NewInt(1), remote)); err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", nonce, err)
+		}
+	}
+	if saved, err := pool.SaveSnapshot(); err != nil || saved != 3 {
+		t.Fatalf("on-demand snapshot mismatch: have %d/%v, want %d/nil", saved, err, 3)
+	}
+	// Terminate the pool, include the first transaction and ensure the rest survive
+	pool.Stop()
+	statedb.SetNonce(crypto.PubkeyToAddress(remote.PublicKey), 1)
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	pending, queued := pool.Stats()
+	if pending != 1 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+	if queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that snapshots expire stale remote entries and tolerate corruption.
+func TestTransactionSnapshotRecovery(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, _ := crypto.GenerateKey()
+
+	var (
+		now   = uint64(time.Now().Unix())
+		stale = uint64(time.Now().Add(-2 * time.Hour).Unix())
+		snap  = newTxSnapshot(filepath.Join(dir, "snapshot"))
+	)
+	entries := []*txSnapshotEntry{
+		{Tx: pricedTransaction(0, 100000, big.NewInt(1), key), Time: now},
+		{Tx: pricedTransaction(1, 100000, big.NewInt(1), key), Time: now},
+		{Tx: pricedTransaction(2, 100000, big.NewInt(1), key), Time: stale},
+		{Tx: pricedTransaction(3, 100000, big.NewInt(1), key), Time: stale, Local: true},
+		{Tx: pricedTransaction(4, 100000, big.NewInt(1), key), Time: now},
+	}
+	if err := snap.save(entries); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+	// Ensure stale remote transactions expire, but local ones are kept
+	loaded, expired, err := snap.load(time.Hour)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if len(loaded) != 4 || expired != 1 {
+		t.Fatalf("loaded snapshot mismatch: have %d/%d, want %d/%d", len(loaded), expired, 4, 1)
+	}
+	// Corrupt the body of the first record and truncate the last one
+	blob, err := ioutil.ReadFile(snap.path)
+	if err != nil {
+		t.Fatalf("failed to read snapshot: %v", err)
+	}
+	blob[len(txSnapshotMagic)+16] ^= 0xff
+	blob = blob[:len(blob)-10]
+	if err := ioutil.WriteFile(snap.path, blob, 0644); err != nil {
+		t.Fatalf("failed to corrupt snapshot: %v", err)
+	}
+	loaded, _, err = snap.load(time.Hour)
+	if err != nil {
+		t.Fatalf("failed to load corrupted snapshot: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("recovered snapshot mismatch: have %d entries, want %d", len(loaded), 2)
+	}
+	for i, nonce := range []uint64{1, 3} {
+		if loaded[i].Tx.Nonce() != nonce {
+			t.Errorf("entry %d: nonce mismatch: have %d, want %d", i, loaded[i].Tx.Nonce(), nonce)
+		}
+	}
+}
+
+// Tests that concurrent on-demand snapshots, taken while transactions are being
+// added, each leave an intact snapshot behind.
+func TestTransactionSnapshotConcurrentSave(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Snapshot = filepath.Join(dir, "snapshot")
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	var (
+		errs = make(chan error, 8)
+		done = make(chan struct{})
+	)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			for {
+				select {
+				case <-done:
+					errs <- nil
+					return
+				default:
+				}
+				if _, err := pool.SaveSnapshot(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	for nonce := uint64(0); nonce < 16; nonce++ {
+		if err := pool.AddRemote(pricedTransaction(nonce, 100000, big.NewInt(1), key)); err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", nonce, err)
+		}
+	}
+	close(done)
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("failed to save snapshot: %v", err)
+		}
+	}
+	if saved, err := pool.SaveSnapshot(); err != nil || saved != 16 {
+		t.Fatalf("final snapshot mismatch: have %d/%v, want %d/nil", saved, err, 16)
+	}
+	loaded, _, err := pool.snap.load(time.Hour)
+	if err != nil || len(loaded) != 16 {
+		t.Fatalf("loaded snapshot mismatch: have %d/%v, want %d/nil", len(loaded), err, 16)
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/consensus"
+	"github.com/relianz2019/relianz/core/state"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/core/ufo"
+	"github.com/relianz2019/relianz/log"
+)
+
+// Names of the built-in transaction admission hooks.
+const (
+	BlacklistValidator = "blacklist" // Rejects transactions to blacklisted recipients
+	UfoLimitValidator  = "ufolimit"  // Rate limits ufo payloads sent by non-signers
+	MinPriceValidator  = "minprice"  // Enforces a minimum gas price per recipient
+)
+
+// Machine readable reasons of the built-in transaction admission hooks.
+const (
+	RejectBlacklisted = "blacklisted"
+	RejectRateLimited = "ratelimited"
+	RejectUnderpriced = "underpriced"
+	RejectPolicy      = "policy" // Reason of hooks not returning a TxRejection
+)
+
+// txRejectionErrorCode is the JSON-RPC error code transactions refused by an
+// admission hook are reported with.
+const txRejectionErrorCode = -32003
+
+var (
+	// txValidatorRegistry holds the transaction admission hook factories by name,
+	// ready to be configured into the pool.
+	txValidatorRegistry = map[string]TxValidatorFactory{
+		BlacklistValidator: newBlacklistValidator,
+		UfoLimitValidator:  newUfoLimitValidator,
+		MinPriceValidator:  newMinPriceValidator,
+	}
+	txValidatorRegistryLock sync.RWMutex
+)
+
+// TxValidator is a transaction admission hook of the pool, consulted after the
+// built-in validity checks passed. Hooks run in their configured order, the
+// first one refusing a transaction keeps it out of the pool.
+type TxValidator interface {
+	// Name returns the name the hook reports its rejections under.
+	Name() string
+
+	// ValidateTx checks whether the transaction of the given sender may enter the
+	// pool, given the pending state of the pool and whether the transaction was
+	// submitted locally. A *TxRejection should be returned to refuse it with a
+	// specific reason. The method is called with the pool lock held.
+	ValidateTx(tx *types.Transaction, from common.Address, state *state.ManagedState, local bool) error
+}
+
+// TxAcceptor is an optional interface of admission hooks tracking the
+// transactions actually entering the pool, e.g. to count them against a quota.
+// AcceptTx is called with the pool lock held, once a transaction passed every
+// hook and was inserted into the pool.
+type TxAcceptor interface {
+	AcceptTx(tx *types.Transaction, from common.Address, local bool)
+}
+
+// TxValidatorConfig is the configuration of a transaction admission hook. The
+// fields beyond the name are interpreted by the hook itself.
+type TxValidatorConfig struct {
+	Name      string           // Registered name of the hook
+	Addresses []common.Address `toml:",omitempty"` // Recipients the hook applies to (blacklist, minprice)
+	GasPrice  *big.Int         `toml:",omitempty"` // Minimum gas price of the recipients (minprice)
+	Limit     uint64           `toml:",omitempty"` // Maximum number of payloads per sender and interval (ufolimit)
+	Interval  time.Duration    `toml:",omitempty"` // Interval the payload limit applies to (ufolimit)
+}
+
+// TxValidatorContext is the chain context admission hooks are created with.
+type TxValidatorContext struct {
+	Chain  consensus.ChainReader // Chain the pool operates on, nil if unknown
+	Engine consensus.Engine      // Consensus engine of the chain, nil if unknown
+}
+
+// TxValidatorFactory creates a transaction admission hook from its configuration.
+type TxValidatorFactory func(config *TxValidatorConfig, ctx *TxValidatorContext) (TxValidator, error)
+
+// RegisterTxValidator registers a transaction admission hook factory under the
+// given name, allowing nodes to configure custom hooks. Registrations are
+// expected to happen at node setup, before the pool is created.
+func RegisterTxValidator(name string, factory TxValidatorFactory) error {
+	if name == "" {
+		return errors.New("unnamed transaction validator")
+	}
+	txValidatorRegistryLock.Lock()
+	defer txValidatorRegistryLock.Unlock()
+
+	if _, ok := txValidatorRegistry[name]; ok {
+		return fmt.Errorf("transaction validator %q already registered", name)
+	}
+	txValidatorRegistry[name] = factory
+	return nil
+}
+
+// NewTxValidators creates the ordered chain of admission hooks configured.
+func NewTxValidators(configs []TxValidatorConfig, ctx *TxValidatorContext) ([]TxValidator, error) {
+	txValidatorRegistryLock.RLock()
+	defer txValidatorRegistryLock.RUnlock()
+
+	validators := make([]TxValidator, 0, len(configs))
+	for i := range configs {
+		factory, ok := txValidatorRegistry[configs[i].Name]
+		if !ok {
+			return nil, fmt.Errorf("transaction validator %q not registered", configs[i].Name)
+		}
+		validator, err := factory(&configs[i], ctx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction validator %q: %v", configs[i].Name, err)
+		}
+		validators = append(validators, validator)
+	}
+	return validators, nil
+}
+
+// TxRejection is the structured reason of a transaction refused by an admission
+// hook. It is returned as the error of the pool insertion and posted to the
+// rejection feed of the pool.
+type TxRejection struct {
+	Validator string         `json:"validator"` // Name of the refusing hook
+	Reason    string         `json:"reason"`    // Machine readable reason of the refusal
+	Message   string         `json:"message"`   // Human readable explanation of the refusal
+	Hash      common.Hash    `json:"hash"`      // Hash of the refused transaction
+	From      common.Address `json:"from"`      // Sender of the refused transaction
+	Local     bool           `json:"local"`     // Whether the transaction was submitted locally
+}
+
+// Error implements error, describing the refusal.
+func (r *TxRejection) Error() string {
+	return fmt.Sprintf("transaction rejected by %s: %s", r.Validator, r.Message)
+}
+
+// ErrorCode implements rpc.Error, reporting the refusal with a distinct code.
+func (r *TxRejection) ErrorCode() int {
+	return txRejectionErrorCode
+}
+
+// ErrorData implements rpc.DataError, attaching the structured reason to the
+// error returned to RPC callers.
+func (r *TxRejection) ErrorData() interface{} {
+	return r
+}
+
+// blacklistValidator rejects every transaction to one of a set of recipients.
+type blacklistValidator struct {
+	recipients map[common.Address]struct{}
+}
+
+// newBlacklistValidator creates an admission hook rejecting transactions to the
+// configured addresses.
+func newBlacklistValidator(config *TxValidatorConfig, ctx *TxValidatorContext) (TxValidator, error) {
+	if len(config.Addresses) == 0 {
+		return nil, errors.New("no blacklisted addresses")
+	}
+	v := &blacklistValidator{recipients: make(map[common.Address]struct{})}
+	for _, addr := range config.Addresses {
+		v.recipients[addr] = struct{}{}
+	}
+	return v, nil
+}
+
+func (v *blacklistValidator) Name() string { return BlacklistValidator }
+
+func (v *blacklistValidator) ValidateTx(tx *types.Transaction, from common.Address, state *state.ManagedState, local bool) error {
+	if to := tx.To(); to != nil {
+		if _, ok := v.recipients[*to]; ok {
+			return &TxRejection{Reason: RejectBlacklisted, Message: fmt.Sprintf("recipient %x is blacklisted", *to)}
+		}
+	}
+	return nil
+}
+
+// minPriceValidator rejects remote transactions to a set of recipients paying
+// less than a minimum gas price.
+type minPriceValidator struct {
+	recipients map[common.Address]struct{}
+	price      *big.Int
+}
+
+// newMinPriceValidator creates an admission hook enforcing the configured gas
+// price on transactions to the configured addresses.
+func newMinPriceValidator(config *TxValidatorConfig, ctx *TxValidatorContext) (TxValidator, error) {
+	if len(config.Addresses) == 0 {
+		return nil, errors.New("no priced addresses")
+	}
+	if config.GasPrice == nil || config.GasPrice.Sign() <= 0 {
+		return nil, errors.New("no minimum gas price")
+	}
+	v := &minPriceValidator{
+		recipients: make(map[common.Address]struct{}),
+		price:      new(big.Int).Set(config.GasPrice),
+	}
+	for _, addr := range config.Addresses {
+		v.recipients[addr] = struct{}{}
+	}
+	return v, nil
+}
+
+func (v *minPriceValidator) Name() string { return MinPriceValidator }
+
+func (v *minPriceValidator) ValidateTx(tx *types.Transaction, from common.Address, state *state.ManagedState, local bool) error {
+	// Local transactions are exempt from pricing, as everywhere in the pool
+	if local || tx.To() == nil {
+		return nil
+	}
+	if _, ok := v.recipients[*tx.To()]; ok && tx.GasPrice().Cmp(v.price) < 0 {
+		return &TxRejection{Reason: RejectUnderpriced, Message: fmt.Sprintf("gas price %v below %v required by recipient %x", tx.GasPrice(), v.price, *tx.To())}
+	}
+	return nil
+}
+
+// ufoLimitValidator rate limits the ufo consensus payloads remote non-signers
+// may send, counting them in fixed intervals.
+type ufoLimitValidator struct {
+	ctx      *TxValidatorContext
+	limit    uint64
+	interval time.Duration
+
+	start  time.Time                 // Start of the current counting interval
+	counts map[common.Address]uint64 // Payloads counted per sender in the current interval
+}
+
+// newUfoLimitValidator creates an admission hook limiting non-signers to the
+// configured number of ufo payloads per interval.
+func newUfoLimitValidator(config *TxValidatorConfig, ctx *TxValidatorContext) (TxValidator, error) {
+	if config.Limit == 0 {
+		return nil, errors.New("no payload limit")
+	}
+	if config.Interval <= 0 {
+		return nil, errors.New("no limit interval")
+	}
+	return &ufoLimitValidator{
+		ctx:      ctx,
+		limit:    config.Limit,
+		interval: config.Interval,
+		counts:   make(map[common.Address]uint64),
+	}, nil
+}
+
+func (v *ufoLimitValidator) Name() string { return UfoLimitValidator }
+
+func (v *ufoLimitValidator) ValidateTx(tx *types.Transaction, from common.Address, state *state.ManagedState, local bool) error {
+	if !v.limited(tx, from, local) {
+		return nil
+	}
+	if v.counts[from] >= v.limit {
+		return &TxRejection{Reason: RejectRateLimited, Message: fmt.Sprintf("more than %d ufo payloads per %v from non-signer", v.limit, v.interval)}
+	}
+	return nil
+}
+
+// AcceptTx implements TxAcceptor, counting the payloads against the quota of
+// the sender only once they entered the pool.
+func (v *ufoLimitValidator) AcceptTx(tx *types.Transaction, from common.Address, local bool) {
+	if v.limited(tx, from, local) {
+		v.counts[from]++
+	}
+}
+
+// limited reports whether the transaction counts against the payload quota of
+// its sender, starting a new counting interval if the current one elapsed.
+func (v *ufoLimitValidator) limited(tx *types.Transaction, from common.Address, local bool) bool {
+	if local || !ufo.IsUfo(tx.Data()) || v.isSigner(from) {
+		return false
+	}
+	if now := time.Now(); now.Sub(v.start) >= v.interval {
+		v.start, v.counts = now, make(map[common.Address]uint64)
+	}
+	return true
+}
+
+// isSigner reports whether the address is in charge of sealing at the current
+// chain head. Without a signer aware consensus engine nobody is a signer.
+func (v *ufoLimitValidator) isSigner(addr common.Address) bool {
+	if v.ctx == nil || v.ctx.Chain == nil {
+		return false
+	}
+	reader, ok := v.ctx.Engine.(SignerSetReader)
+	if !ok {
+		return false
+	}
+	signers, err := reader.Signers(v.ctx.Chain, v.ctx.Chain.CurrentHeader())
+	if err != nil {
+		log.Debug("Failed to retrieve signers for ufo limit", "err", err)
+		return false
+	}
+	for _, signer := range signers {
+		if signer == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,278 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/consensus"
+	"github.com/relianz2019/relianz/core/state"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/core/ufo"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/event"
+	"github.com/relianz2019/relianz/params"
+)
+
+func recipientTransaction(nonce uint64, to common.Address, gasprice *big.Int, data []byte, key *ecdsa.PrivateKey) *types.Transaction {
+	tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(100), 100000, gasprice, data), types.HomesteadSigner{}, key)
+	return tx
+}
+
+// Tests that misconfigured admission hooks are refused.
+func TestTxValidatorConfigs(t *testing.T) {
+	tests := []struct {
+		config TxValidatorConfig
+		fail   bool
+	}{
+		{TxValidatorConfig{Name: BlacklistValidator, Addresses: []common.Address{{0x01}}}, false},
+		{TxValidatorConfig{Name: BlacklistValidator}, true},
+		{TxValidatorConfig{Name: MinPriceValidator, Addresses: []common.Address{{0x01}}, GasPrice: big.NewInt(1)}, false},
+		{TxValidatorConfig{Name: MinPriceValidator, Addresses: []common.Address{{0x01}}}, true},
+		{TxValidatorConfig{Name: UfoLimitValidator, Limit: 1, Interval: time.Minute}, false},
+		{TxValidatorConfig{Name: UfoLimitValidator, Limit: 1}, true},
+		{TxValidatorConfig{Name: "unknown"}, true},
+	}
+	for i, tt := range tests {
+		_, err := NewTxValidators([]TxValidatorConfig{tt.config}, new(TxValidatorContext))
+		if tt.fail && err == nil {
+			t.Errorf("test %d: misconfigured validator accepted", i)
+		}
+		if !tt.fail && err != nil {
+			t.Errorf("test %d: failed to create validator: %v", i, err)
+		}
+	}
+	if err := RegisterTxValidator(BlacklistValidator, newBlacklistValidator); err == nil {
+		t.Errorf("duplicate validator registration accepted")
+	}
+}
+
+// Tests that the admission hooks of the pool refuse transactions with structured
+// reasons, reported on the rejection feed.
+func TestTransactionPoolValidators(t *testing.T) {
+	t.Parallel()
+
+	var (
+		blacklisted = common.Address{0x01}
+		priced      = common.Address{0x02}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Validators = []TxValidatorConfig{
+		{Name: BlacklistValidator, Addresses: []common.Address{blacklisted}},
+		{Name: MinPriceValidator, Addresses: []common.Address{priced}, GasPrice: big.NewInt(10)},
+		{Name: UfoLimitValidator, Limit: 1, Interval: time.Hour},
+	}
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	rejections := make(chan TxRejectedEvent, 8)
+	sub := pool.SubscribeTxRejectedEvent(rejections)
+	defer sub.Unsubscribe()
+
+	// Use separate accounts, as local accounts are exempt from later remote checks
+	remote, _ := crypto.GenerateKey()
+	local, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(100000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(100000000))
+
+	payload := ufo.Encode(&ufo.Vote{})
+	tests := []struct {
+		tx     *types.Transaction
+		local  bool
+		reason string
+	}{
+		{recipientTransaction(0, blacklisted, big.NewInt(1), nil, remote), false, RejectBlacklisted},
+		{recipientTransaction(0, priced, big.NewInt(1), nil, remote), false, RejectUnderpriced},
+		{recipientTransaction(0, priced, big.NewInt(10), nil, remote), false, ""},
+		{recipientTransaction(1, common.Address{0x03}, big.NewInt(1), payload, remote), false, ""},
+		{recipientTransaction(2, common.Address{0x03}, big.NewInt(1), payload, remote), false, RejectRateLimited},
+
+		{recipientTransaction(0, blacklisted, big.NewInt(1), nil, local), true, RejectBlacklisted},
+		{recipientTransaction(0, priced, big.NewInt(1), nil, local), true, ""},
+		{recipientTransaction(1, common.Address{0x03}, big.NewInt(1), payload, local), true, ""},
+		{recipientTransaction(2, common.Address{0x03}, big.NewInt(1), payload, local), true, ""},
+	}
+	for i, tt := range tests {
+		var err error
+		if tt.local {
+			err = pool.AddLocal(tt.tx)
+		} else {
+			err = pool.AddRemote(tt.tx)
+		}
+		if tt.reason == "" {
+			if err != nil {
+				t.Fatalf("test %d: failed to add transaction: %v", i, err)
+			}
+			continue
+		}
+		rejection, ok := err.(*TxRejection)
+		if !ok {
+			t.Fatalf("test %d: error mismatch: have %v, want rejection", i, err)
+		}
+		if rejection.Reason != tt.reason || rejection.Hash != tt.tx.Hash() || rejection.Local != tt.local {
+			t.Errorf("test %d: rejection mismatch: have %+v, want reason %s", i, rejection, tt.reason)
+		}
+		select {
+		case ev := <-rejections:
+			if ev.Rejection.Hash != tt.tx.Hash() {
+				t.Errorf("test %d: rejection event mismatch: have %x, want %x", i, ev.Rejection.Hash, tt.tx.Hash())
+			}
+		case <-time.After(time.Second):
+			t.Errorf("test %d: rejection event not fired", i)
+		}
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// signerSetEngine is a consensus engine reporting a static set of signers.
+type signerSetEngine struct {
+	consensus.Engine
+	staticSigners
+}
+
+// Tests that the ufo payload limit exempts the signers reported by the engine
+// and only counts payloads once they were accepted into the pool.
+func TestUfoLimitValidator(t *testing.T) {
+	signer, _ := crypto.GenerateKey()
+	sender, _ := crypto.GenerateKey()
+
+	ctx := &TxValidatorContext{
+		Chain:  newFinalityTestChain(1),
+		Engine: signerSetEngine{staticSigners: staticSigners{crypto.PubkeyToAddress(signer.PublicKey)}},
+	}
+	validator, err := newUfoLimitValidator(&TxValidatorConfig{Name: UfoLimitValidator, Limit: 1, Interval: time.Hour}, ctx)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+	v := validator.(*ufoLimitValidator)
+
+	payload := ufo.Encode(&ufo.Vote{})
+	for i := uint64(0); i < 3; i++ {
+		tx := recipientTransaction(i, common.Address{0x03}, big.NewInt(1), payload, signer)
+		if err := v.ValidateTx(tx, crypto.PubkeyToAddress(signer.PublicKey), nil, false); err != nil {
+			t.Fatalf("signer payload %d rejected: %v", i, err)
+		}
+		v.AcceptTx(tx, crypto.PubkeyToAddress(signer.PublicKey), false)
+	}
+	from := crypto.PubkeyToAddress(sender.PublicKey)
+	tx := recipientTransaction(0, common.Address{0x03}, big.NewInt(1), payload, sender)
+	for i := 0; i < 3; i++ {
+		if err := v.ValidateTx(tx, from, nil, false); err != nil {
+			t.Fatalf("validation %d of unaccepted payload rejected: %v", i, err)
+		}
+	}
+	v.AcceptTx(tx, from, false)
+	if err := v.ValidateTx(tx, from, nil, false); err == nil {
+		t.Fatalf("payload over the limit accepted")
+	}
+}
+
+// Tests that ufo payloads refused by the pool after passing the admission hooks
+// don't count against the quota of their sender.
+func TestTransactionPoolUfoLimitAccepted(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Validators = []TxValidatorConfig{{Name: UfoLimitValidator, Limit: 2, Interval: time.Hour}}
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(100000000))
+
+	payload := ufo.Encode(&ufo.Vote{})
+	if err := pool.AddRemote(recipientTransaction(0, common.Address{0x03}, big.NewInt(1), payload, key)); err != nil {
+		t.Fatalf("failed to add first payload: %v", err)
+	}
+	if err := pool.AddRemote(recipientTransaction(0, common.Address{0x04}, big.NewInt(1), payload, key)); err != ErrReplaceUnderpriced {
+		t.Fatalf("underpriced replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if err := pool.AddRemote(recipientTransaction(1, common.Address{0x03}, big.NewInt(1), payload, key)); err != nil {
+		t.Fatalf("failed to add second payload: %v", err)
+	}
+	err := pool.AddRemote(recipientTransaction(2, common.Address{0x03}, big.NewInt(1), payload, key))
+	if rejection, ok := err.(*TxRejection); !ok || rejection.Reason != RejectRateLimited {
+		t.Fatalf("third payload error mismatch: have %v, want rate limit", err)
+	}
+}
+
+// Tests that rejection events are sent outside the pool lock, so a subscriber not
+// consuming them does not stall the pool.
+func TestTransactionPoolRejectionsUnlocked(t *testing.T) {
+	t.Parallel()
+
+	blacklisted := common.Address{0x01}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Validators = []TxValidatorConfig{{Name: BlacklistValidator, Addresses: []common.Address{blacklisted}}}
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	rejections := make(chan TxRejectedEvent)
+	sub := pool.SubscribeTxRejectedEvent(rejections)
+	defer sub.Unsubscribe()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(100000000))
+
+	// Reject a transaction while nobody consumes the rejection event
+	tx := recipientTransaction(0, blacklisted, big.NewInt(1), nil, key)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- pool.AddRemote(tx)
+	}()
+	time.Sleep(100 * time.Millisecond) // Wait for the rejection to block on the event
+
+	stats := make(chan struct{})
+	go func() {
+		pool.Stats()
+		close(stats)
+	}()
+	select {
+	case <-stats:
+	case <-time.After(time.Second):
+		t.Fatalf("pool stalled by rejection event subscriber")
+	}
+	select {
+	case ev := <-rejections:
+		if ev.Rejection.Hash != tx.Hash() {
+			t.Errorf("rejection event mismatch: have %x, want %x", ev.Rejection.Hash, tx.Hash())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("rejection event missing")
+	}
+	if err := <-errc; err == nil {
+		t.Fatalf("blacklisted transaction accepted")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/crypto"
+)
+
+// ErrInvalidConfirmationSig is returned if the signature of a block confirmation
+// is malformed or cannot be recovered.
+var ErrInvalidConfirmationSig = errors.New("invalid confirmation signature")
+
+// Confirmation is a signer's statement that it has accepted a block. Once a
+// quorum of signers has confirmed a block, it is considered final.
+type Confirmation struct {
+	ChainId   *big.Int    // Chain id the confirmation is valid on (replay protection)
+	Number    uint64      // Number of the confirmed block
+	Hash      common.Hash // Hash of the confirmed block
+	Signature []byte      // Signature of the signer over SigHash
+}
+
+// NewConfirmation creates an unsigned confirmation of the given block.
+func NewConfirmation(chainId *big.Int, header *Header) *Confirmation {
+	return &Confirmation{
+		ChainId: new(big.Int).Set(chainId),
+		Number:  header.Number.Uint64(),
+		Hash:    header.Hash(),
+	}
+}
+
+// SigHash returns the hash to be signed by the confirming signer.
+func (c *Confirmation) SigHash() common.Hash {
+	return rlpHash([]interface{}{
+		c.ChainId,
+		c.Number,
+		c.Hash,
+	})
+}
+
+// ID returns a unique identifier of the signed confirmation.
+func (c *Confirmation) ID() common.Hash {
+	return rlpHash(c)
+}
+
+// Signer recovers the address of the account that signed the confirmation.
+func (c *Confirmation) Signer() (common.Address, error) {
+	if len(c.Signature) != 65 {
+		return common.Address{}, ErrInvalidConfirmationSig
+	}
+	hash := c.SigHash()
+	pubkey, err := crypto.Ecrecover(hash[:], c.Signature)
+	if err != nil {
+		return common.Address{}, ErrInvalidConfirmationSig
+	}
+	var addr common.Address
+	copy(addr[:], crypto.Keccak256(pubkey[1:])[12:])
+	return addr, nil
+}
+
+// SignConfirmation signs the confirmation with the given private key.
+func SignConfirmation(c *Confirmation, prv *ecdsa.PrivateKey) (*Confirmation, error) {
+	hash := c.SigHash()
+	sig, err := crypto.Sign(hash[:], prv)
+	if err != nil {
+		return nil, err
+	}
+	cpy := *c
+	cpy.Signature = sig
+	return &cpy, nil
+}
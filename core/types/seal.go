@@ -0,0 +1,41 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/relianz2019/relianz/common"
+)
+
+// SealRecord describes how a block was sealed by a signer queue based consensus
+// engine.
+type SealRecord struct {
+	Signer    common.Address   // Signer that sealed the block
+	LoopStart uint64           // Start time of the signer loop the block belongs to
+	Queue     []common.Address `rlp:"-"` // Signer queue of the loop in slot order, stored once per loop
+	Missing   []common.Address // Signers that missed their slot since the parent block
+	Reward    *big.Int         // Block reward minted for the block, shared by the signer and its voters
+}
+
+// SignerStats accumulates the sealing activity of a signer over a range of blocks.
+type SignerStats struct {
+	Signer common.Address // Signer the statistics belong to
+	Sealed uint64         // Number of blocks sealed by the signer
+	Missed uint64         // Number of slots missed by the signer
+	Reward *big.Int       // Total block reward minted for the blocks of the signer
+}
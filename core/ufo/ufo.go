@@ -0,0 +1,453 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ufo implements the encoding of the custom transaction payloads driving
+// the Alien consensus engine.
+//
+// A payload is a colon separated list of fields:
+//
+//	ufo:<version>:<category>:<event>[:<arguments>...]
+//
+// The supported messages of version 1 are:
+//
+//	ufo:1:event:vote
+//	ufo:1:event:confirm:<number>
+//	ufo:1:event:proposal:proposal_type:<type>:vlcnt:<loops>:<key>:<value>
+//	ufo:1:event:declare:hash:<proposal hash>:decision:<yes|no>
+//	ufo:1:sc:notify:<side chain hash>:<number>:<block hash>
+package ufo
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+)
+
+const (
+	// Prefix is the leading field of every ufo payload.
+	Prefix = "ufo"
+
+	// Version is the only payload version currently supported.
+	Version = 1
+
+	// Separator delimits the fields of a payload.
+	Separator = ":"
+)
+
+// Category groups the events of the ufo protocol.
+type Category string
+
+const (
+	CategoryEvent     Category = "event" // Consensus events of the chain itself
+	CategorySideChain Category = "sc"    // Messages exchanged with side chains
+)
+
+// Event identifies the kind of a ufo payload.
+type Event string
+
+const (
+	EventVote            Event = "vote"
+	EventConfirm         Event = "confirm"
+	EventProposal        Event = "proposal"
+	EventDeclare         Event = "declare"
+	EventSideChainNotify Event = "notify"
+)
+
+var (
+	// ErrNotUfo is returned if the data does not carry the ufo prefix.
+	ErrNotUfo = errors.New("not a ufo payload")
+
+	// ErrUnsupportedVersion is returned if the payload version is unknown.
+	ErrUnsupportedVersion = errors.New("unsupported ufo version")
+
+	// ErrUnknownEvent is returned if the category or event is unknown.
+	ErrUnknownEvent = errors.New("unknown ufo event")
+
+	// ErrMalformed is returned if the arguments of a known event are invalid.
+	ErrMalformed = errors.New("malformed ufo payload")
+)
+
+// Payload is a decoded ufo message.
+type Payload interface {
+	// Category returns the category the payload belongs to.
+	Category() Category
+
+	// Event returns the event type of the payload.
+	Event() Event
+
+	// args returns the encoded arguments following the event field.
+	args() []string
+}
+
+// Encode serialises a payload into transaction data.
+func Encode(p Payload) []byte {
+	fields := append([]string{Prefix, strconv.Itoa(Version), string(p.Category()), string(p.Event())}, p.args()...)
+	return []byte(strings.Join(fields, Separator))
+}
+
+// IsUfo reports whether data carries the ufo prefix, regardless of validity.
+func IsUfo(data []byte) bool {
+	return strings.HasPrefix(string(data), Prefix+Separator)
+}
+
+// Decode parses transaction data into a typed payload.
+func Decode(data []byte) (Payload, error) {
+	if !IsUfo(data) {
+		return nil, ErrNotUfo
+	}
+	fields := strings.Split(string(data), Separator)
+	if len(fields) < 4 {
+		return nil, malformed("missing category or event")
+	}
+	version, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil || strconv.FormatUint(version, 10) != fields[1] {
+		return nil, fmt.Errorf("%v: %q", ErrUnsupportedVersion, fields[1])
+	}
+	if version != Version {
+		return nil, fmt.Errorf("%v: %d", ErrUnsupportedVersion, version)
+	}
+	category, event, args := Category(fields[2]), Event(fields[3]), fields[4:]
+
+	switch {
+	case category == CategoryEvent && event == EventVote:
+		return decodeVote(args)
+	case category == CategoryEvent && event == EventConfirm:
+		return decodeConfirm(args)
+	case category == CategoryEvent && event == EventProposal:
+		return decodeProposal(args)
+	case category == CategoryEvent && event == EventDeclare:
+		return decodeDeclare(args)
+	case category == CategorySideChain && event == EventSideChainNotify:
+		return decodeSideChainNotify(args)
+	}
+	return nil, fmt.Errorf("%v: %s%s%s", ErrUnknownEvent, category, Separator, event)
+}
+
+// malformed wraps a description of an argument error into ErrMalformed.
+func malformed(format string, args ...interface{}) error {
+	return fmt.Errorf("%v: %s", ErrMalformed, fmt.Sprintf(format, args...))
+}
+
+// Vote is cast by the sender of a transaction for its recipient as a signer
+// candidate. The vote is weighted with the balance of the sender.
+type Vote struct{}
+
+func (v *Vote) Category() Category { return CategoryEvent }
+func (v *Vote) Event() Event       { return EventVote }
+func (v *Vote) args() []string     { return nil }
+
+func decodeVote(args []string) (*Vote, error) {
+	if len(args) != 0 {
+		return nil, malformed("vote takes no arguments, have %d", len(args))
+	}
+	return new(Vote), nil
+}
+
+// Confirm is sent by a signer to confirm a block it accepted.
+type Confirm struct {
+	Number uint64 `json:"number"` // Number of the confirmed block
+}
+
+func (c *Confirm) Category() Category { return CategoryEvent }
+func (c *Confirm) Event() Event       { return EventConfirm }
+func (c *Confirm) args() []string     { return []string{strconv.FormatUint(c.Number, 10)} }
+
+func decodeConfirm(args []string) (*Confirm, error) {
+	if len(args) != 1 {
+		return nil, malformed("confirm takes a block number, have %d arguments", len(args))
+	}
+	number, err := parseUint(args[0])
+	if err != nil {
+		return nil, malformed("invalid block number %q", args[0])
+	}
+	return &Confirm{Number: number}, nil
+}
+
+// ProposalType enumerates the chain parameters a proposal may change.
+type ProposalType uint64
+
+const (
+	ProposalCandidateAdd          ProposalType = 1 // Add a signer candidate
+	ProposalCandidateRemove       ProposalType = 2 // Remove a signer candidate
+	ProposalMinerRewardModify     ProposalType = 3 // Change the miner share of the block reward
+	ProposalSideChainAdd          ProposalType = 4 // Register a side chain
+	ProposalSideChainRemove       ProposalType = 5 // Deregister a side chain
+	ProposalMinVoterBalance       ProposalType = 6 // Change the minimum voter balance
+	ProposalProposalDepositModify ProposalType = 7 // Change the deposit required to propose
+)
+
+// proposalKeys maps every proposal type to the argument key of its value.
+var proposalKeys = map[ProposalType]string{
+	ProposalCandidateAdd:          "candidate",
+	ProposalCandidateRemove:       "candidate",
+	ProposalMinerRewardModify:     "mrpt",
+	ProposalSideChainAdd:          "schash",
+	ProposalSideChainRemove:       "schash",
+	ProposalMinVoterBalance:       "mvb",
+	ProposalProposalDepositModify: "mpd",
+}
+
+// Proposal requests a change of a chain parameter, to be decided upon by the
+// signers through declarations within the given number of loops. Only the value
+// field matching the proposal type is set.
+type Proposal struct {
+	Type       ProposalType `json:"proposalType"`
+	ValidLoops uint64       `json:"validLoops"`
+
+	Candidate              *common.Address `json:"candidate,omitempty"`
+	MinerRewardPerThousand *uint64         `json:"minerRewardPerThousand,omitempty"`
+	SideChainHash          *common.Hash    `json:"sideChainHash,omitempty"`
+	MinVoterBalance        *big.Int        `json:"minVoterBalance,omitempty"`
+	ProposalDeposit        *big.Int        `json:"proposalDeposit,omitempty"`
+}
+
+func (p *Proposal) Category() Category { return CategoryEvent }
+func (p *Proposal) Event() Event       { return EventProposal }
+
+func (p *Proposal) args() []string {
+	args := []string{
+		"proposal_type", strconv.FormatUint(uint64(p.Type), 10),
+		"vlcnt", strconv.FormatUint(p.ValidLoops, 10),
+	}
+	var value string
+	switch p.Type {
+	case ProposalCandidateAdd, ProposalCandidateRemove:
+		if p.Candidate != nil {
+			value = hexutil.Encode(p.Candidate[:])
+		}
+	case ProposalMinerRewardModify:
+		if p.MinerRewardPerThousand != nil {
+			value = strconv.FormatUint(*p.MinerRewardPerThousand, 10)
+		}
+	case ProposalSideChainAdd, ProposalSideChainRemove:
+		if p.SideChainHash != nil {
+			value = hexutil.Encode(p.SideChainHash[:])
+		}
+	case ProposalMinVoterBalance:
+		if p.MinVoterBalance != nil {
+			value = p.MinVoterBalance.String()
+		}
+	case ProposalProposalDepositModify:
+		if p.ProposalDeposit != nil {
+			value = p.ProposalDeposit.String()
+		}
+	}
+	if key, ok := proposalKeys[p.Type]; ok {
+		args = append(args, key, value)
+	}
+	return args
+}
+
+// NewProposal creates a proposal of the given type, parsing value the same way
+// as the corresponding payload field.
+func NewProposal(typ ProposalType, validLoops uint64, value string) (*Proposal, error) {
+	key, ok := proposalKeys[typ]
+	if !ok {
+		return nil, malformed("unknown proposal type %d", typ)
+	}
+	return decodeProposal([]string{
+		"proposal_type", strconv.FormatUint(uint64(typ), 10),
+		"vlcnt", strconv.FormatUint(validLoops, 10),
+		key, value,
+	})
+}
+
+func decodeProposal(args []string) (*Proposal, error) {
+	if len(args)%2 != 0 {
+		return nil, malformed("proposal arguments must be key/value pairs")
+	}
+	values := make(map[string]string)
+	for i := 0; i < len(args); i += 2 {
+		if _, ok := values[args[i]]; ok {
+			return nil, malformed("duplicate proposal key %q", args[i])
+		}
+		values[args[i]] = args[i+1]
+	}
+	typ, err := parseUint(values["proposal_type"])
+	if err != nil {
+		return nil, malformed("invalid proposal type %q", values["proposal_type"])
+	}
+	proposal := &Proposal{Type: ProposalType(typ)}
+	key, ok := proposalKeys[proposal.Type]
+	if !ok {
+		return nil, malformed("unknown proposal type %d", typ)
+	}
+	if proposal.ValidLoops, err = parseUint(values["vlcnt"]); err != nil {
+		return nil, malformed("invalid valid loop count %q", values["vlcnt"])
+	}
+	if len(values) != 3 {
+		return nil, malformed("proposal type %d takes exactly the %q value", typ, key)
+	}
+	value, ok := values[key]
+	if !ok {
+		return nil, malformed("proposal type %d is missing the %q value", typ, key)
+	}
+	switch proposal.Type {
+	case ProposalCandidateAdd, ProposalCandidateRemove:
+		addr, err := parseAddress(value)
+		if err != nil {
+			return nil, malformed("invalid candidate %q", value)
+		}
+		proposal.Candidate = &addr
+
+	case ProposalMinerRewardModify:
+		reward, err := parseUint(value)
+		if err != nil || reward > 1000 {
+			return nil, malformed("invalid miner reward per thousand %q", value)
+		}
+		proposal.MinerRewardPerThousand = &reward
+
+	case ProposalSideChainAdd, ProposalSideChainRemove:
+		hash, err := parseHash(value)
+		if err != nil {
+			return nil, malformed("invalid side chain hash %q", value)
+		}
+		proposal.SideChainHash = &hash
+
+	case ProposalMinVoterBalance:
+		if proposal.MinVoterBalance, err = parseBig(value); err != nil {
+			return nil, malformed("invalid min voter balance %q", value)
+		}
+
+	case ProposalProposalDepositModify:
+		if proposal.ProposalDeposit, err = parseBig(value); err != nil {
+			return nil, malformed("invalid proposal deposit %q", value)
+		}
+	}
+	return proposal, nil
+}
+
+// Declare is a signer's decision on a pending proposal.
+type Declare struct {
+	ProposalHash common.Hash `json:"proposalHash"` // Hash of the transaction carrying the proposal
+	Decision     bool        `json:"decision"`     // Whether the signer agrees with the proposal
+}
+
+func (d *Declare) Category() Category { return CategoryEvent }
+func (d *Declare) Event() Event       { return EventDeclare }
+
+func (d *Declare) args() []string {
+	decision := "no"
+	if d.Decision {
+		decision = "yes"
+	}
+	return []string{"hash", hexutil.Encode(d.ProposalHash[:]), "decision", decision}
+}
+
+func decodeDeclare(args []string) (*Declare, error) {
+	if len(args) != 4 || args[0] != "hash" || args[2] != "decision" {
+		return nil, malformed("declare takes a hash and a decision")
+	}
+	hash, err := parseHash(args[1])
+	if err != nil {
+		return nil, malformed("invalid proposal hash %q", args[1])
+	}
+	declare := &Declare{ProposalHash: hash}
+	switch args[3] {
+	case "yes":
+		declare.Decision = true
+	case "no":
+		declare.Decision = false
+	default:
+		return nil, malformed("invalid decision %q", args[3])
+	}
+	return declare, nil
+}
+
+// SideChainNotify is sent by a side chain signer to the main chain to announce a
+// block of the side chain.
+type SideChainNotify struct {
+	ChainHash common.Hash `json:"chainHash"` // Genesis hash identifying the side chain
+	Number    uint64      `json:"number"`    // Number of the announced side chain block
+	BlockHash common.Hash `json:"blockHash"` // Hash of the announced side chain block
+}
+
+func (n *SideChainNotify) Category() Category { return CategorySideChain }
+func (n *SideChainNotify) Event() Event       { return EventSideChainNotify }
+
+func (n *SideChainNotify) args() []string {
+	return []string{hexutil.Encode(n.ChainHash[:]), strconv.FormatUint(n.Number, 10), hexutil.Encode(n.BlockHash[:])}
+}
+
+func decodeSideChainNotify(args []string) (*SideChainNotify, error) {
+	if len(args) != 3 {
+		return nil, malformed("side chain notify takes a chain hash, a number and a block hash")
+	}
+	chain, err := parseHash(args[0])
+	if err != nil {
+		return nil, malformed("invalid side chain hash %q", args[0])
+	}
+	number, err := parseUint(args[1])
+	if err != nil {
+		return nil, malformed("invalid block number %q", args[1])
+	}
+	block, err := parseHash(args[2])
+	if err != nil {
+		return nil, malformed("invalid block hash %q", args[2])
+	}
+	return &SideChainNotify{ChainHash: chain, Number: number, BlockHash: block}, nil
+}
+
+// parseUint parses a canonical decimal unsigned integer.
+func parseUint(s string) (uint64, error) {
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if strconv.FormatUint(n, 10) != s {
+		return 0, fmt.Errorf("non-canonical number %q", s)
+	}
+	return n, nil
+}
+
+// parseBig parses a canonical decimal non-negative big integer.
+func parseBig(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 || n.String() != s {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+// parseHash parses a 32 byte hex hash, accepting any of the custom hex prefixes.
+func parseHash(s string) (common.Hash, error) {
+	b, err := hexutil.Decode(hexutil.CPToHex(s))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(b) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("invalid hash length %d", len(b))
+	}
+	return common.BytesToHash(b), nil
+}
+
+// parseAddress parses a 20 byte hex address, accepting any of the custom hex
+// prefixes.
+func parseAddress(s string) (common.Address, error) {
+	b, err := hexutil.Decode(hexutil.CPToHex(s))
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(b) != common.AddressLength {
+		return common.Address{}, fmt.Errorf("invalid address length %d", len(b))
+	}
+	return common.BytesToAddress(b), nil
+}
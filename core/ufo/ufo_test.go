@@ -0,0 +1,147 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package ufo
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+)
+
+var (
+	testAddr = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	testHash = common.HexToHash("0x0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	testMrpt = uint64(618)
+
+	testAddrHex = hexutil.Encode(testAddr[:])
+	testHashHex = hexutil.Encode(testHash[:])
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		payload Payload
+		encoded string
+	}{
+		{&Vote{}, "ufo:1:event:vote"},
+		{&Confirm{Number: 123}, "ufo:1:event:confirm:123"},
+		{
+			&Proposal{Type: ProposalCandidateAdd, ValidLoops: 2, Candidate: &testAddr},
+			"ufo:1:event:proposal:proposal_type:1:vlcnt:2:candidate:" + testAddrHex,
+		},
+		{
+			&Proposal{Type: ProposalMinerRewardModify, ValidLoops: 1, MinerRewardPerThousand: &testMrpt},
+			"ufo:1:event:proposal:proposal_type:3:vlcnt:1:mrpt:618",
+		},
+		{
+			&Proposal{Type: ProposalSideChainAdd, ValidLoops: 3, SideChainHash: &testHash},
+			"ufo:1:event:proposal:proposal_type:4:vlcnt:3:schash:" + testHashHex,
+		},
+		{
+			&Proposal{Type: ProposalMinVoterBalance, ValidLoops: 1, MinVoterBalance: big.NewInt(100)},
+			"ufo:1:event:proposal:proposal_type:6:vlcnt:1:mvb:100",
+		},
+		{&Declare{ProposalHash: testHash, Decision: true}, "ufo:1:event:declare:hash:" + testHashHex + ":decision:yes"},
+		{&Declare{ProposalHash: testHash}, "ufo:1:event:declare:hash:" + testHashHex + ":decision:no"},
+		{
+			&SideChainNotify{ChainHash: testHash, Number: 7, BlockHash: testHash},
+			"ufo:1:sc:notify:" + testHashHex + ":7:" + testHashHex,
+		},
+	}
+	for i, tt := range tests {
+		if enc := string(Encode(tt.payload)); enc != tt.encoded {
+			t.Errorf("test %d: encoding mismatch: have %s, want %s", i, enc, tt.encoded)
+			continue
+		}
+		dec, err := Decode([]byte(tt.encoded))
+		if err != nil {
+			t.Errorf("test %d: failed to decode: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(dec, tt.payload) {
+			t.Errorf("test %d: decoded payload mismatch: have %+v, want %+v", i, dec, tt.payload)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   error
+	}{
+		{"", ErrNotUfo},
+		{"hello", ErrNotUfo},
+		{"ufo", ErrNotUfo},
+		{"ufo:1:event", ErrMalformed},
+		{"ufo:2:event:vote", ErrUnsupportedVersion},
+		{"ufo:01:event:vote", ErrUnsupportedVersion},
+		{"ufo:x:event:vote", ErrUnsupportedVersion},
+		{"ufo:1:event:unknown", ErrUnknownEvent},
+		{"ufo:1:sc:vote", ErrUnknownEvent},
+		{"ufo:1:event:vote:1", ErrMalformed},
+		{"ufo:1:event:confirm", ErrMalformed},
+		{"ufo:1:event:confirm:-1", ErrMalformed},
+		{"ufo:1:event:confirm:0123", ErrMalformed},
+		{"ufo:1:event:confirm:1:2", ErrMalformed},
+		{"ufo:1:event:proposal:proposal_type:1:vlcnt:2", ErrMalformed},
+		{"ufo:1:event:proposal:proposal_type:9:vlcnt:2:candidate:" + testAddrHex, ErrMalformed},
+		{"ufo:1:event:proposal:proposal_type:1:vlcnt:2:candidate:0x1234", ErrMalformed},
+		{"ufo:1:event:proposal:proposal_type:1:vlcnt:2:mrpt:10", ErrMalformed},
+		{"ufo:1:event:proposal:proposal_type:3:vlcnt:2:mrpt:1001", ErrMalformed},
+		{"ufo:1:event:proposal:proposal_type:3:vlcnt:2:vlcnt:2", ErrMalformed},
+		{"ufo:1:event:proposal:proposal_type:3:vlcnt:2:mrpt:1:mvb:1", ErrMalformed},
+		{"ufo:1:event:declare:hash:" + testHashHex + ":decision:maybe", ErrMalformed},
+		{"ufo:1:event:declare:hash:0x12:decision:yes", ErrMalformed},
+		{"ufo:1:sc:notify:" + testHashHex + ":7", ErrMalformed},
+	}
+	for i, tt := range tests {
+		_, err := Decode([]byte(tt.input))
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err.Error()) {
+			t.Errorf("test %d (%q): error mismatch: have %v, want %v", i, tt.input, err, tt.err)
+		}
+	}
+}
+
+func TestDecodeCustomHexPrefix(t *testing.T) {
+	input := "ufo:1:event:declare:hash:" + hexutil.HexToCP(testHashHex) + ":decision:yes"
+	dec, err := Decode([]byte(input))
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if want := (&Declare{ProposalHash: testHash, Decision: true}); !reflect.DeepEqual(dec, want) {
+		t.Errorf("decoded payload mismatch: have %+v, want %+v", dec, want)
+	}
+}
+
+func TestNewProposal(t *testing.T) {
+	proposal, err := NewProposal(ProposalCandidateRemove, 4, testAddrHex)
+	if err != nil {
+		t.Fatalf("failed to create proposal: %v", err)
+	}
+	if want := (&Proposal{Type: ProposalCandidateRemove, ValidLoops: 4, Candidate: &testAddr}); !reflect.DeepEqual(proposal, want) {
+		t.Errorf("proposal mismatch: have %+v, want %+v", proposal, want)
+	}
+	if _, err := NewProposal(ProposalMinerRewardModify, 4, "lots"); err == nil {
+		t.Errorf("invalid proposal value accepted")
+	}
+	if _, err := NewProposal(ProposalType(0), 4, "1"); err == nil {
+		t.Errorf("unknown proposal type accepted")
+	}
+}
@@ -19,7 +19,23 @@ package vm
 import (
 	"math/big"
 
+	"github.com/hashicorp/golang-lru"
 	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/metrics"
+)
+
+// analysisCacheSize is the number of code analyses kept in the shared cache. As
+// the bitmap is an eighth of the code size, a full cache of maximum sized
+// contracts takes up about 12MB.
+const analysisCacheSize = 4096
+
+var (
+	// analysisCache holds the code analysis of recently executed contracts by
+	// code hash, shared by every EVM instance of the process.
+	analysisCache, _ = lru.New(analysisCacheSize)
+
+	analysisHitCounter  = metrics.NewRegisteredCounter("vm/analysis/hits", nil)
+	analysisMissCounter = metrics.NewRegisteredCounter("vm/analysis/misses", nil)
 )
 
 // destinations stores one map per contract (keyed by hash of code).
@@ -38,12 +54,30 @@ func (d destinations) has(codehash common.Hash, code []byte, dest *big.Int) bool
 
 	m, analysed := d[codehash]
 	if !analysed {
-		m = codeBitmap(code)
+		m = analyse(codehash, code)
 		d[codehash] = m
 	}
 	return OpCode(code[udest]) == JUMPDEST && m.codeSegment(udest)
 }
 
+// analyse returns the code analysis of the given code, retrieving it from the
+// shared cache if the code was analysed before. Code without a hash (i.e. not
+// yet deployed) is analysed but not cached.
+func analyse(codehash common.Hash, code []byte) bitvec {
+	if codehash == (common.Hash{}) {
+		return codeBitmap(code)
+	}
+	if cached, ok := analysisCache.Get(codehash); ok {
+		analysisHitCounter.Inc(1)
+		return cached.(bitvec)
+	}
+	analysisMissCounter.Inc(1)
+
+	bits := codeBitmap(code)
+	analysisCache.Add(codehash, bits)
+	return bits
+}
+
 // bitvec is a bit vector which maps bytes in a program.
 // An unset bit means the byte is an opcode, a set bit means
 // it's data (i.e. argument of PUSHxx).
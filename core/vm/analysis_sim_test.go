@@ -0,0 +1,120 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm_test
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/relianz2019/relianz/accounts/abi"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/contracts/ens/contract"
+	"github.com/relianz2019/relianz/core"
+	"github.com/relianz2019/relianz/core/state"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/core/vm"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/params"
+)
+
+// Benchmarks simulating a block worth of signed transactions calling a compiled
+// contract against a copy of the pending state, the way pooled transactions are
+// executed ahead of their inclusion, with and without the shared code analysis
+// cache.
+func BenchmarkAnalysisTxSimulation(b *testing.B) {
+	const txs = 200
+
+	var (
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		config   = params.TestChainConfig
+		signer   = types.MakeSigner(config, big.NewInt(1))
+		header   = &types.Header{Number: big.NewInt(1), Time: big.NewInt(0), Difficulty: big.NewInt(0), GasLimit: txs * 100000}
+		coinbase = common.Address{0xc0}
+		sdb      = state.NewDatabase(ethdb.NewMemDatabase())
+	)
+	statedb, _ := state.New(common.Hash{}, sdb)
+	statedb.SetBalance(sender, new(big.Int).Mul(big.NewInt(params.Rlzer), big.NewInt(100)))
+
+	// Deploy the ENS public resolver and sign the calls to it
+	resolver, err := abi.JSON(strings.NewReader(contract.PublicResolverABI))
+	if err != nil {
+		b.Fatalf("failed to parse resolver abi: %v", err)
+	}
+	ctor, err := resolver.Pack("", common.Address{0xe5})
+	if err != nil {
+		b.Fatalf("failed to pack constructor: %v", err)
+	}
+	deploy, _ := types.SignTx(types.NewContractCreation(0, new(big.Int), 3000000, big.NewInt(1), append(common.FromHex(contract.PublicResolverBin), ctor...)), signer, key)
+	apply := func(statedb *state.StateDB, tx *types.Transaction, gp *core.GasPool) error {
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return err
+		}
+		vmenv := vm.NewEVM(core.NewEVMContext(msg, header, nil, &coinbase), statedb, config, vm.Config{})
+		if _, _, failed, err := core.ApplyMessage(vmenv, msg, gp); err != nil {
+			return err
+		} else if failed {
+			return errors.New("execution failed")
+		}
+		return nil
+	}
+	if err := apply(statedb, deploy, new(core.GasPool).AddGas(header.GasLimit)); err != nil {
+		b.Fatalf("failed to deploy resolver: %v", err)
+	}
+	root, _ := statedb.Commit(true)
+	statedb, _ = state.New(root, sdb)
+
+	address := crypto.CreateAddress(sender, 0)
+	if len(statedb.GetCode(address)) == 0 {
+		b.Fatal("resolver not deployed")
+	}
+	pending := make([]*types.Transaction, txs)
+	for i := range pending {
+		var data []byte
+		if i%2 == 0 {
+			data, err = resolver.Pack("addr", [32]byte{byte(i)})
+		} else {
+			data, err = resolver.Pack("supportsInterface", [4]byte{0x3b, 0x3b, 0x57, 0xde})
+		}
+		if err != nil {
+			b.Fatalf("failed to pack call: %v", err)
+		}
+		pending[i], _ = types.SignTx(types.NewTransaction(uint64(i+1), address, new(big.Int), 100000, big.NewInt(1), data), signer, key)
+	}
+	simulate := func(b *testing.B, cached bool) {
+		for i := 0; i < b.N; i++ {
+			var (
+				simulated = statedb.Copy()
+				gp        = new(core.GasPool).AddGas(header.GasLimit)
+			)
+			for _, tx := range pending {
+				if !cached {
+					vm.PurgeAnalysisCache()
+				}
+				if err := apply(simulated, tx, gp); err != nil {
+					b.Fatalf("transaction %x: %v", tx.Hash(), err)
+				}
+			}
+		}
+	}
+	b.Run("uncached", func(b *testing.B) { simulate(b, false) })
+	b.Run("cached", func(b *testing.B) { simulate(b, true) })
+}
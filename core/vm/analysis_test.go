@@ -16,7 +16,15 @@
 
 package vm
 
-import "testing"
+import (
+	"math/big"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/state"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/params"
+)
 
 func TestJumpDestAnalysis(t *testing.T) {
 	tests := []struct {
@@ -51,3 +59,87 @@ func TestJumpDestAnalysis(t *testing.T) {
 	}
 
 }
+
+// Tests that code analyses are shared through the cache by code hash, while code
+// without a hash is never cached.
+func TestAnalysisCache(t *testing.T) {
+	analysisCache.Purge()
+	defer analysisCache.Purge()
+
+	var (
+		code = []byte{byte(PUSH1), byte(JUMPDEST), byte(JUMPDEST)}
+		hash = common.Hash{0x01}
+		dest = big.NewInt(1)
+	)
+	if (destinations{}).has(common.Hash{}, code, dest) {
+		t.Error("push data reported as jump destination")
+	}
+	if analysisCache.Len() != 0 {
+		t.Errorf("unhashed code cached: have %d entries", analysisCache.Len())
+	}
+	if !(destinations{}).has(hash, code, big.NewInt(2)) {
+		t.Error("jump destination not found")
+	}
+	if !analysisCache.Contains(hash) {
+		t.Fatal("analysis not cached")
+	}
+	// Subsequent contexts must pick the cached analysis up, even for other code
+	analysisCache.Add(hash, codeBitmap([]byte{byte(JUMPDEST), byte(JUMPDEST)}))
+	if !(destinations{}).has(hash, code, dest) {
+		t.Error("cached analysis not used")
+	}
+}
+
+// repeatedCallCode is a synthetic contract analysed on every call, jumping over
+// a maximum sized block of push data to its exit.
+func repeatedCallCode() []byte {
+	var (
+		size = params.MaxCodeSize - 2
+		code = make([]byte, 0, params.MaxCodeSize)
+	)
+	code = append(code, byte(PUSH2), byte(size>>8), byte(size), byte(JUMP))
+	for len(code)+33 <= size {
+		code = append(code, byte(PUSH32))
+		code = append(code, make([]byte, 32)...)
+	}
+	for len(code) < size {
+		code = append(code, byte(POP))
+	}
+	return append(code, byte(JUMPDEST), byte(STOP))
+}
+
+// Benchmarks calling the same synthetic contract many times, each call in a fresh
+// EVM, with and without the shared code analysis cache. The analysis dominates
+// the calls, bounding the speedup the cache can bring.
+func BenchmarkAnalysisRepeatedCalls(b *testing.B) {
+	const txs = 200
+
+	var (
+		address    = common.BytesToAddress([]byte("contract"))
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+		config     = &params.ChainConfig{ChainId: big.NewInt(1), HomesteadBlock: big.NewInt(0), ByzantiumBlock: big.NewInt(0)}
+		vmctx      = Context{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: big.NewInt(0),
+		}
+	)
+	statedb.CreateAccount(address)
+	statedb.SetCode(address, repeatedCallCode())
+
+	replay := func(b *testing.B, cached bool) {
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < txs; j++ {
+				if !cached {
+					analysisCache.Purge()
+				}
+				vmenv := NewEVM(vmctx, statedb, config, Config{})
+				if _, _, err := vmenv.Call(AccountRef(common.Address{}), address, nil, 100000, new(big.Int)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+	b.Run("uncached", func(b *testing.B) { replay(b, false) })
+	b.Run("cached", func(b *testing.B) { replay(b, true) })
+}
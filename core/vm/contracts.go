@@ -18,6 +18,7 @@ package vm
 
 import (
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"math/big"
 
@@ -59,6 +60,50 @@ var PrecompiledContractsByzantium = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{8}): &bn256Pairing{},
 }
 
+// PrecompiledContractsIstanbul contains the default set of pre-compiled Relianz
+// contracts used in the Istanbul release.
+var PrecompiledContractsIstanbul = map[common.Address]PrecompiledContract{
+	common.BytesToAddress([]byte{1}): &ecrecover{},
+	common.BytesToAddress([]byte{2}): &sha256hash{},
+	common.BytesToAddress([]byte{3}): &ripemd160hash{},
+	common.BytesToAddress([]byte{4}): &dataCopy{},
+	common.BytesToAddress([]byte{5}): &bigModExp{},
+	common.BytesToAddress([]byte{6}): &bn256AddIstanbul{},
+	common.BytesToAddress([]byte{7}): &bn256ScalarMulIstanbul{},
+	common.BytesToAddress([]byte{8}): &bn256PairingIstanbul{},
+	common.BytesToAddress([]byte{9}): &blake2F{},
+}
+
+// ActivePrecompiles returns the set of pre-compiled contracts active at the
+// given block number of the chain, including the custom contracts activated by
+// the chain configuration. Custom contracts without a registered implementation
+// are skipped, ValidatePrecompiles is expected to have rejected them already.
+func ActivePrecompiles(config *params.ChainConfig, num *big.Int) map[common.Address]PrecompiledContract {
+	var precompiles map[common.Address]PrecompiledContract
+	switch {
+	case config.IsIstanbul(num):
+		precompiles = PrecompiledContractsIstanbul
+	case config.IsByzantium(num):
+		precompiles = PrecompiledContractsByzantium
+	default:
+		precompiles = PrecompiledContractsHomestead
+	}
+	custom := config.ActivePrecompiles(num)
+	if len(custom) == 0 {
+		return precompiles
+	}
+	active := make(map[common.Address]PrecompiledContract, len(precompiles)+len(custom))
+	for addr, p := range precompiles {
+		active[addr] = p
+	}
+	for _, precompile := range custom {
+		if p, ok := registeredPrecompile(precompile.Name); ok {
+			active[precompile.Address] = p
+		}
+	}
+	return active
+}
+
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
 func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
 	gas := p.RequiredGas(input)
@@ -293,6 +338,15 @@ func (c *bn256Add) Run(input []byte) ([]byte, error) {
 	return res.Marshal(), nil
 }
 
+// bn256AddIstanbul implements a native elliptic curve point addition priced
+// according to the Istanbul gas schedule.
+type bn256AddIstanbul struct{ bn256Add }
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bn256AddIstanbul) RequiredGas(input []byte) uint64 {
+	return params.Bn256AddGasIstanbul
+}
+
 // bn256ScalarMul implements a native elliptic curve scalar multiplication.
 type bn256ScalarMul struct{}
 
@@ -311,6 +365,15 @@ func (c *bn256ScalarMul) Run(input []byte) ([]byte, error) {
 	return res.Marshal(), nil
 }
 
+// bn256ScalarMulIstanbul implements a native elliptic curve scalar
+// multiplication priced according to the Istanbul gas schedule.
+type bn256ScalarMulIstanbul struct{ bn256ScalarMul }
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bn256ScalarMulIstanbul) RequiredGas(input []byte) uint64 {
+	return params.Bn256ScalarMulGasIstanbul
+}
+
 var (
 	// true32Byte is returned if the bn256 pairing check succeeds.
 	true32Byte = []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
@@ -358,3 +421,72 @@ func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
 	}
 	return false32Byte, nil
 }
+
+// bn256PairingIstanbul implements a pairing pre-compile for the bn256 curve
+// priced according to the Istanbul gas schedule.
+type bn256PairingIstanbul struct{ bn256Pairing }
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bn256PairingIstanbul) RequiredGas(input []byte) uint64 {
+	return params.Bn256PairingBaseGasIstanbul + uint64(len(input)/192)*params.Bn256PairingPerPointGasIstanbul
+}
+
+const blake2FInputLength = 213
+
+var (
+	// errBlake2FInvalidInputLength is returned if the blake2 compression input
+	// is not exactly 213 bytes long.
+	errBlake2FInvalidInputLength = errors.New("invalid input length")
+
+	// errBlake2FInvalidFinalFlag is returned if the final block indicator flag
+	// of the blake2 compression input is neither 0 nor 1.
+	errBlake2FInvalidFinalFlag = errors.New("invalid final flag")
+)
+
+// blake2F implements the BLAKE2b compression function F as a native contract.
+// The input is the big endian number of rounds (4 bytes), the state vector h
+// (64 bytes), the message block m (128 bytes), the offset counters t (16 bytes)
+// and the final block indicator flag f (1 byte), with h, m and t encoded as
+// little endian words.
+type blake2F struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract,
+// which is one per round.
+func (c *blake2F) RequiredGas(input []byte) uint64 {
+	if len(input) != blake2FInputLength {
+		// The input is rejected by Run, don't charge for the rounds
+		return 0
+	}
+	return uint64(binary.BigEndian.Uint32(input[0:4]))
+}
+
+func (c *blake2F) Run(input []byte) ([]byte, error) {
+	if len(input) != blake2FInputLength {
+		return nil, errBlake2FInvalidInputLength
+	}
+	var final bool
+	switch input[212] {
+	case 0:
+	case 1:
+		final = true
+	default:
+		return nil, errBlake2FInvalidFinalFlag
+	}
+	var (
+		rounds = binary.BigEndian.Uint32(input[0:4])
+		h      [8]uint64
+		m      [16]uint64
+		t      [2]uint64
+	)
+	decodeBlake2bWords(h[:], input[4:68])
+	decodeBlake2bWords(m[:], input[68:196])
+	decodeBlake2bWords(t[:], input[196:212])
+
+	blake2bF(&h, &m, t, final, rounds)
+
+	output := make([]byte, 64)
+	for i := range h {
+		binary.LittleEndian.PutUint64(output[i*8:], h[i])
+	}
+	return output, nil
+}
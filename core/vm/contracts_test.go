@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/params"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -32,6 +33,14 @@ type precompiledTest struct {
 	noBenchmark     bool // Benchmark primarily the worst-cases
 }
 
+// precompiledFailureTest defines the input/error pairs for precompiled
+// contract failure tests.
+type precompiledFailureTest struct {
+	input         string
+	expectedError error
+	name          string
+}
+
 // modexpTests are the test and benchmark data for the modexp precompiled contract.
 var modexpTests = []precompiledTest{
 	{
@@ -336,8 +345,58 @@ var bn256PairingTests = []precompiledTest{
 	},
 }
 
+// blake2FTests are the test and benchmark data for the blake2 compression
+// precompiled contract, taken from EIP 152.
+var blake2FTests = []precompiledTest{
+	{
+		input:    "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b6162630000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000030000000000000000000000000000000001",
+		expected: "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923",
+		name:     "vector 5",
+	},
+	{
+		input:    "0000000048c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b6162630000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000030000000000000000000000000000000001",
+		expected: "08c9bcf367e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d282e6ad7f520e511f6c3e2b8c68059b9442be0454267ce079217e1319cde05b",
+		name:     "vector 4",
+	},
+	{
+		input:    "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b6162630000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000030000000000000000000000000000000000",
+		expected: "75ab69d3190a562c51aef8d88f1c2775876944407270c42c9844252c26d2875298743e7f6d5ea2f2d3e8d226039cd31b4e426ac4f2d3d666a610c2116fde4735",
+		name:     "vector 6",
+	},
+	{
+		input:    "0000000148c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b6162630000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000030000000000000000000000000000000001",
+		expected: "b63a380cb2897d521994a85234ee2c181b5f844d2c624c002677e9703449d2fba551b3a8333bcdf5f2f7e08993d53923de3d64fcc68c034e717b9293fed7a421",
+		name:     "vector 7",
+	},
+}
+
+// blake2FMalformedTests are the malformed inputs the blake2 compression
+// precompiled contract must reject, taken from EIP 152.
+var blake2FMalformedTests = []precompiledFailureTest{
+	{
+		input:         "",
+		expectedError: errBlake2FInvalidInputLength,
+		name:          "vector 0: empty input",
+	},
+	{
+		input:         "00000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b6162630000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000030000000000000000000000000000000001",
+		expectedError: errBlake2FInvalidInputLength,
+		name:          "vector 1: less than 213 bytes input",
+	},
+	{
+		input:         "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b616263000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000003000000000000000000000000000000000100",
+		expectedError: errBlake2FInvalidInputLength,
+		name:          "vector 2: more than 213 bytes input",
+	},
+	{
+		input:         "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b6162630000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000030000000000000000000000000000000002",
+		expectedError: errBlake2FInvalidFinalFlag,
+		name:          "vector 3: malformed final block indicator flag",
+	},
+}
+
 func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
-	p := PrecompiledContractsByzantium[common.HexToAddress(addr)]
+	p := PrecompiledContractsIstanbul[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(test.input)
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
 		nil, new(big.Int), p.RequiredGas(in))
@@ -350,11 +409,23 @@ func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
 	})
 }
 
+func testPrecompiledFailure(addr string, test precompiledFailureTest, t *testing.T) {
+	p := PrecompiledContractsIstanbul[common.HexToAddress(addr)]
+	in := common.Hex2Bytes(test.input)
+	contract := NewContract(AccountRef(common.HexToAddress("1337")),
+		nil, new(big.Int), p.RequiredGas(in))
+	t.Run(test.name, func(t *testing.T) {
+		if _, err := RunPrecompiledContract(p, in, contract); err != test.expectedError {
+			t.Errorf("Expected error %v, got %v", test.expectedError, err)
+		}
+	})
+}
+
 func benchmarkPrecompiled(addr string, test precompiledTest, bench *testing.B) {
 	if test.noBenchmark {
 		return
 	}
-	p := PrecompiledContractsByzantium[common.HexToAddress(addr)]
+	p := PrecompiledContractsIstanbul[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(test.input)
 	reqGas := p.RequiredGas(in)
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
@@ -481,3 +552,62 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+// Tests the gas prices of the elliptic curve precompiles before and after the
+// Istanbul repricing of EIP 1108.
+func TestPrecompiledBn256Repricing(t *testing.T) {
+	pairing := common.Hex2Bytes(bn256PairingTests[0].input)
+	tests := []struct {
+		addr                byte
+		input               []byte
+		byzantium, istanbul uint64
+	}{
+		{6, nil, 500, 150},
+		{7, nil, 40000, 6000},
+		{8, nil, 100000, 45000},
+		{8, pairing, 100000 + uint64(len(pairing)/192)*80000, 45000 + uint64(len(pairing)/192)*34000},
+	}
+	for i, tt := range tests {
+		addr := common.BytesToAddress([]byte{tt.addr})
+		if gas := PrecompiledContractsByzantium[addr].RequiredGas(tt.input); gas != tt.byzantium {
+			t.Errorf("test %d: byzantium gas mismatch: have %d, want %d", i, gas, tt.byzantium)
+		}
+		if gas := PrecompiledContractsIstanbul[addr].RequiredGas(tt.input); gas != tt.istanbul {
+			t.Errorf("test %d: istanbul gas mismatch: have %d, want %d", i, gas, tt.istanbul)
+		}
+	}
+}
+
+// Tests the sample inputs of the blake2 compression function from EIP 152.
+func TestPrecompiledBlake2F(t *testing.T) {
+	for _, test := range blake2FTests {
+		testPrecompiled("09", test, t)
+	}
+}
+
+// Benchmarks the sample inputs of the blake2 compression function from EIP 152.
+func BenchmarkPrecompiledBlake2F(bench *testing.B) {
+	for _, test := range blake2FTests {
+		benchmarkPrecompiled("09", test, bench)
+	}
+}
+
+// Tests the malformed inputs of the blake2 compression function from EIP 152.
+func TestPrecompiledBlake2FMalformed(t *testing.T) {
+	for _, test := range blake2FMalformedTests {
+		testPrecompiledFailure("09", test, t)
+	}
+}
+
+// Tests that the blake2 compression function is not available before Istanbul.
+func TestPrecompiledBlake2FActivation(t *testing.T) {
+	config := &params.ChainConfig{ByzantiumBlock: big.NewInt(0), IstanbulBlock: big.NewInt(10)}
+	addr := common.BytesToAddress([]byte{9})
+
+	if _, ok := ActivePrecompiles(config, big.NewInt(9))[addr]; ok {
+		t.Error("blake2 compression available before Istanbul")
+	}
+	if _, ok := ActivePrecompiles(config, big.NewInt(10))[addr]; !ok {
+		t.Error("blake2 compression missing from Istanbul")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/core/state"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/params"
+)
+
+var sstoreTests = []struct {
+	original byte
+	kalgan   bool
+	gaspool  uint64
+	input    string
+	used     uint64
+	refund   uint64
+	failure  bool
+}{
+	{0, true, 100000, "0x60006000556000600055", 1612, 0, false},                 // 0 -> 0 -> 0
+	{0, true, 100000, "0x60006000556001600055", 20812, 0, false},                // 0 -> 0 -> 1
+	{0, true, 100000, "0x60016000556000600055", 20812, 19200, false},            // 0 -> 1 -> 0
+	{0, true, 100000, "0x60016000556002600055", 20812, 0, false},                // 0 -> 1 -> 2
+	{0, true, 100000, "0x60016000556001600055", 20812, 0, false},                // 0 -> 1 -> 1
+	{1, true, 100000, "0x60006000556000600055", 5812, 15000, false},             // 1 -> 0 -> 0
+	{1, true, 100000, "0x60006000556001600055", 5812, 4200, false},              // 1 -> 0 -> 1
+	{1, true, 100000, "0x60006000556002600055", 5812, 0, false},                 // 1 -> 0 -> 2
+	{1, true, 100000, "0x60026000556000600055", 5812, 15000, false},             // 1 -> 2 -> 0
+	{1, true, 100000, "0x60026000556003600055", 5812, 0, false},                 // 1 -> 2 -> 3
+	{1, true, 100000, "0x60026000556001600055", 5812, 4200, false},              // 1 -> 2 -> 1
+	{1, true, 100000, "0x60026000556002600055", 5812, 0, false},                 // 1 -> 2 -> 2
+	{1, true, 100000, "0x60016000556000600055", 5812, 15000, false},             // 1 -> 1 -> 0
+	{1, true, 100000, "0x60016000556002600055", 5812, 0, false},                 // 1 -> 1 -> 2
+	{1, true, 100000, "0x60016000556001600055", 1612, 0, false},                 // 1 -> 1 -> 1
+	{0, true, 100000, "0x600160005560006000556001600055", 40818, 19200, false},  // 0 -> 1 -> 0 -> 1
+	{1, true, 100000, "0x600060005560016000556000600055", 10818, 19200, false},  // 1 -> 0 -> 1 -> 0
+	{1, true, 2306, "0x6001600055", 2306, 0, true},                              // 1 -> 1, stipend left
+	{1, true, 2307, "0x6001600055", 806, 0, false},                              // 1 -> 1, sentry passed
+	{0, false, 100000, "0x60006000556000600055", 10012, 0, false},               // 0 -> 0 -> 0, flat pricing
+	{0, false, 100000, "0x60016000556000600055", 25012, 15000, false},           // 0 -> 1 -> 0, flat pricing
+	{1, false, 100000, "0x600060005560016000556000600055", 30018, 30000, false}, // 1 -> 0 -> 1 -> 0, flat pricing
+}
+
+// Tests that SSTORE is charged with net gas metering once the Kalgan fork is
+// active, and with the flat pricing before.
+func TestSStoreGas(t *testing.T) {
+	for i, tt := range sstoreTests {
+		address := common.BytesToAddress([]byte("contract"))
+
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+		statedb.CreateAccount(address)
+		statedb.SetCode(address, hexutil.MustDecode(tt.input))
+		statedb.SetState(address, common.Hash{}, common.BytesToHash([]byte{tt.original}))
+		statedb.Finalise(true) // Push the state into the "original" slot
+
+		config := &params.ChainConfig{
+			ChainId:        big.NewInt(1),
+			HomesteadBlock: big.NewInt(0),
+			EIP150Block:    big.NewInt(0),
+			EIP155Block:    big.NewInt(0),
+			EIP158Block:    big.NewInt(0),
+			ByzantiumBlock: big.NewInt(0),
+			Alien:          new(params.AlienConfig),
+		}
+		if tt.kalgan {
+			config.Alien.KalganBlock = big.NewInt(0)
+		}
+		vmctx := Context{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: big.NewInt(0),
+		}
+		vmenv := NewEVM(vmctx, statedb, config, Config{})
+
+		_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, tt.gaspool, new(big.Int))
+		if (err != nil) != tt.failure {
+			t.Errorf("test %d: failure mismatch: have %v, want failure %v", i, err, tt.failure)
+		}
+		if used := tt.gaspool - gas; used != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %v, want %v", i, used, tt.used)
+		}
+		if refund := vmenv.StateDB.GetRefund(); refund != tt.refund {
+			t.Errorf("test %d: gas refund mismatch: have %v, want %v", i, refund, tt.refund)
+		}
+	}
+}
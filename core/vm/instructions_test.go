@@ -0,0 +1,154 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/core/state"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/params"
+)
+
+// Tests the CREATE2 address derivation and gas cost against the examples of
+// EIP-1014.
+func TestCreate2Addresses(t *testing.T) {
+	type testcase struct {
+		origin   string
+		salt     string
+		code     string
+		expected string
+	}
+	for i, tt := range []testcase{
+		{
+			origin:   "0x0000000000000000000000000000000000000000",
+			salt:     "0x0000000000000000000000000000000000000000",
+			code:     "0x00",
+			expected: "0x4d1a2e2bb4f88f0250f26ffff098b0b30b26bf38",
+		},
+		{
+			origin:   "0xdeadbeef00000000000000000000000000000000",
+			salt:     "0x0000000000000000000000000000000000000000",
+			code:     "0x00",
+			expected: "0xB928f69Bb1D91Cd65274e3c79d8986362984fDA3",
+		},
+		{
+			origin:   "0xdeadbeef00000000000000000000000000000000",
+			salt:     "0xfeed000000000000000000000000000000000000",
+			code:     "0x00",
+			expected: "0xD04116cDd17beBE565EB2422F2497E06cC1C9833",
+		},
+		{
+			origin:   "0x0000000000000000000000000000000000000000",
+			salt:     "0x0000000000000000000000000000000000000000",
+			code:     "0xdeadbeef",
+			expected: "0x70f2b2914A2a4b783FaEFb75f459A580616Fcb5e",
+		},
+		{
+			origin:   "0x00000000000000000000000000000000deadbeef",
+			salt:     "0xcafebabe",
+			code:     "0xdeadbeef",
+			expected: "0x60f3f640a8508fC6a86d45DF051962668E1e8AC7",
+		},
+		{
+			origin:   "0x00000000000000000000000000000000deadbeef",
+			salt:     "0xcafebabe",
+			code:     "0xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+			expected: "0x1d8bfDC5D46DC4f61D6b6115972536eBE6A8854C",
+		},
+		{
+			origin:   "0x0000000000000000000000000000000000000000",
+			salt:     "0x0000000000000000000000000000000000000000",
+			code:     "0x",
+			expected: "0xE33C0C7F7df4809055C3ebA6c09CFe4BaF1BD9e0",
+		},
+	} {
+		origin := common.BytesToAddress(common.FromHex(tt.origin))
+		salt := common.BytesToHash(common.FromHex(tt.salt))
+		code := common.FromHex(tt.code)
+		codeHash := crypto.Keccak256(code)
+
+		address := CreateAddress2(origin, salt, codeHash)
+		if expected := common.BytesToAddress(common.FromHex(tt.expected)); address != expected {
+			t.Errorf("test %d: address mismatch: have %x, want %x", i, address, expected)
+		}
+		// The deployment code is charged per hashed word on top of the base cost
+		stack := newstack()
+		stack.push(new(big.Int).SetBytes(salt.Bytes()))
+		stack.push(big.NewInt(int64(len(code))))
+		stack.push(big.NewInt(0))
+		stack.push(big.NewInt(0))
+
+		gas, err := gasCreate2(params.GasTableConstantinople, nil, nil, stack, nil, 0)
+		if err != nil {
+			t.Fatalf("test %d: failed to calculate gas: %v", i, err)
+		}
+		if expected := params.Create2Gas + params.Sha3WordGas*toWordSize(uint64(len(code))); gas != expected {
+			t.Errorf("test %d: gas mismatch: have %d, want %d", i, gas, expected)
+		}
+	}
+}
+
+// Tests that EXTCODEHASH distinguishes missing and empty accounts from existing
+// accounts without code, as specified by EIP-1052.
+func TestOpExtCodeHash(t *testing.T) {
+	var (
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+		env        = NewEVM(Context{}, statedb, params.TestChainConfig, Config{})
+		code       = hexutil.MustDecode("0x6001600055")
+
+		missing  = common.Address{0x01}
+		empty    = common.Address{0x02}
+		funded   = common.Address{0x03}
+		contract = common.Address{0x04}
+		suicided = common.Address{0x05}
+		deleted  = common.Address{0x06}
+	)
+	statedb.CreateAccount(empty)
+	statedb.AddBalance(funded, big.NewInt(1))
+	statedb.SetCode(contract, code)
+	statedb.SetCode(suicided, code)
+	statedb.SetCode(deleted, code)
+	statedb.Suicide(deleted)
+	statedb.Finalise(true)
+	statedb.Suicide(suicided)
+
+	tests := []struct {
+		address common.Address
+		want    common.Hash
+	}{
+		{missing, common.Hash{}},
+		{empty, common.Hash{}},
+		{funded, emptyCodeHash},
+		{contract, crypto.Keccak256Hash(code)},
+		{suicided, crypto.Keccak256Hash(code)},
+		{deleted, common.Hash{}},
+	}
+	for i, tt := range tests {
+		stack := newstack()
+		stack.push(new(big.Int).SetBytes(tt.address.Bytes()))
+		opExtCodeHash(new(uint64), env, nil, nil, stack)
+
+		if have := common.BigToHash(stack.pop()); have != tt.want {
+			t.Errorf("test %d: hash mismatch for %x: have %x, want %x", i, tt.address, have, tt.want)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"sync/atomic"
 
+	"github.com/relianz2019/relianz/common"
 	"github.com/relianz2019/relianz/common/math"
 	"github.com/relianz2019/relianz/params"
 )
@@ -39,6 +40,10 @@ type Config struct {
 	// may be left uninitialised and will be set to the default
 	// table.
 	JumpTable [256]operation
+	// Precompiles contains the pre-compiled contracts callable
+	// by address. This may be left uninitialised and will be
+	// set to the default set.
+	Precompiles map[common.Address]PrecompiledContract
 }
 
 // Interpreter is used to run Relianz based contracts and will utilise the
@@ -62,6 +67,8 @@ func NewInterpreter(evm *EVM, cfg Config) *Interpreter {
 	// we'll set the default jump table.
 	if !cfg.JumpTable[STOP].valid {
 		switch {
+		case evm.ChainConfig().IsIstanbul(evm.BlockNumber):
+			cfg.JumpTable = istanbulInstructionSet
 		case evm.ChainConfig().IsConstantinople(evm.BlockNumber):
 			cfg.JumpTable = constantinopleInstructionSet
 		case evm.ChainConfig().IsByzantium(evm.BlockNumber):
@@ -0,0 +1,180 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/params"
+)
+
+// SignerQueuePrecompile is the name the built-in pre-compiled contract exposing
+// the Alien signer queue is registered under.
+const SignerQueuePrecompile = "alienSignerQueue"
+
+var (
+	// revertSelector is the ABI selector of Error(string), the revert reason
+	// format of Solidity.
+	revertSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+
+	// errNoSignerQueue is returned by the signer queue precompile if the EVM
+	// context cannot provide the signer queue.
+	errNoSignerQueue = errors.New("signer queue unavailable")
+
+	// precompileRegistry holds the custom pre-compiled contract implementations
+	// by name, ready to be activated through the chain configuration.
+	precompileRegistry = map[string]PrecompiledContract{
+		SignerQueuePrecompile: &signerQueue{},
+	}
+	precompileRegistryLock sync.RWMutex
+)
+
+// ContextualPrecompiledContract is a pre-compiled contract depending on the
+// context of the EVM it is called in, not just on its input.
+type ContextualPrecompiledContract interface {
+	PrecompiledContract
+
+	// Bind returns the contract to run within the given EVM context. Any gas
+	// needed to gather the context is to be charged from the calling contract
+	// before doing so.
+	Bind(ctx Context, contract *Contract) (PrecompiledContract, error)
+}
+
+// RegisterPrecompile registers a custom pre-compiled contract implementation
+// under the given name. The contract is only callable once a precompile of the
+// chain configuration activates it, so registrations are expected to happen at
+// node setup, before any chain is opened.
+func RegisterPrecompile(name string, p PrecompiledContract) error {
+	if name == "" {
+		return errors.New("unnamed precompile")
+	}
+	precompileRegistryLock.Lock()
+	defer precompileRegistryLock.Unlock()
+
+	if _, ok := precompileRegistry[name]; ok {
+		return fmt.Errorf("precompile %q already registered", name)
+	}
+	precompileRegistry[name] = p
+	return nil
+}
+
+// registeredPrecompile retrieves the custom pre-compiled contract registered
+// under the given name.
+func registeredPrecompile(name string) (PrecompiledContract, bool) {
+	precompileRegistryLock.RLock()
+	defer precompileRegistryLock.RUnlock()
+
+	p, ok := precompileRegistry[name]
+	return p, ok
+}
+
+// ValidatePrecompiles verifies that every custom pre-compiled contract of the
+// chain configuration has a registered implementation and does not shadow a
+// built-in contract. Nodes missing an implementation would otherwise silently
+// diverge from the network once the contract activates.
+func ValidatePrecompiles(config *params.ChainConfig) error {
+	if err := config.CheckPrecompiles(); err != nil {
+		return err
+	}
+	for _, precompile := range config.Precompiles {
+		if _, ok := registeredPrecompile(precompile.Name); !ok {
+			return fmt.Errorf("precompile %q not registered", precompile.Name)
+		}
+		if _, ok := PrecompiledContractsIstanbul[precompile.Address]; ok {
+			return fmt.Errorf("precompile %q shadows built-in contract at %x", precompile.Name, precompile.Address)
+		}
+	}
+	return nil
+}
+
+// signerQueue implements a pre-compiled contract returning the signer queue the
+// parent block was sealed in, ABI encoded as an address array.
+type signerQueue struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *signerQueue) RequiredGas(input []byte) uint64 {
+	return params.SignerQueueBaseGas
+}
+
+func (c *signerQueue) Run(input []byte) ([]byte, error) {
+	return nil, errNoSignerQueue
+}
+
+// Bind returns the signer queue contract reading from the given EVM context. The
+// base price is charged before reading the queue from the database. If the queue
+// is unavailable, the call reverts with the reason instead of consuming all gas.
+func (c *signerQueue) Bind(ctx Context, contract *Contract) (PrecompiledContract, error) {
+	if !contract.UseGas(params.SignerQueueBaseGas) {
+		return nil, ErrOutOfGas
+	}
+	if ctx.GetSignerQueue == nil {
+		return &revertedPrecompile{reason: errNoSignerQueue}, nil
+	}
+	queue, err := ctx.GetSignerQueue()
+	if err != nil {
+		return &revertedPrecompile{reason: err}, nil
+	}
+	return &boundSignerQueue{queue: queue}, nil
+}
+
+// boundSignerQueue is the signer queue contract bound to an EVM context, with
+// the base price already paid.
+type boundSignerQueue struct {
+	queue []common.Address
+}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *boundSignerQueue) RequiredGas(input []byte) uint64 {
+	return uint64(len(c.queue)) * params.SignerQueuePerSignerGas
+}
+
+func (c *boundSignerQueue) Run(input []byte) ([]byte, error) {
+	output := make([]byte, 64+32*len(c.queue))
+	output[31] = 32 // Offset of the dynamic array
+	binary.BigEndian.PutUint64(output[56:64], uint64(len(c.queue)))
+	for i, signer := range c.queue {
+		copy(output[64+32*i+12:], signer[:])
+	}
+	return output, nil
+}
+
+// revertedPrecompile is a bound pre-compiled contract whose context could not be
+// gathered. It reverts, returning the reason ABI encoded as an Error(string).
+type revertedPrecompile struct {
+	reason error
+}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *revertedPrecompile) RequiredGas(input []byte) uint64 {
+	return 0
+}
+
+func (c *revertedPrecompile) Run(input []byte) ([]byte, error) {
+	reason := []byte(c.reason.Error())
+
+	output := make([]byte, 4+64+(len(reason)+31)/32*32)
+	copy(output, revertSelector)
+	output[4+31] = 32 // Offset of the reason string
+	binary.BigEndian.PutUint64(output[4+56:4+64], uint64(len(reason)))
+	copy(output[4+64:], reason)
+
+	return output, errExecutionReverted
+}
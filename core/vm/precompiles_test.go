@@ -0,0 +1,169 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/state"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/params"
+)
+
+// testPrecompile is a custom pre-compiled contract echoing its input.
+type testPrecompile struct{}
+
+func (c *testPrecompile) RequiredGas(input []byte) uint64  { return 1 }
+func (c *testPrecompile) Run(input []byte) ([]byte, error) { return input, nil }
+
+func TestValidatePrecompiles(t *testing.T) {
+	// Restore the global registry afterwards, keeping repeated runs independent
+	precompileRegistryLock.Lock()
+	registry := make(map[string]PrecompiledContract, len(precompileRegistry))
+	for name, p := range precompileRegistry {
+		registry[name] = p
+	}
+	precompileRegistryLock.Unlock()
+
+	defer func() {
+		precompileRegistryLock.Lock()
+		precompileRegistry = registry
+		precompileRegistryLock.Unlock()
+	}()
+	if err := RegisterPrecompile("testValidate", &testPrecompile{}); err != nil {
+		t.Fatalf("failed to register precompile: %v", err)
+	}
+	if err := RegisterPrecompile("testValidate", &testPrecompile{}); err == nil {
+		t.Error("duplicate registration accepted")
+	}
+	tests := []struct {
+		precompiles []params.PrecompileConfig
+		fail        bool
+	}{
+		{precompiles: nil},
+		{precompiles: []params.PrecompileConfig{{Name: "testValidate", Address: common.BytesToAddress([]byte{0x01, 0x00}), Block: big.NewInt(0)}}},
+		{precompiles: []params.PrecompileConfig{{Name: SignerQueuePrecompile, Address: common.BytesToAddress([]byte{0x01, 0x01})}}},
+		{precompiles: []params.PrecompileConfig{{Name: "testMissing", Address: common.BytesToAddress([]byte{0x01, 0x00})}}, fail: true},
+		{precompiles: []params.PrecompileConfig{{Name: "testValidate", Address: common.BytesToAddress([]byte{0x09})}}, fail: true},
+		{precompiles: []params.PrecompileConfig{{Address: common.BytesToAddress([]byte{0x01, 0x00})}}, fail: true},
+		{precompiles: []params.PrecompileConfig{
+			{Name: "testValidate", Address: common.BytesToAddress([]byte{0x01, 0x00})},
+			{Name: SignerQueuePrecompile, Address: common.BytesToAddress([]byte{0x01, 0x00})},
+		}, fail: true},
+	}
+	for i, test := range tests {
+		err := ValidatePrecompiles(&params.ChainConfig{Precompiles: test.precompiles})
+		if test.fail && err == nil {
+			t.Errorf("test %d: expected failure", i)
+		}
+		if !test.fail && err != nil {
+			t.Errorf("test %d: unexpected failure: %v", i, err)
+		}
+	}
+}
+
+func TestActivePrecompiles(t *testing.T) {
+	addr := common.BytesToAddress([]byte{0x01, 0x02})
+	config := &params.ChainConfig{
+		ByzantiumBlock: big.NewInt(0),
+		Precompiles:    []params.PrecompileConfig{{Name: SignerQueuePrecompile, Address: addr, Block: big.NewInt(10)}},
+	}
+	if _, ok := ActivePrecompiles(config, big.NewInt(9))[addr]; ok {
+		t.Error("custom precompile active before its block")
+	}
+	active := ActivePrecompiles(config, big.NewInt(10))
+	if _, ok := active[addr]; !ok {
+		t.Error("custom precompile missing after its block")
+	}
+	if len(active) != len(PrecompiledContractsByzantium)+1 {
+		t.Errorf("active precompile count mismatch: have %d, want %d", len(active), len(PrecompiledContractsByzantium)+1)
+	}
+	if _, ok := PrecompiledContractsByzantium[addr]; ok {
+		t.Error("custom precompile leaked into the built-in set")
+	}
+}
+
+func TestSignerQueuePrecompile(t *testing.T) {
+	var (
+		addr   = common.BytesToAddress([]byte{0x01, 0x03})
+		queue  = []common.Address{{0x01}, {0x02}, {0x03}}
+		config = &params.ChainConfig{
+			ChainId:        big.NewInt(1),
+			HomesteadBlock: big.NewInt(0),
+			EIP150Block:    big.NewInt(0),
+			EIP155Block:    big.NewInt(0),
+			EIP158Block:    big.NewInt(0),
+			ByzantiumBlock: big.NewInt(0),
+			Precompiles:    []params.PrecompileConfig{{Name: SignerQueuePrecompile, Address: addr, Block: big.NewInt(0)}},
+		}
+	)
+	want := make([]byte, 64+32*len(queue))
+	want[31], want[63] = 32, byte(len(queue))
+	for i, signer := range queue {
+		copy(want[64+32*i+12:], signer[:])
+	}
+	gas := params.SignerQueueBaseGas + uint64(len(queue))*params.SignerQueuePerSignerGas
+
+	// revert returns the Error(string) revert output for the given reason.
+	revert := func(reason string) []byte {
+		output := make([]byte, 4+64+(len(reason)+31)/32*32)
+		copy(output, []byte{0x08, 0xc3, 0x79, 0xa0})
+		output[4+31], output[4+63] = 32, byte(len(reason))
+		copy(output[4+64:], reason)
+		return output
+	}
+	tests := []struct {
+		queue   GetSignerQueueFunc
+		gaspool uint64
+		output  []byte
+		used    uint64
+		failure bool
+	}{
+		{queue: func() ([]common.Address, error) { return queue, nil }, gaspool: gas, output: want, used: gas},
+		{queue: func() ([]common.Address, error) { return queue, nil }, gaspool: gas - 1, used: gas - 1, failure: true},
+		// The queue must not be read before the base price is paid
+		{queue: func() ([]common.Address, error) { panic("queue read without gas") }, gaspool: params.SignerQueueBaseGas - 1, used: params.SignerQueueBaseGas - 1, failure: true},
+		// Unavailable queues revert, refunding the gas left
+		{queue: func() ([]common.Address, error) { return nil, errors.New("no queue") }, gaspool: gas, output: revert("no queue"), used: params.SignerQueueBaseGas, failure: true},
+		{queue: nil, gaspool: gas, output: revert(errNoSignerQueue.Error()), used: params.SignerQueueBaseGas, failure: true},
+	}
+	for i, tt := range tests {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+		vmctx := Context{
+			CanTransfer:    func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:       func(StateDB, common.Address, common.Address, *big.Int) {},
+			GetSignerQueue: tt.queue,
+			BlockNumber:    big.NewInt(0),
+		}
+		vmenv := NewEVM(vmctx, statedb, config, Config{})
+
+		output, left, err := vmenv.Call(AccountRef(common.Address{}), addr, nil, tt.gaspool, new(big.Int))
+		if (err != nil) != tt.failure {
+			t.Errorf("test %d: failure mismatch: have %v, want failure %v", i, err, tt.failure)
+		}
+		if !bytes.Equal(output, tt.output) {
+			t.Errorf("test %d: output mismatch: have %x, want %x", i, output, tt.output)
+		}
+		if used := tt.gaspool - left; used != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %d, want %d", i, used, tt.used)
+		}
+	}
+}
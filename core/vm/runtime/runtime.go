@@ -17,8 +17,10 @@
 package runtime
 
 import (
+	"fmt"
 	"math"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/relianz2019/relianz/common"
@@ -33,6 +35,7 @@ import (
 // the EVM.
 type Config struct {
 	ChainConfig *params.ChainConfig
+	Fork        string // Fork to target if no chain config is given, see ForkChainConfig
 	Difficulty  *big.Int
 	Origin      common.Address
 	Coinbase    common.Address
@@ -48,15 +51,53 @@ type Config struct {
 	GetHashFn func(n uint64) common.Hash
 }
 
+// forks are the names of the forks ForkChainConfig can target, in activation
+// order.
+var forks = []string{"Frontier", "Homestead", "EIP150", "EIP155", "EIP158", "Byzantium", "Constantinople", "Istanbul"}
+
+// ForkChainConfig returns a chain configuration with the named fork and all the
+// ones preceding it activated from the genesis block on. The fork name is
+// matched case insensitively.
+func ForkChainConfig(fork string) (*params.ChainConfig, error) {
+	config := &params.ChainConfig{ChainId: big.NewInt(1)}
+	blocks := []**big.Int{
+		nil, // Frontier has no switch block
+		&config.HomesteadBlock,
+		&config.EIP150Block,
+		&config.EIP155Block,
+		&config.EIP158Block,
+		&config.ByzantiumBlock,
+		&config.ConstantinopleBlock,
+		&config.IstanbulBlock,
+	}
+	for i, name := range forks {
+		if blocks[i] != nil {
+			*blocks[i] = new(big.Int)
+		}
+		if strings.EqualFold(name, fork) {
+			return config, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown fork %q, want one of %s", fork, strings.Join(forks, ", "))
+}
+
 // sets defaults on the config
-func setDefaults(cfg *Config) {
+func setDefaults(cfg *Config) error {
 	if cfg.ChainConfig == nil {
-		cfg.ChainConfig = &params.ChainConfig{
-			ChainId:        big.NewInt(1),
-			HomesteadBlock: new(big.Int),
-			EIP150Block:    new(big.Int),
-			EIP155Block:    new(big.Int),
-			EIP158Block:    new(big.Int),
+		if cfg.Fork != "" {
+			config, err := ForkChainConfig(cfg.Fork)
+			if err != nil {
+				return err
+			}
+			cfg.ChainConfig = config
+		} else {
+			cfg.ChainConfig = &params.ChainConfig{
+				ChainId:        big.NewInt(1),
+				HomesteadBlock: new(big.Int),
+				EIP150Block:    new(big.Int),
+				EIP155Block:    new(big.Int),
+				EIP158Block:    new(big.Int),
+			}
 		}
 	}
 
@@ -83,6 +124,7 @@ func setDefaults(cfg *Config) {
 			return common.BytesToHash(crypto.Keccak256([]byte(new(big.Int).SetUint64(n).String())))
 		}
 	}
+	return nil
 }
 
 // Execute executes the code using the input as call data during the execution.
@@ -94,8 +136,9 @@ func Execute(code, input []byte, cfg *Config) ([]byte, *state.StateDB, error) {
 	if cfg == nil {
 		cfg = new(Config)
 	}
-	setDefaults(cfg)
-
+	if err := setDefaults(cfg); err != nil {
+		return nil, nil, err
+	}
 	if cfg.State == nil {
 		cfg.State, _ = state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
 	}
@@ -124,8 +167,9 @@ func Create(input []byte, cfg *Config) ([]byte, common.Address, uint64, error) {
 	if cfg == nil {
 		cfg = new(Config)
 	}
-	setDefaults(cfg)
-
+	if err := setDefaults(cfg); err != nil {
+		return nil, common.Address{}, 0, err
+	}
 	if cfg.State == nil {
 		cfg.State, _ = state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
 	}
@@ -150,8 +194,9 @@ func Create(input []byte, cfg *Config) ([]byte, common.Address, uint64, error) {
 // Call, unlike Execute, requires a config and also requires the State field to
 // be set.
 func Call(address common.Address, input []byte, cfg *Config) ([]byte, uint64, error) {
-	setDefaults(cfg)
-
+	if err := setDefaults(cfg); err != nil {
+		return nil, 0, err
+	}
 	vmenv := NewEnv(cfg)
 
 	sender := cfg.State.GetOrNewStateObject(cfg.Origin)
@@ -116,6 +116,40 @@ func TestCall(t *testing.T) {
 	}
 }
 
+func TestForkOpcodes(t *testing.T) {
+	code := []byte{
+		byte(vm.CHAINID),
+		byte(vm.PUSH1), 0,
+		byte(vm.MSTORE),
+		byte(vm.SELFBALANCE),
+		byte(vm.PUSH1), 32,
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 64,
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	}
+	state, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	state.AddBalance(common.BytesToAddress([]byte("contract")), big.NewInt(42))
+
+	ret, _, err := Execute(code, nil, &Config{Fork: "istanbul", State: state})
+	if err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	if id := new(big.Int).SetBytes(ret[:32]); id.Cmp(big.NewInt(1)) != 0 {
+		t.Error("Expected chain id 1, got", id)
+	}
+	if balance := new(big.Int).SetBytes(ret[32:]); balance.Cmp(big.NewInt(42)) != 0 {
+		t.Error("Expected balance 42, got", balance)
+	}
+	// The opcodes must be rejected prior to Istanbul
+	if _, _, err := Execute(code, nil, &Config{Fork: "Constantinople"}); err == nil {
+		t.Error("expected invalid opcode error before Istanbul")
+	}
+	if _, _, err := Execute(code, nil, &Config{Fork: "Serenity"}); err == nil {
+		t.Error("expected unknown fork error")
+	}
+}
+
 func BenchmarkCall(b *testing.B) {
 	var definition = `[{"constant":true,"inputs":[],"name":"seller","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"abort","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"value","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"constant":false,"inputs":[],"name":"refund","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"buyer","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmReceived","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"state","outputs":[{"name":"","type":"uint8"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmPurchase","outputs":[],"type":"function"},{"inputs":[],"type":"constructor"},{"anonymous":false,"inputs":[],"name":"Aborted","type":"event"},{"anonymous":false,"inputs":[],"name":"PurchaseConfirmed","type":"event"},{"anonymous":false,"inputs":[],"name":"ItemReceived","type":"event"},{"anonymous":false,"inputs":[],"name":"Refunded","type":"event"}]`
 
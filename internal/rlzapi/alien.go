@@ -0,0 +1,140 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/core/ufo"
+	"github.com/relianz2019/relianz/rpc"
+)
+
+var (
+	errNotAlien       = errors.New("chain is not running the Alien consensus engine")
+	errNoVoteToChange = errors.New("no vote counted for voter, cast one first")
+	errSameCandidate  = errors.New("voter already votes for candidate")
+)
+
+// VoteStatus is the state of a voter's stake in the Alien signer election.
+type VoteStatus struct {
+	Voter           common.Address   `json:"voter"`
+	BlockNumber     hexutil.Uint64   `json:"blockNumber"`     // Block the status was evaluated at
+	Stake           *hexutil.Big     `json:"stake"`           // Balance the vote is weighted with
+	MinVoterBalance *hexutil.Big     `json:"minVoterBalance"` // Minimum balance for a vote to be counted
+	StakeCounts     bool             `json:"stakeCounts"`     // Whether the stake reaches the minimum
+	Candidate       *common.Address  `json:"candidate"`       // Candidate the vote is counted for, nil if none
+	CandidateSigner bool             `json:"candidateSigner"` // Whether the candidate is projected to seal the next loop
+	NextSigners     []common.Address `json:"nextSigners"`     // Signers projected to seal the next loop
+}
+
+// PublicAlienAPI provides an API to inspect the Alien signer election.
+type PublicAlienAPI struct {
+	b Backend
+}
+
+// NewPublicAlienAPI creates a new Alien election API.
+func NewPublicAlienAPI(b Backend) *PublicAlienAPI {
+	return &PublicAlienAPI{b}
+}
+
+// CheckVote reports the voter's stake against the minimum voter balance, the
+// candidate its vote is counted for and the signers projected to seal the next
+// loop at the given block, defaulting to the latest one.
+func (s *PublicAlienAPI) CheckVote(ctx context.Context, voter common.Address, blockNr *rpc.BlockNumber) (*VoteStatus, error) {
+	config := s.b.ChainConfig().Alien
+	if config == nil {
+		return nil, errNotAlien
+	}
+	number := rpc.LatestBlockNumber
+	if blockNr != nil {
+		number = *blockNr
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, number)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	stake := state.GetBalance(voter)
+	if err := state.Error(); err != nil {
+		return nil, err
+	}
+	minBalance := new(big.Int)
+	if config.MinVoterBalance != nil {
+		minBalance.Set(config.MinVoterBalance)
+	}
+	status := &VoteStatus{
+		Voter:           voter,
+		BlockNumber:     hexutil.Uint64(header.Number.Uint64()),
+		Stake:           (*hexutil.Big)(stake),
+		MinVoterBalance: (*hexutil.Big)(minBalance),
+		StakeCounts:     stake.Cmp(minBalance) >= 0,
+	}
+	candidate, voted, err := s.b.VoteOf(ctx, header, voter)
+	if err != nil {
+		return nil, err
+	}
+	if status.NextSigners, err = s.b.NextSigners(ctx, header); err != nil {
+		return nil, err
+	}
+	if voted {
+		status.Candidate = &candidate
+		for _, signer := range status.NextSigners {
+			if signer == candidate {
+				status.CandidateSigner = true
+				break
+			}
+		}
+	}
+	return status, nil
+}
+
+// Vote casts the stake of the from account for the candidate by sending it a
+// signed Alien vote transaction. The key of the from account is decrypted with
+// passwd. A later vote for another candidate replaces this one.
+func (s *PrivateAccountAPI) Vote(ctx context.Context, from common.Address, candidate common.Address, passwd string) (common.Hash, error) {
+	if s.b.ChainConfig().Alien == nil {
+		return common.Hash{}, errNotAlien
+	}
+	data := hexutil.Bytes(ufo.Encode(&ufo.Vote{}))
+	return s.SendTransaction(ctx, SendTxArgs{From: from, To: &candidate, Data: &data}, passwd)
+}
+
+// ChangeVote moves the stake of the from account, which must already have a
+// vote counted, over to another candidate.
+func (s *PrivateAccountAPI) ChangeVote(ctx context.Context, from common.Address, candidate common.Address, passwd string) (common.Hash, error) {
+	if s.b.ChainConfig().Alien == nil {
+		return common.Hash{}, errNotAlien
+	}
+	header, err := s.b.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if header == nil || err != nil {
+		return common.Hash{}, err
+	}
+	current, voted, err := s.b.VoteOf(ctx, header, from)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if !voted {
+		return common.Hash{}, errNoVoteToChange
+	}
+	if current == candidate {
+		return common.Hash{}, errSameCandidate
+	}
+	return s.Vote(ctx, from, candidate, passwd)
+}
@@ -0,0 +1,154 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core/state"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/params"
+	"github.com/relianz2019/relianz/rpc"
+)
+
+// alienTestBackend is an API backend serving a single head block along with a
+// static Alien election. Methods not needed by the tests panic.
+type alienTestBackend struct {
+	Backend
+
+	config  *params.ChainConfig
+	state   *state.StateDB
+	header  *types.Header
+	votes   map[common.Address]common.Address
+	signers []common.Address
+}
+
+func newAlienTestBackend(config *params.ChainConfig) *alienTestBackend {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	return &alienTestBackend{
+		config: config,
+		state:  statedb,
+		header: &types.Header{Number: big.NewInt(10)},
+		votes:  make(map[common.Address]common.Address),
+	}
+}
+
+func (b *alienTestBackend) ChainConfig() *params.ChainConfig { return b.config }
+
+func (b *alienTestBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	return b.header, nil
+}
+
+func (b *alienTestBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	return b.state, b.header, nil
+}
+
+func (b *alienTestBackend) VoteOf(ctx context.Context, header *types.Header, voter common.Address) (common.Address, bool, error) {
+	candidate, ok := b.votes[voter]
+	return candidate, ok, nil
+}
+
+func (b *alienTestBackend) NextSigners(ctx context.Context, header *types.Header) ([]common.Address, error) {
+	return b.signers, nil
+}
+
+// Tests that the vote status reports the stake against the minimum voter
+// balance and whether the voted candidate is projected to seal the next loop.
+func TestCheckVote(t *testing.T) {
+	alien := *params.AllAlienProtocolChanges.Alien
+	alien.MinVoterBalance = big.NewInt(1000)
+
+	config := *params.AllAlienProtocolChanges
+	config.Alien = &alien
+
+	var (
+		backend   = newAlienTestBackend(&config)
+		api       = NewPublicAlienAPI(backend)
+		elected   = common.Address{0x01}
+		unelected = common.Address{0x02}
+		rich      = common.Address{0xa1}
+		poor      = common.Address{0xa2}
+		idle      = common.Address{0xa3}
+	)
+	backend.state.AddBalance(rich, big.NewInt(1000))
+	backend.state.AddBalance(poor, big.NewInt(999))
+	backend.votes[rich] = elected
+	backend.votes[poor] = unelected
+	backend.signers = []common.Address{elected}
+
+	tests := []struct {
+		voter     common.Address
+		counts    bool
+		candidate *common.Address
+		signer    bool
+	}{
+		{rich, true, &elected, true},
+		{poor, false, &unelected, false},
+		{idle, false, nil, false},
+	}
+	for i, tt := range tests {
+		status, err := api.CheckVote(context.Background(), tt.voter, nil)
+		if err != nil {
+			t.Fatalf("test %d: failed to check vote: %v", i, err)
+		}
+		if status.Voter != tt.voter || uint64(status.BlockNumber) != 10 {
+			t.Errorf("test %d: status header mismatch: have %x at %d", i, status.Voter, status.BlockNumber)
+		}
+		if status.StakeCounts != tt.counts {
+			t.Errorf("test %d: stake counting mismatch: have %v, want %v", i, status.StakeCounts, tt.counts)
+		}
+		if !reflect.DeepEqual(status.Candidate, tt.candidate) {
+			t.Errorf("test %d: candidate mismatch: have %v, want %v", i, status.Candidate, tt.candidate)
+		}
+		if status.CandidateSigner != tt.signer {
+			t.Errorf("test %d: candidate signer mismatch: have %v, want %v", i, status.CandidateSigner, tt.signer)
+		}
+		if !reflect.DeepEqual(status.NextSigners, backend.signers) {
+			t.Errorf("test %d: next signers mismatch: have %x, want %x", i, status.NextSigners, backend.signers)
+		}
+	}
+}
+
+// Tests that changing a vote requires a vote to be counted for another
+// candidate, and that the election calls are refused on non-Alien chains.
+func TestChangeVote(t *testing.T) {
+	var (
+		backend   = newAlienTestBackend(params.AllAlienProtocolChanges)
+		api       = NewPrivateAccountAPI(backend, new(AddrLocker))
+		voter     = common.Address{0xa1}
+		candidate = common.Address{0x01}
+	)
+	if _, err := api.ChangeVote(context.Background(), voter, candidate, ""); err != errNoVoteToChange {
+		t.Errorf("change without vote error mismatch: have %v, want %v", err, errNoVoteToChange)
+	}
+	backend.votes[voter] = candidate
+	if _, err := api.ChangeVote(context.Background(), voter, candidate, ""); err != errSameCandidate {
+		t.Errorf("change to same candidate error mismatch: have %v, want %v", err, errSameCandidate)
+	}
+	backend.config = params.TestChainConfig
+	if _, err := api.ChangeVote(context.Background(), voter, common.Address{0x02}, ""); err != errNotAlien {
+		t.Errorf("non-alien change error mismatch: have %v, want %v", err, errNotAlien)
+	}
+	if _, err := NewPublicAlienAPI(backend).CheckVote(context.Background(), voter, nil); err != errNotAlien {
+		t.Errorf("non-alien check error mismatch: have %v, want %v", err, errNotAlien)
+	}
+}
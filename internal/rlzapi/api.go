@@ -34,6 +34,7 @@ import (
 	"github.com/relianz2019/relianz/core"
 	"github.com/relianz2019/relianz/core/rawdb"
 	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/core/ufo"
 	"github.com/relianz2019/relianz/core/vm"
 	"github.com/relianz2019/relianz/crypto"
 	"github.com/relianz2019/relianz/log"
@@ -95,6 +96,53 @@ func (s *PublicRelianzAPI) Syncing() (interface{}, error) {
 	}, nil
 }
 
+// DecodeUfoPayload parses the data of an Alien consensus transaction into its
+// typed fields, returning an error if the payload is malformed.
+func (s *PublicRelianzAPI) DecodeUfoPayload(data hexutil.Bytes) (map[string]interface{}, error) {
+	payload, err := ufo.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{
+		"version":  hexutil.Uint(ufo.Version),
+		"category": payload.Category(),
+		"event":    payload.Event(),
+	}
+	switch p := payload.(type) {
+	case *ufo.Confirm:
+		fields["number"] = hexutil.Uint64(p.Number)
+
+	case *ufo.Proposal:
+		fields["proposalType"] = hexutil.Uint64(p.Type)
+		fields["validLoops"] = hexutil.Uint64(p.ValidLoops)
+		if p.Candidate != nil {
+			fields["candidate"] = *p.Candidate
+		}
+		if p.MinerRewardPerThousand != nil {
+			fields["minerRewardPerThousand"] = hexutil.Uint64(*p.MinerRewardPerThousand)
+		}
+		if p.SideChainHash != nil {
+			fields["sideChainHash"] = *p.SideChainHash
+		}
+		if p.MinVoterBalance != nil {
+			fields["minVoterBalance"] = (*hexutil.Big)(p.MinVoterBalance)
+		}
+		if p.ProposalDeposit != nil {
+			fields["proposalDeposit"] = (*hexutil.Big)(p.ProposalDeposit)
+		}
+
+	case *ufo.Declare:
+		fields["proposalHash"] = p.ProposalHash
+		fields["decision"] = p.Decision
+
+	case *ufo.SideChainNotify:
+		fields["chainHash"] = p.ChainHash
+		fields["number"] = hexutil.Uint64(p.Number)
+		fields["blockHash"] = p.BlockHash
+	}
+	return fields, nil
+}
+
 // PublicTxPoolAPI offers and API for the transaction pool. It only operates on data that is non confidential.
 type PublicTxPoolAPI struct {
 	b Backend
@@ -117,7 +165,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -125,19 +173,49 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["queued"][account.Hex()] = dump
 	}
 	return content
 }
 
+// Rejections creates a subscription streaming the structured reason of every
+// transaction refused by an admission hook of the pool.
+func (s *PublicTxPoolAPI) Rejections(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		rejections := make(chan core.TxRejectedEvent, 128)
+		sub := s.b.SubscribeTxRejectedEvent(rejections)
+
+		for {
+			select {
+			case ev := <-rejections:
+				notifier.Notify(rpcSub.ID, ev.Rejection)
+			case <-rpcSub.Err():
+				sub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				sub.Unsubscribe()
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // Status returns the number of pending and queued transaction in the pool.
 func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	pending, queue := s.b.Stats()
 	return map[string]hexutil.Uint{
 		"pending": hexutil.Uint(pending),
 		"queued":  hexutil.Uint(queue),
+		"bytes":   hexutil.Uint(s.b.TxPoolBytes()),
 	}
 }
 
@@ -899,8 +977,8 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 	return result
 }
 
-// newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
-func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
+// NewRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
+func NewRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
 	return newRPCTransaction(tx, common.Hash{}, 0, 0)
 }
 
@@ -1012,7 +1090,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	}
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
-		return newRPCPendingTransaction(tx)
+		return NewRPCPendingTransaction(tx)
 	}
 	// Transaction unknown, return as such
 	return nil
@@ -1309,7 +1387,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, err := s.b.AccountManager().Find(accounts.Account{Address: from}); err == nil {
-			transactions = append(transactions, newRPCPendingTransaction(tx))
+			transactions = append(transactions, NewRPCPendingTransaction(tx))
 		}
 	}
 	return transactions, nil
@@ -1416,7 +1494,7 @@ func NewPrivateDebugAPI(b Backend) *PrivateDebugAPI {
 
 // ChaindbProperty returns leveldb properties of the chain database.
 func (api *PrivateDebugAPI) ChaindbProperty(property string) (string, error) {
-	ldb, ok := api.b.ChainDb().(interface {
+	ldb, ok := rawdb.KeyValueStore(api.b.ChainDb()).(interface {
 		LDB() *leveldb.DB
 	})
 	if !ok {
@@ -1431,7 +1509,7 @@ func (api *PrivateDebugAPI) ChaindbProperty(property string) (string, error) {
 }
 
 func (api *PrivateDebugAPI) ChaindbCompact() error {
-	ldb, ok := api.b.ChainDb().(interface {
+	ldb, ok := rawdb.KeyValueStore(api.b.ChainDb()).(interface {
 		LDB() *leveldb.DB
 	})
 	if !ok {
@@ -48,6 +48,7 @@ type Backend interface {
 	// BlockChain API
 	SetHead(number uint64)
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
+	FinalizedHeader(ctx context.Context) (*types.Header, error)
 	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
 	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
 	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
@@ -64,8 +65,15 @@ type Backend interface {
 	GetPoolTransaction(txHash common.Hash) *types.Transaction
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
 	Stats() (pending int, queued int)
+	TxPoolBytes() uint64
+	TxPoolPriceBump() uint64
 	TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
+	SubscribeTxRejectedEvent(chan<- core.TxRejectedEvent) event.Subscription
+
+	// Alien voting API
+	VoteOf(ctx context.Context, header *types.Header, voter common.Address) (common.Address, bool, error)
+	NextSigners(ctx context.Context, header *types.Header) ([]common.Address, error)
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
@@ -113,6 +121,11 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPrivateAccountAPI(apiBackend, nonceLock),
 			Public:    false,
+		}, {
+			Namespace: "alien",
+			Version:   "1.0",
+			Service:   NewPublicAlienAPI(apiBackend),
+			Public:    true,
 		},
 	}
 }
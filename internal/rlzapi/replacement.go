@@ -0,0 +1,206 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/relianz2019/relianz/accounts"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/core"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/params"
+)
+
+// ReplacementResult is the outcome of replacing a pooled transaction. If the
+// sender is not managed by the node or locked, the replacement is returned
+// unsigned for an external signer to sign and submit it through
+// sendRawTransaction.
+type ReplacementResult struct {
+	Replaced common.Hash        `json:"replaced"` // Hash of the replaced transaction
+	Hash     *common.Hash       `json:"hash"`     // Hash of the submitted replacement, nil if unsigned
+	Signed   bool               `json:"signed"`   // Whether the replacement was signed and submitted
+	Tx       *types.Transaction `json:"tx"`       // Replacement transaction
+}
+
+// SpeedUpTransaction replaces a pooled transaction with an identical one paying
+// the given gas price, which defaults to the minimum price accepted as a
+// replacement by the pool. If the sender is locked, the replacement is returned
+// unsigned, use personal_speedUpTransaction to sign it with the passphrase.
+func (s *PublicTransactionPoolAPI) SpeedUpTransaction(ctx context.Context, hash common.Hash, gasPrice *hexutil.Big) (*ReplacementResult, error) {
+	from, replacement, err := speedUpTransaction(ctx, s.b, hash, gasPrice)
+	if err != nil {
+		return nil, err
+	}
+	return s.replace(ctx, hash, from, replacement)
+}
+
+// CancelTransaction replaces a pooled transaction with an empty transfer of the
+// sender to itself, paying at least the minimum price accepted as a replacement
+// by the pool and the currently suggested gas price. If the sender is locked,
+// the replacement is returned unsigned, use personal_cancelTransaction to sign
+// it with the passphrase.
+func (s *PublicTransactionPoolAPI) CancelTransaction(ctx context.Context, hash common.Hash) (*ReplacementResult, error) {
+	from, replacement, err := cancelTransaction(ctx, s.b, hash)
+	if err != nil {
+		return nil, err
+	}
+	return s.replace(ctx, hash, from, replacement)
+}
+
+// replace signs and submits the replacement of a transaction if the sender is
+// managed by the node and unlocked, or returns it unsigned otherwise.
+func (s *PublicTransactionPoolAPI) replace(ctx context.Context, replaced common.Hash, from common.Address, tx *types.Transaction) (*ReplacementResult, error) {
+	if _, err := s.b.AccountManager().Find(accounts.Account{Address: from}); err != nil {
+		return &ReplacementResult{Replaced: replaced, Tx: tx}, nil
+	}
+	signed, err := s.sign(from, tx)
+	if _, locked := err.(*accounts.AuthNeededError); locked {
+		return &ReplacementResult{Replaced: replaced, Tx: tx}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return submitReplacement(ctx, s.b, replaced, signed)
+}
+
+// SpeedUpTransaction replaces a pooled transaction of a managed account with an
+// identical one paying the given gas price, which defaults to the minimum price
+// accepted as a replacement by the pool. The replacement is signed with the key
+// of the sender unlocked by the passphrase and submitted.
+func (s *PrivateAccountAPI) SpeedUpTransaction(ctx context.Context, hash common.Hash, gasPrice *hexutil.Big, passwd string) (*ReplacementResult, error) {
+	from, replacement, err := speedUpTransaction(ctx, s.b, hash, gasPrice)
+	if err != nil {
+		return nil, err
+	}
+	return s.replace(ctx, hash, from, replacement, passwd)
+}
+
+// CancelTransaction replaces a pooled transaction of a managed account with an
+// empty transfer of the sender to itself. The replacement is signed with the key
+// of the sender unlocked by the passphrase and submitted.
+func (s *PrivateAccountAPI) CancelTransaction(ctx context.Context, hash common.Hash, passwd string) (*ReplacementResult, error) {
+	from, replacement, err := cancelTransaction(ctx, s.b, hash)
+	if err != nil {
+		return nil, err
+	}
+	return s.replace(ctx, hash, from, replacement, passwd)
+}
+
+// replace signs the replacement of a transaction with the passphrase of the
+// sender and submits it.
+func (s *PrivateAccountAPI) replace(ctx context.Context, replaced common.Hash, from common.Address, tx *types.Transaction, passwd string) (*ReplacementResult, error) {
+	account := accounts.Account{Address: from}
+	wallet, err := s.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	var chainID *big.Int
+	if config := s.b.ChainConfig(); config.IsEIP155(s.b.CurrentBlock().Number()) {
+		chainID = config.ChainId
+	}
+	signed, err := wallet.SignTxWithPassphrase(account, passwd, tx, chainID)
+	if err != nil {
+		return nil, err
+	}
+	return submitReplacement(ctx, s.b, replaced, signed)
+}
+
+// speedUpTransaction assembles the replacement of a pooled transaction paying
+// the given or the minimum replacement gas price.
+func speedUpTransaction(ctx context.Context, b Backend, hash common.Hash, gasPrice *hexutil.Big) (common.Address, *types.Transaction, error) {
+	tx, from, err := pooledTransaction(b, hash)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	price, err := replacementPrice(ctx, b, tx, gasPrice, false)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	if to := tx.To(); to != nil {
+		return from, types.NewTransaction(tx.Nonce(), *to, tx.Value(), tx.Gas(), price, tx.Data()), nil
+	}
+	return from, types.NewContractCreation(tx.Nonce(), tx.Value(), tx.Gas(), price, tx.Data()), nil
+}
+
+// cancelTransaction assembles the empty self transfer replacing a pooled
+// transaction.
+func cancelTransaction(ctx context.Context, b Backend, hash common.Hash) (common.Address, *types.Transaction, error) {
+	tx, from, err := pooledTransaction(b, hash)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	price, err := replacementPrice(ctx, b, tx, nil, true)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return from, types.NewTransaction(tx.Nonce(), from, new(big.Int), params.TxGas, price, nil), nil
+}
+
+// pooledTransaction retrieves a transaction still waiting in the pool along
+// with its sender.
+func pooledTransaction(b Backend, hash common.Hash) (*types.Transaction, common.Address, error) {
+	tx := b.GetPoolTransaction(hash)
+	if tx == nil {
+		return nil, common.Address{}, fmt.Errorf("transaction %#x not pending", hash)
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	return tx, from, nil
+}
+
+// replacementPrice returns the gas price a replacement of the transaction pays.
+// A requested price below the minimum accepted by the pool is refused. Without
+// a requested price the minimum is used, raised to the suggested gas price if
+// requested.
+func replacementPrice(ctx context.Context, b Backend, tx *types.Transaction, gasPrice *hexutil.Big, suggest bool) (*big.Int, error) {
+	minimum := core.ReplacementPrice(tx.GasPrice(), b.TxPoolPriceBump())
+	if gasPrice != nil {
+		if price := (*big.Int)(gasPrice); price.Cmp(minimum) < 0 {
+			return nil, fmt.Errorf("gas price %v below replacement minimum %v", price, minimum)
+		}
+		return (*big.Int)(gasPrice), nil
+	}
+	if suggest {
+		suggested, err := b.SuggestPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if suggested.Cmp(minimum) > 0 {
+			return suggested, nil
+		}
+	}
+	return minimum, nil
+}
+
+// submitReplacement submits the signed replacement of a transaction.
+func submitReplacement(ctx context.Context, b Backend, replaced common.Hash, signed *types.Transaction) (*ReplacementResult, error) {
+	hash, err := submitTransaction(ctx, b, signed)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplacementResult{Replaced: replaced, Hash: &hash, Signed: true, Tx: signed}, nil
+}
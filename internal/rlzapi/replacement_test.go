@@ -0,0 +1,226 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/relianz2019/relianz/accounts"
+	"github.com/relianz2019/relianz/accounts/keystore"
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/params"
+)
+
+// replacementTestBackend is an API backend serving a transaction pool with a
+// keystore managed account. Methods not needed by the tests panic.
+type replacementTestBackend struct {
+	Backend
+
+	am        *accounts.Manager
+	suggested *big.Int
+	pool      map[common.Hash]*types.Transaction
+	sent      []*types.Transaction
+}
+
+func (b *replacementTestBackend) AccountManager() *accounts.Manager { return b.am }
+func (b *replacementTestBackend) ChainConfig() *params.ChainConfig  { return params.TestChainConfig }
+func (b *replacementTestBackend) TxPoolPriceBump() uint64           { return 10 }
+func (b *replacementTestBackend) CurrentBlock() *types.Block {
+	return types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+}
+
+func (b *replacementTestBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	return b.suggested, nil
+}
+
+func (b *replacementTestBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	return b.pool[hash]
+}
+
+func (b *replacementTestBackend) SendTx(ctx context.Context, tx *types.Transaction) error {
+	b.sent = append(b.sent, tx)
+	return nil
+}
+
+// newReplacementTestBackend creates a backend with a single locked keystore
+// account, which has a transfer paying a gas price of 100 waiting in the pool.
+func newReplacementTestBackend(t *testing.T) (*replacementTestBackend, *keystore.KeyStore, accounts.Account, *types.Transaction, func()) {
+	dir, err := ioutil.TempDir("", "replacement-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary keystore: %v", err)
+	}
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("pass")
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("failed to create account: %v", err)
+	}
+	tx, err := ks.SignTxWithPassphrase(account, "pass", types.NewTransaction(3, common.Address{0x01}, big.NewInt(1000), 50000, big.NewInt(100), []byte{0x02}), nil)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("failed to sign pooled transaction: %v", err)
+	}
+	backend := &replacementTestBackend{
+		am:        accounts.NewManager(ks),
+		suggested: big.NewInt(50),
+		pool:      map[common.Hash]*types.Transaction{tx.Hash(): tx},
+	}
+	return backend, ks, account, tx, func() { backend.am.Close(); os.RemoveAll(dir) }
+}
+
+// Tests that speeding up a transaction resends it paying the requested gas price
+// or the minimum replacement price, refusing prices the pool would not accept.
+func TestSpeedUpTransaction(t *testing.T) {
+	backend, ks, account, tx, cleanup := newReplacementTestBackend(t)
+	defer cleanup()
+
+	if err := ks.Unlock(account, "pass"); err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	api := NewPublicTransactionPoolAPI(backend, new(AddrLocker))
+	signer := types.NewEIP155Signer(params.TestChainConfig.ChainId)
+
+	tests := []struct {
+		gasPrice *hexutil.Big
+		want     *big.Int
+		fail     bool
+	}{
+		{nil, big.NewInt(110), false},
+		{(*hexutil.Big)(big.NewInt(109)), nil, true},
+		{(*hexutil.Big)(big.NewInt(200)), big.NewInt(200), false},
+	}
+	for i, tt := range tests {
+		backend.sent = nil
+
+		res, err := api.SpeedUpTransaction(context.Background(), tx.Hash(), tt.gasPrice)
+		if tt.fail {
+			if err == nil || len(backend.sent) != 0 {
+				t.Errorf("test %d: underpriced replacement accepted", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: failed to speed up transaction: %v", i, err)
+		}
+		if !res.Signed || res.Replaced != tx.Hash() || len(backend.sent) != 1 || *res.Hash != backend.sent[0].Hash() {
+			t.Fatalf("test %d: replacement not submitted: %+v", i, res)
+		}
+		sent := backend.sent[0]
+		if from, err := types.Sender(signer, sent); err != nil || from != account.Address {
+			t.Errorf("test %d: sender mismatch: have %x, want %x", i, from, account.Address)
+		}
+		if sent.GasPrice().Cmp(tt.want) != 0 {
+			t.Errorf("test %d: gas price mismatch: have %v, want %v", i, sent.GasPrice(), tt.want)
+		}
+		if sent.Nonce() != tx.Nonce() || *sent.To() != *tx.To() || sent.Value().Cmp(tx.Value()) != 0 || sent.Gas() != tx.Gas() || string(sent.Data()) != string(tx.Data()) {
+			t.Errorf("test %d: replacement differs from the original transaction", i)
+		}
+	}
+	if _, err := api.SpeedUpTransaction(context.Background(), common.Hash{0xff}, nil); err == nil {
+		t.Errorf("missing transaction sped up")
+	}
+}
+
+// Tests that cancelling a transaction resends its nonce as an empty transfer of
+// the sender to itself, paying at least the suggested gas price.
+func TestCancelTransaction(t *testing.T) {
+	backend, ks, account, tx, cleanup := newReplacementTestBackend(t)
+	defer cleanup()
+
+	if err := ks.Unlock(account, "pass"); err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	api := NewPublicTransactionPoolAPI(backend, new(AddrLocker))
+
+	for i, tt := range []struct {
+		suggested *big.Int
+		want      *big.Int
+	}{
+		{big.NewInt(50), big.NewInt(110)},
+		{big.NewInt(500), big.NewInt(500)},
+	} {
+		backend.suggested, backend.sent = tt.suggested, nil
+
+		res, err := api.CancelTransaction(context.Background(), tx.Hash())
+		if err != nil {
+			t.Fatalf("test %d: failed to cancel transaction: %v", i, err)
+		}
+		if !res.Signed || len(backend.sent) != 1 {
+			t.Fatalf("test %d: cancellation not submitted: %+v", i, res)
+		}
+		sent := backend.sent[0]
+		if sent.Nonce() != tx.Nonce() || *sent.To() != account.Address || sent.Value().Sign() != 0 || sent.Gas() != params.TxGas || len(sent.Data()) != 0 {
+			t.Errorf("test %d: cancellation is not an empty self transfer", i)
+		}
+		if sent.GasPrice().Cmp(tt.want) != 0 {
+			t.Errorf("test %d: gas price mismatch: have %v, want %v", i, sent.GasPrice(), tt.want)
+		}
+	}
+}
+
+// Tests that the replacements of a locked account are returned unsigned by the
+// public API, and signed with the passphrase through the personal API.
+func TestReplaceLockedAccount(t *testing.T) {
+	backend, _, account, tx, cleanup := newReplacementTestBackend(t)
+	defer cleanup()
+
+	res, err := NewPublicTransactionPoolAPI(backend, new(AddrLocker)).CancelTransaction(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatalf("failed to cancel transaction of locked account: %v", err)
+	}
+	if res.Signed || res.Hash != nil || res.Tx == nil || len(backend.sent) != 0 {
+		t.Fatalf("replacement of locked account submitted: %+v", res)
+	}
+	if res.Tx.Nonce() != tx.Nonce() || *res.Tx.To() != account.Address {
+		t.Errorf("unsigned cancellation mismatch")
+	}
+	personal := NewPrivateAccountAPI(backend, new(AddrLocker))
+	if _, err := personal.SpeedUpTransaction(context.Background(), tx.Hash(), nil, "wrong"); err == nil || len(backend.sent) != 0 {
+		t.Fatalf("replacement signed with wrong passphrase")
+	}
+	res, err = personal.SpeedUpTransaction(context.Background(), tx.Hash(), nil, "pass")
+	if err != nil {
+		t.Fatalf("failed to speed up transaction with passphrase: %v", err)
+	}
+	if !res.Signed || len(backend.sent) != 1 || backend.sent[0].GasPrice().Cmp(big.NewInt(110)) != 0 {
+		t.Fatalf("replacement not submitted: %+v", res)
+	}
+	signer := types.NewEIP155Signer(params.TestChainConfig.ChainId)
+	if from, err := types.Sender(signer, backend.sent[0]); err != nil || from != account.Address {
+		t.Errorf("sender mismatch: have %x, want %x", from, account.Address)
+	}
+
+	// Accounts not managed by the node always get the replacement unsigned
+	key, _ := crypto.GenerateKey()
+	foreign, _ := types.SignTx(types.NewTransaction(0, common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(100), nil), types.HomesteadSigner{}, key)
+	backend.pool[foreign.Hash()] = foreign
+
+	res, err = NewPublicTransactionPoolAPI(backend, new(AddrLocker)).SpeedUpTransaction(context.Background(), foreign.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to speed up foreign transaction: %v", err)
+	}
+	if res.Signed || res.Tx.GasPrice().Cmp(big.NewInt(110)) != 0 {
+		t.Errorf("foreign replacement mismatch: %+v", res)
+	}
+}
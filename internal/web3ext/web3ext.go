@@ -135,6 +135,30 @@ web3._extend({
 			call: 'alien_getSnapshotByHeaderTime',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'checkVote',
+			call: 'alien_checkVote',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getSealRecord',
+			call: 'alien_getSealRecord',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getSignerUptime',
+			call: 'alien_getSignerUptime',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getSignerPayouts',
+			call: 'alien_getSignerPayouts',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 	]
 });
 `
@@ -236,6 +260,12 @@ web3._extend({
 			call: 'debug_dumpBlock',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'accountRange',
+			call: 'debug_accountRange',
+			params: 5,
+			inputFormatter: [null, null, null, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'chaindbProperty',
 			call: 'debug_chaindbProperty',
@@ -461,6 +491,22 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
 		}),
+		new web3._extend.Method({
+			name: 'decodeUfoPayload',
+			call: 'rlz_decodeUfoPayload',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'speedUpTransaction',
+			call: 'rlz_speedUpTransaction',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'cancelTransaction',
+			call: 'rlz_cancelTransaction',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -479,10 +525,16 @@ web3._extend({
 });
 `
 
-const Miner_JS = `
-web3._extend({
-	property: 'miner',
-	methods: [
+// Miner_JS is the miner extension including the methods of every optional miner
+// feature, used if the node does not report the features it supports.
+var Miner_JS = MinerJS(MinerFeatures)
+
+// MinerFeatures lists the optional miner features in the order their methods are
+// added to the miner extension.
+var MinerFeatures = []string{"pow", "schedule"}
+
+// minerMethods are the miner methods every node supports.
+const minerMethods = `
 		new web3._extend.Method({
 			name: 'start',
 			call: 'miner_start',
@@ -510,14 +562,43 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.utils.fromDecimal]
 		}),
+		new web3._extend.Method({
+			name: 'features',
+			call: 'miner_features'
+		}),`
+
+// minerFeatureMethods are the miner methods of the optional miner features.
+var minerFeatureMethods = map[string]string{
+	"pow": `
 		new web3._extend.Method({
 			name: 'getHashrate',
 			call: 'miner_getHashrate'
-		}),
+		}),`,
+	"schedule": `
+		new web3._extend.Method({
+			name: 'schedule',
+			call: 'miner_schedule',
+			params: 1,
+			inputFormatter: [null]
+		}),`,
+}
+
+// MinerJS generates the miner extension with the methods of the given optional
+// miner features. Unknown features are ignored.
+func MinerJS(features []string) string {
+	methods := minerMethods
+	for _, feature := range features {
+		methods += minerFeatureMethods[feature]
+	}
+	return `
+web3._extend({
+	property: 'miner',
+	methods: [` + methods + `
 	],
 	properties: []
 });
 `
+}
 
 const Net_JS = `
 web3._extend({
@@ -568,6 +649,29 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'vote',
+			call: 'personal_vote',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'changeVote',
+			call: 'personal_changeVote',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'speedUpTransaction',
+			call: 'personal_speedUpTransaction',
+			params: 3,
+			inputFormatter: [null, web3._extend.utils.fromDecimal, null]
+		}),
+		new web3._extend.Method({
+			name: 'cancelTransaction',
+			call: 'personal_cancelTransaction',
+			params: 2
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -638,7 +742,12 @@ web3._extend({
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
-	methods: [],
+	methods: [
+		new web3._extend.Method({
+			name: 'save',
+			call: 'txpool_save'
+		}),
+	],
 	properties:
 	[
 		new web3._extend.Property({
@@ -18,6 +18,7 @@ package les
 
 import (
 	"context"
+	"errors"
 	"math/big"
 
 	"github.com/relianz2019/relianz/accounts"
@@ -38,6 +39,11 @@ import (
 	"github.com/relianz2019/relianz/rpc"
 )
 
+var (
+	errNoLightFinality  = errors.New("finalized block unavailable, light clients cannot prove block finality")
+	errNoLightVoteTally = errors.New("vote tally unavailable, light clients don't track consensus snapshots")
+)
+
 type LesApiBackend struct {
 	rlz *LightRelianz
 	gpo *gasprice.Oracle
@@ -57,6 +63,9 @@ func (b *LesApiBackend) SetHead(number uint64) {
 }
 
 func (b *LesApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	if blockNr == rpc.FinalizedBlockNumber {
+		return b.FinalizedHeader(ctx)
+	}
 	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
 		return b.rlz.blockchain.CurrentHeader(), nil
 	}
@@ -64,6 +73,24 @@ func (b *LesApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	return b.rlz.blockchain.GetHeaderByNumberOdr(ctx, uint64(blockNr))
 }
 
+// FinalizedHeader always fails for light clients, as they don't take part in the
+// confirmation protocol and cannot prove that a block is final.
+func (b *LesApiBackend) FinalizedHeader(ctx context.Context) (*types.Header, error) {
+	return nil, errNoLightFinality
+}
+
+// VoteOf always fails for light clients, as they don't maintain the snapshots
+// the vote tally is recorded in.
+func (b *LesApiBackend) VoteOf(ctx context.Context, header *types.Header, voter common.Address) (common.Address, bool, error) {
+	return common.Address{}, false, errNoLightVoteTally
+}
+
+// NextSigners always fails for light clients, as they don't maintain the
+// snapshots the signer election is based upon.
+func (b *LesApiBackend) NextSigners(ctx context.Context, header *types.Header) ([]common.Address, error) {
+	return nil, errNoLightVoteTally
+}
+
 func (b *LesApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
 	header, err := b.HeaderByNumber(ctx, blockNr)
 	if header == nil || err != nil {
@@ -132,6 +159,15 @@ func (b *LesApiBackend) Stats() (pending int, queued int) {
 	return b.rlz.txPool.Stats(), 0
 }
 
+func (b *LesApiBackend) TxPoolBytes() uint64 {
+	return b.rlz.txPool.Bytes()
+}
+
+func (b *LesApiBackend) TxPoolPriceBump() uint64 {
+	// Light clients relay transactions to servers enforcing the default rules
+	return core.DefaultTxPoolConfig.PriceBump
+}
+
 func (b *LesApiBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
 	return b.rlz.txPool.Content()
 }
@@ -140,6 +176,22 @@ func (b *LesApiBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.S
 	return b.rlz.txPool.SubscribeNewTxsEvent(ch)
 }
 
+func (b *LesApiBackend) SubscribeDroppedTxsEvent(ch chan<- core.DroppedTxsEvent) event.Subscription {
+	// Light clients only relay their own transactions, never dropping any
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+func (b *LesApiBackend) SubscribeTxRejectedEvent(ch chan<- core.TxRejectedEvent) event.Subscription {
+	// Light clients run no admission hooks
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *LesApiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.rlz.blockchain.SubscribeChainEvent(ch)
 }
@@ -152,6 +204,14 @@ func (b *LesApiBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) e
 	return b.rlz.blockchain.SubscribeChainSideEvent(ch)
 }
 
+func (b *LesApiBackend) SubscribeChainFinalizedEvent(ch chan<- core.ChainFinalizedEvent) event.Subscription {
+	// Light clients don't take part in the confirmation protocol
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *LesApiBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return b.rlz.blockchain.SubscribeLogsEvent(ch)
 }
@@ -0,0 +1,42 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"testing"
+
+	"github.com/relianz2019/relianz/rpc"
+)
+
+// Tests that light clients refuse the finalized block tag with a clear error, as
+// they cannot prove the finality of blocks.
+func TestFinalizedBlockTag(t *testing.T) {
+	var (
+		backend = &LesApiBackend{}
+		ctx     = context.Background()
+	)
+	if _, err := backend.HeaderByNumber(ctx, rpc.FinalizedBlockNumber); err != errNoLightFinality {
+		t.Errorf("finalized header error mismatch: have %v, want %v", err, errNoLightFinality)
+	}
+	if _, err := backend.BlockByNumber(ctx, rpc.FinalizedBlockNumber); err != errNoLightFinality {
+		t.Errorf("finalized block error mismatch: have %v, want %v", err, errNoLightFinality)
+	}
+	if _, _, err := backend.StateAndHeaderByNumber(ctx, rpc.FinalizedBlockNumber); err != errNoLightFinality {
+		t.Errorf("finalized state error mismatch: have %v, want %v", err, errNoLightFinality)
+	}
+}
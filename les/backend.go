@@ -68,6 +68,9 @@ type LightRelianz struct {
 	bloomRequests                              chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer, chtIndexer, bloomTrieIndexer *core.ChainIndexer
 
+	signerIndexer *core.ChainIndexer // Alien signer history indexer (nil if unsupported)
+	signerHistory *rlz.SignerHistory // Alien signer history query service (nil if unsupported)
+
 	ApiBackend *LesApiBackend
 
 	eventMux       *event.TypeMux
@@ -126,6 +129,16 @@ func New(ctx *node.ServiceContext, config *rlz.Config) (*LightRelianz, error) {
 		return nil, err
 	}
 	leth.bloomIndexer.Start(leth.blockchain)
+
+	if chainConfig.Alien != nil {
+		if reader, ok := leth.engine.(core.SealRecordReader); ok {
+			leth.signerIndexer = rlz.NewSignerIndexer(chainDb, reader, params.SignerIndexBlocks)
+			leth.signerIndexer.Start(leth.blockchain)
+			leth.signerHistory = rlz.NewSignerHistory(chainDb, leth.signerIndexer, reader, params.SignerIndexBlocks)
+		} else {
+			log.Warn("Consensus engine cannot describe sealed blocks, signer history disabled")
+		}
+	}
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -171,20 +184,41 @@ func (s *LightDummyAPI) Coinbase() (common.Address, error) {
 	return common.Address{}, fmt.Errorf("not supported")
 }
 
-// Hashrate returns the POW hashrate
-func (s *LightDummyAPI) Hashrate() hexutil.Uint {
-	return 0
-}
-
 // Mining returns an indication if this node is currently mining.
 func (s *LightDummyAPI) Mining() bool {
 	return false
 }
 
+// LightDummyPoWAPI stands in for the proof-of-work miner API on light clients of
+// chains whose consensus engine supports the consensus.FeaturePoW feature.
+type LightDummyPoWAPI struct{}
+
+// Hashrate returns the POW hashrate
+func (s *LightDummyPoWAPI) Hashrate() hexutil.Uint {
+	return 0
+}
+
 // APIs returns the collection of RPC services the relianz package offers.
 // NOTE, some of these services probably need to be moved to somewhere else.
 func (s *LightRelianz) APIs() []rpc.API {
-	return append(ethapi.GetAPIs(s.ApiBackend), []rpc.API{
+	apis := ethapi.GetAPIs(s.ApiBackend)
+	if s.signerHistory != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "alien",
+			Version:   "1.0",
+			Service:   rlz.NewPublicSignerHistoryAPI(s.signerHistory),
+			Public:    true,
+		})
+	}
+	if consensus.HasFeature(consensus.MinerFeatures(s.engine), consensus.FeaturePoW) {
+		apis = append(apis, rpc.API{
+			Namespace: "rlz",
+			Version:   "1.0",
+			Service:   &LightDummyPoWAPI{},
+			Public:    true,
+		})
+	}
+	return append(apis, []rpc.API{
 		{
 			Namespace: "rlz",
 			Version:   "1.0",
@@ -252,6 +286,9 @@ func (s *LightRelianz) Stop() error {
 	if s.bloomTrieIndexer != nil {
 		s.bloomTrieIndexer.Close()
 	}
+	if s.signerIndexer != nil {
+		s.signerIndexer.Close()
+	}
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	s.txPool.Stop()
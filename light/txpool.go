@@ -336,6 +336,17 @@ func (pool *TxPool) Stats() (pending int) {
 	return
 }
 
+// Bytes returns the total encoded size of the currently pending transactions.
+func (pool *TxPool) Bytes() (size uint64) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	for _, tx := range pool.pending {
+		size += uint64(tx.Size())
+	}
+	return
+}
+
 // validateTx checks whether a transaction is valid according to the consensus rules.
 func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error {
 	// Validate sender
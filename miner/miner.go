@@ -42,6 +42,13 @@ type Backend interface {
 	ChainDb() ethdb.Database
 }
 
+// Confirmer is implemented by block finality subsystems which signers feed with
+// confirmations of the blocks they accepted.
+type Confirmer interface {
+	// Confirm signs a confirmation of header on behalf of signer and publishes it.
+	Confirm(signer common.Address, header *types.Header) error
+}
+
 // Miner creates blocks and searches for proof-of-work values.
 type Miner struct {
 	mux *event.TypeMux
@@ -52,6 +59,7 @@ type Miner struct {
 	mining   int32
 	rlz      Backend
 	engine   consensus.Engine
+	features []consensus.MinerFeature // Optional miner features supported by the engine
 
 	canStart    int32 // can start indicates whether we can start the mining operation
 	shouldStart int32 // should start indicates whether we should start after sync
@@ -62,6 +70,7 @@ func New(rlz Backend, config *params.ChainConfig, mux *event.TypeMux, engine con
 		rlz:      rlz,
 		mux:      mux,
 		engine:   engine,
+		features: consensus.MinerFeatures(engine),
 		worker:   newWorker(config, engine, common.Address{}, rlz, mux),
 		canStart: 1,
 	}
@@ -154,6 +163,23 @@ func (self *Miner) HashRate() (tot int64) {
 	return
 }
 
+// Features returns the optional miner features supported by the consensus engine.
+func (self *Miner) Features() []consensus.MinerFeature {
+	return self.features
+}
+
+// SetConfirmer sets the finality subsystem new chain heads are confirmed with
+// while mining. Confirmations are disabled if c is nil.
+func (self *Miner) SetConfirmer(c Confirmer) {
+	self.worker.setConfirmer(c)
+}
+
+// Schedule returns the next n slots of the signer in the Alien signer schedule,
+// as projected from the loop of the current chain head.
+func (self *Miner) Schedule(signer common.Address, n int) ([]Slot, error) {
+	return self.worker.schedule(signer, 0, n)
+}
+
 func (self *Miner) SetExtra(extra []byte) error {
 	if uint64(len(extra)) > params.MaximumExtraDataSize {
 		return fmt.Errorf("Extra exceeds max length. %d > %v", len(extra), params.MaximumExtraDataSize)
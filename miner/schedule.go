@@ -0,0 +1,157 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"time"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/consensus"
+	"github.com/relianz2019/relianz/core"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/params"
+)
+
+const (
+	// scheduleLead is how long ahead of its own slot a signer starts preparing
+	// the block to seal, leaving time to fill it with transactions.
+	scheduleLead = 500 * time.Millisecond
+
+	// maxScheduleSlots is the maximum number of slots a schedule query returns.
+	maxScheduleSlots = 1024
+)
+
+var (
+	// errNoSchedule is returned if the signer schedule of the chain is unknown.
+	errNoSchedule = errors.New("consensus engine has no signer schedule")
+
+	// errNotElected is returned if the signer holds no slot in the schedule.
+	errNotElected = errors.New("signer not elected")
+
+	// errNoSignerQueue is returned if the signer queue of the loop a chain head was
+	// sealed in cannot be found among its ancestors.
+	errNoSignerQueue = errors.New("signer queue of the loop not found")
+)
+
+// headerReader is the subset of the chain needed to look up the first block of
+// a signer loop.
+type headerReader interface {
+	GetHeader(hash common.Hash, number uint64) *types.Header
+}
+
+// Slot is a time slot in which a signer is in turn to seal a block.
+type Slot struct {
+	Time      hexutil.Uint64 `json:"time"`      // Timestamp the slot opens at
+	Signer    common.Address `json:"signer"`    // Signer in turn during the slot
+	Projected bool           `json:"projected"` // Whether the slot lies beyond the current loop, subject to the next election
+}
+
+// schedule is the signer queue of the Alien loop a chain head was sealed in.
+// Every signer is in turn for one period per loop, in queue order.
+type schedule struct {
+	period    uint64
+	loopStart uint64
+	queue     []common.Address
+}
+
+// newSchedule retrieves the signer schedule the given chain head was sealed in.
+// The genesis loop is sealed by the self voted signers of the configuration.
+//
+// Only the first block of a loop carries its signer queue, so the ancestors of
+// the head are searched for it, at most one loop of blocks back.
+func newSchedule(config *params.AlienConfig, engine consensus.Engine, chain headerReader, head *types.Header) (*schedule, error) {
+	if config == nil || config.Period == 0 {
+		return nil, errNoSchedule
+	}
+	if head.Number.Sign() == 0 {
+		return genesisSchedule(config), nil
+	}
+	reader, ok := engine.(core.SealRecordReader)
+	if !ok {
+		return nil, errNoSchedule
+	}
+	record, err := reader.SealRecord(head)
+	if err != nil {
+		return nil, err
+	}
+	loopStart := record.LoopStart
+	for header, n := head, uint64(0); len(record.Queue) == 0; n++ {
+		if n >= config.MaxSignerCount || record.LoopStart != loopStart {
+			return nil, errNoSignerQueue
+		}
+		if header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1); header == nil {
+			return nil, errNoSignerQueue
+		}
+		if header.Number.Sign() == 0 {
+			return genesisSchedule(config), nil
+		}
+		if record, err = reader.SealRecord(header); err != nil {
+			return nil, err
+		}
+	}
+	if record.LoopStart != loopStart {
+		return nil, errNoSignerQueue
+	}
+	return &schedule{period: config.Period, loopStart: loopStart, queue: record.Queue}, nil
+}
+
+// genesisSchedule returns the schedule of the genesis loop, in which the self
+// voted signers of the configuration take turns.
+func genesisSchedule(config *params.AlienConfig) *schedule {
+	queue := make([]common.Address, len(config.SelfVoteSigners))
+	for i, signer := range config.SelfVoteSigners {
+		queue[i] = common.Address(signer)
+	}
+	return &schedule{period: config.Period, loopStart: config.GenesisTimestamp, queue: queue}
+}
+
+// inturn returns whether the signer is in turn during the slot covering the given
+// time. Times past the current loop assume the signer queue stays the same.
+func (s *schedule) inturn(signer common.Address, time uint64) bool {
+	if len(s.queue) == 0 || time < s.loopStart {
+		return false
+	}
+	return s.queue[(time-s.loopStart)/s.period%uint64(len(s.queue))] == signer
+}
+
+// slots returns up to n slots of the signer opening strictly after the given
+// time. Slots past the current loop assume the signer queue stays the same.
+func (s *schedule) slots(signer common.Address, after uint64, n int) []Slot {
+	if len(s.queue) == 0 || n <= 0 {
+		return nil
+	}
+	var index uint64
+	if after >= s.loopStart {
+		index = (after-s.loopStart)/s.period + 1
+	}
+	var (
+		size  = uint64(len(s.queue))
+		slots []Slot
+	)
+	for end := index + uint64(n)*size; index < end && len(slots) < n; index++ {
+		if s.queue[index%size] == signer {
+			slots = append(slots, Slot{
+				Time:      hexutil.Uint64(s.loopStart + index*s.period),
+				Signer:    signer,
+				Projected: index >= size,
+			})
+		}
+	}
+	return slots
+}
@@ -0,0 +1,160 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/consensus/alien"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/ethdb"
+	"github.com/relianz2019/relianz/params"
+	"github.com/relianz2019/relianz/rlp"
+)
+
+// Tests that the slots of a signer are derived from its position in the signer
+// queue, with slots beyond the current loop marked as projected.
+func TestScheduleSlots(t *testing.T) {
+	var (
+		a, b, c = common.Address{0x0a}, common.Address{0x0b}, common.Address{0x0c}
+		sched   = &schedule{period: 3, loopStart: 100, queue: []common.Address{a, b, c, b}}
+	)
+	tests := []struct {
+		signer common.Address
+		after  uint64
+		n      int
+		want   []Slot
+	}{
+		// Slots opening after the given time in the current loop
+		{b, 100, 2, []Slot{{Time: 103, Signer: b}, {Time: 109, Signer: b}}},
+		{a, 50, 1, []Slot{{Time: 100, Signer: a}}},
+		// The slot currently open is skipped
+		{c, 106, 1, []Slot{{Time: 118, Signer: c, Projected: true}}},
+		// Slots of later loops are projected from the current queue
+		{a, 100, 2, []Slot{{Time: 112, Signer: a, Projected: true}, {Time: 124, Signer: a, Projected: true}}},
+		{b, 108, 2, []Slot{{Time: 109, Signer: b}, {Time: 115, Signer: b, Projected: true}}},
+		// Signers outside of the queue are never in turn
+		{common.Address{0x0d}, 100, 3, nil},
+		{a, 100, 0, nil},
+	}
+	for i, tt := range tests {
+		if have := sched.slots(tt.signer, tt.after, tt.n); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: slots mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that the genesis loop is sealed by the self voted signers.
+func TestScheduleGenesis(t *testing.T) {
+	config := &params.AlienConfig{
+		Period:           2,
+		GenesisTimestamp: 1000,
+		SelfVoteSigners:  []common.UnprefixedAddress{{0x01}, {0x02}},
+	}
+	sched, err := newSchedule(config, nil, nil, &types.Header{Number: big.NewInt(0)})
+	if err != nil {
+		t.Fatalf("failed to create genesis schedule: %v", err)
+	}
+	want := []Slot{{Time: 1002, Signer: common.Address{0x02}}, {Time: 1006, Signer: common.Address{0x02}, Projected: true}}
+	if have := sched.slots(common.Address{0x02}, 1000, 2); !reflect.DeepEqual(have, want) {
+		t.Errorf("slots mismatch: have %v, want %v", have, want)
+	}
+	if _, err := newSchedule(config, nil, nil, &types.Header{Number: big.NewInt(1)}); err != errNoSchedule {
+		t.Errorf("error mismatch for engine without schedule: have %v, want %v", err, errNoSchedule)
+	}
+}
+
+// testHeaderChain is a header store for looking up the ancestors of a head.
+type testHeaderChain map[common.Hash]*types.Header
+
+func (c testHeaderChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+// sealAlienHeader creates a header on top of parent carrying the given header
+// extra, sealed by key the way the Alien engine seals blocks.
+func sealAlienHeader(t *testing.T, engine *alien.Alien, parent *types.Header, extra alien.HeaderExtra, key *ecdsa.PrivateKey) *types.Header {
+	enc, err := rlp.EncodeToBytes(extra)
+	if err != nil {
+		t.Fatalf("failed to encode header extra: %v", err)
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		Time:       new(big.Int).SetUint64(extra.LoopStartTime),
+		Extra:      append(append(make([]byte, 32), enc...), make([]byte, 65)...),
+	}
+	sig, err := crypto.Sign(engine.SealHash(header).Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to seal header: %v", err)
+	}
+	copy(header.Extra[len(header.Extra)-65:], sig)
+	return header
+}
+
+// Tests that the schedule of a chain head sealed by the Alien engine is taken
+// from the signer queue of the first block of its loop.
+func TestScheduleAlien(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	var (
+		config = &params.AlienConfig{Period: 3, MaxSignerCount: 3, TrantorBlock: big.NewInt(0)}
+		engine = alien.New(config, ethdb.NewMemDatabase())
+
+		a, b, c = common.Address{0x0a}, common.Address{0x0b}, common.Address{0x0c}
+		genesis = &types.Header{Number: big.NewInt(0)}
+		first   = sealAlienHeader(t, engine, genesis, alien.HeaderExtra{LoopStartTime: 100, SignerQueue: []common.Address{a, b, c}}, key)
+		second  = sealAlienHeader(t, engine, first, alien.HeaderExtra{LoopStartTime: 100}, key)
+		orphan  = sealAlienHeader(t, engine, second, alien.HeaderExtra{LoopStartTime: 200}, key)
+		chain   = testHeaderChain{genesis.Hash(): genesis, first.Hash(): first, second.Hash(): second, orphan.Hash(): orphan}
+	)
+	sched, err := newSchedule(config, engine, chain, second)
+	if err != nil {
+		t.Fatalf("failed to create schedule: %v", err)
+	}
+	if sched.loopStart != 100 || !reflect.DeepEqual(sched.queue, []common.Address{a, b, c}) {
+		t.Errorf("schedule mismatch: have loop %d queue %x, want loop 100 queue %x", sched.loopStart, sched.queue, []common.Address{a, b, c})
+	}
+	// Signers are only in turn during their own slots
+	for i, tt := range []struct {
+		signer common.Address
+		time   uint64
+		inturn bool
+	}{
+		{a, 99, false}, {a, 100, true}, {a, 102, true}, {b, 102, false},
+		{b, 103, true}, {c, 108, true}, {a, 109, true}, {common.Address{0x0d}, 100, false},
+	} {
+		if have := sched.inturn(tt.signer, tt.time); have != tt.inturn {
+			t.Errorf("test %d: in turn mismatch for %x at %d: have %v, want %v", i, tt.signer, tt.time, have, tt.inturn)
+		}
+	}
+	if slots := sched.slots(common.Address{0x0d}, 100, 1); len(slots) != 0 {
+		t.Errorf("slots of signer not elected: %v", slots)
+	}
+	// A loop whose first block is not among the ancestors has no known schedule
+	if _, err := newSchedule(config, engine, chain, orphan); err != errNoSignerQueue {
+		t.Errorf("error mismatch for loop without queue: have %v, want %v", err, errNoSignerQueue)
+	}
+}
@@ -100,6 +100,7 @@ type worker struct {
 	chainHeadSub event.Subscription
 	chainSideCh  chan core.ChainSideEvent
 	chainSideSub event.Subscription
+	scheduleCh   chan struct{} // Notifies the update loop to recompute the next own slot
 	wg           sync.WaitGroup
 
 	agents map[Agent]struct{}
@@ -110,8 +111,9 @@ type worker struct {
 	proc    core.Validator
 	chainDb ethdb.Database
 
-	coinbase common.Address
-	extra    []byte
+	coinbase  common.Address
+	extra     []byte
+	confirmer Confirmer // Finality subsystem to confirm new chain heads with (PBFT only)
 
 	currentMu sync.Mutex
 	current   *Work
@@ -141,6 +143,7 @@ func newWorker(config *params.ChainConfig, engine consensus.Engine, coinbase com
 		txsCh:          make(chan core.NewTxsEvent, txChanSize),
 		chainHeadCh:    make(chan core.ChainHeadEvent, chainHeadChanSize),
 		chainSideCh:    make(chan core.ChainSideEvent, chainSideChanSize),
+		scheduleCh:     make(chan struct{}, 1),
 		chainDb:        rlz.ChainDb(),
 		recv:           make(chan *Result, resultQueueSize),
 		chain:          rlz.BlockChain(),
@@ -167,6 +170,7 @@ func (self *worker) setRlzerbase(addr common.Address) {
 	self.mu.Lock()
 	defer self.mu.Unlock()
 	self.coinbase = addr
+	self.reschedule()
 }
 
 func (self *worker) setExtra(extra []byte) {
@@ -175,6 +179,12 @@ func (self *worker) setExtra(extra []byte) {
 	self.extra = extra
 }
 
+func (self *worker) setConfirmer(confirmer Confirmer) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.confirmer = confirmer
+}
+
 func (self *worker) pending() (*types.Block, *state.StateDB) {
 	if atomic.LoadInt32(&self.mining) == 0 {
 		// return a snapshot to avoid contention on currentMu mutex
@@ -211,6 +221,7 @@ func (self *worker) start() {
 	for agent := range self.agents {
 		agent.Start()
 	}
+	self.reschedule()
 }
 
 func (self *worker) stop() {
@@ -225,6 +236,68 @@ func (self *worker) stop() {
 	}
 	atomic.StoreInt32(&self.mining, 0)
 	atomic.StoreInt32(&self.atWork, 0)
+	self.reschedule()
+}
+
+// reschedule notifies the update loop that the next own slot needs recomputing.
+func (self *worker) reschedule() {
+	select {
+	case self.scheduleCh <- struct{}{}:
+	default:
+	}
+}
+
+// nextSlot returns the next slot of the coinbase opening after the given time
+// and the current time. It returns errNoSchedule if not mining or the schedule
+// is unknown, and errNotElected if the coinbase holds no slot in it.
+func (self *worker) nextSlot(after uint64) (Slot, error) {
+	if atomic.LoadInt32(&self.mining) == 0 {
+		return Slot{}, errNoSchedule
+	}
+	self.mu.Lock()
+	coinbase := self.coinbase
+	self.mu.Unlock()
+
+	slots, err := self.schedule(coinbase, after, 1)
+	if err != nil {
+		return Slot{}, err
+	}
+	if len(slots) == 0 {
+		return Slot{}, errNotElected
+	}
+	return slots[0], nil
+}
+
+// inturn returns whether the coinbase is in turn to seal the given header on top
+// of its parent. If the schedule is unknown, the engine is left to decide.
+//
+// The caller must hold self.mu.
+func (self *worker) inturn(parent, header *types.Header) bool {
+	sched, err := newSchedule(self.config.Alien, self.engine, self.chain, parent)
+	if err != nil {
+		return true
+	}
+	return sched.inturn(self.coinbase, header.Time.Uint64())
+}
+
+// schedule returns up to n slots of the signer opening after the given time, the
+// current time and the chain head.
+func (self *worker) schedule(signer common.Address, after uint64, n int) ([]Slot, error) {
+	head := self.chain.CurrentHeader()
+	sched, err := newSchedule(self.config.Alien, self.engine, self.chain, head)
+	if err != nil {
+		return nil, err
+	}
+	if now := uint64(time.Now().Unix()); after < now {
+		after = now
+	}
+	if headTime := head.Time.Uint64(); after < headTime {
+		after = headTime
+	}
+	if n > maxScheduleSlots {
+		n = maxScheduleSlots
+	}
+	return sched.slots(signer, after, n), nil
 }
 
 func (self *worker) register(agent Agent) {
@@ -251,13 +324,47 @@ func (self *worker) update() {
 	if self.config.Alien != nil && self.config.Alien.Period > 0 {
 		alienDelay = time.Duration(self.config.Alien.Period) * time.Second
 	}
+	// Alien signers sleep until just ahead of their own slots in the signer
+	// schedule and signers not elected wait for the chain to elect them. Only
+	// nodes without a schedule to follow keep polling once every period.
+	var (
+		wake     <-chan time.Time
+		inturn   bool   // Whether wake fires ahead of an own slot
+		next     uint64 // Opening time of the own slot wake fires ahead of
+		prepared uint64 // Opening time of the last own slot work was prepared for
+	)
+	plan := func() {
+		slot, err := self.nextSlot(prepared)
+		switch err {
+		case nil:
+			at := time.Unix(int64(slot.Time), 0).Add(-scheduleLead)
+			wake, inturn, next = time.After(time.Until(at)), true, uint64(slot.Time)
+			log.Debug("Scheduled next sealing slot", "slot", uint64(slot.Time), "wait", common.PrettyDuration(time.Until(at)))
+		case errNotElected:
+			wake, inturn = nil, false
+			log.Debug("Signer not elected, waiting for next loop")
+		default:
+			wake, inturn = time.After(alienDelay), false
+		}
+	}
+	plan()
 
 	for {
 		// A real event arrived, process interesting content
 		select {
 		// Handle ChainHeadEvent
-		case <-self.chainHeadCh:
-			self.commitNewWork()
+		case ev := <-self.chainHeadCh:
+			self.confirmHead(ev.Block)
+
+			// Scheduled signers only rebuild work if the head precedes an own slot
+			// still open, the pending block trails the chain until their next slot.
+			if !inturn || (ev.Block.Time().Uint64() < prepared && uint64(time.Now().Unix()) < prepared+self.config.Alien.Period) {
+				self.commitNewWork()
+			}
+			plan()
+
+		case <-self.scheduleCh:
+			plan()
 
 		// Handle ChainSideEvent
 		case ev := <-self.chainSideCh:
@@ -294,11 +401,15 @@ func (self *worker) update() {
 					self.commitNewWork()
 				}
 			}
-		case <-time.After(alienDelay):
+		case <-wake:
 			// try to seal block in each period, even no new block received in dpos
-			if self.config.Alien != nil && self.config.Alien.Period > 0 {
+			if inturn {
+				prepared = next
+				self.commitNewWork()
+			} else if self.config.Alien != nil && self.config.Alien.Period > 0 {
 				self.commitNewWork()
 			}
+			plan()
 
 		// System stopped
 		case <-self.txsSub.Err():
@@ -436,6 +547,14 @@ func (self *worker) commitNewWork() {
 	if parent.Time().Cmp(new(big.Int).SetInt64(tstamp)) >= 0 {
 		tstamp = parent.Time().Int64() + 1
 	}
+	// Alien signers prepare their block just ahead of their slot, stamp it with
+	// the time the slot opens at
+	alienMining := self.config.Alien != nil && atomic.LoadInt32(&self.mining) == 1
+	if alienMining {
+		if slots, err := self.schedule(self.coinbase, uint64(tstamp)-1, 1); err == nil && len(slots) > 0 && uint64(slots[0].Time) <= uint64(tstamp)+1 {
+			tstamp = int64(slots[0].Time)
+		}
+	}
 	// this will ensure we're not going off too far in the future
 	if now := time.Now().Unix(); tstamp > now+1 {
 		wait := time.Duration(tstamp-now) * time.Second
@@ -504,6 +623,13 @@ func (self *worker) commitNewWork() {
 		log.Error("Failed to finalize block for sealing", "err", err)
 		return
 	}
+	// Alien sealing attempts outside of the own slot are bound to fail, only keep
+	// the pending block up to date then.
+	if alienMining && !self.inturn(parent.Header(), header) {
+		log.Debug("Updated pending work outside own slot", "number", work.Block.Number(), "txs", work.tcount)
+		self.updateSnapshot()
+		return
+	}
 	// We only care about logging if we're actually mining.
 	if atomic.LoadInt32(&self.mining) == 1 {
 		log.Info("Commit new mining work", "number", work.Block.Number(), "txs", work.tcount, "uncles", len(uncles), "elapsed", common.PrettyDuration(time.Since(tstart)))
@@ -511,52 +637,25 @@ func (self *worker) commitNewWork() {
 	}
 	self.push(work)
 	self.updateSnapshot()
-	// todo: add params into rlz, to decide if or not send this tx
-	if self.config.Alien != nil && self.config.Alien.PBFTEnable {
-		err = self.sendConfirmTx(parent.Number())
-		if err != nil {
-			log.Info("Fail to Sign the transaction by coinbase", "err", err)
-		}
-	}
-
 }
 
-// For PBFT of alien consensus, the follow code may move later
-// After confirm a new block, the miner send a custom transaction to self, which value is 0
-// and data like "ufo:1:event:confirm:123" (ufo is prefix, 1 is version, 123 is block number
-// to let the next signer now this signer already confirm this block
-func (self *worker) sendConfirmTx(blockNumber *big.Int) error {
-	wallets := self.rlz.AccountManager().Wallets()
-	// wallets check
-	if len(wallets) == 0 {
-		return nil
-	}
-	for _, wallet := range wallets {
-		if len(wallet.Accounts()) == 0 {
-			continue
-		} else {
-			for _, account := range wallet.Accounts() {
-				if account.Address == self.coinbase {
-					// coinbase account found
-					// send custom tx
-					nonce := self.snapshotState.GetNonce(account.Address)
-					tmpTx := types.NewTransaction(nonce, account.Address, big.NewInt(0), uint64(100000), big.NewInt(10000), []byte(fmt.Sprintf("ufo:1:event:confirm:%d", blockNumber)))
-					signedTx, err := wallet.SignTx(account, tmpTx, self.rlz.BlockChain().Config().ChainId)
-					if err != nil {
-						return err
-					} else {
-						err = self.rlz.TxPool().AddLocal(signedTx)
-						if err != nil {
-							return err
-						}
-					}
-					return nil
-				}
-			}
-		}
+// confirmHead confirms a new chain head for PBFT finality while mining. Every
+// head is confirmed as it arrives, independent of whether new work is prepared
+// on top of it.
+func (self *worker) confirmHead(block *types.Block) {
+	if self.config.Alien == nil || !self.config.Alien.PBFTEnable || atomic.LoadInt32(&self.mining) == 0 {
+		return
+	}
+	self.mu.Lock()
+	confirmer, coinbase := self.confirmer, self.coinbase
+	self.mu.Unlock()
 
+	if confirmer == nil {
+		return
+	}
+	if err := confirmer.Confirm(coinbase, block.Header()); err != nil && err != core.ErrKnownConfirmation && err != core.ErrStaleConfirmation {
+		log.Debug("Failed to confirm chain head", "number", block.Number(), "hash", block.Hash(), "err", err)
 	}
-	return nil
 }
 
 func (self *worker) commitUncle(work *Work, uncle *types.Header) error {
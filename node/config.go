@@ -66,6 +66,11 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DBEngine is the storage engine new databases are created with, either
+	// "leveldb" or "bolt". Existing databases are always opened with the engine
+	// they were created with. Defaults to LevelDB if empty.
+	DBEngine string `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
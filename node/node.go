@@ -27,11 +27,13 @@ import (
 	"sync"
 
 	"github.com/relianz2019/relianz/accounts"
+	"github.com/relianz2019/relianz/core/rawdb"
 	"github.com/relianz2019/relianz/ethdb"
 	"github.com/relianz2019/relianz/event"
 	"github.com/relianz2019/relianz/internal/debug"
 	"github.com/relianz2019/relianz/log"
 	"github.com/relianz2019/relianz/p2p"
+	"github.com/relianz2019/relianz/params"
 	"github.com/relianz2019/relianz/rpc"
 	"github.com/prometheus/prometheus/util/flock"
 )
@@ -570,7 +572,45 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (ethdb.Database, er
 	if n.config.DataDir == "" {
 		return ethdb.NewMemDatabase(), nil
 	}
-	return ethdb.NewLDBDatabase(n.config.resolvePath(name), cache, handles)
+	return ethdb.NewDatabase(n.config.DBEngine, n.config.resolvePath(name), cache, handles)
+}
+
+// OpenDatabaseWithFreezer opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's data directory,
+// also attaching a chain freezer to it that moves ancient chain data from the
+// database to immutable append-only files. If the node is an ephemeral one, a
+// memory database is returned.
+func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer string, threshold uint64) (ethdb.Database, error) {
+	if n.config.DataDir == "" {
+		return ethdb.NewMemDatabase(), nil
+	}
+	return openDatabaseWithFreezer(n.config, name, cache, handles, freezer, threshold)
+}
+
+// openDatabaseWithFreezer opens a persistent database with a chain freezer in
+// the given freezer directory, defaulting to "ancient" within the database. A
+// zero threshold disables the freezer.
+func openDatabaseWithFreezer(config *Config, name string, cache, handles int, freezer string, threshold uint64) (ethdb.Database, error) {
+	if threshold != 0 && threshold < params.FreezerThresholdMin {
+		return nil, fmt.Errorf("freezer threshold %d below the minimum of %d blocks", threshold, params.FreezerThresholdMin)
+	}
+	root := config.resolvePath(name)
+	db, err := ethdb.NewDatabase(config.DBEngine, root, cache, handles)
+	if err != nil || threshold == 0 {
+		return db, err
+	}
+	switch {
+	case freezer == "":
+		freezer = filepath.Join(root, "ancient")
+	case !filepath.IsAbs(freezer):
+		freezer = config.resolvePath(freezer)
+	}
+	frdb, err := rawdb.NewDatabaseWithFreezer(db, freezer, threshold)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return frdb, nil
 }
 
 // ResolvePath returns the absolute path of a resource in the instance directory.
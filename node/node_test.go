@@ -26,6 +26,7 @@ import (
 
 	"github.com/relianz2019/relianz/crypto"
 	"github.com/relianz2019/relianz/p2p"
+	"github.com/relianz2019/relianz/params"
 	"github.com/relianz2019/relianz/rpc"
 )
 
@@ -103,6 +104,32 @@ func TestNodeUsedDataDir(t *testing.T) {
 	}
 }
 
+// Tests that a freezer is only attached to a database if its threshold leaves
+// enough recent blocks out of it to reorg.
+func TestNodeFreezerThreshold(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	stack, err := New(&Config{DataDir: dir})
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if db, err := stack.OpenDatabaseWithFreezer("chaindata", 0, 0, "", params.FreezerThresholdMin-1); err == nil {
+		db.Close()
+		t.Fatalf("freezer attached with a threshold below the minimum")
+	}
+	for _, threshold := range []uint64{0, params.FreezerThresholdMin} {
+		db, err := stack.OpenDatabaseWithFreezer("chaindata", 0, 0, "", threshold)
+		if err != nil {
+			t.Fatalf("threshold %d: failed to open database: %v", threshold, err)
+		}
+		db.Close()
+	}
+}
+
 // Tests whether services can be registered and duplicates caught.
 func TestServiceRegistry(t *testing.T) {
 	stack, err := New(testNodeConfig())
@@ -43,13 +43,25 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (et
 	if ctx.config.DataDir == "" {
 		return ethdb.NewMemDatabase(), nil
 	}
-	db, err := ethdb.NewLDBDatabase(ctx.config.resolvePath(name), cache, handles)
+	db, err := ethdb.NewDatabase(ctx.config.DBEngine, ctx.config.resolvePath(name), cache, handles)
 	if err != nil {
 		return nil, err
 	}
 	return db, nil
 }
 
+// OpenDatabaseWithFreezer opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's data directory,
+// also attaching a chain freezer to it that moves ancient chain data from the
+// database to immutable append-only files. If the node is an ephemeral one, a
+// memory database is returned.
+func (ctx *ServiceContext) OpenDatabaseWithFreezer(name string, cache int, handles int, freezer string, threshold uint64) (ethdb.Database, error) {
+	if ctx.config.DataDir == "" {
+		return ethdb.NewMemDatabase(), nil
+	}
+	return openDatabaseWithFreezer(ctx.config, name, cache, handles, freezer, threshold)
+}
+
 // ResolvePath resolves a user path into the data directory if that was relative
 // and if the user actually uses persistent storage. It will return an empty string
 // for emphemeral storage and the user's own input for absolute paths.
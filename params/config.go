@@ -145,23 +145,23 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllRlzashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(RlzashConfig), nil, nil}
+	AllRlzashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, new(RlzashConfig), nil, nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Relianz core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
 
 	// AllAlienProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Relianz core developers into the Alien consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllAlienProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, &AlienConfig{Period: 3, Epoch: 30000, MaxSignerCount: 21, MinVoterBalance: new(big.Int).Mul(big.NewInt(10000), big.NewInt(1000000000000000000)), GenesisTimestamp: 0, SelfVoteSigners: []common.UnprefixedAddress{}}}
+	AllAlienProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, &AlienConfig{Period: 3, Epoch: 30000, MaxSignerCount: 21, MinVoterBalance: new(big.Int).Mul(big.NewInt(10000), big.NewInt(1000000000000000000)), GenesisTimestamp: 0, SelfVoteSigners: []common.UnprefixedAddress{}}}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(RlzashConfig), nil, nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, new(RlzashConfig), nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -184,6 +184,13 @@ type ChainConfig struct {
 
 	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`      // Byzantium switch block (nil = no fork, 0 = already on byzantium)
 	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
+	IstanbulBlock       *big.Int `json:"istanbulBlock,omitempty"`       // Istanbul switch block (nil = no fork, 0 = already activated)
+
+	// Additional named upgrades, in activation order, on top of the built-in ones
+	Forks []Fork `json:"forks,omitempty"`
+
+	// Custom pre-compiled contracts registered with the EVM by the node
+	Precompiles []PrecompileConfig `json:"precompiles,omitempty"`
 
 	// Various consensus engines
 	Rlzash *RlzashConfig `json:"ethash,omitempty"`
@@ -233,6 +240,7 @@ type AlienConfig struct {
 
 	TrantorBlock  *big.Int          `json:"trantorBlock,omitempty"`  // Trantor switch block (nil = no fork)
 	TerminusBlock *big.Int          `json:"terminusBlock,omitempty"` // Terminus switch block (nil = no fork)
+	KalganBlock   *big.Int          `json:"kalganBlock,omitempty"`   // Kalgan switch block (nil = no fork, 0 = already activated), enables net gas metering for SSTORE
 	LightConfig   *AlienLightConfig `json:"lightConfig,omitempty"`
 }
 
@@ -251,6 +259,11 @@ func (a *AlienConfig) IsTerminus(num *big.Int) bool {
 	return isForked(a.TerminusBlock, num)
 }
 
+// IsKalgan returns whether num is either equal to the Kalgan block or greater.
+func (a *AlienConfig) IsKalgan(num *big.Int) bool {
+	return isForked(a.KalganBlock, num)
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -264,16 +277,11 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Engine: %v}",
-		c.ChainId,
-		c.HomesteadBlock,
-		c.EIP150Block,
-		c.EIP155Block,
-		c.EIP158Block,
-		c.ByzantiumBlock,
-		c.ConstantinopleBlock,
-		engine,
-	)
+	var forks string
+	for _, fork := range c.AllForks() {
+		forks += fmt.Sprintf("%s: %v ", fork.Name, fork.Block)
+	}
+	return fmt.Sprintf("{ChainID: %v %sEngine: %v}", c.ChainId, forks, engine)
 }
 
 // IsHomestead returns whether num is either equal to the homestead block or greater.
@@ -301,6 +309,11 @@ func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
 	return isForked(c.ConstantinopleBlock, num)
 }
 
+// IsIstanbul returns whether num is either equal to the Istanbul block or greater.
+func (c *ChainConfig) IsIstanbul(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -309,6 +322,8 @@ func (c *ChainConfig) GasTable(num *big.Int) GasTable {
 		return GasTableHomestead
 	}
 	switch {
+	case c.IsConstantinople(num):
+		return GasTableConstantinople
 	case c.IsEIP158(num):
 		return GasTableEIP158
 	case c.IsEIP150(num):
@@ -337,30 +352,77 @@ func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *Confi
 }
 
 func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
-	if isForkIncompatible(c.HomesteadBlock, newcfg.HomesteadBlock, head) {
-		return newCompatError("Homestead fork block", c.HomesteadBlock, newcfg.HomesteadBlock)
-	}
-	if isForkIncompatible(c.EIP150Block, newcfg.EIP150Block, head) {
-		return newCompatError("EIP150 fork block", c.EIP150Block, newcfg.EIP150Block)
-	}
-	if isForkIncompatible(c.EIP155Block, newcfg.EIP155Block, head) {
-		return newCompatError("EIP155 fork block", c.EIP155Block, newcfg.EIP155Block)
+	if err := c.checkForksCompatible(newcfg, head); err != nil {
+		return err
 	}
-	if isForkIncompatible(c.EIP158Block, newcfg.EIP158Block, head) {
-		return newCompatError("EIP158 fork block", c.EIP158Block, newcfg.EIP158Block)
+	if err := c.checkPrecompilesCompatible(newcfg, head); err != nil {
+		return err
 	}
 	if c.IsEIP158(head) && !configNumEqual(c.ChainId, newcfg.ChainId) {
 		return newCompatError("EIP158 chain ID", c.EIP158Block, newcfg.EIP158Block)
 	}
-	if isForkIncompatible(c.ByzantiumBlock, newcfg.ByzantiumBlock, head) {
-		return newCompatError("Byzantium fork block", c.ByzantiumBlock, newcfg.ByzantiumBlock)
+	if err := c.checkAlienCompatible(newcfg, head); err != nil {
+		return err
 	}
-	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
-		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
+	return nil
+}
+
+// checkAlienCompatible checks the consensus relevant parameters of the Alien engine.
+// Its fork blocks are covered by the fork registry, while the remaining parameters
+// are in effect since genesis and cannot be changed once blocks have been imported.
+func (c *ChainConfig) checkAlienCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
+	stored, updated := c.Alien, newcfg.Alien
+	if stored == nil && updated == nil {
+		return nil
+	}
+	if head.Sign() > 0 && (stored == nil || updated == nil) {
+		return newCompatValueError("Alien consensus engine", boolToBig(stored != nil), boolToBig(updated != nil))
+	}
+	if stored == nil || updated == nil || head.Sign() == 0 {
+		return nil
+	}
+	switch {
+	case stored.Period != updated.Period:
+		return newCompatValueError("Alien period", new(big.Int).SetUint64(stored.Period), new(big.Int).SetUint64(updated.Period))
+	case stored.Epoch != updated.Epoch:
+		return newCompatValueError("Alien epoch", new(big.Int).SetUint64(stored.Epoch), new(big.Int).SetUint64(updated.Epoch))
+	case stored.MaxSignerCount != updated.MaxSignerCount:
+		return newCompatValueError("Alien max signer count", new(big.Int).SetUint64(stored.MaxSignerCount), new(big.Int).SetUint64(updated.MaxSignerCount))
+	case !configNumEqual(stored.MinVoterBalance, updated.MinVoterBalance):
+		return newCompatValueError("Alien min voter balance", stored.MinVoterBalance, updated.MinVoterBalance)
+	case stored.GenesisTimestamp != updated.GenesisTimestamp:
+		return newCompatValueError("Alien genesis timestamp", new(big.Int).SetUint64(stored.GenesisTimestamp), new(big.Int).SetUint64(updated.GenesisTimestamp))
+	case stored.SideChain != updated.SideChain:
+		return newCompatValueError("Alien side chain mode", boolToBig(stored.SideChain), boolToBig(updated.SideChain))
+	case stored.PBFTEnable != updated.PBFTEnable:
+		return newCompatValueError("Alien PBFT mode", boolToBig(stored.PBFTEnable), boolToBig(updated.PBFTEnable))
+	case !signersEqual(stored.SelfVoteSigners, updated.SelfVoteSigners):
+		return newCompatValueError("Alien genesis signer count", big.NewInt(int64(len(stored.SelfVoteSigners))), big.NewInt(int64(len(updated.SelfVoteSigners))))
 	}
 	return nil
 }
 
+// signersEqual returns whether two genesis signer lists are identical, order included.
+func signersEqual(x, y []common.UnprefixedAddress) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// boolToBig converts a boolean config switch into a number for error reporting.
+func boolToBig(b bool) *big.Int {
+	if b {
+		return big.NewInt(1)
+	}
+	return big.NewInt(0)
+}
+
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
 // block s2 because head is already past the fork.
 func isForkIncompatible(s1, s2, head *big.Int) bool {
@@ -389,7 +451,7 @@ func configNumEqual(x, y *big.Int) bool {
 // ChainConfig that would alter the past.
 type ConfigCompatError struct {
 	What string
-	// block numbers of the stored and new configurations
+	// block numbers (or parameter values for non-fork settings) of the stored and new configurations
 	StoredConfig, NewConfig *big.Int
 	// the block number to which the local chain must be rewound to correct the error
 	RewindTo uint64
@@ -412,6 +474,13 @@ func newCompatError(what string, storedblock, newblock *big.Int) *ConfigCompatEr
 	return err
 }
 
+// newCompatValueError creates a compatibility error for a parameter which is in
+// effect since genesis. The stored and new fields hold the conflicting values and
+// the only way to correct the error is to rewind the chain to the genesis block.
+func newCompatValueError(what string, storedval, newval *big.Int) *ConfigCompatError {
+	return &ConfigCompatError{what, storedval, newval, 0}
+}
+
 func (err *ConfigCompatError) Error() string {
 	return fmt.Sprintf("mismatching %s in database (have %d, want %d, rewindto %d)", err.What, err.StoredConfig, err.NewConfig, err.RewindTo)
 }
@@ -424,7 +493,9 @@ func (err *ConfigCompatError) Error() string {
 type Rules struct {
 	ChainId                                   *big.Int
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158 bool
-	IsByzantium                               bool
+	IsByzantium, IsConstantinople, IsIstanbul bool
+
+	active map[string]bool // Every upgrade active at the block, by name
 }
 
 func (c *ChainConfig) Rules(num *big.Int) Rules {
@@ -432,5 +503,26 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	if chainId == nil {
 		chainId = new(big.Int)
 	}
-	return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num)}
+	active := make(map[string]bool)
+	for _, fork := range c.AllForks() {
+		if isForked(fork.Block, num) {
+			active[fork.Name] = true
+		}
+	}
+	return Rules{
+		ChainId:          new(big.Int).Set(chainId),
+		IsHomestead:      active["Homestead"],
+		IsEIP150:         active["EIP150"],
+		IsEIP155:         active["EIP155"],
+		IsEIP158:         active["EIP158"],
+		IsByzantium:      active["Byzantium"],
+		IsConstantinople: active["Constantinople"],
+		IsIstanbul:       active["Istanbul"],
+		active:           active,
+	}
+}
+
+// IsActive returns whether the named upgrade is active under these rules.
+func (r Rules) IsActive(name string) bool {
+	return r.active[name]
 }
@@ -79,3 +79,95 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckAlienCompatible(t *testing.T) {
+	type test struct {
+		stored, new *AlienConfig
+		head        uint64
+		wantErr     *ConfigCompatError
+	}
+	tests := []test{
+		{stored: &AlienConfig{Period: 3}, new: &AlienConfig{Period: 3}, head: 100, wantErr: nil},
+		{
+			stored:  &AlienConfig{Period: 3, TrantorBlock: big.NewInt(10)},
+			new:     &AlienConfig{Period: 3, TrantorBlock: big.NewInt(20)},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &AlienConfig{TrantorBlock: big.NewInt(10)},
+			new:    &AlienConfig{TrantorBlock: big.NewInt(20)},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "Trantor fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &AlienConfig{TrantorBlock: big.NewInt(10), TerminusBlock: big.NewInt(30)},
+			new:    &AlienConfig{TrantorBlock: big.NewInt(10), TerminusBlock: nil},
+			head:   40,
+			wantErr: &ConfigCompatError{
+				What:         "Terminus fork block",
+				StoredConfig: big.NewInt(30),
+				NewConfig:    nil,
+				RewindTo:     29,
+			},
+		},
+		{stored: &AlienConfig{Period: 3}, new: &AlienConfig{Period: 5}, head: 0, wantErr: nil},
+		{
+			stored: &AlienConfig{Period: 3},
+			new:    &AlienConfig{Period: 5},
+			head:   1,
+			wantErr: &ConfigCompatError{
+				What:         "Alien period",
+				StoredConfig: big.NewInt(3),
+				NewConfig:    big.NewInt(5),
+				RewindTo:     0,
+			},
+		},
+		{
+			stored: &AlienConfig{MaxSignerCount: 21},
+			new:    &AlienConfig{MaxSignerCount: 7},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "Alien max signer count",
+				StoredConfig: big.NewInt(21),
+				NewConfig:    big.NewInt(7),
+				RewindTo:     0,
+			},
+		},
+		{
+			stored: &AlienConfig{SideChain: false},
+			new:    &AlienConfig{SideChain: true},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "Alien side chain mode",
+				StoredConfig: big.NewInt(0),
+				NewConfig:    big.NewInt(1),
+				RewindTo:     0,
+			},
+		},
+		{
+			// The lowest conflict wins, regardless of which field it stems from.
+			stored: &AlienConfig{Period: 3, TrantorBlock: big.NewInt(10)},
+			new:    &AlienConfig{Period: 5, TrantorBlock: big.NewInt(20)},
+			head:   25,
+			wantErr: &ConfigCompatError{
+				What:         "Alien period",
+				StoredConfig: big.NewInt(3),
+				NewConfig:    big.NewInt(5),
+				RewindTo:     0,
+			},
+		},
+	}
+	for _, test := range tests {
+		stored, new := &ChainConfig{Alien: test.stored}, &ChainConfig{Alien: test.new}
+		err := stored.CheckCompatible(new, test.head)
+		if !reflect.DeepEqual(err, test.wantErr) {
+			t.Errorf("error mismatch:\nstored: %v\nnew: %v\nhead: %v\nerr: %v\nwant: %v", test.stored, test.new, test.head, err, test.wantErr)
+		}
+	}
+}
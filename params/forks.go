@@ -0,0 +1,166 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Fork is a named network upgrade scheduled at a specific block.
+type Fork struct {
+	Name  string   `json:"name"`            // Unique name of the upgrade
+	Block *big.Int `json:"block,omitempty"` // Activation block (nil = no fork, 0 = already activated)
+}
+
+// builtinFork describes a network upgrade known to the protocol along with the
+// accessor used to look up its activation block in a chain configuration.
+type builtinFork struct {
+	name     string
+	optional bool // Whether the fork is only reported when scheduled
+	block    func(c *ChainConfig) *big.Int
+}
+
+// builtinForks is the ordered list of network upgrades known to the protocol.
+// Upgrades that are not part of the protocol itself (e.g. those of a private
+// network) should be scheduled through ChainConfig.Forks instead.
+var builtinForks = []builtinFork{
+	{name: "Homestead", block: func(c *ChainConfig) *big.Int { return c.HomesteadBlock }},
+	{name: "EIP150", block: func(c *ChainConfig) *big.Int { return c.EIP150Block }},
+	{name: "EIP155", block: func(c *ChainConfig) *big.Int { return c.EIP155Block }},
+	{name: "EIP158", block: func(c *ChainConfig) *big.Int { return c.EIP158Block }},
+	{name: "Byzantium", block: func(c *ChainConfig) *big.Int { return c.ByzantiumBlock }},
+	{name: "Constantinople", block: func(c *ChainConfig) *big.Int { return c.ConstantinopleBlock }},
+	{name: "Istanbul", block: func(c *ChainConfig) *big.Int { return c.IstanbulBlock }},
+	{name: "Trantor", optional: true, block: func(c *ChainConfig) *big.Int {
+		if c.Alien == nil {
+			return nil
+		}
+		return c.Alien.TrantorBlock
+	}},
+	{name: "Terminus", optional: true, block: func(c *ChainConfig) *big.Int {
+		if c.Alien == nil {
+			return nil
+		}
+		return c.Alien.TerminusBlock
+	}},
+	{name: "Kalgan", optional: true, block: func(c *ChainConfig) *big.Int {
+		if c.Alien == nil {
+			return nil
+		}
+		return c.Alien.KalganBlock
+	}},
+}
+
+// AllForks returns every network upgrade of the configuration in activation
+// order: the built-in protocol upgrades first, followed by the custom forks.
+// Optional built-in upgrades are only included if scheduled.
+func (c *ChainConfig) AllForks() []Fork {
+	forks := make([]Fork, 0, len(builtinForks)+len(c.Forks))
+	for _, fork := range builtinForks {
+		block := fork.block(c)
+		if fork.optional && block == nil {
+			continue
+		}
+		forks = append(forks, Fork{Name: fork.name, Block: block})
+	}
+	return append(forks, c.Forks...)
+}
+
+// ForkBlock returns the activation block of the named upgrade. The boolean is
+// false if the configuration does not know about the fork at all.
+func (c *ChainConfig) ForkBlock(name string) (*big.Int, bool) {
+	for _, fork := range builtinForks {
+		if fork.name == name {
+			return fork.block(c), true
+		}
+	}
+	for _, fork := range c.Forks {
+		if fork.Name == name {
+			return fork.Block, true
+		}
+	}
+	return nil, false
+}
+
+// IsActive returns whether num is either equal to the activation block of the
+// named upgrade or greater. Unknown upgrades are never active.
+func (c *ChainConfig) IsActive(name string, num *big.Int) bool {
+	block, _ := c.ForkBlock(name)
+	return isForked(block, num)
+}
+
+// CheckForkOrder verifies that the custom forks have unique names that do not
+// shadow any built-in upgrade, and that they are scheduled in activation order.
+// Unscheduled forks may only be followed by other unscheduled forks.
+func (c *ChainConfig) CheckForkOrder() error {
+	seen := make(map[string]bool)
+	for _, fork := range builtinForks {
+		seen[fork.name] = true
+	}
+	var last *Fork
+	for i := range c.Forks {
+		fork := &c.Forks[i]
+		if fork.Name == "" {
+			return fmt.Errorf("unnamed fork at position %d", i)
+		}
+		if seen[fork.Name] {
+			return fmt.Errorf("duplicate fork %q", fork.Name)
+		}
+		seen[fork.Name] = true
+
+		if last != nil {
+			switch {
+			case last.Block == nil && fork.Block != nil:
+				return fmt.Errorf("unsupported fork ordering: %v not enabled, but %v enabled at %v", last.Name, fork.Name, fork.Block)
+			case last.Block != nil && fork.Block != nil && last.Block.Cmp(fork.Block) > 0:
+				return fmt.Errorf("unsupported fork ordering: %v enabled at %v, but %v enabled at %v", last.Name, last.Block, fork.Name, fork.Block)
+			}
+		}
+		last = fork
+	}
+	return nil
+}
+
+// checkForksCompatible checks every built-in and custom upgrade of the stored
+// configuration against the new one, returning the first conflict.
+func (c *ChainConfig) checkForksCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
+	for _, fork := range builtinForks {
+		stored, updated := fork.block(c), fork.block(newcfg)
+		if isForkIncompatible(stored, updated, head) {
+			return newCompatError(fork.name+" fork block", stored, updated)
+		}
+	}
+	names := make([]string, 0, len(c.Forks)+len(newcfg.Forks))
+	seen := make(map[string]bool)
+	for _, forks := range [][]Fork{c.Forks, newcfg.Forks} {
+		for _, fork := range forks {
+			if !seen[fork.Name] {
+				seen[fork.Name] = true
+				names = append(names, fork.Name)
+			}
+		}
+	}
+	for _, name := range names {
+		stored, _ := c.ForkBlock(name)
+		updated, _ := newcfg.ForkBlock(name)
+		if isForkIncompatible(stored, updated, head) {
+			return newCompatError(name+" fork block", stored, updated)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestForkJSONRoundTrip(t *testing.T) {
+	// Configurations without custom forks must keep their encoding.
+	legacy := `{"chainId":1,"homesteadBlock":1,"byzantiumBlock":4}`
+
+	config := new(ChainConfig)
+	if err := json.Unmarshal([]byte(legacy), config); err != nil {
+		t.Fatalf("failed to decode legacy config: %v", err)
+	}
+	if config.Forks != nil {
+		t.Errorf("legacy config gained forks: %v", config.Forks)
+	}
+	blob, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("failed to encode legacy config: %v", err)
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(blob, &fields); err != nil {
+		t.Fatalf("failed to decode legacy encoding: %v", err)
+	}
+	if _, ok := fields["forks"]; ok {
+		t.Errorf("legacy encoding gained forks: %s", blob)
+	}
+	// Custom forks must survive a round trip in order.
+	config.Forks = []Fork{{Name: "Andromeda", Block: big.NewInt(10)}, {Name: "Betelgeuse"}}
+	if blob, err = json.Marshal(config); err != nil {
+		t.Fatalf("failed to encode config: %v", err)
+	}
+	decoded := new(ChainConfig)
+	if err := json.Unmarshal(blob, decoded); err != nil {
+		t.Fatalf("failed to decode config: %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Forks, config.Forks) {
+		t.Errorf("fork mismatch: have %v, want %v", decoded.Forks, config.Forks)
+	}
+}
+
+func TestCheckForkOrder(t *testing.T) {
+	tests := []struct {
+		forks []Fork
+		fail  bool
+	}{
+		{forks: nil},
+		{forks: []Fork{{Name: "A", Block: big.NewInt(1)}, {Name: "B", Block: big.NewInt(1)}, {Name: "C"}}},
+		{forks: []Fork{{Name: "A", Block: big.NewInt(2)}, {Name: "B", Block: big.NewInt(1)}}, fail: true},
+		{forks: []Fork{{Name: "A"}, {Name: "B", Block: big.NewInt(1)}}, fail: true},
+		{forks: []Fork{{Name: "A"}, {Name: "A"}}, fail: true},
+		{forks: []Fork{{Name: "Byzantium", Block: big.NewInt(1)}}, fail: true},
+		{forks: []Fork{{Block: big.NewInt(1)}}, fail: true},
+	}
+	for i, test := range tests {
+		err := (&ChainConfig{Forks: test.forks}).CheckForkOrder()
+		if test.fail && err == nil {
+			t.Errorf("test %d: expected failure", i)
+		}
+		if !test.fail && err != nil {
+			t.Errorf("test %d: unexpected failure: %v", i, err)
+		}
+	}
+}
+
+func TestCustomForkRules(t *testing.T) {
+	config := &ChainConfig{
+		HomesteadBlock: big.NewInt(0),
+		Alien:          &AlienConfig{TrantorBlock: big.NewInt(5)},
+		Forks:          []Fork{{Name: "Andromeda", Block: big.NewInt(10)}},
+	}
+	for _, test := range []struct {
+		num                int64
+		trantor, andromeda bool
+	}{
+		{num: 0},
+		{num: 5, trantor: true},
+		{num: 10, trantor: true, andromeda: true},
+	} {
+		num := big.NewInt(test.num)
+		rules := config.Rules(num)
+		if !rules.IsHomestead || !rules.IsActive("Homestead") {
+			t.Errorf("block %d: homestead not active", test.num)
+		}
+		if have := rules.IsActive("Trantor"); have != test.trantor || config.Alien.IsTrantor(num) != have {
+			t.Errorf("block %d: trantor mismatch: have %v, want %v", test.num, have, test.trantor)
+		}
+		if have := rules.IsActive("Andromeda"); have != test.andromeda || config.IsActive("Andromeda", num) != have {
+			t.Errorf("block %d: andromeda mismatch: have %v, want %v", test.num, have, test.andromeda)
+		}
+		if rules.IsActive("Unknown") {
+			t.Errorf("block %d: unknown fork active", test.num)
+		}
+	}
+}
+
+func TestCheckCustomForkCompatible(t *testing.T) {
+	stored := &ChainConfig{Forks: []Fork{{Name: "Andromeda", Block: big.NewInt(10)}}}
+
+	// Rescheduling a fork ahead of the head is fine
+	if err := stored.CheckCompatible(&ChainConfig{Forks: []Fork{{Name: "Andromeda", Block: big.NewInt(20)}}}, 5); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// Dropping an active fork is not
+	want := &ConfigCompatError{
+		What:         "Andromeda fork block",
+		StoredConfig: big.NewInt(10),
+		NewConfig:    nil,
+		RewindTo:     9,
+	}
+	if err := stored.CheckCompatible(&ChainConfig{}, 15); !reflect.DeepEqual(err, want) {
+		t.Errorf("error mismatch: have %v, want %v", err, want)
+	}
+	// Neither is adding a new fork below the head
+	want = &ConfigCompatError{
+		What:         "Betelgeuse fork block",
+		StoredConfig: nil,
+		NewConfig:    big.NewInt(12),
+		RewindTo:     11,
+	}
+	updated := &ChainConfig{Forks: []Fork{{Name: "Andromeda", Block: big.NewInt(10)}, {Name: "Betelgeuse", Block: big.NewInt(12)}}}
+	if err := stored.CheckCompatible(updated, 15); !reflect.DeepEqual(err, want) {
+		t.Errorf("error mismatch: have %v, want %v", err, want)
+	}
+}
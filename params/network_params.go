@@ -23,4 +23,18 @@ const (
 	// BloomBitsBlocks is the number of blocks a single bloom bit section vector
 	// contains.
 	BloomBitsBlocks uint64 = 4096
+
+	// SignerIndexBlocks is the number of blocks a single signer history section
+	// accumulates statistics for.
+	SignerIndexBlocks uint64 = 4096
+
+	// SideChainNotifyBlocks is the number of side chain blocks between two
+	// notifications of the main chain.
+	SideChainNotifyBlocks uint64 = 1
+
+	// FreezerThresholdMin is the smallest number of recent blocks that must be
+	// kept out of the ancient store. It covers the 128 tries the blockchain
+	// keeps in memory plus a margin for reorgs, as frozen blocks can neither
+	// be reorged nor have side chains.
+	FreezerThresholdMin uint64 = 1024
 )
@@ -0,0 +1,112 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/relianz2019/relianz/common"
+)
+
+// PrecompileConfig activates a custom pre-compiled contract at an address. The
+// implementation is looked up by name among the contracts the node registered
+// with the EVM, so every node of the network has to run the same registrations.
+type PrecompileConfig struct {
+	Name    string         `json:"name"`            // Name the implementation is registered under
+	Address common.Address `json:"address"`         // Address the contract is callable at
+	Block   *big.Int       `json:"block,omitempty"` // Activation block (nil = not activated, 0 = already activated)
+}
+
+// ActivePrecompiles returns the custom pre-compiled contracts activated at the
+// given block number.
+func (c *ChainConfig) ActivePrecompiles(num *big.Int) []PrecompileConfig {
+	var active []PrecompileConfig
+	for _, precompile := range c.Precompiles {
+		if isForked(precompile.Block, num) {
+			active = append(active, precompile)
+		}
+	}
+	return active
+}
+
+// CheckPrecompiles verifies that the custom pre-compiled contracts are named,
+// and that neither a name nor an address is configured twice.
+func (c *ChainConfig) CheckPrecompiles() error {
+	var (
+		names = make(map[string]bool)
+		addrs = make(map[common.Address]string)
+	)
+	for i, precompile := range c.Precompiles {
+		if precompile.Name == "" {
+			return fmt.Errorf("unnamed precompile at position %d", i)
+		}
+		if names[precompile.Name] {
+			return fmt.Errorf("duplicate precompile %q", precompile.Name)
+		}
+		if name, ok := addrs[precompile.Address]; ok {
+			return fmt.Errorf("precompiles %q and %q share address %x", name, precompile.Name, precompile.Address)
+		}
+		names[precompile.Name] = true
+		addrs[precompile.Address] = precompile.Name
+	}
+	return nil
+}
+
+// precompile returns the configuration of the named custom pre-compiled
+// contract, or nil if it is not configured.
+func (c *ChainConfig) precompile(name string) *PrecompileConfig {
+	for i := range c.Precompiles {
+		if c.Precompiles[i].Name == name {
+			return &c.Precompiles[i]
+		}
+	}
+	return nil
+}
+
+// checkPrecompilesCompatible checks every custom pre-compiled contract of the
+// stored configuration against the new one, returning the first conflict. A
+// contract may neither be rescheduled nor moved once it was activated.
+func (c *ChainConfig) checkPrecompilesCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
+	seen := make(map[string]bool)
+	for _, precompiles := range [][]PrecompileConfig{c.Precompiles, newcfg.Precompiles} {
+		for _, precompile := range precompiles {
+			if seen[precompile.Name] {
+				continue
+			}
+			seen[precompile.Name] = true
+
+			var (
+				stored, updated           = c.precompile(precompile.Name), newcfg.precompile(precompile.Name)
+				storedBlock, updatedBlock *big.Int
+			)
+			if stored != nil {
+				storedBlock = stored.Block
+			}
+			if updated != nil {
+				updatedBlock = updated.Block
+			}
+			if isForkIncompatible(storedBlock, updatedBlock, head) {
+				return newCompatError(precompile.Name+" precompile block", storedBlock, updatedBlock)
+			}
+			if stored != nil && updated != nil && stored.Address != updated.Address && isForked(storedBlock, head) {
+				return newCompatError(precompile.Name+" precompile address", storedBlock, updatedBlock)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+)
+
+func TestCheckPrecompilesCompatible(t *testing.T) {
+	var (
+		addr   = common.BytesToAddress([]byte{0x01, 0x00})
+		stored = &ChainConfig{Precompiles: []PrecompileConfig{{Name: "signers", Address: addr, Block: big.NewInt(10)}}}
+	)
+	// Rescheduling or moving a precompile ahead of the head is fine
+	moved := &ChainConfig{Precompiles: []PrecompileConfig{{Name: "signers", Address: common.BytesToAddress([]byte{0x01, 0x01}), Block: big.NewInt(20)}}}
+	if err := stored.CheckCompatible(moved, 5); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// Moving an active precompile is not
+	moved.Precompiles[0].Block = big.NewInt(10)
+	want := &ConfigCompatError{
+		What:         "signers precompile address",
+		StoredConfig: big.NewInt(10),
+		NewConfig:    big.NewInt(10),
+		RewindTo:     9,
+	}
+	if err := stored.CheckCompatible(moved, 15); !reflect.DeepEqual(err, want) {
+		t.Errorf("error mismatch: have %v, want %v", err, want)
+	}
+	// Neither is dropping it
+	want = &ConfigCompatError{
+		What:         "signers precompile block",
+		StoredConfig: big.NewInt(10),
+		NewConfig:    nil,
+		RewindTo:     9,
+	}
+	if err := stored.CheckCompatible(&ChainConfig{}, 15); !reflect.DeepEqual(err, want) {
+		t.Errorf("error mismatch: have %v, want %v", err, want)
+	}
+}
+
+func TestCheckPrecompiles(t *testing.T) {
+	addr := common.BytesToAddress([]byte{0x01, 0x00})
+	tests := []struct {
+		precompiles []PrecompileConfig
+		fail        bool
+	}{
+		{precompiles: nil},
+		{precompiles: []PrecompileConfig{{Name: "a", Address: addr}, {Name: "b", Address: common.BytesToAddress([]byte{0x01, 0x01})}}},
+		{precompiles: []PrecompileConfig{{Address: addr}}, fail: true},
+		{precompiles: []PrecompileConfig{{Name: "a", Address: addr}, {Name: "a", Address: common.BytesToAddress([]byte{0x01, 0x01})}}, fail: true},
+		{precompiles: []PrecompileConfig{{Name: "a", Address: addr}, {Name: "b", Address: addr}}, fail: true},
+	}
+	for i, test := range tests {
+		err := (&ChainConfig{Precompiles: test.precompiles}).CheckPrecompiles()
+		if test.fail && err == nil {
+			t.Errorf("test %d: expected failure", i)
+		}
+		if !test.fail && err != nil {
+			t.Errorf("test %d: unexpected failure: %v", i, err)
+		}
+	}
+}
@@ -28,6 +28,7 @@ import (
 
 	"github.com/relianz2019/relianz/common"
 	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/consensus"
 	"github.com/relianz2019/relianz/core"
 	"github.com/relianz2019/relianz/core/rawdb"
 	"github.com/relianz2019/relianz/core/state"
@@ -61,24 +62,15 @@ func (api *PublicRlzereumAPI) Coinbase() (common.Address, error) {
 	return api.Rlzerbase()
 }
 
-// Hashrate returns the POW hashrate
-func (api *PublicRlzereumAPI) Hashrate() hexutil.Uint64 {
-	return hexutil.Uint64(api.e.Miner().HashRate())
-}
-
 // PublicMinerAPI provides an API to control the miner.
 // It offers only mrlzods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
-	e     *Rlzereum
-	agent *miner.RemoteAgent
+	e *Rlzereum
 }
 
 // NewPublicMinerAPI create a new PublicMinerAPI instance.
 func NewPublicMinerAPI(e *Rlzereum) *PublicMinerAPI {
-	agent := miner.NewRemoteAgent(e.BlockChain(), e.Engine())
-	e.Miner().Register(agent)
-
-	return &PublicMinerAPI{e, agent}
+	return &PublicMinerAPI{e}
 }
 
 // Mining returns an indication if this node is currently mining.
@@ -86,9 +78,30 @@ func (api *PublicMinerAPI) Mining() bool {
 	return api.e.IsMining()
 }
 
+// PublicPoWMinerAPI provides an API for remote proof-of-work miners. It is only
+// available if the consensus engine supports the consensus.FeaturePoW feature.
+type PublicPoWMinerAPI struct {
+	e     *Rlzereum
+	agent *miner.RemoteAgent
+}
+
+// NewPublicPoWMinerAPI creates a new PublicPoWMinerAPI instance, registering a
+// remote agent with the miner to hand out work packages.
+func NewPublicPoWMinerAPI(e *Rlzereum) *PublicPoWMinerAPI {
+	agent := miner.NewRemoteAgent(e.BlockChain(), e.Engine())
+	e.Miner().Register(agent)
+
+	return &PublicPoWMinerAPI{e, agent}
+}
+
+// Hashrate returns the POW hashrate
+func (api *PublicPoWMinerAPI) Hashrate() hexutil.Uint64 {
+	return hexutil.Uint64(api.e.Miner().HashRate())
+}
+
 // SubmitWork can be used by external miner to submit their POW solution. It returns an indication if the work was
 // accepted. Note, this is not an indication if the provided work was valid!
-func (api *PublicMinerAPI) SubmitWork(nonce types.BlockNonce, solution, digest common.Hash) bool {
+func (api *PublicPoWMinerAPI) SubmitWork(nonce types.BlockNonce, solution, digest common.Hash) bool {
 	return api.agent.SubmitWork(nonce, digest, solution)
 }
 
@@ -96,7 +109,7 @@ func (api *PublicMinerAPI) SubmitWork(nonce types.BlockNonce, solution, digest c
 // result[0], 32 bytes hex encoded current block header pow-hash
 // result[1], 32 bytes hex encoded seed hash used for DAG
 // result[2], 32 bytes hex encoded boundary condition ("target"), 2^256/difficulty
-func (api *PublicMinerAPI) GetWork() ([3]string, error) {
+func (api *PublicPoWMinerAPI) GetWork() ([3]string, error) {
 	if !api.e.IsMining() {
 		if err := api.e.StartMining(false); err != nil {
 			return [3]string{}, err
@@ -112,7 +125,7 @@ func (api *PublicMinerAPI) GetWork() ([3]string, error) {
 // SubmitHashrate can be used for remote miners to submit their hash rate. This enables the node to report the combined
 // hash rate of all miners which submit work through this node. It accepts the miner hash rate and an identifier which
 // must be unique between nodes.
-func (api *PublicMinerAPI) SubmitHashrate(hashrate hexutil.Uint64, id common.Hash) bool {
+func (api *PublicPoWMinerAPI) SubmitHashrate(hashrate hexutil.Uint64, id common.Hash) bool {
 	api.agent.SubmitHashrate(id, uint64(hashrate))
 	return true
 }
@@ -195,11 +208,54 @@ func (api *PrivateMinerAPI) SetRlzerbase(rlzerbase common.Address) bool {
 	return true
 }
 
+// Features returns the optional miner features supported by the consensus engine.
+func (api *PrivateMinerAPI) Features() []consensus.MinerFeature {
+	return api.e.Miner().Features()
+}
+
+// PrivatePoWMinerAPI provides private RPC mrlzods to inspect a proof-of-work
+// miner. It is only available if the consensus engine supports the
+// consensus.FeaturePoW feature.
+type PrivatePoWMinerAPI struct {
+	e *Rlzereum
+}
+
+// NewPrivatePoWMinerAPI creates a new PrivatePoWMinerAPI instance.
+func NewPrivatePoWMinerAPI(e *Rlzereum) *PrivatePoWMinerAPI {
+	return &PrivatePoWMinerAPI{e: e}
+}
+
 // GetHashrate returns the current hashrate of the miner.
-func (api *PrivateMinerAPI) GetHashrate() uint64 {
+func (api *PrivatePoWMinerAPI) GetHashrate() uint64 {
 	return uint64(api.e.miner.HashRate())
 }
 
+// PrivateScheduleMinerAPI provides private RPC mrlzods to inspect the signer
+// schedule of the miner. It is only available if the consensus engine supports
+// the consensus.FeatureSchedule feature.
+type PrivateScheduleMinerAPI struct {
+	e *Rlzereum
+}
+
+// NewPrivateScheduleMinerAPI creates a new PrivateScheduleMinerAPI instance.
+func NewPrivateScheduleMinerAPI(e *Rlzereum) *PrivateScheduleMinerAPI {
+	return &PrivateScheduleMinerAPI{e: e}
+}
+
+// Schedule returns the next n slots, ten by default, in which the rlzerbase is in
+// turn to seal a block according to the Alien signer schedule.
+func (api *PrivateScheduleMinerAPI) Schedule(n *int) ([]miner.Slot, error) {
+	rlzerbase, err := api.e.Rlzerbase()
+	if err != nil {
+		return nil, err
+	}
+	count := 10
+	if n != nil {
+		count = *n
+	}
+	return api.e.Miner().Schedule(rlzerbase, count)
+}
+
 // PrivateAdminAPI is the collection of Rlzereum full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -293,6 +349,24 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// PrivateTxPoolAPI is the collection of transaction pool APIs exposed over the
+// private txpool endpoint.
+type PrivateTxPoolAPI struct {
+	rlz *Rlzereum
+}
+
+// NewPrivateTxPoolAPI creates a new API definition for the private transaction
+// pool methods of the Rlzereum service.
+func NewPrivateTxPoolAPI(rlz *Rlzereum) *PrivateTxPoolAPI {
+	return &PrivateTxPoolAPI{rlz: rlz}
+}
+
+// Save writes all transactions of the pool into the configured snapshot file,
+// returning the number of transactions saved.
+func (api *PrivateTxPoolAPI) Save() (int, error) {
+	return api.rlz.TxPool().SaveSnapshot()
+}
+
 // PublicDebugAPI is the collection of Rlzereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -305,14 +379,52 @@ func NewPublicDebugAPI(rlz *Rlzereum) *PublicDebugAPI {
 	return &PublicDebugAPI{rlz: rlz}
 }
 
+// AccountRangeMaxResults is the maximum number of accounts a single account
+// range request returns.
+const AccountRangeMaxResults = 256
+
 // DumpBlock retrieves the entire state of the database at a given block.
 func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error) {
+	stateDb, err := api.stateAtBlock(blockNr)
+	if err != nil {
+		return state.Dump{}, err
+	}
+	dump := state.Dump{
+		Accounts: make(map[string]state.DumpAccount),
+	}
+	if _, err := stateDb.DumpToCollector(&dump, nil); err != nil {
+		return state.Dump{}, err
+	}
+	return dump, nil
+}
+
+// AccountRange retrieves a page of the accounts in the state at a given block,
+// in the order of their hashed addresses, starting from the given hashed address.
+// The returned dump holds the hashed address to request the next page from.
+func (api *PublicDebugAPI) AccountRange(blockNr rpc.BlockNumber, start hexutil.Bytes, maxResults int, nocode, nostorage bool) (state.IteratorDump, error) {
+	stateDb, err := api.stateAtBlock(blockNr)
+	if err != nil {
+		return state.IteratorDump{}, err
+	}
+	if maxResults <= 0 || maxResults > AccountRangeMaxResults {
+		maxResults = AccountRangeMaxResults
+	}
+	return stateDb.IteratorDump(&state.DumpConfig{
+		SkipCode:    nocode,
+		SkipStorage: nostorage,
+		Start:       start,
+		Max:         maxResults,
+	})
+}
+
+// stateAtBlock retrieves the state of the database at a given block.
+func (api *PublicDebugAPI) stateAtBlock(blockNr rpc.BlockNumber) (*state.StateDB, error) {
 	if blockNr == rpc.PendingBlockNumber {
 		// If we're dumping the pending state, we need to request
 		// both the pending block as well as the pending state from
 		// the miner and operate on those
 		_, stateDb := api.rlz.miner.Pending()
-		return stateDb.RawDump(), nil
+		return stateDb, nil
 	}
 	var block *types.Block
 	if blockNr == rpc.LatestBlockNumber {
@@ -321,13 +433,9 @@ func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error
 		block = api.rlz.blockchain.GetBlockByNumber(uint64(blockNr))
 	}
 	if block == nil {
-		return state.Dump{}, fmt.Errorf("block #%d not found", blockNr)
-	}
-	stateDb, err := api.rlz.BlockChain().StateAt(block.Root())
-	if err != nil {
-		return state.Dump{}, err
+		return nil, fmt.Errorf("block #%d not found", blockNr)
 	}
-	return stateDb.RawDump(), nil
+	return api.rlz.BlockChain().StateAt(block.Root())
 }
 
 // PrivateDebugAPI is the collection of Rlzereum full node APIs exposed over
@@ -18,6 +18,7 @@ package rlz
 
 import (
 	"context"
+	"errors"
 	"math/big"
 
 	"github.com/relianz2019/relianz/accounts"
@@ -37,6 +38,12 @@ import (
 	"github.com/relianz2019/relianz/rpc"
 )
 
+var (
+	errFinalityDisabled = errors.New("finalized block unavailable, PBFT confirmations are disabled")
+	errNoFinalizedBlock = errors.New("no block finalized yet")
+	errNoVoteTally      = errors.New("consensus engine does not elect signers by vote")
+)
+
 // RlzAPIBackend implements rlzapi.Backend for full nodes
 type RlzAPIBackend struct {
 	rlz *Rlzereum
@@ -62,6 +69,10 @@ func (b *RlzAPIBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 		block := b.rlz.miner.PendingBlock()
 		return block.Header(), nil
 	}
+	// Finalized block is only known by the confirmation tracker
+	if blockNr == rpc.FinalizedBlockNumber {
+		return b.FinalizedHeader(ctx)
+	}
 	// Otherwise resolve and return the block
 	if blockNr == rpc.LatestBlockNumber {
 		return b.rlz.blockchain.CurrentBlock().Header(), nil
@@ -75,6 +86,13 @@ func (b *RlzAPIBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 		block := b.rlz.miner.PendingBlock()
 		return block, nil
 	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		header, err := b.FinalizedHeader(ctx)
+		if header == nil || err != nil {
+			return nil, err
+		}
+		return b.rlz.blockchain.GetBlock(header.Hash(), header.Number.Uint64()), nil
+	}
 	// Otherwise resolve and return the block
 	if blockNr == rpc.LatestBlockNumber {
 		return b.rlz.blockchain.CurrentBlock(), nil
@@ -82,6 +100,36 @@ func (b *RlzAPIBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 	return b.rlz.blockchain.GetBlockByNumber(uint64(blockNr)), nil
 }
 
+// FinalizedHeader returns the highest canonical block confirmed by a quorum of
+// signers.
+func (b *RlzAPIBackend) FinalizedHeader(ctx context.Context) (*types.Header, error) {
+	finality := b.rlz.Finality()
+	if finality == nil {
+		return nil, errFinalityDisabled
+	}
+	header := finality.Finalized()
+	if header == nil {
+		return nil, errNoFinalizedBlock
+	}
+	return header, nil
+}
+
+func (b *RlzAPIBackend) VoteOf(ctx context.Context, header *types.Header, voter common.Address) (common.Address, bool, error) {
+	reader, ok := b.rlz.engine.(core.VoteReader)
+	if !ok {
+		return common.Address{}, false, errNoVoteTally
+	}
+	return reader.VoteOf(b.rlz.blockchain, header, voter)
+}
+
+func (b *RlzAPIBackend) NextSigners(ctx context.Context, header *types.Header) ([]common.Address, error) {
+	reader, ok := b.rlz.engine.(core.VoteReader)
+	if !ok {
+		return nil, errNoVoteTally
+	}
+	return reader.NextSigners(b.rlz.blockchain, header)
+}
+
 func (b *RlzAPIBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
 	// Pending state is only known by the miner
 	if blockNr == rpc.PendingBlockNumber {
@@ -152,6 +200,17 @@ func (b *RlzAPIBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) e
 	return b.rlz.BlockChain().SubscribeChainSideEvent(ch)
 }
 
+func (b *RlzAPIBackend) SubscribeChainFinalizedEvent(ch chan<- core.ChainFinalizedEvent) event.Subscription {
+	if finality := b.rlz.Finality(); finality != nil {
+		return finality.SubscribeChainFinalizedEvent(ch)
+	}
+	// PBFT is disabled, no block will ever be finalized
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *RlzAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return b.rlz.BlockChain().SubscribeLogsEvent(ch)
 }
@@ -184,6 +243,14 @@ func (b *RlzAPIBackend) Stats() (pending int, queued int) {
 	return b.rlz.txPool.Stats()
 }
 
+func (b *RlzAPIBackend) TxPoolBytes() uint64 {
+	return b.rlz.txPool.Bytes()
+}
+
+func (b *RlzAPIBackend) TxPoolPriceBump() uint64 {
+	return b.rlz.txPool.PriceBump()
+}
+
 func (b *RlzAPIBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
 	return b.rlz.TxPool().Content()
 }
@@ -192,6 +259,14 @@ func (b *RlzAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.S
 	return b.rlz.TxPool().SubscribeNewTxsEvent(ch)
 }
 
+func (b *RlzAPIBackend) SubscribeDroppedTxsEvent(ch chan<- core.DroppedTxsEvent) event.Subscription {
+	return b.rlz.TxPool().SubscribeDroppedTxsEvent(ch)
+}
+
+func (b *RlzAPIBackend) SubscribeTxRejectedEvent(ch chan<- core.TxRejectedEvent) event.Subscription {
+	return b.rlz.TxPool().SubscribeTxRejectedEvent(ch)
+}
+
 func (b *RlzAPIBackend) Downloader() *downloader.Downloader {
 	return b.rlz.Downloader()
 }
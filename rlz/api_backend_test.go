@@ -0,0 +1,179 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlz
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/consensus/rlzash"
+	"github.com/relianz2019/relianz/core"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/core/vm"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/internal/rlzapi"
+	"github.com/relianz2019/relianz/params"
+	"github.com/relianz2019/relianz/rlz/filters"
+	"github.com/relianz2019/relianz/rlzdb"
+	"github.com/relianz2019/relianz/rpc"
+)
+
+// finalityTestCode is a contract emitting an empty log and returning the number
+// of the block it's executed in.
+var finalityTestCode = common.FromHex("0x4360005260006000a060206000f3")
+
+// Tests that the finalized block tag resolves to the block confirmed by a quorum
+// of signers in the block, state, call and log APIs.
+func TestFinalizedBlockTag(t *testing.T) {
+	var (
+		keys    []*ecdsa.PrivateKey
+		signers testSignerSet
+	)
+	for i := 0; i < 4; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		signers = append(signers, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	var (
+		bankKey, _ = crypto.GenerateKey()
+		bank       = crypto.PubkeyToAddress(bankKey.PublicKey)
+		contract   = common.HexToAddress("0x0100000000000000000000000000000000000000")
+
+		db    = rlzdb.NewMemDatabase()
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				bank:     {Balance: big.NewInt(1000000000000000000)},
+				contract: {Code: finalityTestCode},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, rlzash.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, rlzash.NewFaker(), db, 4, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(bank), contract, big.NewInt(1000), 100000, big.NewInt(1), nil), signer, bankKey)
+		gen.AddTx(tx)
+	})
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	bloomIndexer := NewBloomIndexer(db, params.BloomBitsBlocks)
+	defer bloomIndexer.Close()
+
+	rlz := &Rlzereum{
+		chainConfig:  gspec.Config,
+		blockchain:   blockchain,
+		chainDb:      db,
+		bloomIndexer: bloomIndexer,
+	}
+	backend := &RlzAPIBackend{rlz: rlz}
+	api := rlzapi.NewPublicBlockChainAPI(backend)
+	ctx := context.Background()
+
+	// Without finality tracking the tag must be refused
+	if _, err := api.GetBalance(ctx, contract, rpc.FinalizedBlockNumber); err != errFinalityDisabled {
+		t.Fatalf("finalized balance without tracker: have %v, want %v", err, errFinalityDisabled)
+	}
+	rlz.finality = core.NewFinalityTracker(db, blockchain, signers)
+	defer rlz.finality.Stop()
+
+	if _, err := api.GetBlockByNumber(ctx, rpc.FinalizedBlockNumber, false); err != errNoFinalizedBlock {
+		t.Fatalf("finalized block before confirmations: have %v, want %v", err, errNoFinalizedBlock)
+	}
+	// Confirm the second block by a quorum of the signers
+	final := blocks[1]
+
+	var confs []*types.Confirmation
+	for _, key := range keys[:3] {
+		conf, err := types.SignConfirmation(types.NewConfirmation(gspec.Config.ChainId, final.Header()), key)
+		if err != nil {
+			t.Fatalf("failed to sign confirmation: %v", err)
+		}
+		confs = append(confs, conf)
+	}
+	for i, err := range rlz.finality.Add(confs) {
+		if err != nil {
+			t.Fatalf("confirmation %d rejected: %v", i, err)
+		}
+	}
+	// Block retrieval
+	block, err := api.GetBlockByNumber(ctx, rpc.FinalizedBlockNumber, false)
+	if err != nil {
+		t.Fatalf("failed to retrieve finalized block: %v", err)
+	}
+	if block["hash"] != final.Hash() || block["number"].(*hexutil.Big).ToInt().Cmp(final.Number()) != 0 {
+		t.Errorf("finalized block mismatch: have #%v %x, want #%v %x", block["number"], block["hash"], final.Number(), final.Hash())
+	}
+	// Balances at the finalized and the latest blocks
+	for _, tt := range []struct {
+		number rpc.BlockNumber
+		want   int64
+	}{
+		{rpc.FinalizedBlockNumber, 2000},
+		{rpc.LatestBlockNumber, 4000},
+	} {
+		balance, err := api.GetBalance(ctx, contract, tt.number)
+		if err != nil {
+			t.Fatalf("failed to retrieve balance at %d: %v", tt.number, err)
+		}
+		if balance.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("balance mismatch at %d: have %v, want %v", tt.number, balance, tt.want)
+		}
+	}
+	// Calls executed on top of the finalized block
+	res, err := api.Call(ctx, rlzapi.CallArgs{From: bank, To: &contract}, rpc.FinalizedBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to call at finalized block: %v", err)
+	}
+	if number := new(big.Int).SetBytes(res); number.Cmp(final.Number()) != 0 {
+		t.Errorf("call executed in block %v, want %v", number, final.Number())
+	}
+	// Logs up to and from the finalized block
+	for _, tt := range []struct {
+		begin, end rpc.BlockNumber
+		want       []uint64
+	}{
+		{rpc.EarliestBlockNumber, rpc.FinalizedBlockNumber, []uint64{1, 2}},
+		{rpc.FinalizedBlockNumber, rpc.LatestBlockNumber, []uint64{2, 3, 4}},
+	} {
+		logs, err := filters.New(backend, tt.begin.Int64(), tt.end.Int64(), []common.Address{contract}, nil).Logs(ctx)
+		if err != nil {
+			t.Fatalf("failed to filter logs from %d to %d: %v", tt.begin, tt.end, err)
+		}
+		var have []uint64
+		for _, log := range logs {
+			have = append(have, log.BlockNumber)
+		}
+		if len(have) != len(tt.want) {
+			t.Errorf("logs from %d to %d mismatch: have blocks %v, want %v", tt.begin, tt.end, have, tt.want)
+			continue
+		}
+		for i := range have {
+			if have[i] != tt.want[i] {
+				t.Errorf("logs from %d to %d mismatch: have blocks %v, want %v", tt.begin, tt.end, have, tt.want)
+				break
+			}
+		}
+	}
+}
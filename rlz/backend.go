@@ -35,6 +35,7 @@ import (
 	"github.com/relianz2019/relianz/core"
 	"github.com/relianz2019/relianz/core/bloombits"
 	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/core/sidechain"
 	"github.com/relianz2019/relianz/core/types"
 	"github.com/relianz2019/relianz/core/vm"
 	"github.com/relianz2019/relianz/rlz/downloader"
@@ -59,6 +60,16 @@ type LesServer interface {
 	SetBloomBitsIndexer(bbIndexer *core.ChainIndexer)
 }
 
+var (
+	// errNoMainChain is returned if an Alien side chain is started without a main
+	// chain to anchor to.
+	errNoMainChain = errors.New("side chain requires a main chain RPC endpoint")
+
+	// errPruningInterrupted is returned if an offline state pruning was interrupted
+	// and left part of the state to be deleted.
+	errPruningInterrupted = errors.New("state pruning was interrupted, finish it with relianz snapshot prune-state")
+)
+
 // Rlzereum implements the Rlzereum full node service.
 type Rlzereum struct {
 	config      *Config
@@ -83,6 +94,15 @@ type Rlzereum struct {
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
 
+	signerIndexer *core.ChainIndexer // Alien signer history indexer (nil if unsupported)
+	signerHistory *SignerHistory     // Alien signer history query service (nil if unsupported)
+
+	finality       *core.FinalityTracker // PBFT block finality tracker (nil if disabled)
+	confirmHandler *confirmHandler       // Block confirmation sub-protocol (nil if disabled)
+
+	mainChain *rpc.Client         // Main chain RPC client dialed for an Alien side chain (nil if none)
+	sideChain *sidechain.Notifier // Side chain block notifier of the main chain (nil if no side chain)
+
 	APIBackend *RlzAPIBackend
 
 	miner     *miner.Miner
@@ -113,6 +133,9 @@ func New(ctx *node.ServiceContext, config *Config) (*Rlzereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	if rawdb.ReadPruningRoots(chainDb) != nil {
+		return nil, errPruningInterrupted
+	}
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlock(chainDb, config.Genesis)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
@@ -125,6 +148,21 @@ func New(ctx *node.ServiceContext, config *Config) (*Rlzereum, error) {
 			config.NetworkId = chainConfig.ChainId.Uint64()
 		}
 	}
+	// Side chains are anchored to the main chain through its RPC API
+	var mainChain *rpc.Client
+	if chainConfig.Alien != nil && chainConfig.Alien.SideChain {
+		client := config.MainChainClient
+		if client == nil {
+			if config.MainChainRPC == "" {
+				return nil, errNoMainChain
+			}
+			if mainChain, err = rpc.Dial(config.MainChainRPC); err != nil {
+				return nil, fmt.Errorf("failed to dial main chain: %v", err)
+			}
+			client = mainChain
+		}
+		chainConfig.Alien.MCRPCClient = client
+	}
 	rlz := &Rlzereum{
 		config:         config,
 		chainDb:        chainDb,
@@ -138,6 +176,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Rlzereum, error) {
 		rlzerbase:      config.Rlzerbase,
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
 		bloomIndexer:   NewBloomIndexer(chainDb, params.BloomBitsBlocks),
+		mainChain:      mainChain,
 	}
 
 	log.Info("Initialising TTC protocol", "versions", ProtocolVersions, "network", config.NetworkId)
@@ -151,7 +190,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Rlzereum, error) {
 	}
 	var (
 		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout, SnapshotLimit: config.SnapshotCache}
 	)
 	rlz.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, rlz.chainConfig, rlz.engine, vmConfig)
 	if err != nil {
@@ -165,9 +204,36 @@ func New(ctx *node.ServiceContext, config *Config) (*Rlzereum, error) {
 	}
 	rlz.bloomIndexer.Start(rlz.blockchain)
 
+	if chainConfig.Alien != nil {
+		if reader, ok := rlz.engine.(core.SealRecordReader); ok {
+			rlz.signerIndexer = NewSignerIndexer(chainDb, reader, params.SignerIndexBlocks)
+			rlz.signerIndexer.Start(rlz.blockchain)
+			rlz.signerHistory = NewSignerHistory(chainDb, rlz.signerIndexer, reader, params.SignerIndexBlocks)
+		} else {
+			log.Warn("Consensus engine cannot describe sealed blocks, signer history disabled")
+		}
+	}
+
+	if chainConfig.Alien != nil && chainConfig.Alien.PBFTEnable {
+		if signers, ok := rlz.engine.(core.SignerSetReader); ok {
+			rlz.finality = core.NewFinalityTracker(chainDb, rlz.blockchain, signers)
+			rlz.confirmHandler = newConfirmHandler(rlz.finality, rlz.blockchain)
+		} else {
+			log.Warn("Consensus engine cannot report its signers, PBFT confirmations disabled")
+		}
+	}
+
+	if chainConfig.Alien != nil && chainConfig.Alien.MCRPCClient != nil {
+		mc := sidechain.NewMainChain(chainConfig.Alien.MCRPCClient)
+		rlz.sideChain = sidechain.NewNotifier(mc, rlz.blockchain, params.SideChainNotifyBlocks)
+	}
+
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.Snapshot != "" {
+		config.TxPool.Snapshot = ctx.ResolvePath(config.TxPool.Snapshot)
+	}
 	rlz.txPool = core.NewTxPool(config.TxPool, rlz.chainConfig, rlz.blockchain)
 
 	if rlz.protocolManager, err = NewProtocolManager(rlz.chainConfig, config.SyncMode, config.NetworkId, rlz.eventMux, rlz.txPool, rlz.engine, rlz.blockchain, chainDb); err != nil {
@@ -175,6 +241,9 @@ func New(ctx *node.ServiceContext, config *Config) (*Rlzereum, error) {
 	}
 	rlz.miner = miner.New(rlz, rlz.chainConfig, rlz.EventMux(), rlz.engine)
 	rlz.miner.SetExtra(makeExtraData(config.ExtraData))
+	if rlz.finality != nil {
+		rlz.miner.SetConfirmer(rlz.finality)
+	}
 
 	rlz.APIBackend = &RlzAPIBackend{rlz, nil}
 	gpoParams := config.GPO
@@ -205,11 +274,20 @@ func makeExtraData(extra []byte) []byte {
 
 // CreateDB creates the chain database.
 func CreateDB(ctx *node.ServiceContext, config *Config, name string) (rlzdb.Database, error) {
-	db, err := ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles)
+	var (
+		db  rlzdb.Database
+		err error
+	)
+	if config.SyncMode == downloader.LightSync {
+		// Light clients have no bodies nor receipts to freeze
+		db, err = ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles)
+	} else {
+		db, err = ctx.OpenDatabaseWithFreezer(name, config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, config.DatabaseFreezerThreshold)
+	}
 	if err != nil {
 		return nil, err
 	}
-	if db, ok := db.(*rlzdb.LDBDatabase); ok {
+	if db, ok := rawdb.KeyValueStore(db).(*rlzdb.LDBDatabase); ok {
 		db.Meter("rlz/db/chaindata/")
 	}
 	return db, nil
@@ -256,6 +334,40 @@ func (s *Rlzereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the miner APIs of the optional features the consensus engine supports
+	features := s.miner.Features()
+	if consensus.HasFeature(features, consensus.FeaturePoW) {
+		apis = append(apis, []rpc.API{
+			{
+				Namespace: "rlz",
+				Version:   "1.0",
+				Service:   NewPublicPoWMinerAPI(s),
+				Public:    true,
+			}, {
+				Namespace: "miner",
+				Version:   "1.0",
+				Service:   NewPrivatePoWMinerAPI(s),
+				Public:    false,
+			},
+		}...)
+	}
+	if consensus.HasFeature(features, consensus.FeatureSchedule) {
+		apis = append(apis, rpc.API{
+			Namespace: "miner",
+			Version:   "1.0",
+			Service:   NewPrivateScheduleMinerAPI(s),
+			Public:    false,
+		})
+	}
+	if s.signerHistory != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "alien",
+			Version:   "1.0",
+			Service:   NewPublicSignerHistoryAPI(s.signerHistory),
+			Public:    true,
+		})
+	}
+
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -287,6 +399,11 @@ func (s *Rlzereum) APIs() []rpc.API {
 			Namespace: "admin",
 			Version:   "1.0",
 			Service:   NewPrivateAdminAPI(s),
+		}, {
+			Namespace: "txpool",
+			Version:   "1.0",
+			Service:   NewPrivateTxPoolAPI(s),
+			Public:    false,
 		}, {
 			Namespace: "debug",
 			Version:   "1.0",
@@ -362,6 +479,16 @@ func (s *Rlzereum) StartMining(local bool) error {
 			return fmt.Errorf("signer missing: %v", err)
 		}
 		alien.Authorize(eb, wallet.SignHash, wallet.SignTx)
+
+		if s.sideChain != nil {
+			s.sideChain.Authorize(eb, wallet.SignTx)
+		}
+
+		if s.finality != nil {
+			s.finality.Authorize(eb, func(signer common.Address, hash []byte) ([]byte, error) {
+				return wallet.SignHash(accounts.Account{Address: signer}, hash)
+			})
+		}
 	}
 	if local {
 		// If local (CPU) mining is started, we can disable the transaction rejection
@@ -384,6 +511,7 @@ func (s *Rlzereum) TxPool() *core.TxPool               { return s.txPool }
 func (s *Rlzereum) EventMux() *event.TypeMux           { return s.eventMux }
 func (s *Rlzereum) Engine() consensus.Engine           { return s.engine }
 func (s *Rlzereum) ChainDb() rlzdb.Database            { return s.chainDb }
+func (s *Rlzereum) Finality() *core.FinalityTracker    { return s.finality }
 func (s *Rlzereum) IsListening() bool                  { return true } // Always listening
 func (s *Rlzereum) RlzVersion() int                    { return int(s.protocolManager.SubProtocols[0].Version) }
 func (s *Rlzereum) NetVersion() uint64                 { return s.networkId }
@@ -392,10 +520,14 @@ func (s *Rlzereum) Downloader() *downloader.Downloader { return s.protocolManage
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *Rlzereum) Protocols() []p2p.Protocol {
-	if s.lesServer == nil {
-		return s.protocolManager.SubProtocols
+	protos := s.protocolManager.SubProtocols
+	if s.confirmHandler != nil {
+		protos = append(protos, s.confirmHandler.Protocols()...)
+	}
+	if s.lesServer != nil {
+		protos = append(protos, s.lesServer.Protocols()...)
 	}
-	return append(s.protocolManager.SubProtocols, s.lesServer.Protocols()...)
+	return protos
 }
 
 // Start implements node.Service, starting all internal goroutines needed by the
@@ -417,6 +549,12 @@ func (s *Rlzereum) Start(srvr *p2p.Server) error {
 	}
 	// Start the networking layer and the light server if requested
 	s.protocolManager.Start(maxPeers)
+	if s.confirmHandler != nil {
+		s.confirmHandler.Start()
+	}
+	if s.sideChain != nil {
+		s.sideChain.Start()
+	}
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
@@ -427,8 +565,21 @@ func (s *Rlzereum) Start(srvr *p2p.Server) error {
 // Rlzereum protocol.
 func (s *Rlzereum) Stop() error {
 	s.bloomIndexer.Close()
+	if s.signerIndexer != nil {
+		s.signerIndexer.Close()
+	}
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
+	if s.confirmHandler != nil {
+		s.confirmHandler.Stop()
+		s.finality.Stop()
+	}
+	if s.sideChain != nil {
+		s.sideChain.Stop()
+	}
+	if s.mainChain != nil {
+		s.mainChain.Close()
+	}
 	if s.lesServer != nil {
 		s.lesServer.Stop()
 	}
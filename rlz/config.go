@@ -31,6 +31,7 @@ import (
 	"github.com/relianz2019/relianz/rlz/downloader"
 	"github.com/relianz2019/relianz/rlz/gasprice"
 	"github.com/relianz2019/relianz/params"
+	"github.com/relianz2019/relianz/rpc"
 )
 
 // DefaultConfig contains default settings for use on the Rlzereum main net.
@@ -43,12 +44,14 @@ var DefaultConfig = Config{
 		DatasetsInMem:  1,
 		DatasetsOnDisk: 2,
 	},
-	NetworkId:     1,
-	LightPeers:    100,
-	DatabaseCache: 768,
-	TrieCache:     256,
-	TrieTimeout:   5 * time.Minute,
-	GasPrice:      big.NewInt(18 * params.Shannon),
+	NetworkId:                1,
+	LightPeers:               100,
+	DatabaseCache:            768,
+	DatabaseFreezerThreshold: 90000,
+	TrieCache:                256,
+	TrieTimeout:              5 * time.Minute,
+	SnapshotCache:            102,
+	GasPrice:                 big.NewInt(18 * params.Shannon),
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
@@ -88,11 +91,14 @@ type Config struct {
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
 	// Database options
-	SkipBcVersionCheck bool `toml:"-"`
-	DatabaseHandles    int  `toml:"-"`
-	DatabaseCache      int
-	TrieCache          int
-	TrieTimeout        time.Duration
+	SkipBcVersionCheck       bool `toml:"-"`
+	DatabaseHandles          int  `toml:"-"`
+	DatabaseCache            int
+	DatabaseFreezer          string // Directory of the ancient chain data, defaulting to within chaindata
+	DatabaseFreezerThreshold uint64 // Number of recent blocks kept out of the freezer (at least params.FreezerThresholdMin), zero disables it
+	TrieCache                int
+	TrieTimeout              time.Duration
+	SnapshotCache            int
 
 	// Mining-related options
 	Rlzerbase    common.Address `toml:",omitempty"`
@@ -112,6 +118,10 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Side chain options
+	MainChainRPC    string      `toml:",omitempty"` // Main chain RPC endpoint an Alien side chain is anchored to
+	MainChainClient *rpc.Client `toml:"-"`          // Main chain RPC client, overriding MainChainRPC (e.g. in process)
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 }
@@ -0,0 +1,330 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlz
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/event"
+	"github.com/relianz2019/relianz/log"
+	"github.com/relianz2019/relianz/p2p"
+	"gopkg.in/fatih/set.v0"
+)
+
+// Constants to match up confirmation protocol versions and messages
+const (
+	pbft1 = 1
+)
+
+// ConfirmProtocolName is the official short name of the block confirmation
+// sub-protocol used during capability negotiation.
+var ConfirmProtocolName = "pbft"
+
+// confirmation protocol message codes
+const (
+	ConfirmationsMsg = 0x00
+)
+
+const (
+	// confirmChanSize is the size of channel listening to NewConfirmationsEvent.
+	confirmChanSize = 256
+
+	// headChanSize is the size of channel listening to ChainHeadEvent.
+	headChanSize = 10
+
+	maxKnownConfirmations = 8192 // Maximum confirmation ids to keep in the known list (prevent DOS)
+
+	// maxQueuedConfirmations is the maximum number of confirmation batches to
+	// queue up before dropping broadcasts.
+	maxQueuedConfirmations = 128
+
+	// maxConfirmationStrikes is the maximum number of confirmations too far ahead
+	// of the local head a peer may send between two chain heads before it gets
+	// dropped. Honest peers only relay confirmations near their own head, so a
+	// few are tolerated while syncing up.
+	maxConfirmationStrikes = 256
+)
+
+// confirmPeer is a remote peer speaking the block confirmation sub-protocol.
+type confirmPeer struct {
+	id string
+
+	*p2p.Peer
+	rw p2p.MsgReadWriter
+
+	known   *set.Set                   // Set of confirmation ids known to be known by this peer
+	strikes uint32                     // Confirmations refused as too far ahead since the last chain head (atomic)
+	queued  chan []*types.Confirmation // Queue of confirmations to broadcast to the peer
+	term    chan struct{}              // Termination channel to stop the broadcaster
+}
+
+func newConfirmPeer(p *p2p.Peer, rw p2p.MsgReadWriter) *confirmPeer {
+	return &confirmPeer{
+		Peer:   p,
+		rw:     rw,
+		id:     fmt.Sprintf("%x", p.ID().Bytes()[:8]),
+		known:  set.New(),
+		queued: make(chan []*types.Confirmation, maxQueuedConfirmations),
+		term:   make(chan struct{}),
+	}
+}
+
+// broadcast is a write loop that sends queued confirmations to the remote peer.
+func (p *confirmPeer) broadcast() {
+	for {
+		select {
+		case confs := <-p.queued:
+			if err := p.SendConfirmations(confs); err != nil {
+				return
+			}
+			p.Log().Trace("Broadcast confirmations", "count", len(confs))
+
+		case <-p.term:
+			return
+		}
+	}
+}
+
+// MarkConfirmation marks a confirmation as known for the peer, ensuring that it
+// will never be propagated to this particular peer.
+func (p *confirmPeer) MarkConfirmation(id common.Hash) {
+	for p.known.Size() >= maxKnownConfirmations {
+		p.known.Pop()
+	}
+	p.known.Add(id)
+}
+
+// SendConfirmations sends signed block confirmations to the peer and includes
+// their ids in its known set.
+func (p *confirmPeer) SendConfirmations(confs []*types.Confirmation) error {
+	for _, conf := range confs {
+		p.MarkConfirmation(conf.ID())
+	}
+	return p2p.Send(p.rw, ConfirmationsMsg, confs)
+}
+
+// AsyncSendConfirmations queues confirmations for propagation to the peer. If
+// the peer's broadcast queue is full, the event is silently dropped.
+func (p *confirmPeer) AsyncSendConfirmations(confs []*types.Confirmation) {
+	select {
+	case p.queued <- confs:
+		for _, conf := range confs {
+			p.MarkConfirmation(conf.ID())
+		}
+	default:
+		p.Log().Debug("Dropping confirmation propagation", "count", len(confs))
+	}
+}
+
+// headSubscriber is the subset of the blockchain the confirmation handler needs
+// to follow the chain head with.
+type headSubscriber interface {
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// confirmHandler runs the block confirmation sub-protocol, relaying signed
+// confirmations between peers and feeding them into the finality tracker.
+type confirmHandler struct {
+	finality *core.FinalityTracker
+	chain    headSubscriber
+
+	peers map[string]*confirmPeer
+	lock  sync.RWMutex
+
+	confirmCh  chan core.NewConfirmationsEvent
+	confirmSub event.Subscription
+	headCh     chan core.ChainHeadEvent
+	headSub    event.Subscription
+
+	wg sync.WaitGroup
+}
+
+func newConfirmHandler(finality *core.FinalityTracker, chain headSubscriber) *confirmHandler {
+	return &confirmHandler{
+		finality:  finality,
+		chain:     chain,
+		peers:     make(map[string]*confirmPeer),
+		confirmCh: make(chan core.NewConfirmationsEvent, confirmChanSize),
+		headCh:    make(chan core.ChainHeadEvent, headChanSize),
+	}
+}
+
+// Protocols returns the p2p protocol descriptors of the confirmation sub-protocol.
+func (h *confirmHandler) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{{
+		Name:    ConfirmProtocolName,
+		Version: pbft1,
+		Length:  1,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			h.wg.Add(1)
+			defer h.wg.Done()
+			return h.handle(newConfirmPeer(p, rw))
+		},
+	}}
+}
+
+// Start begins relaying locally accepted confirmations to the connected peers
+// and rechecking the finality of blocks as the chain progresses.
+func (h *confirmHandler) Start() {
+	h.confirmSub = h.finality.SubscribeNewConfirmationsEvent(h.confirmCh)
+	go h.broadcastLoop()
+
+	h.headSub = h.chain.SubscribeChainHeadEvent(h.headCh)
+	go h.recheckLoop()
+}
+
+// Stop terminates the broadcast and recheck loops and waits for all peer handlers
+// to exit.
+func (h *confirmHandler) Stop() {
+	h.confirmSub.Unsubscribe()
+	h.headSub.Unsubscribe()
+
+	h.lock.Lock()
+	for _, p := range h.peers {
+		p.Disconnect(p2p.DiscQuitting)
+	}
+	h.lock.Unlock()
+
+	h.wg.Wait()
+}
+
+// handle is the callback invoked to manage the life cycle of a confirmation peer.
+func (h *confirmHandler) handle(p *confirmPeer) error {
+	h.lock.Lock()
+	if _, ok := h.peers[p.id]; ok {
+		h.lock.Unlock()
+		return errAlreadyRegistered
+	}
+	h.peers[p.id] = p
+	h.lock.Unlock()
+
+	go p.broadcast()
+	defer func() {
+		h.lock.Lock()
+		delete(h.peers, p.id)
+		h.lock.Unlock()
+		close(p.term)
+	}()
+	for {
+		if err := h.handleMsg(p); err != nil {
+			p.Log().Debug("Confirmation message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMsg is invoked whenever an inbound message is received from a remote
+// peer. The remote connection is torn down upon returning any error.
+func (h *confirmHandler) handleMsg(p *confirmPeer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case ConfirmationsMsg:
+		var confs []*types.Confirmation
+		if err := msg.Decode(&confs); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for i, conf := range confs {
+			if conf == nil {
+				return errResp(ErrDecode, "confirmation %d is nil", i)
+			}
+			p.MarkConfirmation(conf.ID())
+		}
+		for i, err := range h.finality.Add(confs) {
+			switch {
+			case err == types.ErrInvalidConfirmationSig:
+				return errResp(ErrDecode, "confirmation %d: %v", i, err)
+
+			case err == core.ErrConfirmationNotSigner:
+				// Confirmations are only relayed once accepted, so the peer made it up
+				return errResp(ErrInvalidConfirmation, "confirmation %d: %v", i, err)
+
+			case err == core.ErrFutureConfirmation && atomic.AddUint32(&p.strikes, 1) > maxConfirmationStrikes:
+				return errResp(ErrInvalidConfirmation, "confirmation %d: %v", i, err)
+
+			case err != nil:
+				log.Trace("Discarded confirmation", "number", confs[i].Number, "hash", confs[i].Hash, "err", err)
+			}
+		}
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+	return nil
+}
+
+// broadcastLoop propagates accepted confirmations to all peers not yet knowing
+// about them.
+func (h *confirmHandler) broadcastLoop() {
+	for {
+		select {
+		case ev := <-h.confirmCh:
+			h.lock.RLock()
+			for _, p := range h.peers {
+				var confs []*types.Confirmation
+				for _, conf := range ev.Confirmations {
+					if !p.known.Has(conf.ID()) {
+						confs = append(confs, conf)
+					}
+				}
+				if len(confs) > 0 {
+					p.AsyncSendConfirmations(confs)
+				}
+			}
+			h.lock.RUnlock()
+
+		// Err() channel will be closed when unsubscribing.
+		case <-h.confirmSub.Err():
+			return
+		}
+	}
+}
+
+// recheckLoop rechecks the confirmations held by the finality tracker whenever
+// the chain head changes, counting those of newly imported blocks and finalizing
+// blocks which became canonical through a reorg. The strikes of the peers are
+// forgiven as the head moves on.
+func (h *confirmHandler) recheckLoop() {
+	for {
+		select {
+		case <-h.headCh:
+			h.finality.Recheck()
+
+			h.lock.RLock()
+			for _, p := range h.peers {
+				atomic.StoreUint32(&p.strikes, 0)
+			}
+			h.lock.RUnlock()
+
+		// Err() channel will be closed when unsubscribing.
+		case <-h.headSub.Err():
+			return
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlz
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/consensus"
+	"github.com/relianz2019/relianz/consensus/rlzash"
+	"github.com/relianz2019/relianz/core"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/core/vm"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/p2p"
+	"github.com/relianz2019/relianz/p2p/discover"
+	"github.com/relianz2019/relianz/params"
+	"github.com/relianz2019/relianz/rlzdb"
+)
+
+// testSignerSet is a signer set reader returning the same signers for every block.
+type testSignerSet []common.Address
+
+func (s testSignerSet) Signers(chain consensus.ChainReader, header *types.Header) ([]common.Address, error) {
+	return s, nil
+}
+
+// newTestConfirmPeer starts running the confirmation protocol of h for a new
+// peer, returning the remote end of the connection.
+func newTestConfirmPeer(h *confirmHandler, name string) (*p2p.MsgPipeRW, <-chan error) {
+	app, net := p2p.MsgPipe()
+
+	var id discover.NodeID
+	rand.Read(id[:])
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- h.Protocols()[0].Run(p2p.NewPeer(id, name, nil), net)
+	}()
+	return app, errc
+}
+
+// Tests that confirmations received ahead of their block are counted once the
+// block is imported, finalizing it and relaying them to the other peers.
+func TestConfirmationPropagation(t *testing.T) {
+	var (
+		keys    []*ecdsa.PrivateKey
+		signers testSignerSet
+	)
+	for i := 0; i < 4; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		signers = append(signers, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	var (
+		db      = rlzdb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, rlzash.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, rlzash.NewFaker(), db, 4, nil)
+	if _, err := blockchain.InsertChain(blocks[:3]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	finality := core.NewFinalityTracker(db, blockchain, signers)
+	defer finality.Stop()
+
+	handler := newConfirmHandler(finality, blockchain)
+	handler.Start()
+	defer handler.Stop()
+
+	source, sourceErr := newTestConfirmPeer(handler, "source")
+	defer source.Close()
+	sink, _ := newTestConfirmPeer(handler, "sink")
+	defer sink.Close()
+
+	for i := 0; i < 100; i++ {
+		handler.lock.RLock()
+		registered := len(handler.peers)
+		handler.lock.RUnlock()
+
+		if registered == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Confirm the block not yet imported by a quorum of the signers
+	head := blocks[3].Header()
+
+	var confs []*types.Confirmation
+	for _, key := range keys[:3] {
+		conf, err := types.SignConfirmation(types.NewConfirmation(gspec.Config.ChainId, head), key)
+		if err != nil {
+			t.Fatalf("failed to sign confirmation: %v", err)
+		}
+		confs = append(confs, conf)
+	}
+	if err := p2p.Send(source, ConfirmationsMsg, confs); err != nil {
+		t.Fatalf("failed to send confirmations: %v", err)
+	}
+	// The handler processes messages in order, so the confirmations are queued up
+	// once a follow-up message is accepted
+	if err := p2p.Send(source, ConfirmationsMsg, []*types.Confirmation{}); err != nil {
+		t.Fatalf("failed to send follow-up message: %v", err)
+	}
+	if final := finality.Finalized(); final != nil {
+		t.Fatalf("block finalized before being imported: %v", final.Number)
+	}
+	// Import the block, which must finalize it and relay the confirmations
+	if _, err := blockchain.InsertChain(blocks[3:]); err != nil {
+		t.Fatalf("failed to insert head block: %v", err)
+	}
+	if err := p2p.ExpectMsg(sink, ConfirmationsMsg, confs); err != nil {
+		t.Fatalf("confirmations not relayed: %v", err)
+	}
+	if final := finality.Finalized(); final == nil || final.Hash() != head.Hash() {
+		t.Fatalf("finalized block mismatch: have %v, want %v", final, head.Number)
+	}
+	// Confirmations with malformed signatures must get the sender disconnected
+	bad := types.NewConfirmation(gspec.Config.ChainId, head)
+	bad.Number++
+	bad.Signature = make([]byte, 64)
+	if err := p2p.Send(source, ConfirmationsMsg, []*types.Confirmation{bad}); err != nil {
+		t.Fatalf("failed to send bad confirmation: %v", err)
+	}
+	select {
+	case err := <-sourceErr:
+		if err == nil {
+			t.Errorf("peer dropped without error")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("peer not dropped for bad confirmation")
+	}
+}
+
+// Tests that peers are dropped for relaying confirmations of known blocks by
+// non-signers, as only accepted confirmations are ever relayed.
+func TestConfirmationUnauthorizedDrop(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	outsider, _ := crypto.GenerateKey()
+
+	var (
+		db      = rlzdb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, rlzash.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+
+	finality := core.NewFinalityTracker(db, blockchain, testSignerSet{crypto.PubkeyToAddress(key.PublicKey)})
+	defer finality.Stop()
+
+	handler := newConfirmHandler(finality, blockchain)
+	handler.Start()
+	defer handler.Stop()
+
+	peer, errc := newTestConfirmPeer(handler, "peer")
+	defer peer.Close()
+
+	conf, err := types.SignConfirmation(types.NewConfirmation(gspec.Config.ChainId, genesis.Header()), outsider)
+	if err != nil {
+		t.Fatalf("failed to sign confirmation: %v", err)
+	}
+	if err := p2p.Send(peer, ConfirmationsMsg, []*types.Confirmation{conf}); err != nil {
+		t.Fatalf("failed to send confirmation: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Errorf("peer dropped without error")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("peer not dropped for unauthorized confirmation")
+	}
+}
@@ -28,7 +28,10 @@ import (
 	rlzereum "github.com/relianz2019/relianz"
 	"github.com/relianz2019/relianz/common"
 	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/core"
 	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/core/ufo"
+	"github.com/relianz2019/relianz/internal/rlzapi"
 	"github.com/relianz2019/relianz/rlzdb"
 	"github.com/relianz2019/relianz/event"
 	"github.com/relianz2019/relianz/rpc"
@@ -133,9 +136,34 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 	return pendingTxSub.ID
 }
 
+// PendingTxCriteria narrows a pending transaction subscription down to the
+// transactions of interest. Empty fields match any transaction.
+type PendingTxCriteria struct {
+	FullTx   bool             `json:"fullTx"`   // Notify full transactions instead of hashes
+	From     []common.Address `json:"from"`     // Senders to match
+	To       []common.Address `json:"to"`       // Recipients to match
+	MinValue *hexutil.Big     `json:"minValue"` // Minimum value transferred
+	Ufo      []ufo.Event      `json:"ufo"`      // Ufo payload events to match
+}
+
+// DroppedTransaction is the notification of a pending transaction leaving the
+// transaction pool without being included in a block.
+type DroppedTransaction struct {
+	Hash        common.Hash  `json:"hash"`
+	Reason      string       `json:"reason"`
+	Replacement *common.Hash `json:"replacement,omitempty"` // Transaction taking its place, if replaced
+}
+
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
 // enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+//
+// If criteria are given, only the matching transactions are notified, either as
+// hashes or as full transactions. Unlike the rest of the criteria, drops and
+// replacements of the matching transactions are not notified here but by the
+// droppedTransactions subscription: each subscription streams a single type of
+// notification, as clients of the hash mode could otherwise not tell a drop
+// notification from a pending transaction.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, crit *PendingTxCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -143,6 +171,11 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 
 	rpcSub := notifier.CreateSubscription()
 
+	if crit != nil {
+		go api.filteredPendingTransactions(notifier, rpcSub, *crit)
+		return rpcSub, nil
+	}
+
 	go func() {
 		txHashes := make(chan []common.Hash, 128)
 		pendingTxSub := api.events.SubscribePendingTxs(txHashes)
@@ -168,6 +201,73 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// filteredPendingTransactions notifies the pending transactions matching the
+// criteria until the subscription is closed.
+func (api *PublicFilterAPI) filteredPendingTransactions(notifier *rpc.Notifier, rpcSub *rpc.Subscription, crit PendingTxCriteria) {
+	txs := make(chan []*types.Transaction, 128)
+	pendingTxSub := api.events.SubscribeFilteredPendingTxs(crit, txs)
+	defer pendingTxSub.Unsubscribe()
+
+	for {
+		select {
+		case txs := <-txs:
+			for _, tx := range txs {
+				if crit.FullTx {
+					notifier.Notify(rpcSub.ID, ethapi.NewRPCPendingTransaction(tx))
+				} else {
+					notifier.Notify(rpcSub.ID, tx.Hash())
+				}
+			}
+		case <-rpcSub.Err():
+			return
+		case <-notifier.Closed():
+			return
+		}
+	}
+}
+
+// DroppedTransactions creates a subscription that is triggered each time a
+// transaction matching the criteria leaves the transaction pool without being
+// included in a block, e.g. as it was replaced or evicted. It complements the
+// filtered newPendingTransactions subscription with the same criteria, whose
+// full transaction option is ignored here.
+func (api *PublicFilterAPI) DroppedTransactions(ctx context.Context, crit *PendingTxCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if crit == nil {
+		crit = new(PendingTxCriteria)
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		drops := make(chan core.DroppedTxsEvent, 128)
+		droppedTxSub := api.events.SubscribeDroppedTxs(*crit, drops)
+		defer droppedTxSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-drops:
+				var replacement *common.Hash
+				if ev.Replacement != nil {
+					hash := ev.Replacement.Hash()
+					replacement = &hash
+				}
+				for _, tx := range ev.Txs {
+					notifier.Notify(rpcSub.ID, &DroppedTransaction{Hash: tx.Hash(), Reason: ev.Reason, Replacement: replacement})
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with rlz_getFilterChanges.
 //
@@ -233,6 +333,37 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 	return rpcSub, nil
 }
 
+// NewFinalizedHeads send a notification each time a block is confirmed by a quorum
+// of signers and becomes final.
+func (api *PublicFilterAPI) NewFinalizedHeads(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		headers := make(chan *types.Header)
+		headersSub := api.events.SubscribeFinalizedHeads(headers)
+
+		for {
+			select {
+			case h := <-headers:
+				notifier.Notify(rpcSub.ID, h)
+			case <-rpcSub.Err():
+				headersSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				headersSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
 func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
@@ -130,7 +130,7 @@ func benchmarkBloomBits(b *testing.B, sectionSize uint64) {
 		if i%20 == 0 {
 			db.Close()
 			db, _ = rlzdb.NewLDBDatabase(benchDataDir, 128, 1024)
-			backend = &testBackend{mux, db, cnt, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)}
+			backend = &testBackend{mux, db, cnt, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)}
 		}
 		var addr common.Address
 		addr[0] = byte(i)
@@ -147,9 +147,8 @@ func benchmarkBloomBits(b *testing.B, sectionSize uint64) {
 }
 
 func forEachKey(db rlzdb.Database, startPrefix, endPrefix []byte, fn func(key []byte)) {
-	it := db.(*rlzdb.LDBDatabase).NewIterator()
-	it.Seek(startPrefix)
-	for it.Valid() {
+	it := db.NewIteratorWithStart(nil, startPrefix)
+	for it.Next() {
 		key := it.Key()
 		cmpLen := len(key)
 		if len(endPrefix) < cmpLen {
@@ -159,7 +158,6 @@ func forEachKey(db rlzdb.Database, startPrefix, endPrefix []byte, fn func(key []
 			break
 		}
 		fn(common.CopyBytes(key))
-		it.Next()
 	}
 	it.Release()
 }
@@ -191,7 +189,7 @@ func BenchmarkNoBloomBits(b *testing.B) {
 	fmt.Println("Running filter benchmarks...")
 	start := time.Now()
 	mux := new(event.TypeMux)
-	backend := &testBackend{mux, db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)}
+	backend := &testBackend{mux, db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)}
 	filter := New(backend, 0, int64(*headNum), []common.Address{{}}, nil)
 	filter.Logs(context.Background())
 	d := time.Since(start)
@@ -24,6 +24,7 @@ import (
 	"github.com/relianz2019/relianz/core"
 	"github.com/relianz2019/relianz/core/bloombits"
 	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/core/ufo"
 	"github.com/relianz2019/relianz/rlzdb"
 	"github.com/relianz2019/relianz/event"
 	"github.com/relianz2019/relianz/rpc"
@@ -37,9 +38,11 @@ type Backend interface {
 	GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error)
 
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
+	SubscribeDroppedTxsEvent(chan<- core.DroppedTxsEvent) event.Subscription
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
+	SubscribeChainFinalizedEvent(ch chan<- core.ChainFinalizedEvent) event.Subscription
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
@@ -102,6 +105,23 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	}
 	head := header.Number.Uint64()
 
+	// Resolve the finalized block tag if requested on either end
+	final := rpc.FinalizedBlockNumber.Int64()
+	if f.begin == final || f.end == final {
+		header, err := f.backend.HeaderByNumber(ctx, rpc.FinalizedBlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			return nil, nil
+		}
+		if f.begin == final {
+			f.begin = header.Number.Int64()
+		}
+		if f.end == final {
+			f.end = header.Number.Int64()
+		}
+	}
 	if f.begin == -1 {
 		f.begin = int64(head)
 	}
@@ -275,6 +295,45 @@ Logs:
 	return ret
 }
 
+// filterTxs creates a slice of transactions matching the given criteria.
+func filterTxs(txs []*types.Transaction, crit *PendingTxCriteria) []*types.Transaction {
+	var ret []*types.Transaction
+Txs:
+	for _, tx := range txs {
+		if len(crit.From) > 0 {
+			var signer types.Signer = types.FrontierSigner{}
+			if tx.Protected() {
+				signer = types.NewEIP155Signer(tx.ChainId())
+			}
+			from, err := types.Sender(signer, tx)
+			if err != nil || !includes(crit.From, from) {
+				continue
+			}
+		}
+		if len(crit.To) > 0 && (tx.To() == nil || !includes(crit.To, *tx.To())) {
+			continue
+		}
+		if crit.MinValue != nil && tx.Value().Cmp(crit.MinValue.ToInt()) < 0 {
+			continue
+		}
+		if len(crit.Ufo) > 0 {
+			payload, err := ufo.Decode(tx.Data())
+			if err != nil {
+				continue
+			}
+			for _, event := range crit.Ufo {
+				if payload.Event() == event {
+					ret = append(ret, tx)
+					continue Txs
+				}
+			}
+			continue
+		}
+		ret = append(ret, tx)
+	}
+	return ret
+}
+
 func bloomFilter(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
 	if len(addresses) > 0 {
 		var included bool
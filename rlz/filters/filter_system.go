@@ -53,6 +53,14 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// FinalizedBlocksSubscription queries headers of blocks that became final
+	FinalizedBlocksSubscription
+	// FilteredPendingTransactionsSubscription queries pending transactions matching
+	// the criteria
+	FilteredPendingTransactionsSubscription
+	// DroppedTransactionsSubscription queries transactions matching the criteria
+	// that leave the transaction pool without being mined
+	DroppedTransactionsSubscription
 	// LastSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -62,12 +70,16 @@ const (
 	// txChanSize is the size of channel listening to NewTxsEvent.
 	// The number is referenced from the size of tx pool.
 	txChanSize = 4096
+	// dropChanSize is the size of channel listening to DroppedTxsEvent.
+	dropChanSize = 256
 	// rmLogsChanSize is the size of channel listening to RemovedLogsEvent.
 	rmLogsChanSize = 10
 	// logsChanSize is the size of channel listening to LogsEvent.
 	logsChanSize = 10
 	// chainEvChanSize is the size of channel listening to ChainEvent.
 	chainEvChanSize = 10
+	// finalizedEvChanSize is the size of channel listening to ChainFinalizedEvent.
+	finalizedEvChanSize = 10
 )
 
 var (
@@ -80,8 +92,11 @@ type subscription struct {
 	created   time.Time
 	logsCrit  rlzereum.FilterQuery
 	logs      chan []*types.Log
+	txsCrit   PendingTxCriteria
 	hashes    chan []common.Hash
 	headers   chan *types.Header
+	txs       chan []*types.Transaction
+	drops     chan core.DroppedTxsEvent
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
 }
@@ -96,18 +111,22 @@ type EventSystem struct {
 
 	// Subscriptions
 	txsSub        event.Subscription         // Subscription for new transaction event
+	dropsSub      event.Subscription         // Subscription for dropped transaction event
 	logsSub       event.Subscription         // Subscription for new log event
 	rmLogsSub     event.Subscription         // Subscription for removed log event
 	chainSub      event.Subscription         // Subscription for new chain event
+	finalizedSub  event.Subscription         // Subscription for finalized block event
 	pendingLogSub *event.TypeMuxSubscription // Subscription for pending log event
 
 	// Channels
-	install   chan *subscription         // install filter for event notification
-	uninstall chan *subscription         // remove filter for event notification
-	txsCh     chan core.NewTxsEvent      // Channel to receive new transactions event
-	logsCh    chan []*types.Log          // Channel to receive new log event
-	rmLogsCh  chan core.RemovedLogsEvent // Channel to receive removed log event
-	chainCh   chan core.ChainEvent       // Channel to receive new chain event
+	install     chan *subscription            // install filter for event notification
+	uninstall   chan *subscription            // remove filter for event notification
+	txsCh       chan core.NewTxsEvent         // Channel to receive new transactions event
+	dropsCh     chan core.DroppedTxsEvent     // Channel to receive dropped transactions event
+	logsCh      chan []*types.Log             // Channel to receive new log event
+	rmLogsCh    chan core.RemovedLogsEvent    // Channel to receive removed log event
+	chainCh     chan core.ChainEvent          // Channel to receive new chain event
+	finalizedCh chan core.ChainFinalizedEvent // Channel to receive finalized block event
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -118,28 +137,32 @@ type EventSystem struct {
 // or by stopping the given mux.
 func NewEventSystem(mux *event.TypeMux, backend Backend, lightMode bool) *EventSystem {
 	m := &EventSystem{
-		mux:       mux,
-		backend:   backend,
-		lightMode: lightMode,
-		install:   make(chan *subscription),
-		uninstall: make(chan *subscription),
-		txsCh:     make(chan core.NewTxsEvent, txChanSize),
-		logsCh:    make(chan []*types.Log, logsChanSize),
-		rmLogsCh:  make(chan core.RemovedLogsEvent, rmLogsChanSize),
-		chainCh:   make(chan core.ChainEvent, chainEvChanSize),
+		mux:         mux,
+		backend:     backend,
+		lightMode:   lightMode,
+		install:     make(chan *subscription),
+		uninstall:   make(chan *subscription),
+		txsCh:       make(chan core.NewTxsEvent, txChanSize),
+		dropsCh:     make(chan core.DroppedTxsEvent, dropChanSize),
+		logsCh:      make(chan []*types.Log, logsChanSize),
+		rmLogsCh:    make(chan core.RemovedLogsEvent, rmLogsChanSize),
+		chainCh:     make(chan core.ChainEvent, chainEvChanSize),
+		finalizedCh: make(chan core.ChainFinalizedEvent, finalizedEvChanSize),
 	}
 
 	// Subscribe events
 	m.txsSub = m.backend.SubscribeNewTxsEvent(m.txsCh)
+	m.dropsSub = m.backend.SubscribeDroppedTxsEvent(m.dropsCh)
 	m.logsSub = m.backend.SubscribeLogsEvent(m.logsCh)
 	m.rmLogsSub = m.backend.SubscribeRemovedLogsEvent(m.rmLogsCh)
 	m.chainSub = m.backend.SubscribeChainEvent(m.chainCh)
+	m.finalizedSub = m.backend.SubscribeChainFinalizedEvent(m.finalizedCh)
 	// TODO(rjl493456442): use feed to subscribe pending log event
 	m.pendingLogSub = m.mux.Subscribe(core.PendingLogsEvent{})
 
 	// Make sure none of the subscriptions are empty
-	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil ||
-		m.pendingLogSub.Closed() {
+	if m.txsSub == nil || m.dropsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil ||
+		m.finalizedSub == nil || m.pendingLogSub.Closed() {
 		log.Crit("Subscribe for event system failed")
 	}
 
@@ -175,6 +198,8 @@ func (sub *Subscription) Unsubscribe() {
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
+			case <-sub.f.txs:
+			case <-sub.f.drops:
 			}
 		}
 
@@ -208,6 +233,10 @@ func (es *EventSystem) SubscribeLogs(crit rlzereum.FilterQuery, logs chan []*typ
 		to = rpc.BlockNumber(crit.ToBlock.Int64())
 	}
 
+	// finality is only resolved for historical log queries
+	if from == rpc.FinalizedBlockNumber || to == rpc.FinalizedBlockNumber {
+		return nil, fmt.Errorf("finalized block tag is not supported by log subscriptions")
+	}
 	// only interested in pending logs
 	if from == rpc.PendingBlockNumber && to == rpc.PendingBlockNumber {
 		return es.subscribePendingLogs(crit, logs), nil
@@ -242,6 +271,8 @@ func (es *EventSystem) subscribeMinedPendingLogs(crit rlzereum.FilterQuery, logs
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		txs:       make(chan []*types.Transaction),
+		drops:     make(chan core.DroppedTxsEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -259,6 +290,8 @@ func (es *EventSystem) subscribeLogs(crit rlzereum.FilterQuery, logs chan []*typ
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		txs:       make(chan []*types.Transaction),
+		drops:     make(chan core.DroppedTxsEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -276,6 +309,8 @@ func (es *EventSystem) subscribePendingLogs(crit rlzereum.FilterQuery, logs chan
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		txs:       make(chan []*types.Transaction),
+		drops:     make(chan core.DroppedTxsEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -292,6 +327,26 @@ func (es *EventSystem) SubscribeNewHeads(headers chan *types.Header) *Subscripti
 		logs:      make(chan []*types.Log),
 		hashes:    make(chan []common.Hash),
 		headers:   headers,
+		txs:       make(chan []*types.Transaction),
+		drops:     make(chan core.DroppedTxsEvent),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeFinalizedHeads creates a subscription that writes the header of a block
+// that has been confirmed by a quorum of signers and became final.
+func (es *EventSystem) SubscribeFinalizedHeads(headers chan *types.Header) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       FinalizedBlocksSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   headers,
+		txs:       make(chan []*types.Transaction),
+		drops:     make(chan core.DroppedTxsEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -308,6 +363,46 @@ func (es *EventSystem) SubscribePendingTxs(hashes chan []common.Hash) *Subscript
 		logs:      make(chan []*types.Log),
 		hashes:    hashes,
 		headers:   make(chan *types.Header),
+		txs:       make(chan []*types.Transaction),
+		drops:     make(chan core.DroppedTxsEvent),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeFilteredPendingTxs creates a subscription that writes the transactions
+// matching the given criteria as they enter the transaction pool.
+func (es *EventSystem) SubscribeFilteredPendingTxs(crit PendingTxCriteria, txs chan []*types.Transaction) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       FilteredPendingTransactionsSubscription,
+		txsCrit:   crit,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   make(chan *types.Header),
+		txs:       txs,
+		drops:     make(chan core.DroppedTxsEvent),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeDroppedTxs creates a subscription that writes the transactions
+// matching the given criteria as they leave the transaction pool unmined.
+func (es *EventSystem) SubscribeDroppedTxs(crit PendingTxCriteria, drops chan core.DroppedTxsEvent) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       DroppedTransactionsSubscription,
+		txsCrit:   crit,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   make(chan *types.Header),
+		txs:       make(chan []*types.Transaction),
+		drops:     drops,
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -356,6 +451,21 @@ func (es *EventSystem) broadcast(filters filterIndex, ev interface{}) {
 		for _, f := range filters[PendingTransactionsSubscription] {
 			f.hashes <- hashes
 		}
+		for _, f := range filters[FilteredPendingTransactionsSubscription] {
+			if matchedTxs := filterTxs(e.Txs, &f.txsCrit); len(matchedTxs) > 0 {
+				f.txs <- matchedTxs
+			}
+		}
+	case core.DroppedTxsEvent:
+		for _, f := range filters[DroppedTransactionsSubscription] {
+			if matchedTxs := filterTxs(e.Txs, &f.txsCrit); len(matchedTxs) > 0 {
+				f.drops <- core.DroppedTxsEvent{Txs: matchedTxs, Reason: e.Reason, Replacement: e.Replacement}
+			}
+		}
+	case core.ChainFinalizedEvent:
+		for _, f := range filters[FinalizedBlocksSubscription] {
+			f.headers <- e.Header
+		}
 	case core.ChainEvent:
 		for _, f := range filters[BlocksSubscription] {
 			f.headers <- e.Block.Header()
@@ -451,9 +561,11 @@ func (es *EventSystem) eventLoop() {
 	defer func() {
 		es.pendingLogSub.Unsubscribe()
 		es.txsSub.Unsubscribe()
+		es.dropsSub.Unsubscribe()
 		es.logsSub.Unsubscribe()
 		es.rmLogsSub.Unsubscribe()
 		es.chainSub.Unsubscribe()
+		es.finalizedSub.Unsubscribe()
 	}()
 
 	index := make(filterIndex)
@@ -466,12 +578,16 @@ func (es *EventSystem) eventLoop() {
 		// Handle subscribed events
 		case ev := <-es.txsCh:
 			es.broadcast(index, ev)
+		case ev := <-es.dropsCh:
+			es.broadcast(index, ev)
 		case ev := <-es.logsCh:
 			es.broadcast(index, ev)
 		case ev := <-es.rmLogsCh:
 			es.broadcast(index, ev)
 		case ev := <-es.chainCh:
 			es.broadcast(index, ev)
+		case ev := <-es.finalizedCh:
+			es.broadcast(index, ev)
 		case ev, active := <-es.pendingLogSub.Chan():
 			if !active { // system stopped
 				return
@@ -501,12 +617,16 @@ func (es *EventSystem) eventLoop() {
 		// System stopped
 		case <-es.txsSub.Err():
 			return
+		case <-es.dropsSub.Err():
+			return
 		case <-es.logsSub.Err():
 			return
 		case <-es.rmLogsSub.Err():
 			return
 		case <-es.chainSub.Err():
 			return
+		case <-es.finalizedSub.Err():
+			return
 		}
 	}
 }
@@ -27,11 +27,15 @@ import (
 
 	rlzereum "github.com/relianz2019/relianz"
 	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
 	"github.com/relianz2019/relianz/consensus/rlzash"
 	"github.com/relianz2019/relianz/core"
 	"github.com/relianz2019/relianz/core/bloombits"
 	"github.com/relianz2019/relianz/core/rawdb"
 	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/core/ufo"
+	"github.com/relianz2019/relianz/crypto"
+	"github.com/relianz2019/relianz/internal/rlzapi"
 	"github.com/relianz2019/relianz/rlzdb"
 	"github.com/relianz2019/relianz/event"
 	"github.com/relianz2019/relianz/params"
@@ -46,6 +50,7 @@ type testBackend struct {
 	rmLogsFeed *event.Feed
 	logsFeed   *event.Feed
 	chainFeed  *event.Feed
+	dropFeed   *event.Feed
 }
 
 func (b *testBackend) ChainDb() rlzdb.Database {
@@ -100,6 +105,10 @@ func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Sub
 	return b.txFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeDroppedTxsEvent(ch chan<- core.DroppedTxsEvent) event.Subscription {
+	return b.dropFeed.Subscribe(ch)
+}
+
 func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.rmLogsFeed.Subscribe(ch)
 }
@@ -112,6 +121,13 @@ func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subsc
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeChainFinalizedEvent(ch chan<- core.ChainFinalizedEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
@@ -158,7 +174,7 @@ func TestBlockSubscription(t *testing.T) {
 		rmLogsFeed  = new(event.Feed)
 		logsFeed    = new(event.Feed)
 		chainFeed   = new(event.Feed)
-		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api         = NewPublicFilterAPI(backend, false)
 		genesis     = new(core.Genesis).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, rlzash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {})
@@ -215,7 +231,7 @@ func TestPendingTxFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		transactions = []*types.Transaction{
@@ -265,6 +281,175 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestFilteredPendingTxSubscription tests that pending and dropped transaction
+// subscriptions with criteria only receive the matching transactions.
+func TestFilteredPendingTxSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux      = new(event.TypeMux)
+		db       = rlzdb.NewMemDatabase()
+		txFeed   = new(event.Feed)
+		dropFeed = new(event.Feed)
+		backend  = &testBackend{mux, db, 0, txFeed, new(event.Feed), new(event.Feed), new(event.Feed), dropFeed}
+		api      = NewPublicFilterAPI(backend, false)
+
+		key, _    = crypto.GenerateKey()
+		sender    = crypto.PubkeyToAddress(key.PublicKey)
+		recipient = common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268")
+		signer    = types.HomesteadSigner{}
+	)
+	sign := func(nonce uint64, to common.Address, value int64, data []byte) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(value), 100000, big.NewInt(1), data), signer, key)
+		return tx
+	}
+	var (
+		vote     = sign(0, recipient, 0, ufo.Encode(&ufo.Vote{}))
+		confirm  = sign(1, recipient, 0, ufo.Encode(&ufo.Confirm{Number: 1}))
+		transfer = sign(2, recipient, 1000, nil)
+		small    = sign(3, recipient, 10, nil)
+		other    = sign(4, common.Address{0x01}, 1000, nil)
+		unsigned = types.NewTransaction(0, recipient, big.NewInt(1000), 100000, big.NewInt(1), nil)
+	)
+	tests := []struct {
+		crit    PendingTxCriteria
+		matched []*types.Transaction
+		dropped []*types.Transaction
+	}{
+		{PendingTxCriteria{}, []*types.Transaction{vote, confirm, transfer, small, other, unsigned}, []*types.Transaction{vote}},
+		{PendingTxCriteria{From: []common.Address{sender}}, []*types.Transaction{vote, confirm, transfer, small, other}, []*types.Transaction{vote}},
+		{PendingTxCriteria{To: []common.Address{{0x01}}}, []*types.Transaction{other}, nil},
+		{PendingTxCriteria{MinValue: (*hexutil.Big)(big.NewInt(100)), To: []common.Address{recipient}}, []*types.Transaction{transfer, unsigned}, nil},
+		{PendingTxCriteria{Ufo: []ufo.Event{ufo.EventVote}}, []*types.Transaction{vote}, []*types.Transaction{vote}},
+	}
+	var (
+		txChans   = make([]chan []*types.Transaction, len(tests))
+		dropChans = make([]chan core.DroppedTxsEvent, len(tests))
+	)
+	for i, tt := range tests {
+		txChans[i] = make(chan []*types.Transaction, 1)
+		dropChans[i] = make(chan core.DroppedTxsEvent, 1)
+
+		txSub := api.events.SubscribeFilteredPendingTxs(tt.crit, txChans[i])
+		defer txSub.Unsubscribe()
+		dropSub := api.events.SubscribeDroppedTxs(tt.crit, dropChans[i])
+		defer dropSub.Unsubscribe()
+	}
+	txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{vote, confirm, transfer, small, other, unsigned}})
+	dropFeed.Send(core.DroppedTxsEvent{Txs: []*types.Transaction{vote}, Reason: core.TxDropReplaced, Replacement: confirm})
+
+	for i, tt := range tests {
+		select {
+		case txs := <-txChans[i]:
+			if !reflect.DeepEqual(txs, tt.matched) {
+				t.Errorf("test %d: matched transactions mismatch: have %d, want %d", i, len(txs), len(tt.matched))
+			}
+		case <-time.After(time.Second):
+			t.Errorf("test %d: no transactions received", i)
+		}
+		select {
+		case ev := <-dropChans[i]:
+			if !reflect.DeepEqual(ev.Txs, tt.dropped) {
+				t.Errorf("test %d: dropped transactions mismatch: have %d, want %d", i, len(ev.Txs), len(tt.dropped))
+			}
+			if ev.Reason != core.TxDropReplaced || ev.Replacement != confirm {
+				t.Errorf("test %d: drop event mismatch: have %s %v", i, ev.Reason, ev.Replacement)
+			}
+		case <-time.After(100 * time.Millisecond):
+			if tt.dropped != nil {
+				t.Errorf("test %d: no drop event received", i)
+			}
+		}
+	}
+}
+
+// TestFilteredPendingTxNotifications tests that the rpc subscriptions for pending
+// transactions notify full transactions if requested by the criteria, and that
+// drops are notified on their own subscription.
+func TestFilteredPendingTxNotifications(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux      = new(event.TypeMux)
+		db       = rlzdb.NewMemDatabase()
+		txFeed   = new(event.Feed)
+		dropFeed = new(event.Feed)
+		backend  = &testBackend{mux, db, 0, txFeed, new(event.Feed), new(event.Feed), new(event.Feed), dropFeed}
+		api      = NewPublicFilterAPI(backend, false)
+
+		key, _    = crypto.GenerateKey()
+		sender    = crypto.PubkeyToAddress(key.PublicKey)
+		recipient = common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268")
+	)
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("rlz", api); err != nil {
+		t.Fatalf("failed to register filter api: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	tx, _ := types.SignTx(types.NewTransaction(0, recipient, big.NewInt(1000), 100000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+	replacement, _ := types.SignTx(types.NewTransaction(0, recipient, big.NewInt(1000), 100000, big.NewInt(2), nil), types.HomesteadSigner{}, key)
+	crit := PendingTxCriteria{FullTx: true, From: []common.Address{sender}}
+
+	txs := make(chan *ethapi.RPCTransaction, 16)
+	txSub, err := client.RlzSubscribe(context.Background(), txs, "newPendingTransactions", crit)
+	if err != nil {
+		t.Fatalf("failed to subscribe to pending transactions: %v", err)
+	}
+	defer txSub.Unsubscribe()
+
+	drops := make(chan *DroppedTransaction, 16)
+	dropSub, err := client.RlzSubscribe(context.Background(), drops, "droppedTransactions", crit)
+	if err != nil {
+		t.Fatalf("failed to subscribe to dropped transactions: %v", err)
+	}
+	defer dropSub.Unsubscribe()
+
+	// The event system filters are installed asynchronously, keep sending the
+	// events until the first notifications arrive.
+	timeout := time.After(5 * time.Second)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	var (
+		notified *ethapi.RPCTransaction
+		dropped  *DroppedTransaction
+	)
+	for notified == nil || dropped == nil {
+		select {
+		case notified = <-txs:
+		case dropped = <-drops:
+		case err := <-txSub.Err():
+			t.Fatalf("pending transaction subscription failed: %v", err)
+		case err := <-dropSub.Err():
+			t.Fatalf("dropped transaction subscription failed: %v", err)
+		case <-ticker.C:
+			if notified == nil {
+				txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{tx}})
+			}
+			if dropped == nil {
+				dropFeed.Send(core.DroppedTxsEvent{Txs: []*types.Transaction{tx}, Reason: core.TxDropReplaced, Replacement: replacement})
+			}
+		case <-timeout:
+			t.Fatalf("notifications timeout: pending %v, dropped %v", notified != nil, dropped != nil)
+		}
+	}
+	if notified.Hash != tx.Hash() || notified.From != sender || notified.Value.ToInt().Cmp(tx.Value()) != 0 {
+		t.Errorf("pending transaction mismatch: have %x from %x, want %x from %x", notified.Hash, notified.From, tx.Hash(), sender)
+	}
+	if notified.BlockNumber != nil {
+		t.Errorf("pending transaction has block number %v", notified.BlockNumber)
+	}
+	if dropped.Hash != tx.Hash() || dropped.Reason != core.TxDropReplaced {
+		t.Errorf("dropped transaction mismatch: have %x (%s), want %x (%s)", dropped.Hash, dropped.Reason, tx.Hash(), core.TxDropReplaced)
+	}
+	if dropped.Replacement == nil || *dropped.Replacement != replacement.Hash() {
+		t.Errorf("replacement mismatch: have %v, want %x", dropped.Replacement, replacement.Hash())
+	}
+}
+
 // TestLogFilterCreation test whrlzer a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
@@ -275,7 +460,7 @@ func TestLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		testCases = []struct {
@@ -324,7 +509,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 	)
 
@@ -354,7 +539,7 @@ func TestLogFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -473,7 +658,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -4,6 +4,7 @@ package rlz
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/relianz2019/relianz/common"
 	"github.com/relianz2019/relianz/common/hexutil"
@@ -11,39 +12,54 @@ import (
 	"github.com/relianz2019/relianz/core"
 	"github.com/relianz2019/relianz/rlz/downloader"
 	"github.com/relianz2019/relianz/rlz/gasprice"
+	"github.com/relianz2019/relianz/rpc"
 )
 
 var _ = (*configMarshaling)(nil)
 
 func (c Config) MarshalTOML() (interface{}, error) {
 	type Config struct {
-		Genesis                 *core.Genesis `toml:",omitempty"`
-		NetworkId               uint64
-		SyncMode                downloader.SyncMode
-		LightServ               int  `toml:",omitempty"`
-		LightPeers              int  `toml:",omitempty"`
-		SkipBcVersionCheck      bool `toml:"-"`
-		DatabaseHandles         int  `toml:"-"`
-		DatabaseCache           int
-		Rlzerbase               common.Address `toml:",omitempty"`
-		MinerThreads            int            `toml:",omitempty"`
-		ExtraData               hexutil.Bytes  `toml:",omitempty"`
-		GasPrice                *big.Int
-		Rlzash                  rlzash.Config
-		TxPool                  core.TxPoolConfig
-		GPO                     gasprice.Config
-		EnablePreimageRecording bool
-		DocRoot                 string `toml:"-"`
+		Genesis                  *core.Genesis `toml:",omitempty"`
+		NetworkId                uint64
+		SyncMode                 downloader.SyncMode
+		NoPruning                bool
+		LightServ                int  `toml:",omitempty"`
+		LightPeers               int  `toml:",omitempty"`
+		SkipBcVersionCheck       bool `toml:"-"`
+		DatabaseHandles          int  `toml:"-"`
+		DatabaseCache            int
+		DatabaseFreezer          string
+		DatabaseFreezerThreshold uint64
+		TrieCache                int
+		TrieTimeout              time.Duration
+		SnapshotCache            int
+		Rlzerbase                common.Address `toml:",omitempty"`
+		MinerThreads             int            `toml:",omitempty"`
+		ExtraData                hexutil.Bytes  `toml:",omitempty"`
+		GasPrice                 *big.Int
+		Rlzash                   rlzash.Config
+		TxPool                   core.TxPoolConfig
+		GPO                      gasprice.Config
+		EnablePreimageRecording  bool
+		MainChainRPC             string      `toml:",omitempty"`
+		MainChainClient          *rpc.Client `toml:"-"`
+		DocRoot                  string      `toml:"-"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
+	enc.NoPruning = c.NoPruning
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.DatabaseFreezer = c.DatabaseFreezer
+	enc.DatabaseFreezerThreshold = c.DatabaseFreezerThreshold
+	enc.TrieCache = c.TrieCache
+	enc.TrieTimeout = c.TrieTimeout
+	enc.SnapshotCache = c.SnapshotCache
 	enc.Rlzerbase = c.Rlzerbase
 	enc.MinerThreads = c.MinerThreads
 	enc.ExtraData = c.ExtraData
@@ -52,29 +68,39 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.MainChainRPC = c.MainChainRPC
+	enc.MainChainClient = c.MainChainClient
 	enc.DocRoot = c.DocRoot
 	return &enc, nil
 }
 
 func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	type Config struct {
-		Genesis                 *core.Genesis `toml:",omitempty"`
-		NetworkId               *uint64
-		SyncMode                *downloader.SyncMode
-		LightServ               *int  `toml:",omitempty"`
-		LightPeers              *int  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool `toml:"-"`
-		DatabaseHandles         *int  `toml:"-"`
-		DatabaseCache           *int
-		Rlzerbase               *common.Address `toml:",omitempty"`
-		MinerThreads            *int            `toml:",omitempty"`
-		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
-		GasPrice                *big.Int
-		Rlzash                  *rlzash.Config
-		TxPool                  *core.TxPoolConfig
-		GPO                     *gasprice.Config
-		EnablePreimageRecording *bool
-		DocRoot                 *string `toml:"-"`
+		Genesis                  *core.Genesis `toml:",omitempty"`
+		NetworkId                *uint64
+		SyncMode                 *downloader.SyncMode
+		NoPruning                *bool
+		LightServ                *int  `toml:",omitempty"`
+		LightPeers               *int  `toml:",omitempty"`
+		SkipBcVersionCheck       *bool `toml:"-"`
+		DatabaseHandles          *int  `toml:"-"`
+		DatabaseCache            *int
+		DatabaseFreezer          *string
+		DatabaseFreezerThreshold *uint64
+		TrieCache                *int
+		TrieTimeout              *time.Duration
+		SnapshotCache            *int
+		Rlzerbase                *common.Address `toml:",omitempty"`
+		MinerThreads             *int            `toml:",omitempty"`
+		ExtraData                *hexutil.Bytes  `toml:",omitempty"`
+		GasPrice                 *big.Int
+		Rlzash                   *rlzash.Config
+		TxPool                   *core.TxPoolConfig
+		GPO                      *gasprice.Config
+		EnablePreimageRecording  *bool
+		MainChainRPC             *string     `toml:",omitempty"`
+		MainChainClient          *rpc.Client `toml:"-"`
+		DocRoot                  *string     `toml:"-"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -89,6 +115,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.SyncMode != nil {
 		c.SyncMode = *dec.SyncMode
 	}
+	if dec.NoPruning != nil {
+		c.NoPruning = *dec.NoPruning
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
@@ -104,6 +133,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.DatabaseFreezer != nil {
+		c.DatabaseFreezer = *dec.DatabaseFreezer
+	}
+	if dec.DatabaseFreezerThreshold != nil {
+		c.DatabaseFreezerThreshold = *dec.DatabaseFreezerThreshold
+	}
+	if dec.TrieCache != nil {
+		c.TrieCache = *dec.TrieCache
+	}
+	if dec.TrieTimeout != nil {
+		c.TrieTimeout = *dec.TrieTimeout
+	}
+	if dec.SnapshotCache != nil {
+		c.SnapshotCache = *dec.SnapshotCache
+	}
 	if dec.Rlzerbase != nil {
 		c.Rlzerbase = *dec.Rlzerbase
 	}
@@ -128,6 +172,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.MainChainRPC != nil {
+		c.MainChainRPC = *dec.MainChainRPC
+	}
+	if dec.MainChainClient != nil {
+		c.MainChainClient = dec.MainChainClient
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
@@ -76,6 +76,7 @@ const (
 	ErrNoStatusMsg
 	ErrExtraStatusMsg
 	ErrSuspendedPeer
+	ErrInvalidConfirmation
 )
 
 func (e errCode) String() string {
@@ -93,6 +94,7 @@ var errorToString = map[int]string{
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
 	ErrSuspendedPeer:           "Suspended peer",
+	ErrInvalidConfirmation:     "Invalid confirmation",
 }
 
 type txPool interface {
@@ -0,0 +1,363 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlz
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/common/hexutil"
+	"github.com/relianz2019/relianz/core"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/rlzdb"
+	"github.com/relianz2019/relianz/rpc"
+)
+
+const (
+	// signerIndexConfirms is the number of confirmation blocks before a signer
+	// history section is considered probably final and its statistics are stored.
+	signerIndexConfirms = 256
+
+	// signerIndexThrottling is the time to wait between processing two consecutive
+	// signer history sections.
+	signerIndexThrottling = 100 * time.Millisecond
+
+	// maxSignerHistoryScan is the maximum number of blocks a single history query
+	// may look at one by one, outside of the accumulated section statistics.
+	maxSignerHistoryScan = 65536
+)
+
+var (
+	errSignerHistoryRange = errors.New("invalid block range")
+	errSignerHistoryTag   = errors.New("block tag not supported by the signer history")
+	errUnknownSignerBlock = errors.New("block not found")
+)
+
+// SignerIndexer implements a core.ChainIndexer, recording the signer, the signer
+// queue, the missed slots and the reward of every block sealed by a signer queue
+// based consensus engine, along with per section statistics of every signer.
+type SignerIndexer struct {
+	db     rlzdb.Database        // database instance to write index data into
+	reader core.SealRecordReader // consensus engine describing the sealed headers
+
+	batch   rlzdb.Batch                           // batch collecting the section's index data
+	stats   map[common.Address]*types.SignerStats // statistics accumulated over the section
+	section uint64                                // section number being processed currently
+	head    common.Hash                           // hash of the last header processed
+	loop    uint64                                // start of the last loop whose queue was written
+	looped  bool                                  // whether any loop queue was written in the section
+	err     error                                 // first failure while processing the section
+}
+
+// NewSignerIndexer returns a chain indexer that generates the signer history of
+// the canonical chain. As it only relies on headers, it runs on light clients too.
+func NewSignerIndexer(db rlzdb.Database, reader core.SealRecordReader, size uint64) *core.ChainIndexer {
+	backend := &SignerIndexer{
+		db:     db,
+		reader: reader,
+	}
+	table := rlzdb.NewTable(db, string(rawdb.SignerIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, size, signerIndexConfirms, signerIndexThrottling, "signers")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new signer history
+// section.
+func (b *SignerIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	b.batch = b.db.NewBatch()
+	b.stats = make(map[common.Address]*types.SignerStats)
+	b.section, b.head, b.loop, b.looped, b.err = section, common.Hash{}, 0, false, nil
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, recording how a header was sealed
+// and accumulating it into the section statistics.
+func (b *SignerIndexer) Process(header *types.Header) {
+	b.head = header.Hash()
+	if b.err != nil || header.Number.Sign() == 0 {
+		return // Genesis is not sealed by any signer
+	}
+	record, err := b.reader.SealRecord(header)
+	if err != nil {
+		b.err = fmt.Errorf("block #%d [%x…]: %v", header.Number, b.head[:4], err)
+		return
+	}
+	rawdb.WriteSealRecord(b.batch, b.head, header.Number.Uint64(), record)
+	if len(record.Queue) > 0 && (!b.looped || record.LoopStart != b.loop) {
+		rawdb.WriteSignerQueue(b.batch, record.LoopStart, record.Queue)
+		b.loop, b.looped = record.LoopStart, true
+	}
+	accumulateSealRecord(b.stats, record)
+}
+
+// Commit implements core.ChainIndexerBackend, writing the seal records and the
+// section statistics into the database.
+func (b *SignerIndexer) Commit() error {
+	if b.err != nil {
+		return b.err
+	}
+	rawdb.WriteSignerStats(b.batch, b.section, b.head, sortSignerStats(b.stats))
+	return b.batch.Write()
+}
+
+// accumulateSealRecord adds the sealed block and the missed slots of a record to
+// the per signer statistics.
+func accumulateSealRecord(stats map[common.Address]*types.SignerStats, record *types.SealRecord) {
+	signer := signerStats(stats, record.Signer)
+	signer.Sealed++
+	if record.Reward != nil {
+		signer.Reward.Add(signer.Reward, record.Reward)
+	}
+	for _, missing := range record.Missing {
+		signerStats(stats, missing).Missed++
+	}
+}
+
+// mergeSignerStats adds previously accumulated statistics to the per signer ones.
+func mergeSignerStats(stats map[common.Address]*types.SignerStats, section []*types.SignerStats) {
+	for _, s := range section {
+		signer := signerStats(stats, s.Signer)
+		signer.Sealed += s.Sealed
+		signer.Missed += s.Missed
+		if s.Reward != nil {
+			signer.Reward.Add(signer.Reward, s.Reward)
+		}
+	}
+}
+
+// signerStats retrieves the statistics of a signer, creating them if needed.
+func signerStats(stats map[common.Address]*types.SignerStats, signer common.Address) *types.SignerStats {
+	s, ok := stats[signer]
+	if !ok {
+		s = &types.SignerStats{Signer: signer, Reward: new(big.Int)}
+		stats[signer] = s
+	}
+	return s
+}
+
+// sortSignerStats flattens the per signer statistics, ordered by signer address.
+func sortSignerStats(stats map[common.Address]*types.SignerStats) []*types.SignerStats {
+	sorted := make([]*types.SignerStats, 0, len(stats))
+	for _, s := range stats {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Signer[:], sorted[j].Signer[:]) < 0
+	})
+	return sorted
+}
+
+// SignerHistory answers signer history queries from the signer index, falling
+// back to the consensus engine for blocks not yet indexed.
+type SignerHistory struct {
+	db      rlzdb.Database
+	indexer *core.ChainIndexer
+	reader  core.SealRecordReader
+	size    uint64
+}
+
+// NewSignerHistory creates a signer history on top of a running signer indexer
+// with the given section size.
+func NewSignerHistory(db rlzdb.Database, indexer *core.ChainIndexer, reader core.SealRecordReader, size uint64) *SignerHistory {
+	return &SignerHistory{
+		db:      db,
+		indexer: indexer,
+		reader:  reader,
+		size:    size,
+	}
+}
+
+// Head returns the number of the latest locally known header.
+func (h *SignerHistory) Head() uint64 {
+	hash := rawdb.ReadHeadHeaderHash(h.db)
+	if number := rawdb.ReadHeaderNumber(h.db, hash); number != nil {
+		return *number
+	}
+	return 0
+}
+
+// Record returns the seal record of the canonical block with the given number,
+// including the signer queue of its loop.
+func (h *SignerHistory) Record(number uint64) (*types.SealRecord, common.Hash, error) {
+	hash := rawdb.ReadCanonicalHash(h.db, number)
+	if hash == (common.Hash{}) {
+		return nil, common.Hash{}, errUnknownSignerBlock
+	}
+	if record := rawdb.ReadSealRecord(h.db, hash, number); record != nil {
+		record.Queue = rawdb.ReadSignerQueue(h.db, record.LoopStart)
+		return record, hash, nil
+	}
+	header := rawdb.ReadHeader(h.db, hash, number)
+	if header == nil {
+		return nil, common.Hash{}, errUnknownSignerBlock
+	}
+	record, err := h.reader.SealRecord(header)
+	return record, hash, err
+}
+
+// Stats accumulates the statistics of every signer active in the inclusive block
+// range, using the stored section statistics for all fully covered sections.
+func (h *SignerHistory) Stats(from, to uint64) ([]*types.SignerStats, error) {
+	if from > to {
+		return nil, errSignerHistoryRange
+	}
+	if from == 0 {
+		from = 1 // Genesis is not sealed by any signer
+	}
+	var (
+		sections, _, _ = h.indexer.Sections()
+
+		stats   = make(map[common.Address]*types.SignerStats)
+		scanned = 0
+	)
+	for number := from; number <= to && number >= from; {
+		// Use the section statistics if the whole section is covered and indexed
+		if section := number / h.size; number%h.size == 0 && section < sections && (section+1)*h.size-1 <= to {
+			head := rawdb.ReadCanonicalHash(h.db, (section+1)*h.size-1)
+			if indexed := rawdb.ReadSignerStats(h.db, section, head); indexed != nil {
+				mergeSignerStats(stats, indexed)
+				number += h.size
+				continue
+			}
+		}
+		// Otherwise accumulate the block on its own
+		if scanned++; scanned > maxSignerHistoryScan {
+			return nil, fmt.Errorf("too many unindexed blocks in range, max %d", maxSignerHistoryScan)
+		}
+		record, _, err := h.Record(number)
+		if err != nil {
+			return nil, fmt.Errorf("block #%d: %v", number, err)
+		}
+		accumulateSealRecord(stats, record)
+		number++
+	}
+	return sortSignerStats(stats), nil
+}
+
+// RPCSealRecord is the RPC representation of how a block was sealed.
+type RPCSealRecord struct {
+	Number    hexutil.Uint64   `json:"number"`
+	Hash      common.Hash      `json:"hash"`
+	Signer    common.Address   `json:"signer"`
+	LoopStart hexutil.Uint64   `json:"loopStart"`
+	Queue     []common.Address `json:"queue"`
+	Missing   []common.Address `json:"missing"`
+	Reward    *hexutil.Big     `json:"reward"`
+}
+
+// SignerUptime is the sealing activity of a signer over a block range.
+type SignerUptime struct {
+	Sealed hexutil.Uint64 `json:"sealed"` // Number of blocks sealed by the signer
+	Missed hexutil.Uint64 `json:"missed"` // Number of slots missed by the signer
+	Uptime float64        `json:"uptime"` // Ratio of the signer's slots that were sealed
+}
+
+// PublicSignerHistoryAPI provides an API to query the signer history of a signer
+// queue based consensus engine.
+type PublicSignerHistoryAPI struct {
+	history *SignerHistory
+}
+
+// NewPublicSignerHistoryAPI creates a new signer history API.
+func NewPublicSignerHistoryAPI(history *SignerHistory) *PublicSignerHistoryAPI {
+	return &PublicSignerHistoryAPI{history}
+}
+
+// GetSealRecord returns the signer, signer queue, missed slots and reward of the
+// given block.
+func (api *PublicSignerHistoryAPI) GetSealRecord(blockNr rpc.BlockNumber) (*RPCSealRecord, error) {
+	number, err := api.resolve(blockNr)
+	if err != nil {
+		return nil, err
+	}
+	record, hash, err := api.history.Record(number)
+	if err != nil {
+		return nil, err
+	}
+	return &RPCSealRecord{
+		Number:    hexutil.Uint64(number),
+		Hash:      hash,
+		Signer:    record.Signer,
+		LoopStart: hexutil.Uint64(record.LoopStart),
+		Queue:     record.Queue,
+		Missing:   record.Missing,
+		Reward:    (*hexutil.Big)(record.Reward),
+	}, nil
+}
+
+// GetSignerUptime returns the number of sealed blocks and missed slots of every
+// signer active in the inclusive block range.
+func (api *PublicSignerHistoryAPI) GetSignerUptime(from, to rpc.BlockNumber) (map[common.Address]*SignerUptime, error) {
+	stats, err := api.stats(from, to)
+	if err != nil {
+		return nil, err
+	}
+	uptimes := make(map[common.Address]*SignerUptime, len(stats))
+	for _, s := range stats {
+		uptime := &SignerUptime{Sealed: hexutil.Uint64(s.Sealed), Missed: hexutil.Uint64(s.Missed)}
+		if slots := s.Sealed + s.Missed; slots > 0 {
+			uptime.Uptime = float64(s.Sealed) / float64(slots)
+		}
+		uptimes[s.Signer] = uptime
+	}
+	return uptimes, nil
+}
+
+// GetSignerPayouts returns the total block reward minted for the blocks of every
+// signer active in the inclusive block range, before sharing it with the voters.
+func (api *PublicSignerHistoryAPI) GetSignerPayouts(from, to rpc.BlockNumber) (map[common.Address]*hexutil.Big, error) {
+	stats, err := api.stats(from, to)
+	if err != nil {
+		return nil, err
+	}
+	payouts := make(map[common.Address]*hexutil.Big, len(stats))
+	for _, s := range stats {
+		payouts[s.Signer] = (*hexutil.Big)(s.Reward)
+	}
+	return payouts, nil
+}
+
+// stats resolves the block range and accumulates the signer statistics over it.
+func (api *PublicSignerHistoryAPI) stats(from, to rpc.BlockNumber) ([]*types.SignerStats, error) {
+	begin, err := api.resolve(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := api.resolve(to)
+	if err != nil {
+		return nil, err
+	}
+	return api.history.Stats(begin, end)
+}
+
+// resolve converts a block number into an absolute one, mapping the latest and
+// pending tags onto the local head.
+func (api *PublicSignerHistoryAPI) resolve(blockNr rpc.BlockNumber) (uint64, error) {
+	switch {
+	case blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber:
+		return api.history.Head(), nil
+	case blockNr < 0:
+		return 0, errSignerHistoryTag
+	}
+	return uint64(blockNr), nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlz
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/core"
+	"github.com/relianz2019/relianz/core/rawdb"
+	"github.com/relianz2019/relianz/core/types"
+	"github.com/relianz2019/relianz/rlzdb"
+)
+
+// testSealReader is a deterministic seal record reader rotating through a fixed
+// signer queue, with every fourth block skipping the slot of the next signer.
+type testSealReader struct {
+	queue  []common.Address
+	failed uint64 // Blocks below this number must be served from the index
+}
+
+func (r *testSealReader) SealRecord(header *types.Header) (*types.SealRecord, error) {
+	number := header.Number.Uint64()
+	if number < r.failed {
+		return nil, errors.New("indexed block requested from engine")
+	}
+	record := &types.SealRecord{
+		Signer:    r.queue[number%uint64(len(r.queue))],
+		LoopStart: number / uint64(len(r.queue)),
+		Queue:     r.queue,
+		Reward:    new(big.Int).SetUint64(number),
+	}
+	if number%4 == 0 {
+		record.Missing = []common.Address{r.queue[(number+1)%uint64(len(r.queue))]}
+	}
+	return record, nil
+}
+
+func TestSignerHistory(t *testing.T) {
+	const size = 8
+
+	var (
+		db     = rlzdb.NewMemDatabase()
+		reader = &testSealReader{queue: []common.Address{{0x01}, {0x02}, {0x03}}}
+		parent common.Hash
+	)
+	var headers []*types.Header
+	for i := 0; i < 3*size+5; i++ {
+		header := &types.Header{ParentHash: parent, Number: big.NewInt(int64(i))}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+		headers = append(headers, header)
+		parent = header.Hash()
+	}
+	rawdb.WriteHeadHeaderHash(db, parent)
+
+	// Index the first two sections, after which the engine must not be asked for them
+	backend := &SignerIndexer{db: db, reader: reader}
+	indexer := core.NewChainIndexer(db, rlzdb.NewTable(db, string(rawdb.SignerIndexPrefix)), backend, size, 0, 0, "signers")
+	defer indexer.Close()
+
+	for section := uint64(0); section < 2; section++ {
+		if err := backend.Reset(section, common.Hash{}); err != nil {
+			t.Fatalf("section %d: failed to reset: %v", section, err)
+		}
+		for _, header := range headers[section*size : (section+1)*size] {
+			backend.Process(header)
+		}
+		if err := backend.Commit(); err != nil {
+			t.Fatalf("section %d: failed to commit: %v", section, err)
+		}
+		indexer.AddKnownSectionHead(section, headers[(section+1)*size-1].Hash())
+	}
+	// Calculate the expected statistics before forbidding engine access
+	expect := func(from, to uint64) []*types.SignerStats {
+		stats := make(map[common.Address]*types.SignerStats)
+		for _, header := range headers[from : to+1] {
+			if header.Number.Sign() > 0 {
+				record, _ := reader.SealRecord(header)
+				accumulateSealRecord(stats, record)
+			}
+		}
+		return sortSignerStats(stats)
+	}
+	ranges := [][2]uint64{{0, 2*size - 1}, {3, 2*size + 4}, {size, 3*size + 4}, {5, 5}}
+	wants := make([][]*types.SignerStats, len(ranges))
+	for i, r := range ranges {
+		wants[i] = expect(r[0], r[1])
+	}
+	reader.failed = 2 * size
+
+	history := NewSignerHistory(db, indexer, reader, size)
+	for i, r := range ranges {
+		have, err := history.Stats(r[0], r[1])
+		if err != nil {
+			t.Errorf("range %d-%d: failed to accumulate stats: %v", r[0], r[1], err)
+			continue
+		}
+		if !equalSignerStats(have, wants[i]) {
+			t.Errorf("range %d-%d: stats mismatch: have %v, want %v", r[0], r[1], have, wants[i])
+		}
+	}
+	// Indexed records must carry the signer queue of their loop
+	record, hash, err := history.Record(size + 1)
+	if err != nil {
+		t.Fatalf("failed to retrieve indexed record: %v", err)
+	}
+	if hash != headers[size+1].Hash() || record.Signer != reader.queue[(size+1)%3] || !reflect.DeepEqual(record.Queue, reader.queue) {
+		t.Errorf("indexed record mismatch: have %x %+v", hash, record)
+	}
+	if head := history.Head(); head != uint64(len(headers)-1) {
+		t.Errorf("head mismatch: have %d, want %d", head, len(headers)-1)
+	}
+	if _, err := history.Stats(10, 5); err != errSignerHistoryRange {
+		t.Errorf("inverted range error mismatch: have %v, want %v", err, errSignerHistoryRange)
+	}
+}
+
+// equalSignerStats compares two signer statistics lists by value.
+func equalSignerStats(a, b []*types.SignerStats) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Signer != b[i].Signer || a[i].Sealed != b[i].Sealed || a[i].Missed != b[i].Missed || a[i].Reward.Cmp(b[i].Reward) != 0 {
+			return false
+		}
+	}
+	return true
+}
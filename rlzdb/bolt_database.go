@@ -0,0 +1,337 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/relianz2019/relianz/common"
+	"github.com/relianz2019/relianz/log"
+	"go.etcd.io/bbolt"
+)
+
+const (
+	// boltFile is the name of the data file of a bolt database in its directory.
+	boltFile = "bolt.db"
+
+	// boltChunkSize is the number of keys read by an iterator or deleted by a
+	// range deletion within a single transaction.
+	boltChunkSize = 1024
+)
+
+var (
+	// boltBucket is the bucket all the key/value pairs of a database live in.
+	boltBucket = []byte("relianz")
+
+	// boltKeyPrefix is prepended to all the keys stored in bolt, as bolt rejects
+	// the empty keys the other engines accept.
+	boltKeyPrefix = []byte{0x00}
+
+	// errBoltNotFound is returned if a key is requested that is not found in
+	// the bolt database.
+	errBoltNotFound = errors.New("not found")
+)
+
+// BoltDatabase is a persistent database backed by bolt, a pure Go B+tree store
+// keeping all the data in a single memory mapped file.
+type BoltDatabase struct {
+	fn string    // filename for reporting
+	db *bbolt.DB // Bolt instance
+
+	log log.Logger // Contextual logger tracking the database path
+}
+
+// NewBoltDatabase opens the bolt database in the given directory, creating it
+// if it doesn't exist yet.
+func NewBoltDatabase(dir string) (*BoltDatabase, error) {
+	logger := log.New("database", dir)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	db, err := bbolt.Open(filepath.Join(dir, boltFile), 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	logger.Info("Opened bolt database")
+
+	return &BoltDatabase{
+		fn:  dir,
+		db:  db,
+		log: logger,
+	}, nil
+}
+
+// Path returns the path to the database directory.
+func (db *BoltDatabase) Path() string {
+	return db.fn
+}
+
+// Put inserts the given value into the database.
+func (db *BoltDatabase) Put(key []byte, value []byte) error {
+	return db.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltBucket).Put(boltKey(key), value)
+	})
+}
+
+// Has retrieves if a key is present in the database.
+func (db *BoltDatabase) Has(key []byte) (bool, error) {
+	var has bool
+	err := db.db.View(func(tx *bbolt.Tx) error {
+		has = tx.Bucket(boltBucket).Get(boltKey(key)) != nil
+		return nil
+	})
+	return has, err
+}
+
+// Get retrieves the given key if it's present in the database.
+func (db *BoltDatabase) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := db.db.View(func(tx *bbolt.Tx) error {
+		// Values are only valid during the transaction, copy them out
+		if v := tx.Bucket(boltBucket).Get(boltKey(key)); v != nil {
+			value = common.CopyBytes(v)
+			return nil
+		}
+		return errBoltNotFound
+	})
+	return value, err
+}
+
+// Delete removes the key from the database.
+func (db *BoltDatabase) Delete(key []byte) error {
+	return db.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete(boltKey(key))
+	})
+}
+
+// DeleteRange deletes all the keys in the range [start, end), a chunk of keys
+// per transaction to keep the transactions small.
+func (db *BoltDatabase) DeleteRange(start, end []byte) error {
+	if start = boltKey(start); end != nil {
+		end = boltKey(end)
+	}
+	for done := false; !done; {
+		err := db.db.Update(func(tx *bbolt.Tx) error {
+			var (
+				bucket = tx.Bucket(boltBucket)
+				cursor = bucket.Cursor()
+				keys   [][]byte
+			)
+			k, _ := cursor.Seek(start)
+			for ; k != nil && (end == nil || bytes.Compare(k, end) < 0) && len(keys) < boltChunkSize; k, _ = cursor.Next() {
+				keys = append(keys, common.CopyBytes(k))
+			}
+			// Deleting while moving the cursor skips keys, delete afterwards
+			for _, key := range keys {
+				if err := bucket.Delete(key); err != nil {
+					return err
+				}
+			}
+			done = len(keys) < boltChunkSize
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewIterator creates an iterator over the entire database.
+func (db *BoltDatabase) NewIterator() Iterator {
+	return db.NewIteratorWithPrefix(nil)
+}
+
+// NewIteratorWithPrefix creates an iterator over the database content with a
+// particular key prefix.
+//
+// The iterator reads the database in chunks, each in its own transaction, so
+// that iterating never holds up writers. Unlike LevelDB iterators, it doesn't
+// operate on a snapshot: writes made during iteration may or may not be seen.
+func (db *BoltDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
+	return &boltIterator{
+		db:     db.db,
+		prefix: boltKey(prefix),
+		next:   boltKey(prefix),
+		index:  -1,
+	}
+}
+
+// NewIteratorWithStart creates an iterator over the database content with a
+// particular key prefix, starting at a particular key after the prefix. It has
+// the same consistency as NewIteratorWithPrefix.
+func (db *BoltDatabase) NewIteratorWithStart(prefix, start []byte) Iterator {
+	return &boltIterator{
+		db:     db.db,
+		prefix: boltKey(prefix),
+		next:   append(boltKey(prefix), start...),
+		index:  -1,
+	}
+}
+
+// Close closes the database file.
+func (db *BoltDatabase) Close() {
+	if err := db.db.Close(); err == nil {
+		db.log.Info("Database closed")
+	} else {
+		db.log.Error("Failed to close database", "err", err)
+	}
+}
+
+// NewBatch creates a write-only batch to be committed in a single transaction.
+func (db *BoltDatabase) NewBatch() Batch {
+	return &boltBatch{db: db.db}
+}
+
+// boltKey returns the key a database key is stored under in bolt.
+func boltKey(key []byte) []byte {
+	return append(common.CopyBytes(boltKeyPrefix), key...)
+}
+
+// boltIterator iterates over the keys with a given prefix of a bolt database,
+// reading them a chunk at a time.
+type boltIterator struct {
+	db     *bbolt.DB
+	prefix []byte
+	next   []byte // Stored key to resume reading from
+	done   bool   // Whether all the keys with the prefix have been read
+
+	keys   [][]byte // Chunk of keys being iterated over
+	values [][]byte // Values of the chunk of keys being iterated over
+	index  int      // Position of the iterator within the chunk
+	err    error
+}
+
+// Next moves the iterator to the next key/value pair, reading in the next chunk
+// of the database if the current one is exhausted.
+func (it *boltIterator) Next() bool {
+	if it.index+1 < len(it.keys) {
+		it.index++
+		return true
+	}
+	it.keys, it.values, it.index = nil, nil, -1
+	if it.done || it.err != nil {
+		return false
+	}
+	it.err = it.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(boltBucket).Cursor()
+
+		k, v := cursor.Seek(it.next)
+		for ; k != nil && bytes.HasPrefix(k, it.prefix); k, v = cursor.Next() {
+			if len(it.keys) == boltChunkSize {
+				it.next = common.CopyBytes(k)
+				return nil
+			}
+			it.keys = append(it.keys, common.CopyBytes(k[len(boltKeyPrefix):]))
+			it.values = append(it.values, common.CopyBytes(v))
+		}
+		it.done = true
+		return nil
+	})
+	if it.err != nil || len(it.keys) == 0 {
+		it.done = true
+		return false
+	}
+	it.index = 0
+	return true
+}
+
+// Error returns any accumulated error.
+func (it *boltIterator) Error() error {
+	return it.err
+}
+
+// Key returns the key of the current key/value pair, or nil if done.
+func (it *boltIterator) Key() []byte {
+	if it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	return it.keys[it.index]
+}
+
+// Value returns the value of the current key/value pair, or nil if done.
+func (it *boltIterator) Value() []byte {
+	if it.index < 0 || it.index >= len(it.values) {
+		return nil
+	}
+	return it.values[it.index]
+}
+
+// Release releases the chunk read by the iterator.
+func (it *boltIterator) Release() {
+	it.keys, it.values, it.index = nil, nil, -1
+	it.done = true
+}
+
+// boltBatch is a write-only batch committing to a bolt database in a single
+// transaction.
+type boltBatch struct {
+	db     *bbolt.DB
+	writes []kv
+	size   int
+}
+
+func (b *boltBatch) Put(key, value []byte) error {
+	b.writes = append(b.writes, kv{k: boltKey(key), v: common.CopyBytes(value)})
+	b.size += len(value)
+	return nil
+}
+
+func (b *boltBatch) Delete(key []byte) error {
+	b.writes = append(b.writes, kv{k: boltKey(key), del: true})
+	b.size += len(key)
+	return nil
+}
+
+func (b *boltBatch) Write() error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, kv := range b.writes {
+			if kv.del {
+				if err := bucket.Delete(kv.k); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := bucket.Put(kv.k, kv.v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *boltBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *boltBatch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
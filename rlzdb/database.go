@@ -23,12 +23,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/relianz2019/relianz/common"
 	"github.com/relianz2019/relianz/log"
 	"github.com/relianz2019/relianz/metrics"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
 	"github.com/syndtr/goleveldb/leveldb/filter"
-	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
@@ -121,15 +121,49 @@ func (db *LDBDatabase) Delete(key []byte) error {
 	return db.db.Delete(key, nil)
 }
 
-func (db *LDBDatabase) NewIterator() iterator.Iterator {
+func (db *LDBDatabase) NewIterator() Iterator {
 	return db.db.NewIterator(nil, nil)
 }
 
 // NewIteratorWithPrefix returns a iterator to iterate over subset of database content with a particular prefix.
-func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
 	return db.db.NewIterator(util.BytesPrefix(prefix), nil)
 }
 
+// NewIteratorWithStart returns a iterator to iterate over subset of database content
+// with a particular prefix, starting at a particular key after the prefix.
+func (db *LDBDatabase) NewIteratorWithStart(prefix, start []byte) Iterator {
+	r := util.BytesPrefix(prefix)
+	r.Start = append(common.CopyBytes(prefix), start...)
+	return db.db.NewIterator(r, nil)
+}
+
+// DeleteRange deletes all the keys in the range [start, end), flushing the
+// deletions in batches of ideal size.
+func (db *LDBDatabase) DeleteRange(start, end []byte) error {
+	it := db.db.NewIterator(&util.Range{Start: start, Limit: end}, nil)
+	defer it.Release()
+
+	var (
+		batch = new(leveldb.Batch)
+		size  int
+	)
+	for it.Next() {
+		batch.Delete(it.Key())
+		if size += len(it.Key()); size >= IdealBatchSize {
+			if err := db.db.Write(batch, nil); err != nil {
+				return err
+			}
+			batch.Reset()
+			size = 0
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return db.db.Write(batch, nil)
+}
+
 func (db *LDBDatabase) Close() {
 	// Stop the metrics collection to avoid internal database races
 	db.quitLock.Lock()
@@ -382,6 +416,12 @@ func (b *ldbBatch) Put(key, value []byte) error {
 	return nil
 }
 
+func (b *ldbBatch) Delete(key []byte) error {
+	b.b.Delete(key)
+	b.size += len(key)
+	return nil
+}
+
 func (b *ldbBatch) Write() error {
 	return b.db.Write(b.b, nil)
 }
@@ -425,6 +465,33 @@ func (dt *table) Delete(key []byte) error {
 	return dt.db.Delete(append([]byte(dt.prefix), key...))
 }
 
+func (dt *table) NewIterator() Iterator {
+	return dt.NewIteratorWithPrefix(nil)
+}
+
+func (dt *table) NewIteratorWithPrefix(prefix []byte) Iterator {
+	return &tableIterator{
+		Iterator: dt.db.NewIteratorWithPrefix(append([]byte(dt.prefix), prefix...)),
+		prefix:   len(dt.prefix),
+	}
+}
+
+func (dt *table) NewIteratorWithStart(prefix, start []byte) Iterator {
+	return &tableIterator{
+		Iterator: dt.db.NewIteratorWithStart(append([]byte(dt.prefix), prefix...), start),
+		prefix:   len(dt.prefix),
+	}
+}
+
+func (dt *table) DeleteRange(start, end []byte) error {
+	// An unbounded end is bounded by the end of the table
+	limit := util.BytesPrefix([]byte(dt.prefix)).Limit
+	if end != nil {
+		limit = append([]byte(dt.prefix), end...)
+	}
+	return dt.db.DeleteRange(append([]byte(dt.prefix), start...), limit)
+}
+
 func (dt *table) Close() {
 	// Do nothing; don't close the underlying DB.
 }
@@ -447,6 +514,10 @@ func (tb *tableBatch) Put(key, value []byte) error {
 	return tb.batch.Put(append([]byte(tb.prefix), key...), value)
 }
 
+func (tb *tableBatch) Delete(key []byte) error {
+	return tb.batch.Delete(append([]byte(tb.prefix), key...))
+}
+
 func (tb *tableBatch) Write() error {
 	return tb.batch.Write()
 }
@@ -458,3 +529,16 @@ func (tb *tableBatch) ValueSize() int {
 func (tb *tableBatch) Reset() {
 	tb.batch.Reset()
 }
+
+// tableIterator strips the table prefix off the keys of the iterated database.
+type tableIterator struct {
+	Iterator
+	prefix int
+}
+
+func (it *tableIterator) Key() []byte {
+	if key := it.Iterator.Key(); key != nil {
+		return key[it.prefix:]
+	}
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strconv"
 	"sync"
 	"testing"
@@ -44,6 +45,22 @@ func newTestLDB() (*ethdb.LDBDatabase, func()) {
 	}
 }
 
+func newTestBolt() (*ethdb.BoltDatabase, func()) {
+	dirname, err := ioutil.TempDir(os.TempDir(), "ethdb_test_")
+	if err != nil {
+		panic("failed to create test file: " + err.Error())
+	}
+	db, err := ethdb.NewBoltDatabase(dirname)
+	if err != nil {
+		panic("failed to create test database: " + err.Error())
+	}
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dirname)
+	}
+}
+
 var test_values = []string{"", "a", "1251", "\x00123\x00"}
 
 func TestLDB_PutGet(t *testing.T) {
@@ -56,6 +73,12 @@ func TestMemoryDB_PutGet(t *testing.T) {
 	testPutGet(ethdb.NewMemDatabase(), t)
 }
 
+func TestBolt_PutGet(t *testing.T) {
+	db, remove := newTestBolt()
+	defer remove()
+	testPutGet(db, t)
+}
+
 func testPutGet(db ethdb.Database, t *testing.T) {
 	t.Parallel()
 
@@ -133,6 +156,12 @@ func TestMemoryDB_ParallelPutGet(t *testing.T) {
 	testParallelPutGet(ethdb.NewMemDatabase(), t)
 }
 
+func TestBolt_ParallelPutGet(t *testing.T) {
+	db, remove := newTestBolt()
+	defer remove()
+	testParallelPutGet(db, t)
+}
+
 func testParallelPutGet(db ethdb.Database, t *testing.T) {
 	const n = 8
 	var pending sync.WaitGroup
@@ -188,3 +217,98 @@ func testParallelPutGet(db ethdb.Database, t *testing.T) {
 	}
 	pending.Wait()
 }
+
+func TestLDB_Iterate(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+	testIterate(db, t)
+}
+
+func TestMemoryDB_Iterate(t *testing.T) {
+	testIterate(ethdb.NewMemDatabase(), t)
+}
+
+func TestBolt_Iterate(t *testing.T) {
+	db, remove := newTestBolt()
+	defer remove()
+	testIterate(db, t)
+}
+
+func TestTable_Iterate(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	db.Put([]byte("s"), []byte("outside"))
+	db.Put([]byte("u"), []byte("outside"))
+
+	testIterate(ethdb.NewTable(db, "t"), t)
+
+	// Data outside the table must be left alone
+	if n := db.Len(); n != 2 {
+		t.Errorf("database size mismatch after table deletions: have %d, want %d", n, 2)
+	}
+}
+
+// testIterate checks iteration, batched deletions and range deletions, with
+// enough keys to span several chunks of chunked iterators.
+func testIterate(db ethdb.Database, t *testing.T) {
+	var keys []string
+	for i := 0; i < 3000; i++ {
+		keys = append(keys, fmt.Sprintf("%c%04d", 'a'+i%3, i))
+	}
+	batch := db.NewBatch()
+	for _, key := range keys {
+		batch.Put([]byte(key), []byte("v"+key))
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("batch write failed: %v", err)
+	}
+	sort.Strings(keys)
+
+	// Iterate over all the keys and a prefixed subset of them
+	check := func(it ethdb.Iterator, want []string) {
+		defer it.Release()
+
+		var have []string
+		for it.Next() {
+			if !bytes.Equal(it.Value(), []byte("v"+string(it.Key()))) {
+				t.Fatalf("value mismatch for %q: have %q", it.Key(), it.Value())
+			}
+			have = append(have, string(it.Key()))
+		}
+		if err := it.Error(); err != nil {
+			t.Fatalf("iteration failed: %v", err)
+		}
+		if len(have) != len(want) {
+			t.Fatalf("iterated key count mismatch: have %d, want %d", len(have), len(want))
+		}
+		for i := range have {
+			if have[i] != want[i] {
+				t.Fatalf("iterated key %d mismatch: have %q, want %q", i, have[i], want[i])
+			}
+		}
+	}
+	check(db.NewIterator(), keys)
+	check(db.NewIteratorWithPrefix([]byte("b")), keys[1000:2000])
+	check(db.NewIteratorWithPrefix([]byte("d")), nil)
+	check(db.NewIteratorWithStart([]byte("b"), []byte("2")), keys[1667:2000])
+	check(db.NewIteratorWithStart([]byte("c"), []byte("9")), nil)
+
+	// Delete a single key through a batch and a range of keys
+	batch.Reset()
+	batch.Delete([]byte(keys[0]))
+	if err := batch.Write(); err != nil {
+		t.Fatalf("batch delete failed: %v", err)
+	}
+	if err := db.DeleteRange([]byte("b"), []byte("c")); err != nil {
+		t.Fatalf("range delete failed: %v", err)
+	}
+	check(db.NewIterator(), append(append([]string{}, keys[1:1000]...), keys[2000:]...))
+
+	if err := db.DeleteRange([]byte("a0500"), nil); err != nil {
+		t.Fatalf("unbounded range delete failed: %v", err)
+	}
+	check(db.NewIterator(), keys[1:167])
+	if err := db.DeleteRange(nil, nil); err != nil {
+		t.Fatalf("full range delete failed: %v", err)
+	}
+	check(db.NewIterator(), nil)
+}
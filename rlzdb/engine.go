@@ -0,0 +1,77 @@
+// Copyright 2019 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/relianz2019/relianz/log"
+)
+
+// Engines persistent databases can be backed by.
+const (
+	EngineLevelDB = "leveldb"
+	EngineBolt    = "bolt"
+)
+
+// Engines lists all the supported database engines.
+var Engines = []string{EngineLevelDB, EngineBolt}
+
+// DetectEngine returns the engine of the persistent database in the given
+// directory, or an empty string if there is none.
+func DetectEngine(dir string) string {
+	if _, err := os.Stat(filepath.Join(dir, boltFile)); err == nil {
+		return EngineBolt
+	}
+	if _, err := os.Stat(filepath.Join(dir, "CURRENT")); err == nil {
+		return EngineLevelDB
+	}
+	return ""
+}
+
+// NewDatabase opens the persistent database in the given directory. A new
+// database is created with the requested engine, defaulting to LevelDB, while
+// an existing one is always opened with the engine it was created with. The
+// cache and handles allowances only apply to LevelDB.
+func NewDatabase(engine string, dir string, cache int, handles int) (Database, error) {
+	if existing := DetectEngine(dir); existing != "" {
+		if engine != "" && engine != existing {
+			log.Warn("Opening database with its original engine", "database", dir, "engine", existing, "requested", engine)
+		}
+		engine = existing
+	}
+	switch engine {
+	case "", EngineLevelDB:
+		db, err := NewLDBDatabase(dir, cache, handles)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+
+	case EngineBolt:
+		db, err := NewBoltDatabase(dir)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+
+	default:
+		return nil, fmt.Errorf("unknown database engine %q", engine)
+	}
+}
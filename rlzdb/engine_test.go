@@ -0,0 +1,100 @@
+// Copyright 2014 The go-relianz Authors
+// This file is part of the go-relianz library.
+//
+// The go-relianz library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-relianz library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-relianz library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/relianz2019/relianz/ethdb"
+)
+
+// Tests that the engine of a database directory is detected from its files.
+func TestDetectEngine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethdb_test_")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if engine := ethdb.DetectEngine(filepath.Join(dir, "missing")); engine != "" {
+		t.Errorf("missing database engine mismatch: have %q, want none", engine)
+	}
+	for _, engine := range ethdb.Engines {
+		path := filepath.Join(dir, engine)
+		db, err := ethdb.NewDatabase(engine, path, 0, 0)
+		if err != nil {
+			t.Fatalf("failed to create %s database: %v", engine, err)
+		}
+		db.Close()
+
+		if have := ethdb.DetectEngine(path); have != engine {
+			t.Errorf("detected engine mismatch: have %q, want %q", have, engine)
+		}
+	}
+}
+
+// Tests that new databases are created with the requested engine, defaulting to
+// LevelDB, while existing ones are reopened with their original engine.
+func TestNewDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethdb_test_")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		create string // Engine requested on creation
+		reopen string // Engine requested on reopen
+		want   string // Engine the database must use
+	}{
+		{"", ethdb.EngineBolt, ethdb.EngineLevelDB},
+		{ethdb.EngineLevelDB, ethdb.EngineBolt, ethdb.EngineLevelDB},
+		{ethdb.EngineBolt, ethdb.EngineLevelDB, ethdb.EngineBolt},
+		{ethdb.EngineBolt, "", ethdb.EngineBolt},
+	}
+	for i, tt := range tests {
+		path := filepath.Join(dir, fmt.Sprintf("db%d", i))
+
+		db, err := ethdb.NewDatabase(tt.create, path, 0, 0)
+		if err != nil {
+			t.Fatalf("test %d: failed to create database: %v", i, err)
+		}
+		if err := db.Put([]byte("key"), []byte("value")); err != nil {
+			t.Fatalf("test %d: failed to write database: %v", i, err)
+		}
+		db.Close()
+
+		if db, err = ethdb.NewDatabase(tt.reopen, path, 0, 0); err != nil {
+			t.Fatalf("test %d: failed to reopen database: %v", i, err)
+		}
+		if value, err := db.Get([]byte("key")); err != nil || string(value) != "value" {
+			t.Errorf("test %d: reopened value mismatch: have %q (%v), want %q", i, value, err, "value")
+		}
+		db.Close()
+
+		if engine := ethdb.DetectEngine(path); engine != tt.want {
+			t.Errorf("test %d: engine mismatch: have %q, want %q", i, engine, tt.want)
+		}
+	}
+	if _, err := ethdb.NewDatabase("unknown", filepath.Join(dir, "unknown"), 0, 0); err == nil {
+		t.Errorf("unknown engine accepted")
+	}
+}
@@ -25,12 +25,60 @@ type Putter interface {
 	Put(key []byte, value []byte) error
 }
 
+// Deleter wraps the database delete operation supported by both batches and regular databases.
+type Deleter interface {
+	Delete(key []byte) error
+}
+
+// Iterator iterates over a database's key/value pairs in ascending key order.
+// The key and value slices are only valid until the next call to Next, and an
+// iterator must be released after use. Iterators cannot be used concurrently.
+type Iterator interface {
+	// Next moves the iterator to the next key/value pair. It returns whether the
+	// iterator is exhausted.
+	Next() bool
+
+	// Error returns any accumulated error. Exhausting all the key/value pairs
+	// is not considered to be an error.
+	Error() error
+
+	// Key returns the key of the current key/value pair, or nil if done.
+	Key() []byte
+
+	// Value returns the value of the current key/value pair, or nil if done.
+	Value() []byte
+
+	// Release releases associated resources.
+	Release()
+}
+
+// Iteratee wraps the iterator creation operations of a database.
+type Iteratee interface {
+	// NewIterator creates an iterator over the entire keyspace of the database.
+	NewIterator() Iterator
+
+	// NewIteratorWithPrefix creates an iterator over the subset of the database
+	// content with a particular key prefix.
+	NewIteratorWithPrefix(prefix []byte) Iterator
+
+	// NewIteratorWithStart creates an iterator over the subset of the database
+	// content with a particular key prefix, starting at a particular key given
+	// relative to the prefix.
+	NewIteratorWithStart(prefix, start []byte) Iterator
+}
+
 // Database wraps all database operations. All methods are safe for concurrent use.
 type Database interface {
 	Putter
+	Deleter
+	Iteratee
 	Get(key []byte) ([]byte, error)
 	Has(key []byte) (bool, error)
-	Delete(key []byte) error
+
+	// DeleteRange deletes all the keys in the range [start, end). A nil start
+	// or end leaves the range unbounded on that side.
+	DeleteRange(start, end []byte) error
+
 	Close()
 	NewBatch() Batch
 }
@@ -39,6 +87,7 @@ type Database interface {
 // when Write is called. Batch cannot be used concurrently.
 type Batch interface {
 	Putter
+	Deleter
 	ValueSize() int // amount of data in the batch
 	Write() error
 	// Reset resets the batch for reuse
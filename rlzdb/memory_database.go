@@ -18,6 +18,8 @@ package ethdb
 
 import (
 	"errors"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/relianz2019/relianz/common"
@@ -88,6 +90,53 @@ func (db *MemDatabase) Delete(key []byte) error {
 	return nil
 }
 
+// DeleteRange deletes all the keys in the range [start, end).
+func (db *MemDatabase) DeleteRange(start, end []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	for key := range db.db {
+		if key >= string(start) && (end == nil || key < string(end)) {
+			delete(db.db, key)
+		}
+	}
+	return nil
+}
+
+// NewIterator creates an iterator over a snapshot of the entire database.
+func (db *MemDatabase) NewIterator() Iterator {
+	return db.NewIteratorWithPrefix(nil)
+}
+
+// NewIteratorWithPrefix creates an iterator over a snapshot of the database
+// content with a particular key prefix.
+func (db *MemDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
+	return db.NewIteratorWithStart(prefix, nil)
+}
+
+// NewIteratorWithStart creates an iterator over a snapshot of the database
+// content with a particular key prefix, starting at a particular key after it.
+func (db *MemDatabase) NewIteratorWithStart(prefix, start []byte) Iterator {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	first := string(prefix) + string(start)
+
+	var keys []string
+	for key := range db.db {
+		if strings.HasPrefix(key, string(prefix)) && key >= first {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = common.CopyBytes(db.db[key])
+	}
+	return &memIterator{keys: keys, values: values, index: -1}
+}
+
 func (db *MemDatabase) Close() {}
 
 func (db *MemDatabase) NewBatch() Batch {
@@ -96,7 +145,10 @@ func (db *MemDatabase) NewBatch() Batch {
 
 func (db *MemDatabase) Len() int { return len(db.db) }
 
-type kv struct{ k, v []byte }
+type kv struct {
+	k, v []byte
+	del  bool
+}
 
 type memBatch struct {
 	db     *MemDatabase
@@ -105,16 +157,26 @@ type memBatch struct {
 }
 
 func (b *memBatch) Put(key, value []byte) error {
-	b.writes = append(b.writes, kv{common.CopyBytes(key), common.CopyBytes(value)})
+	b.writes = append(b.writes, kv{k: common.CopyBytes(key), v: common.CopyBytes(value)})
 	b.size += len(value)
 	return nil
 }
 
+func (b *memBatch) Delete(key []byte) error {
+	b.writes = append(b.writes, kv{k: common.CopyBytes(key), del: true})
+	b.size += len(key)
+	return nil
+}
+
 func (b *memBatch) Write() error {
 	b.db.lock.Lock()
 	defer b.db.lock.Unlock()
 
 	for _, kv := range b.writes {
+		if kv.del {
+			delete(b.db.db, string(kv.k))
+			continue
+		}
 		b.db.db[string(kv.k)] = kv.v
 	}
 	return nil
@@ -128,3 +190,40 @@ func (b *memBatch) Reset() {
 	b.writes = b.writes[:0]
 	b.size = 0
 }
+
+// memIterator iterates over a sorted snapshot of a memory database.
+type memIterator struct {
+	keys   []string
+	values [][]byte
+	index  int
+}
+
+func (it *memIterator) Next() bool {
+	if it.index >= len(it.keys) {
+		return false
+	}
+	it.index++
+	return it.index < len(it.keys)
+}
+
+func (it *memIterator) Error() error {
+	return nil
+}
+
+func (it *memIterator) Key() []byte {
+	if it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	return []byte(it.keys[it.index])
+}
+
+func (it *memIterator) Value() []byte {
+	if it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	return it.values[it.index]
+}
+
+func (it *memIterator) Release() {
+	it.keys, it.values = nil, nil
+}